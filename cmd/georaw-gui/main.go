@@ -6,6 +6,7 @@ import (
 
 	"github.com/nir0k/GeoRAW/frontend"
 	"github.com/nir0k/GeoRAW/internal/gui"
+	"github.com/nir0k/GeoRAW/internal/logging"
 	"github.com/wailsapp/wails/v2"
 	wlogger "github.com/wailsapp/wails/v2/pkg/logger"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -20,6 +21,12 @@ func main() {
 		}
 	}
 
+	if settings, err := logging.SettingsFromEnv(); err != nil {
+		log.Printf("georaw: ignoring the log settings in the environment: %v", err)
+	} else {
+		_ = logging.Configure(settings)
+	}
+
 	app := &gui.Backend{}
 
 	err := wails.Run(&options.App{
@@ -31,6 +38,7 @@ func main() {
 		Windows:     &windows.Options{DisableWindowIcon: false}, // use embedded icon.ico by default
 		AssetServer: &assetserver.Options{Assets: frontend.Assets},
 		OnStartup:   app.OnStartup,
+		OnShutdown:  app.OnShutdown,
 		Bind:        []interface{}{app},
 		LogLevel:    wlogger.ERROR,
 	})
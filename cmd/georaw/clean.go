@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/spf13/pflag"
+)
+
+// runCleanSidecars implements the `georaw clean-sidecars` subcommand.
+func runCleanSidecars(args []string) error {
+	var opts app.Options
+	var common commonFlags
+	var inputs []string
+	var moveDir, sidecarDir string
+	var remove, apply bool
+
+	fs := pflag.NewFlagSet("clean-sidecars", pflag.ExitOnError)
+	addInputFlag(fs, &inputs, inputUsage)
+	fs.BoolVarP(&opts.Recursive, "recursive", "r", false, "Scan subdirectories when the input is a folder")
+	addSidecarDirFlag(fs, &sidecarDir)
+	fs.StringVarP(&opts.LogLevel, "log-level", "l", "info", "Logging level for both file and console outputs")
+	fs.StringVar(&opts.LogFile, "log-file", "", "Optional log file path (defaults to a file next to the binary)")
+	fs.StringVar(&moveDir, "move", "", "Move orphan sidecars into this folder, keeping their relative paths")
+	fs.BoolVar(&remove, "delete", false, "Delete orphan sidecars")
+	fs.BoolVar(&apply, "apply", false, "Actually move or delete; without it only the orphans are listed")
+	addLockFlag(fs, &opts.Lock)
+	addMaxFilesFlag(fs, &opts.MaxFiles)
+	addCommonFlags(fs, &common)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if moveDir != "" && remove {
+		return fmt.Errorf("--move and --delete cannot be combined")
+	}
+	if common.interactive {
+		return fmt.Errorf("--interactive is not supported; run without --apply to review the orphans first")
+	}
+	input, err := resolveInputs(inputs, os.Stdin)
+	if err != nil {
+		return err
+	}
+	opts.Inputs = input
+	if err := useSidecarDir(sidecarDir, input); err != nil {
+		return err
+	}
+	opts.PrintSummary = true
+
+	opts.OrphanAction = app.OrphanList
+	switch {
+	case !apply:
+		if moveDir != "" || remove {
+			fmt.Println("Dry run: add --apply to move or delete the orphans listed below.")
+		}
+	case moveDir != "":
+		opts.OrphanAction, opts.OrphanDir = app.OrphanMove, moveDir
+	case remove:
+		opts.OrphanAction = app.OrphanDelete
+	default:
+		return fmt.Errorf("--apply needs --move DIR or --delete")
+	}
+
+	return common.run("clean-sidecars", func() (*app.Summary, error) {
+		sum, err := app.CleanSidecars(context.Background(), opts)
+		if err == nil && opts.OrphanAction == app.OrphanList && !common.table {
+			for _, res := range sum.Files {
+				fmt.Println(res.Path)
+			}
+		}
+		return sum, err
+	})
+}
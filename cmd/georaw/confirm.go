@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+)
+
+// terminalConfirm prints the planned writes grouped by folder and asks whether to
+// write all of them, decide folder by folder, or abort.
+func terminalConfirm(in io.Reader, out io.Writer) app.ConfirmFunc {
+	answers := bufio.NewScanner(in)
+	ask := func(prompt string) string {
+		fmt.Fprint(out, prompt)
+		if !answers.Scan() {
+			fmt.Fprintln(out)
+			return "q"
+		}
+		return strings.ToLower(strings.TrimSpace(answers.Text()))
+	}
+
+	return func(plan []app.PlannedWrite) ([]app.PlannedWrite, error) {
+		dirs, byDir := groupByDir(plan)
+		for _, dir := range dirs {
+			printPlan(out, dir, byDir[dir])
+		}
+
+		for {
+			switch ask(fmt.Sprintf("Write %d sidecars in %d folders? [a]ll / [d]irectory by directory / [q]uit: ", len(plan), len(dirs))) {
+			case "a", "all", "y", "yes":
+				return plan, nil
+			case "q", "quit", "n", "no", "abort":
+				return nil, app.ErrAborted
+			case "d", "dir", "directory":
+				var approved []app.PlannedWrite
+				for _, dir := range dirs {
+				dirPrompt:
+					for {
+						switch ask(fmt.Sprintf("  %s (%d files)? [y]es / [n]o / [q]uit: ", dir, len(byDir[dir]))) {
+						case "y", "yes":
+							approved = append(approved, byDir[dir]...)
+							break dirPrompt
+						case "n", "no":
+							break dirPrompt
+						case "q", "quit", "abort":
+							return nil, app.ErrAborted
+						}
+					}
+				}
+				return approved, nil
+			}
+		}
+	}
+}
+
+func groupByDir(plan []app.PlannedWrite) ([]string, map[string][]app.PlannedWrite) {
+	byDir := make(map[string][]app.PlannedWrite)
+	for _, p := range plan {
+		dir := filepath.Dir(p.Path)
+		byDir[dir] = append(byDir[dir], p)
+	}
+	dirs := make([]string, 0, len(byDir))
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs, byDir
+}
+
+func printPlan(out io.Writer, dir string, items []app.PlannedWrite) {
+	fmt.Fprintf(out, "\n%s\n", dir)
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, p := range items {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", filepath.Base(p.Path), planPosition(p.Point), strings.Join(p.Tags, ", "))
+	}
+	tw.Flush()
+}
+
+func planPosition(pt *app.GeoPoint) string {
+	if pt == nil {
+		return ""
+	}
+	pos := fmt.Sprintf("%.6f, %.6f", pt.Latitude, pt.Longitude)
+	if pt.Altitude != nil {
+		pos += fmt.Sprintf("  %.1fm", *pt.Altitude)
+	}
+	if !pt.Time.IsZero() {
+		pos += "  " + pt.Time.Format(time.RFC3339)
+	}
+	return pos
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/spf13/pflag"
+)
+
+// runCSV implements the `georaw csv` subcommand.
+func runCSV(args []string) error {
+	var opts app.Options
+	var common commonFlags
+	var sidecarDir, input string
+
+	fs := pflag.NewFlagSet("csv", pflag.ExitOnError)
+	fs.StringVarP(&opts.CSVPath, "csv", "c", "", "CSV file with filename,lat,lon[,alt[,time]] rows")
+	fs.StringVarP(&input, "input", "i", "", "Folder to look up CSV file names in (defaults to the CSV's folder)")
+	fs.BoolVarP(&opts.Recursive, "recursive", "r", false, "Scan subdirectories when the input is a folder")
+	addSidecarDirFlag(fs, &sidecarDir)
+	fs.StringVarP(&opts.LogLevel, "log-level", "l", "info", "Logging level for both file and console outputs")
+	fs.StringVar(&opts.LogFile, "log-file", "", "Optional log file path (defaults to a file next to the binary)")
+	fs.DurationVar(&opts.TimeOffset, "time-offset", 0, "Offset added to capture times used for rows without a time column")
+	addOverwriteFlags(fs, &opts)
+	addGPSTimeFlag(fs, &opts.GPSTime)
+	addAltitudeFlags(fs, &opts.Altitude)
+	fs.StringVar(&opts.GeoJSONPath, "geojson", "", "Write a GeoJSON FeatureCollection of tagged photos (path, time, camera, series ID) to this file")
+	addUploadFlags(fs, &opts.Upload)
+	fs.IntVar(&opts.Workers, "workers", 0, "Number of parallel sidecar writers (defaults to the CPU count, up to 8)")
+	fs.BoolVar(&opts.StampRun, "stamp-run", false, "Write georaw:LastRunID/LastRunDate into every sidecar this run writes")
+	addAttributionFlags(fs, &opts.Attribution)
+	addDescriptionFlags(fs, &opts.Description)
+	addLockFlag(fs, &opts.Lock)
+	addReadOnlyFlags(fs, &opts.ReadOnly)
+	addEmbedGPSFlag(fs, &opts.EmbedGPS)
+	addTimeFallbackFlag(fs, &opts.TimeFallback)
+	addMaxFilesFlag(fs, &opts.MaxFiles)
+	addKeepResultsFlag(fs, &opts.KeepResults)
+	addCommonFlags(fs, &common)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts.PrintSummary = true
+	common.reportProgress("csv", &opts.Progress, &opts.ScanProgress)
+	if input != "" {
+		opts.Inputs = []string{input}
+	} else {
+		input = filepath.Dir(opts.CSVPath)
+	}
+	if err := useSidecarDir(sidecarDir, []string{input}); err != nil {
+		return err
+	}
+	confirm, err := common.confirm(nil)
+	if err != nil {
+		return err
+	}
+	opts.Confirm = confirm
+
+	return common.run("csv", func() (*app.Summary, error) {
+		return app.ImportCSV(context.Background(), opts)
+	})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/nir0k/GeoRAW/internal/doctor"
+	"github.com/spf13/pflag"
+)
+
+// runDoctor implements the `georaw doctor` subcommand.
+func runDoctor(args []string) error {
+	var opts app.Options
+	var inputs []string
+	var sidecarDir string
+
+	fs := pflag.NewFlagSet("doctor", pflag.ExitOnError)
+	fs.StringVarP(&opts.GPXPath, "gpx", "g", "", "Track to test-parse (GPX file, Garmin export folder, or strava:)")
+	addInputFlag(fs, &inputs, "Photo folder, file or glob whose sidecar folder must be writable (repeatable)")
+	addSidecarDirFlag(fs, &sidecarDir)
+	fs.StringVar(&opts.LogFile, "log-file", "", "Log file to check (defaults to a file next to the binary)")
+	fs.StringVar(&opts.TimeZone, "timezone", "", "Camera clock time zone to validate")
+	fs.Float64Var(&opts.MaxSpeed, "max-speed", app.DefaultMaxSpeed, "Speed guard threshold to validate")
+	addAltitudeFlags(fs, &opts.Altitude)
+	addOverwriteFlags(fs, &opts)
+	addGPSTimeFlag(fs, &opts.GPSTime)
+	addAttributionFlags(fs, &opts.Attribution)
+	addLockFlag(fs, &opts.Lock)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if len(inputs) > 0 {
+		input, err := resolveInputs(inputs, os.Stdin)
+		if err != nil {
+			return err
+		}
+		opts.Inputs = input
+		if err := useSidecarDir(sidecarDir, input); err != nil {
+			return err
+		}
+	}
+
+	checks := doctor.Run(context.Background(), opts)
+	printChecks(os.Stdout, checks)
+	if n := doctor.Failed(checks); n > 0 {
+		return fmt.Errorf("%d check(s) failed", n)
+	}
+	return nil
+}
+
+func printChecks(w io.Writer, checks []doctor.Check) {
+	for _, c := range checks {
+		fmt.Fprintf(w, "[%-4s] %-14s %s\n", c.Status, c.Name, c.Detail)
+		if c.Fix != "" && (c.Status == doctor.Warn || c.Status == doctor.Fail) {
+			fmt.Fprintf(w, "%21s %s\n", "fix:", c.Fix)
+		}
+	}
+}
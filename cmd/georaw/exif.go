@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/spf13/pflag"
+)
+
+// runExif implements the `georaw exif` subcommand: the GUI's EXIF viewer for the terminal.
+func runExif(args []string) error {
+	var asJSON, noXmp bool
+	var sidecarDir string
+
+	fs := pflag.NewFlagSet("exif", pflag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: georaw exif [flags] FILE...")
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&asJSON, "json", false, "Print the fields as JSON instead of a table")
+	fs.BoolVar(&noXmp, "no-xmp", false, "Leave out XMP and sidecar fields")
+	addSidecarDirFlag(fs, &sidecarDir)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no file given")
+	}
+	if err := useSidecarDir(sidecarDir, fs.Args()); err != nil {
+		return err
+	}
+
+	var all []*media.ExifDetails
+	for _, path := range fs.Args() {
+		details, err := media.ReadExifDetails(path, !noXmp)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		all = append(all, details)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if len(all) == 1 {
+			return enc.Encode(all[0])
+		}
+		return enc.Encode(all)
+	}
+	for i, details := range all {
+		if i > 0 {
+			fmt.Println()
+		}
+		printExifTable(os.Stdout, details)
+	}
+	return nil
+}
+
+// printExifTable writes the fields of one file as aligned label/value rows under a
+// heading per group, keeping the order ReadExifDetails produced.
+func printExifTable(w io.Writer, details *media.ExifDetails) {
+	fmt.Fprintln(w, details.Path)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	group := ""
+	for i, field := range details.Fields {
+		if i == 0 || field.Group != group {
+			group = field.Group
+			name := group
+			if name == "" {
+				name = "Other"
+			}
+			fmt.Fprintf(tw, "[%s]\t\n", name)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", field.Label, field.Value)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/spf13/pflag"
+)
+
+// runHistory implements the `georaw history` subcommand: the runs recorded next
+// to the log file, as a list or one of them in full.
+func runHistory(args []string) error {
+	action := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	var logFile string
+	var limit int
+	var asJSON, files bool
+	fs := pflag.NewFlagSet("history", pflag.ExitOnError)
+	fs.StringVar(&logFile, "log-file", "", "Log file whose runs to show (defaults to the file next to the binary)")
+	fs.IntVar(&limit, "limit", 20, "Most recent runs to list (0 for all)")
+	fs.BoolVar(&asJSON, "json", false, "Print the stored JSON record instead of a readable summary")
+	fs.BoolVar(&files, "files", false, "Also print the per-file results grouped by folder")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch action {
+	case "list":
+		records, err := app.ListRunRecords(logFile, limit)
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			return printJSON(records)
+		}
+		if len(records) == 0 {
+			dir, _ := app.HistoryDir(logFile)
+			fmt.Fprintf(os.Stderr, "No runs recorded in %s\n", dir)
+			return nil
+		}
+		printHistoryList(os.Stdout, records)
+		return nil
+	case "show":
+		if fs.NArg() != 1 {
+			return fmt.Errorf("usage: georaw history show <run-id>")
+		}
+		rec, err := app.LoadRunRecord(logFile, fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			return printJSON(rec)
+		}
+		printRunRecord(os.Stdout, rec, files, colorEnabled())
+		return nil
+	default:
+		return fmt.Errorf("unknown history action %q (expected list or show)", action)
+	}
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printHistoryList writes one line per run: ID, command, start, duration and outcome.
+func printHistoryList(w io.Writer, records []app.RunRecord) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "run\tcommand\tstarted\tduration\tresult\n")
+	for _, rec := range records {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", rec.RunID, rec.Command, rec.Started.Local().Format(time.DateTime),
+			rec.Finished.Sub(rec.Started).Round(time.Second), runOutcome(rec))
+	}
+	tw.Flush()
+}
+
+// runOutcome is the error of a failed run, or the counts of a finished one.
+func runOutcome(rec app.RunRecord) string {
+	if rec.Error != "" {
+		return "failed: " + rec.Error
+	}
+	sum := rec.Summary
+	if sum == nil {
+		return "finished"
+	}
+	parts := []string{fmt.Sprintf("%d processed", sum.Processed)}
+	for _, c := range []struct {
+		n    int
+		name string
+	}{
+		{sum.Unchanged, "unchanged"}, {sum.Skipped, "skipped"}, {sum.OutOfTrack, "out of track"},
+		{sum.Suspicious, "suspicious"}, {sum.MetaError, "meta errors"}, {sum.Failed, "failed"},
+	} {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.n, c.name))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// printRunRecord writes a run: when and how it ran, the options that were set,
+// its counts and statistics, and optionally its per-file results.
+func printRunRecord(w io.Writer, rec *app.RunRecord, files, color bool) {
+	fmt.Fprintf(w, "Run %s (%s)\n", rec.RunID, rec.Command)
+	fmt.Fprintf(w, "  started   %s\n", rec.Started.Local().Format(time.DateTime))
+	fmt.Fprintf(w, "  finished  %s (%s)\n", rec.Finished.Local().Format(time.DateTime), rec.Finished.Sub(rec.Started).Round(time.Second))
+	fmt.Fprintf(w, "  result    %s\n", runOutcome(*rec))
+
+	var options map[string]interface{}
+	if err := json.Unmarshal(rec.Options, &options); err == nil && len(options) > 0 {
+		names := make([]string, 0, len(options))
+		for name, value := range options {
+			if !isEmptyOption(value) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		fmt.Fprintf(w, "\nOptions:\n")
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, name := range names {
+			value, _ := json.Marshal(options[name])
+			fmt.Fprintf(tw, "  %s\t%s\n", name, value)
+		}
+		tw.Flush()
+	}
+
+	sum := rec.Summary
+	if sum == nil {
+		return
+	}
+	fmt.Fprintf(w, "\nFiles: %d processed, %d unchanged, %d skipped, %d out of track, %d suspicious, %d meta errors, %d failed (%d transient, %d read-only)\n",
+		sum.Processed, sum.Unchanged, sum.Skipped, sum.OutOfTrack, sum.Suspicious, sum.MetaError, sum.Failed, sum.Transient, sum.ReadOnly)
+	if sum.Truncated {
+		fmt.Fprintf(w, "The input was cut short by the file limit.\n")
+	}
+	if sum.Stats != nil {
+		fmt.Fprintf(w, "%s\n", sum.Stats)
+	}
+	if sum.Timings != nil {
+		fmt.Fprintf(w, "%s\n", sum.Timings)
+	}
+	if sum.Detection != nil {
+		fmt.Fprintf(w, "%s\n", sum.Detection)
+	}
+	if files {
+		printResultsTable(w, sum, color)
+	}
+}
+
+// isEmptyOption reports whether a decoded option holds nothing worth showing: a
+// zero value, an empty list, or an object whose fields are all empty.
+func isEmptyOption(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		for _, field := range v {
+			if !isEmptyOption(field) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+	"github.com/spf13/pflag"
+)
+
+const inputUsage = "Photo file, directory, or glob pattern; repeat for several. Use - to read paths from stdin or @FILE to read them from a list file"
+
+// addInputFlag registers a repeatable -i/--input flag. Values are not split on
+// commas so file names containing them survive.
+func addInputFlag(fs *pflag.FlagSet, dst *[]string, usage string) {
+	fs.StringArrayVarP(dst, "input", "i", nil, usage)
+}
+
+// addSidecarDirFlag registers --sidecar-dir for the commands that read or write sidecars.
+func addSidecarDirFlag(fs *pflag.FlagSet, dst *string) {
+	fs.StringVar(dst, "sidecar-dir", "", "Read and write sidecars under this folder, mirroring the input folders, instead of next to the photos")
+}
+
+// useSidecarDir sends every sidecar read and write into dir, mirroring the folders
+// below the root of the inputs. An empty dir leaves sidecars next to the photos.
+func useSidecarDir(dir string, inputs []string) error {
+	if dir = strings.TrimSpace(dir); dir == "" {
+		return nil
+	}
+	root, err := media.InputRoot(inputs)
+	if err != nil {
+		return err
+	}
+	return xmp.SetSidecarDir(root, dir)
+}
+
+// resolveInputs expands `-` (stdin) and `@FILE` entries into the paths they list.
+// Every path is passed on whole, so names with ';', spaces or wildcards survive.
+func resolveInputs(values []string, stdin io.Reader) ([]string, error) {
+	var paths []string
+	readStdin := false
+	for _, v := range values {
+		switch {
+		case v == "-":
+			if readStdin {
+				continue
+			}
+			readStdin = true
+			data, err := io.ReadAll(stdin)
+			if err != nil {
+				return nil, fmt.Errorf("read file list from stdin: %w", err)
+			}
+			paths = append(paths, splitPathList(data)...)
+		case strings.HasPrefix(v, "@") && len(v) > 1:
+			data, err := os.ReadFile(v[1:])
+			if err != nil {
+				return nil, fmt.Errorf("read file list: %w", err)
+			}
+			paths = append(paths, splitPathList(data)...)
+		default:
+			paths = append(paths, v)
+		}
+	}
+	return paths, nil
+}
+
+// splitPathList reads one path per line, or NUL-separated paths as written by
+// `find -print0`. Paths are kept as written apart from a Windows line ending, so
+// only NUL-separated lists can hold names with newlines. Empty entries are ignored.
+func splitPathList(data []byte) []string {
+	sep, nul := []byte{'\n'}, bytes.IndexByte(data, 0) >= 0
+	if nul {
+		sep = []byte{0}
+	}
+	var out []string
+	for _, part := range bytes.Split(data, sep) {
+		if !nul {
+			part = bytes.TrimSuffix(part, []byte{'\r'})
+		}
+		if len(part) > 0 {
+			out = append(out, string(part))
+		}
+	}
+	return out
+}
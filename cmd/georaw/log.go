@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/spf13/pflag"
+)
+
+// runLogTrace implements the `georaw log` subcommand: it prints the lifecycle of
+// one photo from the log file and its rotated backups.
+func runLogTrace(args []string) error {
+	var filter app.FileFilter
+	var logFile string
+
+	fs := pflag.NewFlagSet("log", pflag.ExitOnError)
+	fs.StringVar(&filter.Name, "file", "", "Photo to trace: a file name such as IMG_1234.CR3, or a path or its trailing folders")
+	fs.StringVar(&logFile, "log-file", "", "Log file to search (defaults to the file next to the binary)")
+	fs.StringVar(&filter.RunID, "run", "", "Only show lines of this run ID")
+	fs.StringVar(&filter.Stage, "stage", "", "Only show lines of one stage: collect, metadata, match, write or checksum")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	filter.Name = strings.TrimSpace(filter.Name)
+	if filter.Name == "" {
+		return fmt.Errorf("--file is required")
+	}
+	switch filter.Stage {
+	case "", app.StageCollect, app.StageMetadata, app.StageMatch, app.StageWrite, app.StageChecksum:
+	default:
+		return fmt.Errorf("unknown stage %q (expected collect, metadata, match, write or checksum)", filter.Stage)
+	}
+	if logFile == "" {
+		path, err := app.DefaultLogPath()
+		if err != nil {
+			return err
+		}
+		logFile = path
+	}
+
+	n, err := app.TraceFile(os.Stdout, logFile, filter)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		fmt.Fprintf(os.Stderr, "No log lines for %s in %s\n", filter.Name, logFile)
+	}
+	return nil
+}
@@ -4,24 +4,97 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/nir0k/GeoRAW/internal/events"
+	"github.com/nir0k/GeoRAW/internal/fsretry"
+	"github.com/nir0k/GeoRAW/internal/logging"
+	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/nir0k/GeoRAW/internal/notify"
+	"github.com/nir0k/GeoRAW/internal/profiling"
+	"github.com/nir0k/GeoRAW/internal/share"
+	"github.com/nir0k/GeoRAW/internal/strava"
 	"github.com/nir0k/GeoRAW/internal/version"
+	"github.com/nir0k/GeoRAW/internal/xmp"
 	"github.com/spf13/pflag"
 )
 
+// subcommands maps `georaw <name>` to its implementation; anything else runs GPX tagging.
+var subcommands = map[string]func(args []string) error{
+	"series":         runSeries,
+	"series-matrix":  runSeriesMatrix,
+	"normalize":      runNormalize,
+	"pair":           runPair,
+	"csv":            runCSV,
+	"shutter-log":    runShutterLog,
+	"timefix":        runTimefix,
+	"exif":           runExif,
+	"clean-sidecars": runCleanSidecars,
+	"doctor":         runDoctor,
+	"offset":         runOffset,
+	"log":            runLogTrace,
+	"history":        runHistory,
+	"tz-shift":       runTZShift,
+	"watch":          runWatch,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if sub, ok := subcommands[os.Args[1]]; ok {
+			if err := sub(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "georaw %s failed: %v\n", os.Args[1], err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	var opts app.Options
-	var showVersion bool
+	var common commonFlags
+	var showVersion, useStrava bool
+	var sidecarDir string
+	var inputs []string
 
-	pflag.StringVarP(&opts.GPXPath, "gpx", "g", "", "Path to GPX track file")
-	pflag.StringVarP(&opts.InputPath, "input", "i", "", "Path to a photo file, directory, or glob pattern")
+	pflag.StringVarP(&opts.GPXPath, "gpx", "g", "", "Path to GPX track file or Garmin export folder, or a track provider URI such as strava:")
+	pflag.BoolVar(&useStrava, "strava", false, "Download the Strava activities around the capture times instead of reading a GPX (credentials from STRAVA_CLIENT_ID, STRAVA_CLIENT_SECRET, STRAVA_REFRESH_TOKEN)")
+	addInputFlag(pflag.CommandLine, &inputs, inputUsage)
+	addSidecarDirFlag(pflag.CommandLine, &sidecarDir)
 	pflag.BoolVarP(&opts.Recursive, "recursive", "r", false, "Scan subdirectories when the input is a folder")
 	pflag.StringVarP(&opts.LogLevel, "log-level", "l", "info", "Logging level for both file and console outputs")
 	pflag.StringVar(&opts.LogFile, "log-file", "", "Optional log file path (defaults to a file next to the binary)")
 	pflag.DurationVar(&opts.TimeOffset, "time-offset", 0, "Offset added to photo capture time (e.g. -30s or 2m)")
+	pflag.StringVar(&opts.TimeZone, "timezone", "", "Camera clock time zone (IANA name such as Europe/Berlin, or \"auto\" to infer from the GPX); applies DST rules per photo")
 	pflag.BoolVar(&opts.AutoOffset, "auto-offset", true, "Automatically estimate time offset between camera clock and GPX track when time-offset is zero")
-	pflag.BoolVarP(&opts.Overwrite, "overwrite-gps", "w", false, "Overwrite existing GPS data in XMP sidecars")
+	addOverwriteFlags(pflag.CommandLine, &opts)
+	addGPSTimeFlag(pflag.CommandLine, &opts.GPSTime)
+	pflag.BoolVar(&opts.MirrorGPS, "mirror-embedded-gps", false, "Copy GPS already embedded in a photo into its sidecar instead of skipping the photo")
+	addAltitudeFlags(pflag.CommandLine, &opts.Altitude)
+	pflag.BoolVar(&opts.LinearGaps, "linear-gaps", false, "Interpolate straight lines across track gaps instead of following the speed and heading around them")
+	pflag.Float64Var(&opts.MaxSpeed, "max-speed", app.DefaultMaxSpeed, "Hold back photos whose positions imply moving faster than this many km/h between consecutive shots (0 disables)")
+	pflag.BoolVar(&opts.WriteConfidence, "write-confidence", false, "Write the geotag confidence (0-1) into sidecars as georaw:GeotagConfidence")
+	pflag.BoolVar(&opts.NoTrackCache, "no-gpx-cache", false, "Always parse the GPX file instead of reusing the cached track index")
+	pflag.StringVar(&opts.ManifestPath, "manifest", "", "Write the run summary as JSON to this file (for --overwrite-listed or --retry-from in a later run)")
+	pflag.StringVar(&opts.Retry.Manifest, "retry-from", "", "Process only the files this --manifest file records as failed, out_of_track or meta_error")
+	pflag.BoolVar(&opts.Checksums.Record, "checksums", false, "Record the SHA-256 of every RAW in the --manifest file")
+	pflag.StringVar(&opts.Checksums.Verify, "verify-checksums", "", "Check every RAW against the SHA-256 this --manifest file recorded with --checksums")
+	pflag.StringVar(&opts.Jobs.Path, "jobs", "", "CSV or JSON job list giving single files a manual position, their own time offset, or a skip (the input when --input is omitted)")
+	pflag.StringVar(&opts.GeoJSONPath, "geojson", "", "Write a GeoJSON FeatureCollection of tagged photos (path, time, camera, series ID) to this file")
+	addUploadFlags(pflag.CommandLine, &opts.Upload)
+	pflag.IntVar(&opts.Workers, "workers", 0, "Number of parallel sidecar writers (defaults to the CPU count, up to 8)")
+	pflag.BoolVar(&opts.StampRun, "stamp-run", false, "Write georaw:LastRunID/LastRunDate into every sidecar this run writes")
+	addAttributionFlags(pflag.CommandLine, &opts.Attribution)
+	addDescriptionFlags(pflag.CommandLine, &opts.Description)
+	pflag.Float64Var(&opts.Waypoints.Radius, "waypoint-keywords", 0, "Add the names of GPX waypoints within this many meters of a photo as keywords (0 disables)")
+	pflag.StringVar(&opts.Waypoints.Path, "waypoints", "", "GPX file with the named waypoints for --waypoint-keywords (defaults to the --gpx file)")
+	addLockFlag(pflag.CommandLine, &opts.Lock)
+	addReadOnlyFlags(pflag.CommandLine, &opts.ReadOnly)
+	addEmbedGPSFlag(pflag.CommandLine, &opts.EmbedGPS)
+	addTimeFallbackFlag(pflag.CommandLine, &opts.TimeFallback)
+	addMaxFilesFlag(pflag.CommandLine, &opts.MaxFiles)
+	addKeepResultsFlag(pflag.CommandLine, &opts.KeepResults)
+	addCommonFlags(pflag.CommandLine, &common)
 	pflag.BoolVarP(&showVersion, "version", "v", false, "Print version and exit")
 
 	pflag.Parse()
@@ -31,11 +104,208 @@ func main() {
 		return
 	}
 
+	input, err := resolveInputs(inputs, os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "georaw failed: %v\n", err)
+		os.Exit(1)
+	}
+	opts.Inputs = input
 	opts.PrintSummary = true
+	common.reportProgress("tag", &opts.Progress, &opts.ScanProgress)
+	if len(input) == 0 && opts.Jobs.Path != "" {
+		// The listed files are the input; mirror sidecars from the job list's folder.
+		input = []string{filepath.Dir(opts.Jobs.Path)}
+	}
+	if err := useSidecarDir(sidecarDir, input); err != nil {
+		fmt.Fprintf(os.Stderr, "georaw failed: %v\n", err)
+		os.Exit(1)
+	}
+	if opts.Confirm, err = common.confirm(inputs); err != nil {
+		fmt.Fprintf(os.Stderr, "georaw failed: %v\n", err)
+		os.Exit(1)
+	}
+	if useStrava {
+		if opts.GPXPath != "" {
+			fmt.Fprintln(os.Stderr, "georaw failed: use either --gpx or --strava, not both")
+			os.Exit(1)
+		}
+		opts.GPXPath = strava.Scheme + ":"
+	}
 
-	ctx := context.Background()
-	if _, err := app.Run(ctx, opts); err != nil {
+	err = common.run("tag", func() (*app.Summary, error) {
+		return app.Run(context.Background(), opts)
+	})
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "georaw failed: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// commonFlags are accepted by every command.
+type commonFlags struct {
+	prof        profiling.Config
+	notify      notify.Config
+	interactive bool
+	table       bool
+	progress    bool
+	retries     int
+	retryDelay  time.Duration
+	logs        logging.Settings
+	logsErr     error // invalid log settings in the environment
+	force       bool
+}
+
+func addCommonFlags(fs *pflag.FlagSet, c *commonFlags) {
+	fs.StringVar(&c.prof.Dir, "pprof", "", "Write cpu.pprof and heap.pprof for the run into this folder")
+	fs.StringVar(&c.prof.Addr, "pprof-addr", "", "Serve net/http/pprof on this address (e.g. localhost:6060) while running")
+	fs.StringVar(&c.prof.TracePath, "trace", "", "Write a runtime execution trace to this file")
+	fs.StringVar(&c.notify.URL, "notify-url", "", "POST the JSON run summary to this webhook URL when the run completes or fails")
+	fs.BoolVar(&c.notify.IncludeFiles, "notify-files", false, "Include per-file results in the webhook payload")
+	fs.BoolVar(&c.table, "table", false, "Print a color-coded per-file table grouped by directory after the summary (honors NO_COLOR)")
+	fs.IntVar(&c.retries, "io-retries", fsretry.DefaultPolicy.Attempts-1, "Retries of file reads and writes that fail with transient errors (e.g. on SMB/NFS shares)")
+	fs.DurationVar(&c.retryDelay, "io-retry-delay", fsretry.DefaultPolicy.Delay, "Wait before the first I/O retry; doubled for each further one")
+	fs.BoolVar(&c.progress, "progress", false, "Draw a progress line on stderr while the input is scanned and files are processed")
+	fs.BoolVar(&c.interactive, "interactive", false, "Show the planned changes and ask for confirmation (all, per directory, or abort) before writing")
+	fs.BoolVar(&c.force, "force", false, "Read the metadata of files that crashed the decoder in an earlier run instead of skipping them")
+
+	defaults, err := logging.SettingsFromEnv()
+	c.logsErr = err
+	fs.IntVar(&c.logs.MaxSize, "log-max-size", defaults.MaxSize, "Rotate the log file when it reaches this many megabytes (env "+logging.EnvMaxSize+")")
+	fs.IntVar(&c.logs.MaxBackups, "log-max-backups", defaults.MaxBackups, "Rotated log files to keep, 0 for all (env "+logging.EnvMaxBackups+")")
+	fs.IntVar(&c.logs.MaxAge, "log-max-age", defaults.MaxAge, "Days to keep rotated log files, 0 regardless of age (env "+logging.EnvMaxAge+")")
+	fs.BoolVar(&c.logs.Compress, "log-compress", defaults.Compress, "Gzip rotated log files (env "+logging.EnvCompress+")")
+	fs.BoolVar(&c.logs.NoFile, "no-log-file", defaults.NoFile, "Write no log file and log to stderr instead, e.g. in a container (env "+logging.EnvNoFile+")")
+}
+
+// reportProgress points the progress callbacks of a run at a terminal line on
+// stderr when --progress is set.
+func (c commonFlags) reportProgress(context string, progress, scan *func(int, int)) {
+	if !c.progress {
+		return
+	}
+	bus := events.NewBus(context, events.NewTerminal(os.Stderr))
+	*progress, *scan = bus.Progress, bus.Scan
+}
+
+// addOverwriteFlags registers --overwrite-gps and the flags that limit what it replaces.
+func addOverwriteFlags(fs *pflag.FlagSet, opts *app.Options) {
+	fs.BoolVarP(&opts.Overwrite, "overwrite-gps", "w", false, "Overwrite existing GPS data in XMP sidecars")
+	fs.BoolVar(&opts.OverwriteScope.AltitudeOnly, "overwrite-altitude", false, "Replace only the altitude of GPS already in a sidecar")
+	fs.BoolVar(&opts.OverwriteScope.OwnOnly, "overwrite-own", false, "Replace sidecar GPS only where GeoRAW wrote it and it was not changed since")
+	fs.StringVar(&opts.OverwriteScope.ListedIn, "overwrite-listed", "", "Replace sidecar GPS only for files processed in the run that wrote this --manifest file")
+	fs.Float64Var(&opts.OverwriteScope.FartherThan, "overwrite-farther", 0, "Replace sidecar GPS only where the new position is more than this many meters away")
+}
+
+// addGPSTimeFlag registers --gps-time-format for the commands that write GPS sidecars.
+func addGPSTimeFlag(fs *pflag.FlagSet, format *xmp.GPSTimeFormat) {
+	fs.StringVar((*string)(format), "gps-time-format", string(xmp.GPSTimeString), "How the GPS time is written, for strict XMP parsers: string (GPSTimeStamp as HH:MM:SS) or datetime (date and time combined in GPSTimeStamp)")
+}
+
+// addAltitudeFlags registers the flags declaring what source altitudes represent.
+func addAltitudeFlags(fs *pflag.FlagSet, s *app.AltitudeSource) {
+	fs.StringVar(&s.Unit, "altitude-unit", app.AltitudeMeters, "Unit of the source altitudes: m or ft")
+	fs.StringVar(&s.Ref, "altitude-ref", app.AltitudeMSL, "What the source altitudes are measured from: msl (sea level) or ellipsoid (WGS84)")
+	fs.StringVar(&s.Geoid, "geoid", "", "For ellipsoid altitudes: a GeographicLib geoid grid (.pgm, e.g. egm96-5.pgm) or a fixed geoid height in meters")
+}
+
+// addLockFlag registers --lock for the commands that write sidecars.
+func addLockFlag(fs *pflag.FlagSet, mode *app.LockMode) {
+	fs.StringVar((*string)(mode), "lock", string(app.LockRefuse), "When another GeoRAW run is writing the same folder tree: refuse, wait for it, or off")
+}
+
+// addReadOnlyFlags registers --clear-readonly and --readonly-dir for the commands
+// that write GPS sidecars.
+func addReadOnlyFlags(fs *pflag.FlagSet, p *app.ReadOnlyPolicy) {
+	fs.BoolVar(&p.Clear, "clear-readonly", false, "Clear the read-only attribute of a sidecar that cannot be written and write it anyway")
+	fs.StringVar(&p.DivertDir, "readonly-dir", "", "Write sidecars that are read-only (or in a read-only folder) under this folder instead, mirroring the input folders")
+}
+
+// addEmbedGPSFlag registers --embed-gps for the commands that write GPS sidecars.
+func addEmbedGPSFlag(fs *pflag.FlagSet, embed *bool) {
+	fs.BoolVar(embed, "embed-gps", false, "Also write the position into JPEG, TIFF and DNG files themselves (with exiftool, or the built-in exiv2 when built with it); other RAWs keep it in the sidecar only")
+}
+
+// addUploadFlags registers --upload and --upload-visibility for the commands that
+// export positions.
+func addUploadFlags(fs *pflag.FlagSet, u *app.UploadTarget) {
+	fs.StringVar(&u.Spec, "upload", "", "Upload the written positions: osm (GPS trace, needs "+share.EnvOSMToken+"), osm:API_URL, or umap:MAP_URL (new layer, needs "+share.EnvUMapSession+")")
+	fs.StringVar(&u.Visibility, "upload-visibility", "private", "Visibility of an uploaded OSM trace: private, public, trackable or identifiable")
+}
+
+// addTimeFallbackFlag registers --time-fallback for the commands that match by
+// capture time.
+func addTimeFallbackFlag(fs *pflag.FlagSet, f *app.TimeFallback) {
+	fs.StringSliceVar(&f.Sources, "time-fallback", nil, "Where to take the capture time of photos whose EXIF has none, tried in order: filename (e.g. IMG_20240712_101530), sibling (same-named JPEG/HEIF), mtime")
+	fs.StringArrayVar(&f.Patterns, "filename-time", nil, "File name layout of the filename time fallback, e.g. \"CAM1_%Y-%m-%d_%H%M%S\" (repeatable; implies --time-fallback=filename)")
+}
+
+// addMaxFilesFlag registers --max-files for the commands that walk the input.
+func addMaxFilesFlag(fs *pflag.FlagSet, limit *int) {
+	fs.IntVar(limit, "max-files", 0, "Stop scanning the input after this many files and report the run as truncated (0 means no limit)")
+}
+
+// addKeepResultsFlag registers --keep-results for the commands that write a result per file.
+func addKeepResultsFlag(fs *pflag.FlagSet, keep *int) {
+	fs.IntVar(keep, "keep-results", 0, "Keep at most this many per-file results in memory, those needing attention first; the others are buffered in a temporary file for the manifest, GeoJSON and uploads (0 keeps all)")
+}
+
+// addAttributionFlags registers the creator/copyright template flags of the writing commands.
+func addAttributionFlags(fs *pflag.FlagSet, t *app.AttributionTemplate) {
+	fs.StringVar(&t.Creator, "creator", "", "Write dc:creator into every processed sidecar ({year} and {camera} are expanded)")
+	fs.StringVar(&t.Rights, "rights", "", "Write dc:rights into every processed sidecar, e.g. \"(c) {year} Jane Doe\"")
+	fs.StringVar(&t.Credit, "credit", "", "Write photoshop:Credit into every processed sidecar")
+	fs.BoolVar(&t.Overwrite, "overwrite-attribution", false, "Replace creator/rights/credit the sidecar already has")
+}
+
+// addDescriptionFlags registers the caption template flags of the commands that write GPS.
+func addDescriptionFlags(fs *pflag.FlagSet, t *app.DescriptionTemplate) {
+	fs.StringVar(&t.Template, "description", "", "Write dc:description into every processed sidecar, e.g. \"Shot at {place}, {date} — {camera} {lens}\" ({place}, {city}, {region}, {country}, {date}, {time}, {year}, {camera}, {lens})")
+	fs.StringArrayVar(&t.Languages, "description-lang", nil, "Add a dc:description in another language as LANG=TEMPLATE, e.g. \"hu=Készült: {place}\" (repeatable)")
+	fs.StringVar(&t.Title, "title", "", "Write dc:title into every processed sidecar; same placeholders as --description")
+	fs.StringArrayVar(&t.TitleLanguages, "title-lang", nil, "Add a dc:title in another language as LANG=TEMPLATE (repeatable)")
+	fs.BoolVar(&t.Overwrite, "overwrite-description", false, "Replace a title or description the sidecar already has in the same language")
+}
+
+// confirm returns the prompt used by --interactive, or nil when it is off.
+// Answers are read from stdin, so it cannot be combined with `-i -`.
+func (c commonFlags) confirm(inputs []string) (app.ConfirmFunc, error) {
+	if !c.interactive {
+		return nil, nil
+	}
+	for _, in := range inputs {
+		if in == "-" {
+			return nil, fmt.Errorf("--interactive reads answers from stdin and cannot be combined with -i -")
+		}
+	}
+	return terminalConfirm(os.Stdin, os.Stdout), nil
+}
+
+// run executes fn with the requested profilers active and reports its outcome to the webhook.
+func (c commonFlags) run(command string, fn func() (*app.Summary, error)) error {
+	fsretry.SetPolicy(fsretry.Policy{Attempts: c.retries + 1, Delay: c.retryDelay})
+	media.RetrySuspects(c.force)
+	if c.logsErr != nil {
+		return c.logsErr
+	}
+	if err := logging.Configure(c.logs); err != nil {
+		return err
+	}
+	session, err := profiling.Start(c.prof)
+	if err != nil {
+		return err
+	}
+	if session != nil && session.Addr != "" {
+		fmt.Fprintf(os.Stderr, "pprof listening on http://%s/debug/pprof/\n", session.Addr)
+	}
+	sum, runErr := fn()
+	if c.table && sum != nil {
+		printResultsTable(os.Stdout, sum, colorEnabled())
+	}
+	if err := session.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "georaw: failed to finish profiling: %v\n", err)
+	}
+	if err := notify.Send(context.Background(), c.notify, command, sum, runErr); err != nil {
+		fmt.Fprintf(os.Stderr, "georaw: webhook notification failed: %v\n", err)
+	}
+	return runErr
+}
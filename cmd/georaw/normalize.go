@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/spf13/pflag"
+)
+
+// runNormalize implements the `georaw normalize` subcommand.
+func runNormalize(args []string) error {
+	var opts app.Options
+	var common commonFlags
+	var inputs []string
+	var sidecarDir string
+
+	fs := pflag.NewFlagSet("normalize", pflag.ExitOnError)
+	addInputFlag(fs, &inputs, inputUsage)
+	addSidecarDirFlag(fs, &sidecarDir)
+	fs.BoolVarP(&opts.Recursive, "recursive", "r", false, "Scan subdirectories when the input is a folder")
+	fs.StringVarP(&opts.LogLevel, "log-level", "l", "info", "Logging level for both file and console outputs")
+	fs.StringVar(&opts.LogFile, "log-file", "", "Optional log file path (defaults to a file next to the binary)")
+	addOverwriteFlags(fs, &opts)
+	addGPSTimeFlag(fs, &opts.GPSTime)
+	fs.StringVar(&opts.GeoJSONPath, "geojson", "", "Write a GeoJSON FeatureCollection of tagged photos (path, time, camera, series ID) to this file")
+	addUploadFlags(fs, &opts.Upload)
+	fs.IntVar(&opts.Workers, "workers", 0, "Number of parallel sidecar writers (defaults to the CPU count, up to 8)")
+	fs.BoolVar(&opts.StampRun, "stamp-run", false, "Write georaw:LastRunID/LastRunDate into every sidecar this run writes")
+	addAttributionFlags(fs, &opts.Attribution)
+	addDescriptionFlags(fs, &opts.Description)
+	addLockFlag(fs, &opts.Lock)
+	addReadOnlyFlags(fs, &opts.ReadOnly)
+	addEmbedGPSFlag(fs, &opts.EmbedGPS)
+	addMaxFilesFlag(fs, &opts.MaxFiles)
+	addKeepResultsFlag(fs, &opts.KeepResults)
+	addCommonFlags(fs, &common)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	input, err := resolveInputs(inputs, os.Stdin)
+	if err != nil {
+		return err
+	}
+	opts.Inputs = input
+	if err := useSidecarDir(sidecarDir, input); err != nil {
+		return err
+	}
+	if opts.Confirm, err = common.confirm(inputs); err != nil {
+		return err
+	}
+
+	opts.PrintSummary = true
+	common.reportProgress("normalize", &opts.Progress, &opts.ScanProgress)
+
+	return common.run("normalize", func() (*app.Summary, error) {
+		return app.Normalize(context.Background(), opts)
+	})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/nir0k/GeoRAW/internal/strava"
+	"github.com/spf13/pflag"
+)
+
+// runOffset implements the `georaw offset` subcommand.
+func runOffset(args []string) error {
+	var opts app.Options
+	var inputs []string
+	var sidecarDir string
+	var useStrava bool
+
+	fs := pflag.NewFlagSet("offset", pflag.ExitOnError)
+	fs.StringVarP(&opts.GPXPath, "gpx", "g", "", "Path to GPX track file or Garmin export folder, or a track provider URI such as strava:")
+	fs.BoolVar(&useStrava, "strava", false, "Download the Strava activities around the capture times instead of reading a GPX")
+	addInputFlag(fs, &inputs, inputUsage)
+	addSidecarDirFlag(fs, &sidecarDir)
+	fs.BoolVarP(&opts.Recursive, "recursive", "r", false, "Scan subdirectories when the input is a folder")
+	addMaxFilesFlag(fs, &opts.MaxFiles)
+	fs.StringVar(&opts.TimeZone, "timezone", "", "Camera clock time zone (IANA name or \"auto\"), as it will be passed to the tagging run")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	input, err := resolveInputs(inputs, os.Stdin)
+	if err != nil {
+		return err
+	}
+	opts.Inputs = input
+	if err := useSidecarDir(sidecarDir, input); err != nil {
+		return err
+	}
+	if useStrava {
+		if opts.GPXPath != "" {
+			return fmt.Errorf("use either --gpx or --strava, not both")
+		}
+		opts.GPXPath = strava.Scheme + ":"
+	}
+	opts.AutoOffset = true
+
+	report, err := app.EstimateOffset(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+	printOffsetReport(os.Stdout, report)
+	if report.Overall.Error != "" {
+		return fmt.Errorf("no offset detected")
+	}
+	return nil
+}
+
+func printOffsetReport(w io.Writer, r *app.OffsetReport) {
+	fmt.Fprintf(w, "Photos: %d\n", r.Photos)
+	fmt.Fprintf(w, "Recommended offset: %s\n", offsetLine(r.Overall))
+	if r.Overall.DriftPerDay != 0 {
+		fmt.Fprintf(w, "  %s\n", driftLine(r.Overall))
+	}
+	if len(r.Cameras) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Per camera:")
+	for _, c := range r.Cameras {
+		fmt.Fprintf(w, "  %s (%d photos): %s\n", c.Camera, c.Photos, offsetLine(c.DetectedOffset))
+		if c.DriftPerDay != 0 {
+			fmt.Fprintf(w, "    %s\n", driftLine(c.DetectedOffset))
+		}
+	}
+	if r.CamerasDisagree {
+		fmt.Fprintln(w, "The cameras disagree: tag each camera's photos separately with its own --time-offset.")
+	}
+}
+
+func offsetLine(d app.DetectedOffset) string {
+	if d.Error != "" {
+		return "none (" + d.Error + ")"
+	}
+	return fmt.Sprintf("--time-offset %s (confidence %.2f, %d samples, spread %s)", d.Offset, d.Confidence, d.Samples, d.Spread)
+}
+
+func driftLine(d app.DetectedOffset) string {
+	return fmt.Sprintf("clock drifts %s per day from %s UTC; georaw timefix --drift can correct it", d.DriftPerDay, d.DriftAnchor.Format(time.DateTime))
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/nir0k/GeoRAW/internal/series"
+	"github.com/spf13/pflag"
+)
+
+// runSeries implements the `georaw series` subcommand.
+func runSeries(args []string) error {
+	var opts series.Options
+	var common commonFlags
+	var inputs []string
+	var sidecarDir string
+	var mode, pick string
+	var labels []string
+	var colorLabels bool
+
+	fs := pflag.NewFlagSet("series", pflag.ExitOnError)
+	addInputFlag(fs, &inputs, inputUsage)
+	addSidecarDirFlag(fs, &sidecarDir)
+	fs.BoolVarP(&opts.Recursive, "recursive", "r", false, "Scan subdirectories when the input is a folder")
+	fs.StringVarP(&opts.LogLevel, "log-level", "l", "info", "Logging level for both file and console outputs")
+	fs.StringVar(&opts.LogFile, "log-file", "", "Optional log file path (defaults to a file next to the binary)")
+	fs.BoolVarP(&opts.Overwrite, "overwrite", "w", false, "Rewrite series keywords, ratings, labels and positions even if they are already present")
+	fs.StringVar(&mode, "mode", string(series.ModeAuto), "Detection mode: auto, hdr, or pano (constant-exposure panorama sources)")
+	fs.StringVar(&opts.Prefix, "prefix", "", "Series ID prefix (defaults to hdr_mode)")
+	fs.IntVar(&opts.StartIndex, "start-index", 1, "First series index")
+	fs.StringVar(&opts.IDTemplate, "id-template", series.DefaultIDTemplate, "Series ID template with {prefix}, {date}, {camera}, {index} (or {index:N}), {type}")
+	fs.StringVar(&opts.ExtraTags, "extra-tags", "", "Comma-separated keywords added to every tagged frame")
+	fs.StringSliceVar(&opts.SplitAt, "split-at", nil, "File name that must start a new series (repeatable, saved for later runs)")
+	fs.StringSliceVar(&opts.MergeAt, "merge-at", nil, "File name whose series is merged with the next one (repeatable, saved for later runs)")
+	fs.StringVar(&pick, "pick", string(series.PickOff), "Mark the best frame of each series: off, rating or keyword")
+	fs.StringVar(&opts.PickKeyword, "pick-keyword", "series_pick", "Keyword written to the best frame when --pick=keyword")
+	fs.IntVar(&opts.PickRating, "pick-rating", 5, "xmp:Rating written to the best frame when --pick=rating")
+	fs.BoolVar(&opts.WritePosition, "write-position", false, "Write georaw:SeriesID/SeriesIndex/SeriesCount so each frame knows its place in the series")
+	fs.BoolVar(&colorLabels, "color-labels", false, "Write xmp:Label color labels to tagged frames (Blue for HDR, Purple for panorama series)")
+	fs.StringArrayVar(&labels, "label", nil, "Color label for a series type as TYPE=LABEL, e.g. hdr=Purple (repeatable; implies --color-labels for that type)")
+	fs.StringVar(&opts.ContactSheet, "contact-sheet", "", "Write an HTML page with thumbnails, type, EV spread, and timing of every detected series")
+	fs.BoolVar(&opts.GPano, "gpano", false, "In pano mode, write GPano source hints (first/last photo date, photo count, exposure lock) into the frames")
+	fs.BoolVar(&opts.EmbedKeywords, "embed-keywords", false, "Also add the series keywords to JPEG, TIFF and DNG files themselves (with exiftool, or the built-in exiv2 when built with it); other RAWs keep them in the sidecar only")
+	fs.StringVar(&opts.KeywordList, "keyword-list", "", "Write the keywords tagged in this run as a Lightroom keyword list (Metadata > Import Keywords)")
+	fs.BoolVar(&opts.SyncPairs, "sync-pairs", false, "Copy keywords between each tagged RAW and its same-named JPEG/HEIF (written to IMG_0001.JPG.xmp)")
+	fs.BoolVar(&opts.ResetAdjustments, "reset-adjustments", false, "Discard saved split/merge adjustments before applying new ones")
+	fs.BoolVar(&opts.KeepTagged, "keep-tagged", false, "Keep frames whose sidecars already have series keywords in those series (e.g. corrected in Lightroom) and group only untagged frames")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "Detect series and print detection statistics (group sizes, HDR/panorama, rejected) without writing sidecars or adjustments")
+	fs.BoolVar(&opts.StampRun, "stamp-run", false, "Write georaw:LastRunID/LastRunDate into every sidecar this run writes")
+	fs.StringSliceVar(&opts.Cameras.Include, "camera-include", nil, "Use photos of cameras matching these rules: make:TEXT, model:TEXT, TEXT (make or model) or * (defaults to make:canon)")
+	fs.StringSliceVar(&opts.Cameras.Exclude, "camera-exclude", nil, "Skip photos of cameras matching these rules, in the same form as --camera-include")
+	addAttributionFlags(fs, &opts.Attribution)
+	addLockFlag(fs, &opts.Lock)
+	addMaxFilesFlag(fs, &opts.MaxFiles)
+	addCommonFlags(fs, &common)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	input, err := resolveInputs(inputs, os.Stdin)
+	if err != nil {
+		return err
+	}
+	opts.Inputs = input
+	if err := useSidecarDir(sidecarDir, input); err != nil {
+		return err
+	}
+	if opts.Confirm, err = common.confirm(inputs); err != nil {
+		return err
+	}
+
+	base := map[series.Mode]string(nil)
+	if colorLabels {
+		base = series.DefaultLabels
+	}
+	if opts.Labels, err = series.ParseLabels(base, labels); err != nil {
+		return err
+	}
+	opts.Mode = series.Mode(mode)
+	opts.Pick = series.PickMode(pick)
+	opts.PrintSummary = true
+	common.reportProgress("series", &opts.Progress, &opts.ScanProgress)
+
+	return common.run("series", func() (*app.Summary, error) {
+		return series.Run(context.Background(), opts)
+	})
+}
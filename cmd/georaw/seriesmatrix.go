@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/nir0k/GeoRAW/internal/series"
+	"github.com/spf13/pflag"
+)
+
+// runSeriesMatrix implements the `georaw series-matrix` subcommand: the key
+// exposure fields of every frame of a series side by side.
+func runSeriesMatrix(args []string) error {
+	var opts series.Options
+	var inputs []string
+	var sidecarDir, id, mode string
+	var asJSON bool
+
+	fs := pflag.NewFlagSet("series-matrix", pflag.ExitOnError)
+	addInputFlag(fs, &inputs, inputUsage)
+	addSidecarDirFlag(fs, &sidecarDir)
+	fs.BoolVarP(&opts.Recursive, "recursive", "r", false, "Scan subdirectories when the input is a folder")
+	fs.StringVar(&id, "id", "", "Compare only the frames tagged with this series ID (default: every series in the input, plus the untagged frames)")
+	fs.StringVar(&mode, "mode", string(series.ModeAuto), "Checks for frames without series keywords: auto, hdr, or pano")
+	fs.StringVar(&opts.Prefix, "prefix", "", "Series ID prefix the IDs were tagged with, if not the default")
+	fs.StringVar(&opts.IDTemplate, "id-template", series.DefaultIDTemplate, "Series ID template the IDs were tagged with")
+	fs.BoolVar(&asJSON, "json", false, "Print the matrices as JSON instead of tables")
+	addMaxFilesFlag(fs, &opts.MaxFiles)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	input, err := resolveInputs(inputs, os.Stdin)
+	if err != nil {
+		return err
+	}
+	opts.Inputs = input
+	if err := useSidecarDir(sidecarDir, input); err != nil {
+		return err
+	}
+	opts.Mode = series.Mode(mode)
+
+	matrices, err := series.CompareSeries(context.Background(), opts, id)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(matrices)
+	}
+	for i := range matrices {
+		if i > 0 {
+			fmt.Println()
+		}
+		printSeriesMatrix(os.Stdout, &matrices[i])
+	}
+	return nil
+}
+
+// printSeriesMatrix writes one series as a table of its frames followed by the
+// integrity issues found, if any.
+func printSeriesMatrix(w io.Writer, m *series.Matrix) {
+	name := m.ID
+	if name == "" {
+		name = "Frames without series keywords"
+	}
+	fmt.Fprintf(w, "%s (%s, %s, %d frames)\n", name, m.Type, m.Camera, len(m.Frames))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "  file\ttime\tgap\tshutter\taperture\tISO\tbias\tfocal\tfocus\tEV\n")
+	for _, f := range m.Frames {
+		if f.Error != "" {
+			fmt.Fprintf(tw, "  %s\t%s\n", filepath.Base(f.Path), f.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%.2fs\t%s\tf/%.1f\t%d\t%+.1f\t%.0fmm\t%s\t%+.1f\n",
+			filepath.Base(f.Path), f.Time.Format("15:04:05.000"), f.Gap, shutterLabel(f.ExposureTime),
+			f.FNumber, f.ISO, f.ExposureBias, f.FocalLength, distanceLabel(f.Distance), f.Stops)
+	}
+	tw.Flush()
+	if len(m.Issues) == 0 {
+		fmt.Fprintln(w, "  consistent")
+		return
+	}
+	for _, issue := range m.Issues {
+		fmt.Fprintf(w, "  ! %s\n", issue)
+	}
+}
+
+// shutterLabel writes an exposure time the way cameras show it.
+func shutterLabel(seconds float64) string {
+	switch {
+	case seconds <= 0:
+		return "-"
+	case seconds < 1:
+		return fmt.Sprintf("1/%.0fs", 1/seconds)
+	default:
+		return fmt.Sprintf("%.1fs", seconds)
+	}
+}
+
+// distanceLabel writes a focus distance, or a dash when the camera did not record one.
+func distanceLabel(meters float64) string {
+	if meters <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2fm", meters)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/spf13/pflag"
+)
+
+// runShutterLog implements the `georaw shutter-log` subcommand.
+func runShutterLog(args []string) error {
+	var opts app.Options
+	var common commonFlags
+	var sidecarDir, input string
+
+	fs := pflag.NewFlagSet("shutter-log", pflag.ExitOnError)
+	fs.StringVar(&opts.ShutterLog, "log", "", "Shutter log (.log/.txt, one line per shot) for all photos of the input (default: the log found in each folder)")
+	fs.StringVarP(&input, "input", "i", "", "Folder with the photos (defaults to the log's folder)")
+	fs.BoolVarP(&opts.Recursive, "recursive", "r", false, "Scan subdirectories when the input is a folder")
+	addSidecarDirFlag(fs, &sidecarDir)
+	fs.StringVarP(&opts.LogLevel, "log-level", "l", "info", "Logging level for both file and console outputs")
+	fs.StringVar(&opts.LogFile, "log-file", "", "Optional log file path (defaults to a file next to the binary)")
+	fs.DurationVar(&opts.TimeOffset, "time-offset", 0, "Offset added to capture times used for lines without a fix time")
+	addOverwriteFlags(fs, &opts)
+	addGPSTimeFlag(fs, &opts.GPSTime)
+	addAltitudeFlags(fs, &opts.Altitude)
+	fs.StringVar(&opts.GeoJSONPath, "geojson", "", "Write a GeoJSON FeatureCollection of tagged photos (path, time, camera, series ID) to this file")
+	addUploadFlags(fs, &opts.Upload)
+	fs.IntVar(&opts.Workers, "workers", 0, "Number of parallel sidecar writers (defaults to the CPU count, up to 8)")
+	fs.BoolVar(&opts.StampRun, "stamp-run", false, "Write georaw:LastRunID/LastRunDate into every sidecar this run writes")
+	addAttributionFlags(fs, &opts.Attribution)
+	addDescriptionFlags(fs, &opts.Description)
+	addLockFlag(fs, &opts.Lock)
+	addReadOnlyFlags(fs, &opts.ReadOnly)
+	addEmbedGPSFlag(fs, &opts.EmbedGPS)
+	addTimeFallbackFlag(fs, &opts.TimeFallback)
+	addMaxFilesFlag(fs, &opts.MaxFiles)
+	addKeepResultsFlag(fs, &opts.KeepResults)
+	addCommonFlags(fs, &common)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts.PrintSummary = true
+	common.reportProgress("shutter-log", &opts.Progress, &opts.ScanProgress)
+	if input != "" {
+		opts.Inputs = []string{input}
+	} else if strings.TrimSpace(opts.ShutterLog) != "" {
+		input = filepath.Dir(opts.ShutterLog)
+	}
+	if err := useSidecarDir(sidecarDir, []string{input}); err != nil {
+		return err
+	}
+	confirm, err := common.confirm(nil)
+	if err != nil {
+		return err
+	}
+	opts.Confirm = confirm
+
+	return common.run("shutter-log", func() (*app.Summary, error) {
+		return app.ImportShutterLog(context.Background(), opts)
+	})
+}
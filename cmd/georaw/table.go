@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+)
+
+// statusColors maps result statuses to ANSI colors for the --table output.
+var statusColors = map[string]string{
+	"processed":    "\033[32m", // green
+	"skipped":      "\033[33m", // yellow
+	"out_of_track": "\033[34m", // blue
+	"suspicious":   "\033[35m", // magenta
+	"failed":       "\033[31m", // red
+	"meta_error":   "\033[31m",
+}
+
+const colorReset = "\033[0m"
+
+// statusOrder fixes the order of per-directory counts.
+var statusOrder = []string{"processed", "unchanged", "skipped", "out_of_track", "suspicious", "meta_error", "failed"}
+
+// printResultsTable writes the per-file results grouped by directory, one line per
+// file, with the status colored when color is set.
+func printResultsTable(w io.Writer, sum *app.Summary, color bool) {
+	byDir := make(map[string][]app.FileResult)
+	for _, res := range sum.Files {
+		dir := filepath.Dir(res.Path)
+		byDir[dir] = append(byDir[dir], res)
+	}
+	dirs := make([]string, 0, len(byDir))
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	paint := func(status string) string {
+		if c, ok := statusColors[status]; ok && color {
+			return c + status + colorReset
+		}
+		return status
+	}
+
+	for _, dir := range dirs {
+		files := byDir[dir]
+		counts := make(map[string]int)
+		for _, res := range files {
+			counts[res.Status]++
+		}
+		var parts []string
+		for _, status := range statusOrder {
+			if counts[status] > 0 {
+				parts = append(parts, fmt.Sprintf("%d %s", counts[status], paint(status)))
+			}
+		}
+		fmt.Fprintf(w, "\n%s (%s)\n", dir, strings.Join(parts, ", "))
+
+		// The status is the last column so its escape codes do not skew the alignment.
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, res := range files {
+			line := paint(res.Status)
+			if msg := tableMessage(res); msg != "" {
+				line += "  " + msg
+			}
+			fmt.Fprintf(tw, "  %s\t%s\n", filepath.Base(res.Path), line)
+		}
+		tw.Flush()
+	}
+	if len(sum.Directories) > 1 {
+		printDirectoryTable(w, sum.Directories, color)
+	}
+}
+
+// printDirectoryTable writes one line of counts per folder and flags the folders
+// with failed, unreadable, unmatched or suspicious files.
+func printDirectoryTable(w io.Writer, dirs []app.DirSummary, color bool) {
+	fmt.Fprintf(w, "\nPer directory:\n")
+	// The folder is the last column so the warning color does not skew the alignment.
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "processed\tunchanged\tskipped\tout_of_track\tsuspicious\tmeta_error\tfailed\t  folder\n")
+	for _, d := range dirs {
+		dir := d.Dir
+		if d.NeedsAttention() {
+			dir += "  needs attention"
+			if color {
+				dir = statusColors["failed"] + dir + colorReset
+			}
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\t%d\t%d\t  %s\n", d.Processed, d.Unchanged, d.Skipped, d.OutOfTrack, d.Suspicious, d.MetaError, d.Failed, dir)
+	}
+	tw.Flush()
+}
+
+// tableMessage drops messages that only repeat the sidecar path.
+func tableMessage(res app.FileResult) string {
+	if res.Status == "processed" && strings.HasSuffix(strings.ToLower(res.Message), ".xmp") {
+		return ""
+	}
+	return res.Message
+}
+
+// colorEnabled reports whether stdout is a terminal and NO_COLOR is unset.
+func colorEnabled() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
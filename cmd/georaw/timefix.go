@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/nir0k/GeoRAW/internal/timefix"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+	"github.com/spf13/pflag"
+)
+
+// runTimefix implements the `georaw timefix` subcommand.
+func runTimefix(args []string) error {
+	var opts app.Options
+	var common commonFlags
+	var inputs, refs []string
+	var anchor, loadPath, savePath, sidecarDir string
+	var fix timefix.Correction
+
+	fs := pflag.NewFlagSet("timefix", pflag.ExitOnError)
+	addInputFlag(fs, &inputs, inputUsage)
+	addSidecarDirFlag(fs, &sidecarDir)
+	fs.BoolVarP(&opts.Recursive, "recursive", "r", false, "Scan subdirectories when the input is a folder")
+	fs.StringVarP(&opts.LogLevel, "log-level", "l", "info", "Logging level for both file and console outputs")
+	fs.StringVar(&opts.LogFile, "log-file", "", "Optional log file path (defaults to a file next to the binary)")
+	fs.DurationVar(&fix.Offset, "offset", 0, "Fixed correction added to capture times (e.g. 1h or -2m30s)")
+	fs.DurationVar(&fix.DriftPerDay, "drift", 0, "Extra correction per day after --drift-anchor (negative when the camera clock runs fast)")
+	fs.StringVar(&anchor, "drift-anchor", "", "Camera time (YYYY-MM-DD HH:MM:SS) at which the drift correction is zero")
+	fs.StringVar(&fix.FromZone, "from-zone", "", "Time zone the camera clock was set to (IANA name)")
+	fs.StringVar(&fix.ToZone, "to-zone", "", "Time zone the photos were actually taken in (IANA name)")
+	fs.StringArrayVar(&refs, "ref", nil, "Reference photo and the true time it was taken, FILE=YYYY-MM-DD HH:MM:SS; one fits an offset, several also fit drift")
+	fs.StringVar(&loadPath, "load", "", "Start from a correction saved with --save")
+	fs.StringVar(&savePath, "save", "", "Save the resulting correction as JSON (without --input nothing else is done)")
+	fs.BoolVar(&opts.TimeFixReset, "reset", false, "Correct from the embedded capture time, discarding earlier corrections")
+	fs.BoolVar(&opts.TimeFixEmbedded, "embedded", false, "Also rewrite the capture time in the files themselves (with exiftool, or the built-in exiv2 when built with it)")
+	fs.BoolVar(&opts.StampRun, "stamp-run", false, "Write georaw:LastRunID/LastRunDate into every sidecar this run writes")
+	addLockFlag(fs, &opts.Lock)
+	addMaxFilesFlag(fs, &opts.MaxFiles)
+	addKeepResultsFlag(fs, &opts.KeepResults)
+	addCommonFlags(fs, &common)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if loadPath != "" {
+		loaded, err := timefix.Load(loadPath)
+		if err != nil {
+			return err
+		}
+		fix = mergeCorrection(loaded, fix)
+	}
+	if anchor != "" {
+		t, err := time.Parse(time.DateTime, anchor)
+		if err != nil {
+			return fmt.Errorf("invalid --drift-anchor: %w", err)
+		}
+		fix.Anchor = t
+	}
+	// The sidecar folder is set up before the references are read, since their
+	// earlier corrections are recorded in their sidecars.
+	var input []string
+	if len(inputs) > 0 {
+		var err error
+		if input, err = resolveInputs(inputs, os.Stdin); err != nil {
+			return err
+		}
+		if err := useSidecarDir(sidecarDir, input); err != nil {
+			return err
+		}
+	}
+	if len(refs) > 0 {
+		fitted, err := fitReferences(refs, opts.TimeFixReset)
+		if err != nil {
+			return err
+		}
+		fix.Offset, fix.DriftPerDay, fix.Anchor = fitted.Offset, fitted.DriftPerDay, fitted.Anchor
+	}
+	if err := fix.Validate(); err != nil {
+		return err
+	}
+	fmt.Printf("Time correction: %s\n", fix)
+
+	if savePath != "" {
+		if err := timefix.Save(savePath, fix); err != nil {
+			return err
+		}
+		fmt.Printf("Saved to %s\n", savePath)
+		if len(inputs) == 0 {
+			return nil
+		}
+	}
+
+	opts.Inputs = input
+	opts.TimeFix = fix
+	opts.PrintSummary = true
+	common.reportProgress("timefix", &opts.Progress, &opts.ScanProgress)
+
+	return common.run("timefix", func() (*app.Summary, error) {
+		return app.FixCaptureTimes(context.Background(), opts)
+	})
+}
+
+// mergeCorrection overlays the parts of flags that were set on a loaded correction.
+func mergeCorrection(base, flags timefix.Correction) timefix.Correction {
+	if flags.Offset != 0 {
+		base.Offset = flags.Offset
+	}
+	if flags.DriftPerDay != 0 {
+		base.DriftPerDay = flags.DriftPerDay
+	}
+	if flags.FromZone != "" || flags.ToZone != "" {
+		base.FromZone, base.ToZone = flags.FromZone, flags.ToZone
+	}
+	return base
+}
+
+// fitReferences reads the camera time of each --ref photo and fits a correction
+// to the true times given for them. Unless reset, the correction goes on top of
+// the shift a sidecar already records, so it is fitted against the shifted time.
+func fitReferences(refs []string, reset bool) (timefix.Correction, error) {
+	samples := make([]timefix.Sample, 0, len(refs))
+	for _, ref := range refs {
+		path, when, ok := strings.Cut(ref, "=")
+		if !ok {
+			return timefix.Correction{}, fmt.Errorf("invalid --ref %q: want FILE=YYYY-MM-DD HH:MM:SS", ref)
+		}
+		truth, err := time.Parse(time.DateTime, strings.TrimSpace(when))
+		if err != nil {
+			return timefix.Correction{}, fmt.Errorf("invalid --ref time %q: %w", when, err)
+		}
+		meta, err := media.ReadMetadata(strings.TrimSpace(path))
+		if err != nil {
+			return timefix.Correction{}, fmt.Errorf("read --ref photo %s: %w", path, err)
+		}
+		camera := meta.CaptureTime
+		if !reset {
+			shift, _ := xmp.ReadTimeShift(xmp.SidecarPath(strings.TrimSpace(path)))
+			camera = camera.Add(shift)
+		}
+		samples = append(samples, timefix.Sample{Camera: camera, True: truth})
+	}
+	return timefix.Fit(samples)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/nir0k/GeoRAW/internal/timefix"
+	"github.com/spf13/pflag"
+)
+
+// runTZShift implements the `georaw tz-shift` subcommand: a time zone only
+// correction for archives imported with the camera set to the wrong zone.
+func runTZShift(args []string) error {
+	var opts app.Options
+	var common commonFlags
+	var inputs []string
+	var sidecarDir string
+	var fix timefix.Correction
+
+	fs := pflag.NewFlagSet("tz-shift", pflag.ExitOnError)
+	addInputFlag(fs, &inputs, inputUsage)
+	addSidecarDirFlag(fs, &sidecarDir)
+	fs.BoolVarP(&opts.Recursive, "recursive", "r", false, "Scan subdirectories when the input is a folder")
+	fs.StringVarP(&opts.LogLevel, "log-level", "l", "info", "Logging level for both file and console outputs")
+	fs.StringVar(&opts.LogFile, "log-file", "", "Optional log file path (defaults to a file next to the binary)")
+	fs.StringVar(&fix.FromZone, "from", "", "Time zone the camera clock was set to (IANA name such as Europe/Budapest, or UTC)")
+	fs.StringVar(&fix.ToZone, "to", "", "Time zone the capture times should be in (IANA name or UTC)")
+	fs.BoolVar(&opts.TimeFixEmbedded, "embedded", false, "Also rewrite DateTimeOriginal/CreateDate and the EXIF time offsets in the files themselves (with exiftool, or the built-in exiv2 when built with it)")
+	fs.BoolVar(&opts.TimeFixReset, "reset", false, "Shift from the embedded capture time, discarding earlier corrections")
+	fs.BoolVar(&opts.StampRun, "stamp-run", false, "Write georaw:LastRunID/LastRunDate into every sidecar this run writes")
+	addLockFlag(fs, &opts.Lock)
+	addMaxFilesFlag(fs, &opts.MaxFiles)
+	addCommonFlags(fs, &common)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fix.FromZone == "" || fix.ToZone == "" {
+		return fmt.Errorf("--from and --to are required")
+	}
+	if err := fix.Validate(); err != nil {
+		return err
+	}
+	input, err := resolveInputs(inputs, os.Stdin)
+	if err != nil {
+		return err
+	}
+	opts.Inputs = input
+	if err := useSidecarDir(sidecarDir, input); err != nil {
+		return err
+	}
+	opts.TimeFix = fix
+	opts.PrintSummary = true
+	common.reportProgress("tz-shift", &opts.Progress, &opts.ScanProgress)
+
+	return common.run("tz-shift", func() (*app.Summary, error) {
+		return app.FixCaptureTimes(context.Background(), opts)
+	})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/nir0k/GeoRAW/internal/strava"
+	"github.com/spf13/pflag"
+)
+
+// runWatch implements the `georaw watch` subcommand: live geotagging of the
+// capture folder of a tethering tool until interrupted.
+func runWatch(args []string) error {
+	var opts app.Options
+	var w app.WatchOptions
+	var common commonFlags
+	var useStrava bool
+	var sidecarDir, input string
+
+	fs := pflag.NewFlagSet("watch", pflag.ExitOnError)
+	fs.StringVarP(&opts.GPXPath, "gpx", "g", "", "Track to match against, read again when a new photo is past its end, e.g. a GPX a phone logger keeps appending to, or a track provider URI")
+	fs.BoolVar(&useStrava, "strava", false, "Download the Strava activities around the capture times instead of reading a GPX")
+	fs.StringVarP(&input, "input", "i", "", "Capture folder the tethering tool writes new photos to")
+	addSidecarDirFlag(fs, &sidecarDir)
+	fs.BoolVarP(&opts.Recursive, "recursive", "r", false, "Also watch subdirectories, for tools that file captures into session folders")
+	fs.DurationVar(&w.Interval, "interval", app.DefaultWatchInterval, "How often the capture folder is checked for new photos")
+	fs.DurationVar(&w.Settle, "settle", app.DefaultWatchSettle, "Tag a new photo once its size has not changed for this long")
+	fs.DurationVar(&w.TrackWait, "track-wait", app.DefaultTrackWait, "How long a photo newer than the end of the track waits for the track to catch up before it is reported out of track")
+	fs.DurationVar(&w.TrackRefresh, "track-refresh", app.DefaultTrackRefresh, "Load the track again after this long even when it covers the new photos")
+	fs.StringVarP(&opts.LogLevel, "log-level", "l", "info", "Logging level for both file and console outputs")
+	fs.StringVar(&opts.LogFile, "log-file", "", "Optional log file path (defaults to a file next to the binary)")
+	fs.DurationVar(&opts.TimeOffset, "time-offset", 0, "Offset added to photo capture time (e.g. -30s or 2m)")
+	fs.StringVar(&opts.TimeZone, "timezone", "", "Camera clock time zone (IANA name such as Europe/Berlin, or \"auto\" to infer from the GPX)")
+	addOverwriteFlags(fs, &opts)
+	addGPSTimeFlag(fs, &opts.GPSTime)
+	fs.BoolVar(&opts.MirrorGPS, "mirror-embedded-gps", false, "Copy GPS already embedded in a photo into its sidecar instead of skipping the photo")
+	addAltitudeFlags(fs, &opts.Altitude)
+	fs.BoolVar(&opts.LinearGaps, "linear-gaps", false, "Interpolate straight lines across track gaps instead of following the speed and heading around them")
+	fs.Float64Var(&opts.MaxSpeed, "max-speed", app.DefaultMaxSpeed, "Hold back photos whose positions imply moving faster than this many km/h between consecutive shots (0 disables)")
+	fs.BoolVar(&opts.WriteConfidence, "write-confidence", false, "Write the geotag confidence (0-1) into sidecars as georaw:GeotagConfidence")
+	fs.StringVar(&opts.ManifestPath, "manifest", "", "Write the summary of the session as JSON to this file when watching stops")
+	fs.StringVar(&opts.GeoJSONPath, "geojson", "", "Write a GeoJSON FeatureCollection of the photos tagged in the session to this file when watching stops")
+	fs.BoolVar(&opts.StampRun, "stamp-run", false, "Write georaw:LastRunID/LastRunDate into every sidecar this session writes")
+	addAttributionFlags(fs, &opts.Attribution)
+	addDescriptionFlags(fs, &opts.Description)
+	fs.Float64Var(&opts.Waypoints.Radius, "waypoint-keywords", 0, "Add the names of GPX waypoints within this many meters of a photo as keywords (0 disables)")
+	fs.StringVar(&opts.Waypoints.Path, "waypoints", "", "GPX file with the named waypoints for --waypoint-keywords (defaults to the --gpx file)")
+	addLockFlag(fs, &opts.Lock)
+	addReadOnlyFlags(fs, &opts.ReadOnly)
+	addEmbedGPSFlag(fs, &opts.EmbedGPS)
+	addTimeFallbackFlag(fs, &opts.TimeFallback)
+	addCommonFlags(fs, &common)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if useStrava {
+		if opts.GPXPath != "" {
+			return fmt.Errorf("use either --gpx or --strava, not both")
+		}
+		opts.GPXPath = strava.Scheme + ":"
+	}
+	if input != "" {
+		opts.Inputs = []string{input}
+	}
+	if err := useSidecarDir(sidecarDir, opts.Inputs); err != nil {
+		return err
+	}
+	opts.PrintSummary = true
+	w.OnResult = func(res app.FileResult) {
+		fmt.Printf("%s %-12s %s", time.Now().Format(time.TimeOnly), res.Status, res.Path)
+		if res.Point != nil {
+			fmt.Printf(" [lat=%.6f lon=%.6f]", res.Point.Latitude, res.Point.Longitude)
+		} else if res.Message != "" {
+			fmt.Printf(": %s", res.Message)
+		}
+		fmt.Println()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Fprintf(os.Stderr, "Watching %s for new photos; press Ctrl+C to stop\n", input)
+	return common.run("watch", func() (*app.Summary, error) {
+		return app.Watch(ctx, opts, w, nil)
+	})
+}
@@ -8,6 +8,7 @@ require (
 	github.com/spf13/pflag v1.0.10
 	github.com/tkrajina/gpxgo v1.3.0
 	github.com/wailsapp/wails/v2 v2.11.0
+	golang.org/x/text v0.22.0
 )
 
 require (
@@ -42,5 +43,4 @@ require (
 	golang.org/x/crypto v0.33.0 // indirect
 	golang.org/x/net v0.35.0 // indirect
 	golang.org/x/sys v0.30.0 // indirect
-	golang.org/x/text v0.22.0 // indirect
 )
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/geoid"
+	"github.com/nir0k/GeoRAW/internal/gpx"
+)
+
+// Units and references of source altitudes.
+const (
+	AltitudeMeters    = "m"
+	AltitudeFeet      = "ft"
+	AltitudeMSL       = "msl"
+	AltitudeEllipsoid = "ellipsoid"
+)
+
+const metersPerFoot = 0.3048
+
+// AltitudeSource declares what the altitudes of the track or CSV represent, so
+// they can be converted to meters above mean sea level, which GPSAltitude holds.
+type AltitudeSource struct {
+	Unit  string // AltitudeMeters (default) or AltitudeFeet
+	Ref   string // AltitudeMSL (default) or AltitudeEllipsoid
+	Geoid string // GeographicLib PGM geoid grid, or a fixed geoid height in meters; needed for AltitudeEllipsoid
+}
+
+// IsZero reports whether altitudes are used as they are.
+func (s AltitudeSource) IsZero() bool {
+	return (s.Unit == "" || s.Unit == AltitudeMeters) && (s.Ref == "" || s.Ref == AltitudeMSL)
+}
+
+// Validate normalizes the settings and checks that they are complete.
+func (s *AltitudeSource) Validate() error {
+	s.Unit = strings.ToLower(strings.TrimSpace(s.Unit))
+	s.Ref = strings.ToLower(strings.TrimSpace(s.Ref))
+	s.Geoid = strings.TrimSpace(s.Geoid)
+	switch s.Unit {
+	case "", "meters", AltitudeMeters:
+		s.Unit = AltitudeMeters
+	case "feet", AltitudeFeet:
+		s.Unit = AltitudeFeet
+	default:
+		return fmt.Errorf("invalid altitude unit %q (expected m or ft)", s.Unit)
+	}
+	switch s.Ref {
+	case "", AltitudeMSL:
+		s.Ref = AltitudeMSL
+		if s.Geoid != "" {
+			return fmt.Errorf("a geoid is only used with ellipsoid altitudes")
+		}
+	case AltitudeEllipsoid:
+		if s.Geoid == "" {
+			return fmt.Errorf("ellipsoid altitudes need a geoid grid file or a geoid height in meters")
+		}
+	default:
+		return fmt.Errorf("invalid altitude reference %q (expected msl or ellipsoid)", s.Ref)
+	}
+	return nil
+}
+
+func (s AltitudeSource) describe() string {
+	desc := fmt.Sprintf("%s above %s", map[string]string{AltitudeMeters: "meters", AltitudeFeet: "feet"}[s.Unit], s.Ref)
+	if s.Ref == AltitudeEllipsoid {
+		desc += " (geoid " + s.Geoid + ")"
+	}
+	return desc
+}
+
+// altitudeConverter turns source altitudes into meters above mean sea level. A nil
+// converter leaves them unchanged.
+type altitudeConverter struct {
+	scale      float64
+	separation float64 // fixed geoid height, used when grid is nil
+	grid       *geoid.Grid
+}
+
+// open prepares the conversion, opening the geoid grid if one is configured.
+func (s AltitudeSource) open() (*altitudeConverter, error) {
+	if s.IsZero() {
+		return nil, nil
+	}
+	c := &altitudeConverter{scale: 1}
+	if s.Unit == AltitudeFeet {
+		c.scale = metersPerFoot
+	}
+	if s.Ref == AltitudeEllipsoid {
+		if n, err := strconv.ParseFloat(s.Geoid, 64); err == nil {
+			c.separation = n
+		} else {
+			grid, err := geoid.Open(s.Geoid)
+			if err != nil {
+				return nil, err
+			}
+			c.grid = grid
+		}
+	}
+	return c, nil
+}
+
+// convert returns coord with its altitude converted; the altitude is copied, never
+// changed in place, since it may belong to the track.
+func (c *altitudeConverter) convert(coord gpx.Coordinate) (gpx.Coordinate, error) {
+	if c == nil || coord.Altitude == nil {
+		return coord, nil
+	}
+	alt := *coord.Altitude * c.scale
+	if c.grid != nil {
+		n, err := c.grid.Separation(coord.Latitude, coord.Longitude)
+		if err != nil {
+			return coord, err
+		}
+		alt -= n
+	} else {
+		alt -= c.separation
+	}
+	coord.Altitude = &alt
+	return coord, nil
+}
+
+func (c *altitudeConverter) close() {
+	if c != nil && c.grid != nil {
+		c.grid.Close()
+	}
+}
@@ -1,37 +1,70 @@
 package app
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/nir0k/GeoRAW/internal/errcode"
 	"github.com/nir0k/GeoRAW/internal/gpx"
 	"github.com/nir0k/GeoRAW/internal/media"
 	"github.com/nir0k/GeoRAW/internal/xmp"
-	"github.com/nir0k/logger"
 )
 
 // FileResult describes per-file outcome.
 type FileResult struct {
-	Path    string `json:"path"`
-	Status  string `json:"status"`  // processed, unchanged, skipped, out_of_track, meta_error, failed
-	Message string `json:"message"` // optional details
+	Path       string    `json:"path"`
+	RelPath    string    `json:"relPath,omitempty"` // path relative to the common folder of all results
+	Status     string    `json:"status"`            // processed, unchanged, skipped, out_of_track, suspicious, meta_error, failed
+	Message    string    `json:"message"`           // optional details
+	Pick       bool      `json:"pick,omitempty"`
+	Point      *GeoPoint `json:"point,omitempty"`      // position written to the sidecar, or held back when suspicious
+	Confidence float64   `json:"confidence,omitempty"` // 0..1 for positions interpolated from the track
+	Transient  bool      `json:"transient,omitempty"`  // failed on a transient I/O error that outlasted the retries
+	Estimated  bool      `json:"estimated,omitempty"`  // position bridged across a track gap with the motion model
+	ReadOnly   bool      `json:"readOnly,omitempty"`   // failed because the sidecar or its folder is read-only
+	TimeSource string    `json:"timeSource,omitempty"` // fallback the capture time came from (mtime, sibling, filename) when the EXIF has none
+
+	Timings *StageTimings `json:"timings,omitempty"` // how long the file spent reading metadata, matching and writing
+
+	Code   errcode.Code   `json:"code,omitempty"`   // error code of a failure, e.g. GEORAW-E012
+	Params errcode.Params `json:"params,omitempty"` // values of the failure for its localized message
+
+	ExistingDistance *float64 `json:"existingDistance,omitempty"` // meters between the GPS the sidecar already had and the computed position
+
+	SHA256   string `json:"sha256,omitempty"`   // hex SHA-256 of the file, recorded with Options.Checksums
+	Checksum string `json:"checksum,omitempty"` // verified, mismatch or new against the Checksums.Verify manifest
 }
 
 // Summary collects overall stats and per-file results.
 type Summary struct {
-	Processed  int          `json:"processed"`
-	Skipped    int          `json:"skipped"`
-	Unchanged  int          `json:"unchanged"`
-	OutOfTrack int          `json:"out_of_track"`
-	Failed     int          `json:"failed"`
-	MetaError  int          `json:"meta_errors"`
-	Files      []FileResult `json:"files"`
+	RunID        string       `json:"runId,omitempty"`
+	Processed    int          `json:"processed"`
+	Skipped      int          `json:"skipped"`
+	Unchanged    int          `json:"unchanged"`
+	OutOfTrack   int          `json:"out_of_track"`
+	Suspicious   int          `json:"suspicious"`
+	Failed       int          `json:"failed"`
+	MetaError    int          `json:"meta_errors"`
+	Transient    int          `json:"transient"`               // failures and metadata errors that may succeed on a rerun
+	ReadOnly     int          `json:"read_only"`               // failures on read-only sidecars or folders
+	Stats        *TripStats   `json:"stats,omitempty"`         // distance and shooting time of the resolved positions
+	Timings      *TimingStats `json:"timings,omitempty"`       // percentiles of the per-file stage timings
+	Detection    *SeriesStats `json:"detection,omitempty"`     // how a series run grouped and classified the frames
+	Truncated    bool         `json:"truncated,omitempty"`     // the input walk stopped at Options.MaxFiles
+	Directories  []DirSummary `json:"directories,omitempty"`   // the counts broken down per folder
+	FilesOmitted int          `json:"files_omitted,omitempty"` // results left out of Files by Options.KeepResults or CompactFiles
+	Files        []FileResult `json:"files"`
+
+	ChecksumsVerified int      `json:"checksums_verified,omitempty"`
+	ChecksumMismatch  int      `json:"checksum_mismatch,omitempty"` // files changed since the Checksums.Verify manifest
+	ChecksumMissing   []string `json:"checksum_missing,omitempty"`  // files the Checksums.Verify manifest recorded that are gone
 }
 
 // Run is the main entry point for the workflow.
@@ -39,69 +72,52 @@ func Run(ctx context.Context, opts Options) (*Summary, error) {
 	return run(ctx, opts, nil)
 }
 
-// RunWithLogger allows piping logs into an in-memory buffer instead of a file.
-func RunWithLogger(ctx context.Context, opts Options, buf *bytes.Buffer) (*Summary, error) {
-	return run(ctx, opts, buf)
+// RunWithLogger also writes the log lines to out, e.g. an events.Bus feeding the GUI.
+func RunWithLogger(ctx context.Context, opts Options, out io.Writer) (*Summary, error) {
+	return run(ctx, opts, out)
 }
 
-func run(ctx context.Context, opts Options, buf *bytes.Buffer) (*Summary, error) {
+func run(ctx context.Context, opts Options, out io.Writer) (sum *Summary, err error) {
 	if err := opts.Validate(); err != nil {
 		return nil, err
 	}
 
-	cfg := logger.LogConfig{
-		FilePath:       opts.LogFile,
-		Format:         "standard",
-		FileLevel:      opts.LogLevel,
-		ConsoleLevel:   opts.LogLevel,
-		ConsoleOutput:  buf != nil,
-		EnableRotation: true,
-		RotationConfig: logger.RotationConfig{
-			MaxSize:    25,
-			MaxBackups: 5,
-			MaxAge:     30,
-			Compress:   true,
-		},
-	}
-	logInstance, err := logger.NewLogger(cfg)
+	logs, err := openRunLog(opts, out)
 	if err != nil {
 		return nil, err
 	}
-	if buf != nil {
-		logInstance.Config.ConsoleOutput = true
-		logInstance.ConsoleLogger = log.New(buf, "", 0)
-	}
-
-	infof := logInstance.Infof
-	warnf := logInstance.Warningf
-	errorf := logInstance.Errorf
-
-	infof("Starting GeoRAW with GPX=%s input=%s recursive=%t offset=%s autoOffset=%t overwrite=%t", opts.GPXPath, opts.InputPath, opts.Recursive, opts.TimeOffset, opts.AutoOffset, opts.Overwrite)
-
-	track, err := gpx.LoadTrack(opts.GPXPath)
+	started := time.Now()
+	defer func() { recordRun("tag", opts, started, sum, err, logs) }()
+	release, err := lockRun(ctx, opts, "tag", logs)
 	if err != nil {
 		return nil, err
 	}
-	start, end := track.Bounds()
-	infof("GPX track loaded with %d points (%s .. %s)", track.PointCount(), start.Format(time.RFC3339), end.Format(time.RFC3339))
+	defer release()
+	infof := logs.infof
+	warnf := logs.warnf
 
-	files, err := media.CollectFiles(opts.InputPath, opts.Recursive)
+	infof("Starting GeoRAW with GPX=%s input=%s recursive=%t offset=%s timeZone=%q autoOffset=%t overwrite=%t", opts.GPXPath, opts.inputText(), opts.Recursive, opts.TimeOffset, opts.TimeZone, opts.AutoOffset, opts.Overwrite)
+	if !opts.OverwriteScope.IsZero() {
+		infof("Replacing existing sidecar GPS limited to: %s", opts.OverwriteScope.describe())
+	}
+	if !opts.Retry.IsZero() {
+		infof("Retrying only the %d files %s records as failed, out of track or without metadata", len(opts.Retry.files), opts.Retry.Manifest)
+	}
+	if !opts.Jobs.IsZero() {
+		infof("Applying the job list %s: %s", opts.Jobs.Path, opts.Jobs.describe())
+	}
+	altitudes, err := opts.Altitude.open()
 	if err != nil {
 		return nil, err
 	}
-	if len(files) == 0 {
-		return nil, fmt.Errorf("no files found to process")
+	defer altitudes.close()
+	if altitudes != nil {
+		infof("Converting track altitudes from %s to meters above sea level", opts.Altitude.describe())
 	}
 
-	totalFiles := 0
-	for _, path := range files {
-		if strings.EqualFold(filepath.Ext(path), ".xmp") {
-			continue
-		}
-		totalFiles++
-	}
-	progressTotal := totalFiles * 2
+	progressTotal := 0
 	progressDone := 0
+	var progressMu sync.Mutex
 	reportProgress := func() {
 		if opts.Progress == nil || progressTotal == 0 {
 			return
@@ -115,83 +131,153 @@ func run(ctx context.Context, opts Options, buf *bytes.Buffer) (*Summary, error)
 		if step <= 0 {
 			return
 		}
+		progressMu.Lock()
+		defer progressMu.Unlock()
 		progressDone += step
 		reportProgress()
 	}
-	reportProgress()
+	// discover grows the progress total while the walk is still finding files.
+	discover := func(files int) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		progressTotal += files * 2
+		reportProgress()
+	}
 
 	var (
-		processed int
-		skipped   int
-		failed    int
-		unchanged int
-		outTrack  int
-		metaError int
-		results   []FileResult
+		count  counters
+		clock  stageClock
+		found  int
+		listed = make(map[string]bool)
 	)
+	// Results go into the store as they are known, so its counts and the
+	// checksums build up during the run instead of after it.
+	store := newResultStore(ctx, opts, &clock, logs)
+	defer store.remove()
+	defer store.close()
 
-	jobs := make([]photoJob, 0, len(files))
+	var jobs []photoJob
 
-	for _, path := range files {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
+	truncated, err := walkInput(opts, logs, func(path string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !opts.Retry.includes(path) {
+			return nil
 		}
+		found++
 
 		ext := strings.ToLower(filepath.Ext(path))
-		if ext == ".xmp" {
-			// Ignore sidecars silently; they may co-exist with RAWs.
-			continue
+		if ext == ".xmp" || skipLivePhotoVideo(path, logs) {
+			// Ignore sidecars and Live Photo videos silently; they may co-exist with RAWs.
+			return nil
 		}
+		discover(1)
+		collected := logs.file(StageCollect, path)
+		collected.debugf("Found %s", path)
 		if !media.SupportedRaw(path) {
-			warnf("Skipping non-RAW file: %s", path)
-			skipped++
-			results = append(results, FileResult{
+			collected.warnf("Skipping non-RAW file: %s", path)
+			count.skipped.Add(1)
+			store.add(FileResult{
 				Path:   path,
 				Status: "skipped",
-			})
+			}, found)
 			advance(2)
-			continue
+			return nil
+		}
+		if override, ok := opts.Jobs.lookup(path); ok {
+			listed[absPath(path)] = true
+			if override.Skip {
+				collected.infof("Skipping %s as %s says", path, override.Where)
+				count.skipped.Add(1)
+				store.add(FileResult{
+					Path:    path,
+					Status:  "skipped",
+					Message: "skipped by the job list",
+				}, found)
+				advance(2)
+				return nil
+			}
 		}
 
-		meta, err := media.ReadMetadata(path)
+		metaLog := logs.file(StageMetadata, path)
+		metaStart := time.Now()
+		meta, timeSource, err := readMetadata(path, opts.TimeFallback)
+		clock.since(path, StageMetadata, metaStart)
 		if err != nil {
-			warnf("Failed to read metadata for %s: %v", path, err)
-			metaError++
-			results = append(results, FileResult{
-				Path:    path,
-				Status:  "meta_error",
-				Message: err.Error(),
-			})
+			metaLog.warnf("Failed to read metadata for %s: %v", path, err)
+			store.add(metaFailure(path, err, &count), found)
 			advance(2)
-			continue
+			return nil
 		}
+		if timeSource != "" {
+			metaLog.warnf("No capture time in the EXIF of %s; using %s from its %s", path, meta.CaptureTime.Format(time.DateTime), timeSource)
+		}
+		metaLog.debugf("Captured %s by %s %s", meta.CaptureTime.Format(time.RFC3339), meta.CameraMake, meta.CameraModel)
 
+		if shift, ok := applyTimeShift(path, &meta); ok {
+			metaLog.debugf("Using corrected capture time of %s (shift %s)", path, shift)
+		}
 		jobs = append(jobs, photoJob{
-			Path: path,
-			Meta: meta,
+			Path:       path,
+			Meta:       meta,
+			TimeSource: timeSource,
+			seq:        found,
 		})
 		advance(1)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == 0 && !opts.Retry.IsZero() {
+		return nil, errcode.New(errcode.RetryNotFound, errcode.Params{
+			"count":    strconv.Itoa(len(opts.Retry.files)),
+			"manifest": opts.Retry.Manifest,
+			"input":    opts.inputText(),
+		})
+	}
+	if found == 0 {
+		return nil, errcode.New(errcode.NoFiles, nil)
+	}
+	if n := opts.Jobs.unmatched(listed); n > 0 {
+		warnf("%d files of the job list %s were not found in the input", n, opts.Jobs.Path)
 	}
 
 	if len(jobs) == 0 {
-		return nil, fmt.Errorf("no RAW files to process")
+		return nil, errcode.New(errcode.NoRAWFiles, nil)
+	}
+	// The track is resolved once the capture times are known, so providers that
+	// download data only fetch what the photos need.
+	track, err := loadTrack(ctx, opts, jobs, logs)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := loadTimeZone(opts.TimeZone, track)
+	if err != nil {
+		return nil, err
+	}
+	if loc != nil {
+		infof("Interpreting capture times in time zone %s (DST-aware)", loc)
+		applyTimeZone(jobs, loc)
 	}
 
 	effectiveOffset := opts.TimeOffset
+	var offsetSpread time.Duration
 	if effectiveOffset == 0 && opts.AutoOffset {
-		offset, samples, err := detectOffset(track, jobs)
+		estimate, err := detectOffset(track, opts.Jobs.automatic(jobs))
 		if err != nil {
 			warnf("Auto offset detection failed, using 0s: %v", err)
 		} else {
-			effectiveOffset = offset
-			infof("Auto-detected time offset: %s using %d samples", effectiveOffset, samples)
+			effectiveOffset, offsetSpread = estimate.Offset, estimate.Spread
+			infof("Auto-detected time offset: %s using %d samples (spread %s)", effectiveOffset, estimate.Samples, offsetSpread)
 		}
 	} else if !opts.AutoOffset {
 		infof("Auto offset disabled, using manual offset: %s", effectiveOffset)
 	}
 
+	tasks := make([]sidecarTask, 0, len(jobs))
 	for _, job := range jobs {
 		select {
 		case <-ctx.Done():
@@ -199,104 +285,170 @@ func run(ctx context.Context, opts Options, buf *bytes.Buffer) (*Summary, error)
 		default:
 		}
 
-		capture := job.Meta.CaptureTime.Add(effectiveOffset).UTC()
-		coord, err := track.CoordinateAt(capture)
-		if err != nil {
-			if errors.Is(err, gpx.ErrTimestampOutOfBounds) {
-				warnf("Capture time outside GPX coverage for %s (%s): %v", job.Path, capture.Format(time.RFC3339), err)
-				outTrack++
-				results = append(results, FileResult{
-					Path:    job.Path,
-					Status:  "out_of_track",
-					Message: err.Error(),
-				})
-				advance(1)
-				continue
-			}
-			errorf("No matching GPX point for %s (%s): %v", job.Path, capture.Format(time.RFC3339), err)
-			failed++
-			results = append(results, FileResult{
-				Path:    job.Path,
-				Status:  "failed",
-				Message: err.Error(),
+		matchLog := logs.file(StageMatch, job.Path)
+		override, _ := opts.Jobs.lookup(job.Path)
+		offset := effectiveOffset
+		if override.Offset != nil {
+			offset = *override.Offset
+			matchLog.debugf("Using the time offset %s from %s for %s", offset, override.Where, job.Path)
+		}
+		capture := job.Meta.CaptureTime.Add(offset).UTC()
+		if override.Coord != nil {
+			matchLog.debugf("Using the position from %s for %s", override.Where, job.Path)
+			tasks = append(tasks, sidecarTask{
+				Job:       job,
+				Capture:   capture,
+				Coord:     *override.Coord,
+				Sidecar:   xmp.SidecarPath(job.Path),
+				Source:    override.Where,
+				Manual:    true,
+				GPSSource: xmp.GPSSourceManual,
 			})
-			advance(1)
 			continue
 		}
-
-		sidecarPath := xmp.SidecarPath(job.Path)
-		wrote, err := xmp.MergeAndWrite(sidecarPath, coord, capture, opts.Overwrite)
-		if errors.Is(err, xmp.ErrGPSAlreadyPresent) {
-			infof("Skipping already geotagged sidecar %s (use --overwrite-gps to replace)", sidecarPath)
-			unchanged++
-			results = append(results, FileResult{
+		if gps := job.Meta.GPS; gps != nil && !opts.Overwrite {
+			if opts.MirrorGPS {
+				matchLog.debugf("Mirroring embedded GPS of %s into its sidecar", job.Path)
+				if !gps.Time.IsZero() {
+					capture = gps.Time
+				}
+				tasks = append(tasks, sidecarTask{
+					Job:       job,
+					Capture:   capture,
+					Coord:     embeddedCoordinate(gps),
+					Sidecar:   xmp.SidecarPath(job.Path),
+					Mirror:    true,
+					GPSSource: xmp.GPSSourceMirror,
+				})
+				continue
+			}
+			source := "camera"
+			if job.Meta.Drone != nil {
+				source = "drone"
+			}
+			matchLog.infof("Skipping %s: GPS already embedded by the %s (use --overwrite-gps to replace)", job.Path, source)
+			count.unchanged.Add(1)
+			store.addJob(job, FileResult{
 				Path:    job.Path,
 				Status:  "unchanged",
-				Message: "GPS already present",
+				Message: "GPS embedded in file",
 			})
 			advance(1)
 			continue
 		}
+		matchStart := time.Now()
+		coord, match, err := track.MatchAt(capture)
+		if err == nil && match.Estimated {
+			matchLog.debugf("Estimated position of %s from the motion across a %s track gap", job.Path, match.Gap)
+		}
+		if err == nil {
+			coord, err = altitudes.convert(coord)
+		}
+		clock.since(job.Path, StageMatch, matchStart)
 		if err != nil {
-			errorf("Failed to write sidecar for %s: %v", job.Path, err)
-			failed++
-			results = append(results, FileResult{
-				Path:    job.Path,
-				Status:  "failed",
-				Message: err.Error(),
-			})
+			if errors.Is(err, gpx.ErrTimestampOutOfBounds) {
+				matchLog.warnf("Capture time outside GPX coverage for %s (%s): %v", job.Path, capture.Format(time.RFC3339), err)
+				count.outTrack.Add(1)
+				store.addJob(job, FailedResult(job.Path, "out_of_track", err))
+				advance(1)
+				continue
+			}
+			matchLog.errorf("No matching GPX point for %s (%s): %v", job.Path, capture.Format(time.RFC3339), err)
+			count.failed.Add(1)
+			store.addJob(job, FailedResult(job.Path, "failed", err))
 			advance(1)
 			continue
 		}
 
-		infof("Geotagged %s (%s %s, %s) -> %s [lat=%.6f lon=%.6f alt=%v]",
-			job.Path,
-			job.Meta.CameraMake,
-			job.Meta.CameraModel,
-			capture.Format(time.RFC3339),
-			sidecarPath,
-			coord.Latitude,
-			coord.Longitude,
-			altText(coord.Altitude),
-		)
-		if wrote {
-			processed++
-			results = append(results, FileResult{
-				Path:    job.Path,
-				Status:  "processed",
-				Message: sidecarPath,
-			})
-		} else {
-			unchanged++
-			results = append(results, FileResult{
-				Path:    job.Path,
-				Status:  "unchanged",
-				Message: "Sidecar existed",
-			})
-		}
-		advance(1)
+		tasks = append(tasks, sidecarTask{
+			Job:        job,
+			Capture:    capture,
+			Coord:      coord,
+			Sidecar:    xmp.SidecarPath(job.Path),
+			Confidence: geotagConfidence(match, offsetSpread),
+			Estimated:  match.Estimated,
+			GPSSource:  trackSource(match),
+		})
+	}
+	jobs = nil // the tasks carry what is still needed of them
+
+	record := func(task sidecarTask, res FileResult) { store.addJob(task.Job, res) }
+	tasks = holdImplausible(tasks, opts.MaxSpeed, record, &count, logs, func() { advance(1) })
+	tasks, err = confirmTasks(opts.Confirm, tasks, record, &count, func() { advance(1) })
+	if err != nil {
+		return nil, err
 	}
 
-	sum := &Summary{
-		Processed:  processed,
-		Skipped:    skipped,
-		Unchanged:  unchanged,
-		OutOfTrack: outTrack,
-		Failed:     failed,
-		MetaError:  metaError,
-		Files:      results,
+	err = writeSidecars(ctx, tasks, opts.Workers, func(task sidecarTask) {
+		defer advance(1)
+		writeStart := time.Now()
+		res := applyTask(ctx, task, opts, &count, logs)
+		clock.since(task.Job.Path, StageWrite, writeStart)
+		record(task, res)
+	})
+	if err != nil {
+		return nil, err
 	}
-	summary := fmt.Sprintf("Finished. processed=%d skipped=%d unchanged=%d out_of_track=%d failed=%d meta_errors=%d", processed, skipped, unchanged, outTrack, failed, metaError)
+
+	store.close()
+	missing := reportMissingChecksums(opts, logs)
+	exportGeoJSON(opts, store.all(), logs)
+	uploadPositions(ctx, opts, store.all(), logs)
+	sum = &Summary{
+		RunID:      opts.RunID,
+		Processed:  int(count.processed.Load()),
+		Skipped:    int(count.skipped.Load()),
+		Unchanged:  int(count.unchanged.Load()),
+		OutOfTrack: int(count.outTrack.Load()),
+		Suspicious: int(count.suspicious.Load()),
+		Failed:     int(count.failed.Load()),
+		MetaError:  int(count.metaError.Load()),
+		Truncated:  truncated,
+
+		ChecksumMissing: missing,
+	}
+	store.summarize(sum)
+	exportManifestFiles(opts, sum, store.all(), logs)
+	summary := fmt.Sprintf("Finished. processed=%d skipped=%d unchanged=%d out_of_track=%d suspicious=%d failed=%d meta_errors=%d transient=%d read_only=%d", sum.Processed, sum.Skipped, sum.Unchanged, sum.OutOfTrack, sum.Suspicious, sum.Failed, sum.MetaError, sum.Transient, sum.ReadOnly) + checksumSummary(opts, sum)
 	if opts.PrintSummary {
 		fmt.Println(summary)
 	}
 	infof("%s", summary)
+	warnTruncated(opts, sum, logs)
+	logTripStats(opts, sum, logs)
+	logTimings(opts, sum, logs)
 	return sum, nil
 }
 
+// embeddedCoordinate converts a camera-recorded position to the writer's coordinate type.
+func embeddedCoordinate(gps *media.GPSPosition) gpx.Coordinate {
+	return gpx.Coordinate{
+		Latitude:  gps.Latitude,
+		Longitude: gps.Longitude,
+		Altitude:  gps.Altitude,
+	}
+}
+
+// trackSource tells a position on a recorded track point from one between two.
+func trackSource(m gpx.Match) xmp.GPSSource {
+	if m.Gap == 0 {
+		return xmp.GPSSourceNearest
+	}
+	return xmp.GPSSourceInterpolated
+}
+
 func altText(val *float64) string {
 	if val == nil {
 		return "n/a"
 	}
 	return fmt.Sprintf("%.2fm", *val)
 }
+
+// distanceText describes how far a new position is from the GPS a sidecar
+// already had, for log lines; empty when it had none.
+func distanceText(meters *float64) string {
+	if meters == nil {
+		return ""
+	}
+	return fmt.Sprintf(", %.0f m from the existing position", *meters)
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+var attributionPlaceholderRegex = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// AttributionTemplate fills dc:creator, dc:rights and photoshop:Credit of every
+// processed sidecar. Each field may use {year} (capture year) and {camera}
+// (camera make and model); empty fields are not written.
+type AttributionTemplate struct {
+	Creator   string
+	Rights    string
+	Credit    string
+	Overwrite bool // replace attribution the sidecar already has
+}
+
+// IsZero reports whether no attribution field is configured.
+func (t AttributionTemplate) IsZero() bool {
+	return strings.TrimSpace(t.Creator) == "" && strings.TrimSpace(t.Rights) == "" && strings.TrimSpace(t.Credit) == ""
+}
+
+// Validate rejects placeholders other than {year} and {camera}.
+func (t AttributionTemplate) Validate() error {
+	for _, tmpl := range []string{t.Creator, t.Rights, t.Credit} {
+		for _, m := range attributionPlaceholderRegex.FindAllStringSubmatch(tmpl, -1) {
+			switch m[1] {
+			case "year", "camera":
+			default:
+				return fmt.Errorf("unknown placeholder {%s} in attribution template", m[1])
+			}
+		}
+	}
+	return nil
+}
+
+// Expand fills the placeholders for one photo.
+func (t AttributionTemplate) Expand(capture time.Time, camera string) xmp.Attribution {
+	expand := func(tmpl string) string {
+		return strings.TrimSpace(attributionPlaceholderRegex.ReplaceAllStringFunc(tmpl, func(token string) string {
+			switch token {
+			case "{year}":
+				if capture.IsZero() {
+					return ""
+				}
+				return strconv.Itoa(capture.Year())
+			case "{camera}":
+				return camera
+			default:
+				return token
+			}
+		}))
+	}
+	return xmp.Attribution{
+		Creator: expand(t.Creator),
+		Rights:  expand(t.Rights),
+		Credit:  expand(t.Credit),
+	}
+}
+
+// Write expands the template for one photo and writes it into sidecar.
+func (t AttributionTemplate) Write(sidecar string, capture time.Time, camera string) (bool, error) {
+	if t.IsZero() {
+		return false, nil
+	}
+	return xmp.SetAttribution(sidecar, t.Expand(capture, camera), t.Overwrite)
+}
+
+// CameraName joins make and model, dropping the make when the model already starts with it
+// (Canon reports "Canon" / "Canon EOS R5").
+func CameraName(make, model string) string {
+	make, model = strings.TrimSpace(make), strings.TrimSpace(model)
+	if make != "" && strings.HasPrefix(strings.ToLower(model), strings.ToLower(make)) {
+		return model
+	}
+	return strings.TrimSpace(make + " " + model)
+}
+
+// applyAttribution writes the attribution template into a sidecar the run processed.
+// Failing to write it is logged but does not fail the file.
+func applyAttribution(opts Options, task sidecarTask, logs runLog) {
+	if opts.Attribution.IsZero() {
+		return
+	}
+	capture := task.Job.Meta.CaptureTime
+	if capture.IsZero() {
+		capture = task.Capture
+	}
+	camera := CameraName(task.Job.Meta.CameraMake, task.Job.Meta.CameraModel)
+	if _, err := opts.Attribution.Write(task.Sidecar, capture, camera); err != nil {
+		logs.warnf("Failed to write attribution into %s: %v", task.Sidecar, err)
+	}
+}
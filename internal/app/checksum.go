@@ -0,0 +1,172 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/fsretry"
+	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/nir0k/GeoRAW/internal/pathnorm"
+)
+
+// Results of comparing a file with the checksum an earlier manifest recorded.
+const (
+	ChecksumVerified = "verified" // the file still has the recorded checksum
+	ChecksumMismatch = "mismatch" // the file changed since the checksum was recorded
+	ChecksumNew      = "new"      // the earlier manifest has no checksum for the file
+)
+
+// Checksums makes a run double as an integrity check of an archive: the SHA-256
+// of every RAW it looks at is recorded in the manifest, and compared with the
+// checksums a manifest of an earlier run recorded.
+type Checksums struct {
+	Record bool   // store the SHA-256 of every RAW in its result (and so in the manifest)
+	Verify string // manifest of an earlier run whose checksums the files must still have; implies Record
+
+	known map[string]recordedChecksum
+}
+
+// recordedChecksum is a checksum read from the Verify manifest.
+type recordedChecksum struct {
+	Path   string
+	SHA256 string
+}
+
+// IsZero reports whether no checksums are computed.
+func (c Checksums) IsZero() bool {
+	return !c.Record && c.Verify == ""
+}
+
+// load reads the checksums to verify from Verify.
+func (c *Checksums) load() error {
+	c.Verify = strings.TrimSpace(c.Verify)
+	if c.Verify == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.Verify)
+	if err != nil {
+		return fmt.Errorf("read checksum manifest: %w", err)
+	}
+	var sum Summary
+	if err := json.Unmarshal(data, &sum); err != nil {
+		return fmt.Errorf("parse checksum manifest %s: %w", c.Verify, err)
+	}
+	c.known = make(map[string]recordedChecksum)
+	for _, res := range sum.Files {
+		if res.SHA256 != "" {
+			c.known[absPath(res.Path)] = recordedChecksum{Path: res.Path, SHA256: res.SHA256}
+		}
+	}
+	if len(c.known) == 0 {
+		return fmt.Errorf("%s records no checksums; write it with --checksums first", c.Verify)
+	}
+	return nil
+}
+
+// needsChecksum reports whether the file of res is hashed: every file the run
+// looked at, which leaves out the skipped ones.
+func needsChecksum(res FileResult) bool {
+	return res.Status != "skipped"
+}
+
+// reportMissingChecksums returns, when verifying, the files the earlier manifest
+// recorded under the input that no longer exist.
+func reportMissingChecksums(opts Options, logs runLog) []string {
+	if opts.Checksums.Verify == "" {
+		return nil
+	}
+	missing := missingChecksummed(opts.Checksums.known, opts.Inputs)
+	for _, path := range missing {
+		logs.errorf("%s has a checksum in %s but no longer exists", path, opts.Checksums.Verify)
+	}
+	return missing
+}
+
+// checkFile hashes one file into its result and compares it with its recorded
+// checksum, if verifying.
+func checkFile(res *FileResult, checks Checksums, logs runLog) {
+	sum, err := fileSHA256(res.Path)
+	if err != nil {
+		logs.errorf("Failed to compute the checksum of %s: %v", res.Path, err)
+		return
+	}
+	res.SHA256 = sum
+	if checks.Verify == "" {
+		return
+	}
+	recorded, ok := checks.known[absPath(res.Path)]
+	switch {
+	case !ok:
+		res.Checksum = ChecksumNew
+		logs.infof("No checksum of %s recorded in %s", res.Path, checks.Verify)
+	case recorded.SHA256 != sum:
+		res.Checksum = ChecksumMismatch
+		logs.errorf("Checksum of %s changed: %s recorded, %s now", res.Path, recorded.SHA256, sum)
+	default:
+		res.Checksum = ChecksumVerified
+		logs.debugf("Checksum of %s verified", res.Path)
+	}
+}
+
+// fileSHA256 returns the hex SHA-256 of the contents of path.
+func fileSHA256(path string) (string, error) {
+	file, err := fsretry.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// missingChecksummed returns the recorded files inside the inputs' folder that no
+// longer exist. Files elsewhere are not this run's business.
+func missingChecksummed(known map[string]recordedChecksum, inputs []string) []string {
+	root, err := media.InputRoot(inputs)
+	if err != nil {
+		return nil
+	}
+	var missing []string
+	for key, recorded := range known {
+		if rel, err := filepath.Rel(root, key); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if _, err := os.Stat(pathnorm.Existing(key)); errors.Is(err, fs.ErrNotExist) {
+			missing = append(missing, recorded.Path)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// CountChecksums counts the results with the given checksum comparison.
+func CountChecksums(results []FileResult, check string) int {
+	n := 0
+	for _, res := range results {
+		if res.Checksum == check {
+			n++
+		}
+	}
+	return n
+}
+
+// checksumSummary describes the verification for the summary line; empty when
+// nothing was verified.
+func checksumSummary(opts Options, sum *Summary) string {
+	if opts.Checksums.Verify == "" {
+		return ""
+	}
+	return fmt.Sprintf(" checksums_verified=%d checksum_mismatch=%d checksum_missing=%d", sum.ChecksumsVerified, sum.ChecksumMismatch, len(sum.ChecksumMissing))
+}
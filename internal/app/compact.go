@@ -0,0 +1,45 @@
+package app
+
+// needsAttention reports whether a result is one a rerun or a manual check
+// should look at.
+func (res FileResult) needsAttention() bool {
+	switch res.Status {
+	case "failed", "meta_error", "out_of_track", "suspicious":
+		return true
+	}
+	return false
+}
+
+// CompactFiles bounds the per-file results of sum to limit entries, for frontends
+// that cannot list hundreds of thousands of rows. Results needing attention are
+// kept first, then the others up to the limit, all in their original order. The
+// counts, folder breakdown and trip statistics are left as they are; the number
+// of dropped entries goes to FilesOmitted. Run applies the same limit while the
+// files are processed when Options.KeepResults is set; CompactFiles is for the
+// other runs, whose results are all in memory anyway.
+func CompactFiles(sum *Summary, limit int) {
+	if sum == nil || limit <= 0 || len(sum.Files) <= limit {
+		return
+	}
+	keep := make([]bool, len(sum.Files))
+	kept := 0
+	for pass := 0; pass < 2 && kept < limit; pass++ {
+		for i, res := range sum.Files {
+			if kept == limit {
+				break
+			}
+			if !keep[i] && (pass == 1 || res.needsAttention()) {
+				keep[i] = true
+				kept++
+			}
+		}
+	}
+	files := make([]FileResult, 0, kept)
+	for i, res := range sum.Files {
+		if keep[i] {
+			files = append(files, res)
+		}
+	}
+	sum.FilesOmitted += len(sum.Files) - kept
+	sum.Files = files
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"math"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// Scales at which each factor of geotagConfidence halves the score.
+const (
+	confidenceGapScale      = 2 * time.Minute // gap between the recorded points around the photo
+	confidenceDistanceScale = 50              // meters to the nearer recorded point
+	confidenceHDOPScale     = 4               // HDOP above 1
+	confidenceSpreadScale   = time.Minute     // disagreement between auto-offset samples
+)
+
+// geotagConfidence scores a track-matched position between 0.01 and 1. Every factor
+// is 1 when ideal and halves the score at its scale, so a photo between two points
+// recorded two minutes apart scores at most 0.5.
+func geotagConfidence(m gpx.Match, offsetSpread time.Duration) float64 {
+	score := halfAt(m.Gap.Seconds(), confidenceGapScale.Seconds()) *
+		halfAt(m.Distance, confidenceDistanceScale) *
+		halfAt(offsetSpread.Seconds(), confidenceSpreadScale.Seconds())
+	if m.HDOP > 1 {
+		score *= halfAt(m.HDOP-1, confidenceHDOPScale)
+	}
+	return math.Max(0.01, math.Round(score*100)/100)
+}
+
+// halfAt falls from 1 at v=0 to 0.5 at v=scale and towards 0 beyond.
+func halfAt(v, scale float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	return scale / (scale + v)
+}
+
+// writeConfidence records the task's confidence in its sidecar when requested.
+func writeConfidence(opts Options, task sidecarTask, logs runLog) {
+	if !opts.WriteConfidence || task.Confidence == 0 {
+		return
+	}
+	if _, err := xmp.SetGeotagConfidence(task.Sidecar, task.Confidence); err != nil {
+		logs.warnf("Failed to write geotag confidence into %s: %v", task.Sidecar, err)
+	}
+}
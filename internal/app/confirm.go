@@ -0,0 +1,59 @@
+package app
+
+import "github.com/nir0k/GeoRAW/internal/errcode"
+
+// ErrAborted is returned when the user aborts a run at the confirmation prompt.
+var ErrAborted = errcode.New(errcode.Aborted, nil)
+
+// PlannedWrite is a sidecar change awaiting confirmation.
+type PlannedWrite struct {
+	Path  string
+	Point *GeoPoint // position to write, nil when the change carries no GPS
+	Tags  []string  // keywords to write
+}
+
+// ConfirmFunc reviews the planned writes before any sidecar is touched and returns
+// the ones to carry out. Returning an error aborts the run without writing.
+type ConfirmFunc func(plan []PlannedWrite) ([]PlannedWrite, error)
+
+// ApprovedPaths returns the set of paths in approved, for matching a confirmed
+// plan back to the work it was built from.
+func ApprovedPaths(approved []PlannedWrite) map[string]bool {
+	set := make(map[string]bool, len(approved))
+	for _, p := range approved {
+		set[p.Path] = true
+	}
+	return set
+}
+
+// confirmTasks asks confirm which tasks may be written. Declined tasks are
+// recorded as skipped and reported through done.
+func confirmTasks(confirm ConfirmFunc, tasks []sidecarTask, record taskResult, count *counters, done func()) ([]sidecarTask, error) {
+	if confirm == nil || len(tasks) == 0 {
+		return tasks, nil
+	}
+	plan := make([]PlannedWrite, len(tasks))
+	for i, task := range tasks {
+		plan[i] = PlannedWrite{Path: task.Job.Path, Point: taskPoint(task)}
+	}
+	approved, err := confirm(plan)
+	if err != nil {
+		return nil, err
+	}
+	keep := ApprovedPaths(approved)
+	out := tasks[:0]
+	for _, task := range tasks {
+		if keep[task.Job.Path] {
+			out = append(out, task)
+			continue
+		}
+		count.skipped.Add(1)
+		record(task, FileResult{
+			Path:    task.Job.Path,
+			Status:  "skipped",
+			Message: "Declined at confirmation",
+		})
+		done()
+	}
+	return out, nil
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+	"github.com/nir0k/GeoRAW/internal/media"
+)
+
+// minCoverage is the share of photos that must fall inside the track before the
+// selection is considered a plausible match for it.
+const minCoverage = 0.5
+
+// Coverage compares the capture times of the selected photos with the GPX track.
+type Coverage struct {
+	Photos     int           `json:"photos"`  // photos with a readable capture time
+	InTrack    int           `json:"inTrack"` // photos whose adjusted time is covered by the track
+	PhotoStart time.Time     `json:"photoStart"`
+	PhotoEnd   time.Time     `json:"photoEnd"`
+	TrackStart time.Time     `json:"trackStart"`
+	TrackEnd   time.Time     `json:"trackEnd"`
+	Offset     time.Duration `json:"offset"`            // offset applied to capture times, including auto-detection
+	Warning    string        `json:"warning,omitempty"` // set when the photos barely overlap the track
+}
+
+// CheckCoverage reads the capture times of the selected photos, applies the time
+// zone and offset the run would use, and reports how many land inside the track.
+// It writes nothing and is meant to run before Run to catch offset mistakes.
+func CheckCoverage(ctx context.Context, opts Options) (*Coverage, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	jobs, track, err := readPhotosAndTrack(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	offset := opts.TimeOffset
+	if offset == 0 && opts.AutoOffset {
+		if detected, err := detectOffset(track, jobs); err == nil {
+			offset = detected.Offset
+		}
+	}
+
+	cov := &Coverage{Photos: len(jobs), Offset: offset}
+	cov.TrackStart, cov.TrackEnd = track.Bounds()
+	for _, job := range jobs {
+		capture := job.Meta.CaptureTime.Add(offset).UTC()
+		if cov.PhotoStart.IsZero() || capture.Before(cov.PhotoStart) {
+			cov.PhotoStart = capture
+		}
+		if capture.After(cov.PhotoEnd) {
+			cov.PhotoEnd = capture
+		}
+		if _, err := track.CoordinateAt(capture); !errors.Is(err, gpx.ErrTimestampOutOfBounds) {
+			cov.InTrack++
+		}
+	}
+	cov.Warning = coverageWarning(cov)
+	return cov, nil
+}
+
+// coverageWarning explains a poor overlap, including how far apart the two ranges
+// are, which usually points at a wrong time zone or offset.
+func coverageWarning(cov *Coverage) string {
+	if float64(cov.InTrack) >= minCoverage*float64(cov.Photos) {
+		return ""
+	}
+	msg := fmt.Sprintf("Only %d of %d photos fall within the GPX track (photos %s – %s, track %s – %s UTC).",
+		cov.InTrack, cov.Photos,
+		cov.PhotoStart.Format(time.DateTime), cov.PhotoEnd.Format(time.DateTime),
+		cov.TrackStart.Format(time.DateTime), cov.TrackEnd.Format(time.DateTime))
+	switch {
+	case cov.PhotoEnd.Before(cov.TrackStart):
+		msg += fmt.Sprintf(" The photos end %s before the track starts.", cov.TrackStart.Sub(cov.PhotoEnd).Round(time.Minute))
+	case cov.PhotoStart.After(cov.TrackEnd):
+		msg += fmt.Sprintf(" The photos start %s after the track ends.", cov.PhotoStart.Sub(cov.TrackEnd).Round(time.Minute))
+	}
+	return msg + " Check the time offset and time zone."
+}
+
+// readPhotosAndTrack reads the capture times of the selected RAW files, loads the
+// track around them, and applies the camera time zone, without writing anything.
+func readPhotosAndTrack(ctx context.Context, opts Options) ([]photoJob, *gpx.TrackIndex, error) {
+	jobs, err := readPhotos(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	track, err := loadTrack(ctx, opts, jobs, discardLog())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loc, err := loadTimeZone(opts.TimeZone, track)
+	if err != nil {
+		return nil, nil, err
+	}
+	if loc != nil {
+		applyTimeZone(jobs, loc)
+	}
+	return jobs, track, nil
+}
+
+// readPhotos reads the capture times, with earlier corrections applied, of the
+// selected RAW files.
+func readPhotos(ctx context.Context, opts Options) ([]photoJob, error) {
+	var jobs []photoJob
+	_, err := walkInput(opts, discardLog(), func(path string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if strings.EqualFold(filepath.Ext(path), ".xmp") || !media.SupportedRaw(path) {
+			return nil
+		}
+		if meta, err := media.ReadMetadata(path); err == nil {
+			applyTimeShift(path, &meta)
+			jobs = append(jobs, photoJob{Path: path, Meta: meta})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("no RAW files with a capture time found")
+	}
+	return jobs, nil
+}
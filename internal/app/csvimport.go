@@ -0,0 +1,400 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/nir0k/GeoRAW/internal/pathnorm"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// csvTimeLayouts are accepted for the optional time column; values without a zone are UTC.
+var csvTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006:01:02 15:04:05",
+}
+
+// csvRow is one parsed line of a coordinate CSV.
+type csvRow struct {
+	Line  int
+	Name  string
+	Coord gpx.Coordinate
+	Time  time.Time
+}
+
+// ImportCSV writes sidecars from a CSV of filename,lat,lon[,alt[,time]] rows instead of
+// a GPX track. File names are resolved against Inputs when set (by relative path
+// or unique base name), otherwise against the CSV's folder. Rows without a time use
+// the photo's capture time.
+func ImportCSV(ctx context.Context, opts Options) (*Summary, error) {
+	return importCSV(ctx, opts, nil)
+}
+
+// ImportCSVWithLogger is ImportCSV with logs piped into an in-memory buffer.
+func ImportCSVWithLogger(ctx context.Context, opts Options, out io.Writer) (*Summary, error) {
+	return importCSV(ctx, opts, out)
+}
+
+func importCSV(ctx context.Context, opts Options, out io.Writer) (sum *Summary, err error) {
+	opts.CSVPath = strings.TrimSpace(opts.CSVPath)
+	if opts.CSVPath == "" {
+		return nil, fmt.Errorf("CSV path is required")
+	}
+	if len(opts.Inputs) == 0 {
+		// Names are resolved next to the CSV.
+		opts.Inputs = []string{filepath.Dir(opts.CSVPath)}
+	}
+	if err := opts.validateCommon(); err != nil {
+		return nil, err
+	}
+
+	logs, err := openRunLog(opts, out)
+	if err != nil {
+		return nil, err
+	}
+	started := time.Now()
+	defer func() { recordRun("csv", opts, started, sum, err, logs) }()
+	release, err := lockRun(ctx, opts, "csv", logs)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	infof := logs.infof
+
+	infof("Starting CSV import with csv=%s input=%s recursive=%t overwrite=%t", opts.CSVPath, opts.inputText(), opts.Recursive, opts.Overwrite)
+
+	rows, err := readCoordinateCSV(opts.CSVPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("CSV file contains no coordinate rows")
+	}
+
+	resolve, truncated, err := csvResolver(opts, logs)
+	if err != nil {
+		return nil, err
+	}
+	imported := make([]importedRow, 0, len(rows))
+	for _, row := range rows {
+		path, err := resolve(row.Name)
+		if err != nil {
+			path = row.Name
+		}
+		imported = append(imported, importedRow{
+			Path:   path,
+			Err:    err,
+			Where:  fmt.Sprintf("CSV line %d", row.Line),
+			Source: fmt.Sprintf("%s:%d", filepath.Base(opts.CSVPath), row.Line),
+			Coord:  row.Coord,
+			Time:   row.Time,
+		})
+	}
+	return writeImported(ctx, opts, logs, imported, truncated)
+}
+
+// importedRow is a coordinate read from an import file for one photo.
+type importedRow struct {
+	Path   string // photo, or the name the file gives when Err is set
+	Err    error  // why the row cannot be written; the photo is reported as failed
+	Skip   bool   // report Err as skipped instead of failed
+	Where  string // position in the import file for log lines, e.g. "CSV line 4"
+	Source string // file:line recorded as where the coordinate came from
+	Coord  gpx.Coordinate
+	Time   time.Time // time given by the import file; the capture time when zero
+}
+
+// writeImported writes the coordinates of imported rows into the sidecars of
+// their photos and summarizes the run.
+func writeImported(ctx context.Context, opts Options, logs runLog, rows []importedRow, truncated bool) (*Summary, error) {
+	infof := logs.infof
+	altitudes, err := opts.Altitude.open()
+	if err != nil {
+		return nil, err
+	}
+	defer altitudes.close()
+	if altitudes != nil {
+		infof("Converting imported altitudes from %s to meters above sea level", opts.Altitude.describe())
+	}
+
+	total := len(rows) * 2
+	done := 0
+	var progressMu sync.Mutex
+	advance := func(step int) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		done += step
+		if opts.Progress != nil {
+			opts.Progress(min(done, total), total)
+		}
+	}
+
+	var (
+		count counters
+		clock stageClock
+	)
+	store := newResultStore(ctx, opts, &clock, logs)
+	defer store.remove()
+	defer store.close()
+	tasks := make([]sidecarTask, 0, len(rows))
+	for i, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		path := row.Path
+		if row.Err != nil {
+			logs.file(StageCollect, path).warnf("%s: %v", row.Where, row.Err)
+			res := FileResult{Path: path, Status: "failed", Message: row.Err.Error()}
+			if row.Skip {
+				res.Status = "skipped"
+				count.skipped.Add(1)
+			} else {
+				count.failed.Add(1)
+			}
+			store.add(res, i)
+			advance(2)
+			continue
+		}
+
+		job := photoJob{Path: path, seq: i}
+		ts := row.Time
+		metaStart := time.Now()
+		meta, timeSource, err := readMetadata(path, opts.TimeFallback)
+		clock.since(path, StageMetadata, metaStart)
+		if err == nil {
+			applyTimeShift(path, &meta)
+			job.Meta = meta
+			if ts.IsZero() {
+				ts = meta.CaptureTime.Add(opts.TimeOffset).UTC()
+				job.TimeSource = timeSource
+			}
+		} else if ts.IsZero() {
+			logs.file(StageMetadata, path).warnf("%s: no time given and no capture time for %s: %v", row.Where, path, err)
+			store.add(metaFailure(path, err, &count), i)
+			advance(2)
+			continue
+		}
+		coord, err := altitudes.convert(row.Coord)
+		if err != nil {
+			logs.file(StageMatch, path).errorf("%s: %v", row.Where, err)
+			count.failed.Add(1)
+			store.add(FailedResult(path, "failed", err), i)
+			advance(2)
+			continue
+		}
+		advance(1)
+
+		if job.TimeSource != "" {
+			logs.file(StageMetadata, path).warnf("No capture time in the EXIF of %s; using %s from its %s", path, job.Meta.CaptureTime.Format(time.DateTime), job.TimeSource)
+		}
+		tasks = append(tasks, sidecarTask{
+			Job:       job,
+			Capture:   ts,
+			Coord:     coord,
+			Sidecar:   xmp.SidecarPath(path),
+			Source:    row.Source,
+			GPSSource: xmp.GPSSourceManual,
+		})
+	}
+
+	record := func(task sidecarTask, res FileResult) { store.addJob(task.Job, res) }
+	tasks, err = confirmTasks(opts.Confirm, tasks, record, &count, func() { advance(1) })
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeSidecars(ctx, tasks, opts.Workers, func(task sidecarTask) {
+		defer advance(1)
+		writeStart := time.Now()
+		res := applyTask(ctx, task, opts, &count, logs)
+		clock.since(task.Job.Path, StageWrite, writeStart)
+		record(task, res)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	store.close()
+	exportGeoJSON(opts, store.all(), logs)
+	uploadPositions(ctx, opts, store.all(), logs)
+	sum := &Summary{
+		RunID:     opts.RunID,
+		Processed: int(count.processed.Load()),
+		Skipped:   int(count.skipped.Load()),
+		Unchanged: int(count.unchanged.Load()),
+		Failed:    int(count.failed.Load()),
+		MetaError: int(count.metaError.Load()),
+		Truncated: truncated,
+	}
+	store.summarize(sum)
+	summary := fmt.Sprintf("Finished. processed=%d unchanged=%d failed=%d meta_errors=%d", sum.Processed, sum.Unchanged, sum.Failed, sum.MetaError)
+	if sum.Skipped > 0 {
+		summary += fmt.Sprintf(" skipped=%d", sum.Skipped)
+	}
+	if opts.PrintSummary {
+		fmt.Println(summary)
+	}
+	infof("%s", summary)
+	warnTruncated(opts, sum, logs)
+	logTripStats(opts, sum, logs)
+	logTimings(opts, sum, logs)
+	return sum, nil
+}
+
+// readCoordinateCSV parses filename,lat,lon[,alt[,time]] rows. The delimiter (comma,
+// semicolon, or tab) is detected from the first line, and a header row is skipped.
+func readCoordinateCSV(path string) ([]csvRow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read csv: %w", err)
+	}
+	data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = detectDelimiter(data)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+
+	var rows []csvRow
+	for first := true; ; first = false {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse csv: %w", err)
+		}
+		line, _ := r.FieldPos(0)
+		if len(record) < 3 {
+			return nil, fmt.Errorf("csv line %d: expected filename,lat,lon[,alt[,time]]", line)
+		}
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		lon, lonErr := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		if latErr != nil || lonErr != nil {
+			if first {
+				continue // header, the first record after any blank or comment lines
+			}
+			return nil, fmt.Errorf("csv line %d: invalid latitude/longitude %q,%q", line, record[1], record[2])
+		}
+
+		row := csvRow{Line: line, Name: strings.TrimSpace(record[0]), Coord: gpx.Coordinate{Latitude: lat, Longitude: lon}}
+		if len(record) > 3 && strings.TrimSpace(record[3]) != "" {
+			alt, err := strconv.ParseFloat(strings.TrimSpace(record[3]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("csv line %d: invalid altitude %q", line, record[3])
+			}
+			row.Coord.Altitude = &alt
+		}
+		if len(record) > 4 && strings.TrimSpace(record[4]) != "" {
+			ts, err := parseCSVTime(strings.TrimSpace(record[4]))
+			if err != nil {
+				return nil, fmt.Errorf("csv line %d: %w", line, err)
+			}
+			row.Time = ts
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// detectDelimiter picks the delimiter from the first line that is neither blank
+// nor a comment.
+func detectDelimiter(data []byte) rune {
+	var first []byte
+	for rest := data; len(rest) > 0 && first == nil; {
+		var line []byte
+		line, rest, _ = bytes.Cut(rest, []byte("\n"))
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 && trimmed[0] != '#' {
+			first = line
+		}
+	}
+	best, bestCount := ',', bytes.Count(first, []byte(","))
+	for _, d := range []rune{';', '\t'} {
+		if n := bytes.Count(first, []byte(string(d))); n > bestCount {
+			best, bestCount = d, n
+		}
+	}
+	return best
+}
+
+func parseCSVTime(raw string) (time.Time, error) {
+	for _, layout := range csvTimeLayouts {
+		if ts, err := time.Parse(layout, raw); err == nil {
+			return ts.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", raw)
+}
+
+// csvResolver returns a function that maps a CSV file name to a photo path, and
+// whether the input walk that indexes the photos stopped at the file limit.
+func csvResolver(opts Options, logs runLog) (func(name string) (string, error), bool, error) {
+	csvDir := filepath.Dir(opts.CSVPath)
+	byBase := make(map[string][]string)
+	byRel := make(map[string]string)
+	root, err := media.InputRoot(opts.Inputs)
+	if err != nil {
+		return nil, false, err
+	}
+	truncated, err := walkInput(opts, logs, func(path string) error {
+		if strings.EqualFold(filepath.Ext(path), ".xmp") {
+			return nil
+		}
+		base := strings.ToLower(pathnorm.Key(filepath.Base(path)))
+		byBase[base] = append(byBase[base], path)
+		if rel, err := filepath.Rel(root, path); err == nil {
+			byRel[strings.ToLower(pathnorm.Key(filepath.ToSlash(rel)))] = path
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return func(name string) (string, error) {
+		if name == "" {
+			return "", fmt.Errorf("empty file name")
+		}
+		if filepath.IsAbs(name) {
+			if _, err := os.Stat(name); err != nil {
+				return "", fmt.Errorf("photo not found: %s", name)
+			}
+			return name, nil
+		}
+		key := strings.ToLower(pathnorm.Key(filepath.ToSlash(filepath.Clean(name))))
+		if path, ok := byRel[key]; ok {
+			return path, nil
+		}
+		switch matches := byBase[strings.ToLower(pathnorm.Key(filepath.Base(name)))]; len(matches) {
+		case 0:
+		case 1:
+			return matches[0], nil
+		default:
+			return "", fmt.Errorf("%s matches %d files; use a relative path", name, len(matches))
+		}
+		if candidate := filepath.Join(csvDir, name); fileExists(candidate) {
+			return candidate, nil
+		}
+		return "", fmt.Errorf("photo not found: %s", name)
+	}, truncated, nil
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,210 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/geocode"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// descriptionPlaceholders are the fields a description template may use; the
+// place fields need a reverse geocode of the written position.
+var descriptionPlaceholders = map[string]bool{
+	"place": true, "city": true, "region": true, "country": true,
+	"date": false, "time": false, "year": false, "camera": false, "lens": false,
+}
+
+// Separators that are dropped when a placeholder around them expands to nothing.
+var (
+	captionSpaceRegex    = regexp.MustCompile(`\s+`)
+	captionSepRunRegex   = regexp.MustCompile(`\s*([,;|·—–])(?:\s*[,;|·—–])+`)
+	captionSepSpaceRegex = regexp.MustCompile(`\s+([,;])`)
+	captionEdgeRegex     = regexp.MustCompile(`^[\s,;|·—–-]+|[\s,;|·—–-]+$`)
+)
+
+// DescriptionTemplate fills dc:description and dc:title of every processed
+// sidecar, e.g. "Shot at {place}, {date} — {camera} {lens}". {place}, {city},
+// {region} and {country} come from reverse geocoding the written position with
+// OpenStreetMap; {date}, {time} and {year} from the capture time; {camera} and
+// {lens} from EXIF. Placeholders without a value are dropped together with the
+// separator next to them.
+type DescriptionTemplate struct {
+	Template       string   // dc:description, written as the x-default alternative
+	Languages      []string // more dc:description alternatives as LANG=TEMPLATE, e.g. "hu=Készült: {place}"
+	Title          string   // dc:title, written as the x-default alternative
+	TitleLanguages []string // more dc:title alternatives as LANG=TEMPLATE
+	Overwrite      bool     // replace alternatives the sidecar already has in the same language
+
+	description []langTemplate    // parsed by load
+	title       []langTemplate    // parsed by load
+	places      *geocode.Resolver // set by load when a template uses place fields
+}
+
+// langTemplate is the template of one xml:lang alternative.
+type langTemplate struct {
+	Lang     string
+	Template string
+}
+
+// IsZero reports whether no description or title is configured.
+func (t DescriptionTemplate) IsZero() bool {
+	return strings.TrimSpace(t.Template) == "" && strings.TrimSpace(t.Title) == "" &&
+		len(t.Languages) == 0 && len(t.TitleLanguages) == 0
+}
+
+// load parses the language alternatives, validates the placeholders and
+// prepares the place lookup.
+func (t *DescriptionTemplate) load() error {
+	var err error
+	if t.description, err = parseLangTemplates(t.Template, t.Languages); err != nil {
+		return fmt.Errorf("description: %w", err)
+	}
+	if t.title, err = parseLangTemplates(t.Title, t.TitleLanguages); err != nil {
+		return fmt.Errorf("title: %w", err)
+	}
+	needsPlace := false
+	for _, lt := range append(append([]langTemplate(nil), t.description...), t.title...) {
+		for _, m := range attributionPlaceholderRegex.FindAllStringSubmatch(lt.Template, -1) {
+			place, ok := descriptionPlaceholders[m[1]]
+			if !ok {
+				return fmt.Errorf("unknown placeholder {%s} in description template", m[1])
+			}
+			needsPlace = needsPlace || place
+		}
+	}
+	if needsPlace && t.places == nil {
+		dir, _ := geocode.DefaultCacheDir()
+		t.places = geocode.NewResolver(dir)
+	}
+	return nil
+}
+
+// parseLangTemplates returns the default template followed by the LANG=TEMPLATE
+// entries.
+func parseLangTemplates(def string, entries []string) ([]langTemplate, error) {
+	var out []langTemplate
+	if def = strings.TrimSpace(def); def != "" {
+		out = append(out, langTemplate{Lang: xmp.DefaultLang, Template: def})
+	}
+	seen := map[string]bool{strings.ToLower(xmp.DefaultLang): def != ""}
+	for _, entry := range entries {
+		lang, tmpl, ok := strings.Cut(entry, "=")
+		lang, tmpl = strings.TrimSpace(lang), strings.TrimSpace(tmpl)
+		if !ok || lang == "" || tmpl == "" || strings.ContainsAny(lang, " \t\"'<>&") {
+			return nil, fmt.Errorf("invalid language template %q (expected LANG=TEMPLATE, e.g. hu=Készült: {place})", entry)
+		}
+		if seen[strings.ToLower(lang)] {
+			return nil, fmt.Errorf("language %q is given twice", lang)
+		}
+		seen[strings.ToLower(lang)] = true
+		out = append(out, langTemplate{Lang: lang, Template: tmpl})
+	}
+	return out, nil
+}
+
+// captionFields are the values the placeholders of one photo expand to. place is
+// the zero Place when the position was not looked up.
+type captionFields struct {
+	Capture time.Time
+	Camera  string
+	Lens    string
+	Place   geocode.Place
+}
+
+// expandCaption fills the placeholders of tmpl for one photo.
+func expandCaption(tmpl string, f captionFields) string {
+	text := attributionPlaceholderRegex.ReplaceAllStringFunc(tmpl, func(token string) string {
+		switch token {
+		case "{place}":
+			return f.Place.Name
+		case "{city}":
+			return f.Place.Locality
+		case "{region}":
+			return f.Place.Region
+		case "{country}":
+			return f.Place.Country
+		case "{camera}":
+			return f.Camera
+		case "{lens}":
+			return f.Lens
+		}
+		if f.Capture.IsZero() {
+			return ""
+		}
+		switch token {
+		case "{date}":
+			return f.Capture.Format("2006-01-02")
+		case "{time}":
+			return f.Capture.Format("15:04")
+		case "{year}":
+			return strconv.Itoa(f.Capture.Year())
+		}
+		return token
+	})
+	return tidyCaption(text)
+}
+
+// expandLang fills every language alternative of templates.
+func expandLang(templates []langTemplate, f captionFields) []xmp.LangValue {
+	out := make([]xmp.LangValue, 0, len(templates))
+	for _, lt := range templates {
+		if v := expandCaption(lt.Template, f); v != "" {
+			out = append(out, xmp.LangValue{Lang: lt.Lang, Value: v})
+		}
+	}
+	return out
+}
+
+// tidyCaption collapses the whitespace and separators left by empty placeholders.
+func tidyCaption(s string) string {
+	s = captionSpaceRegex.ReplaceAllString(s, " ")
+	s = captionSepRunRegex.ReplaceAllString(s, "$1")
+	s = captionSepSpaceRegex.ReplaceAllString(s, "$1")
+	return captionEdgeRegex.ReplaceAllString(s, "")
+}
+
+// applyDescription writes the description and title templates into a sidecar the
+// run processed. A failed place lookup leaves the place fields empty; failing to
+// write is logged but does not fail the file.
+func applyDescription(ctx context.Context, opts Options, task sidecarTask, logs runLog) {
+	t := opts.Description
+	if len(t.description) == 0 && len(t.title) == 0 {
+		return
+	}
+	f := captionFields{
+		Capture: task.Job.Meta.CaptureTime,
+		Camera:  CameraName(task.Job.Meta.CameraMake, task.Job.Meta.CameraModel),
+		Lens:    task.Job.Meta.Lens,
+	}
+	if f.Capture.IsZero() {
+		f.Capture = task.Capture
+	}
+	if t.places != nil {
+		place, err := t.places.Reverse(ctx, task.Coord.Latitude, task.Coord.Longitude)
+		if err != nil {
+			logs.warnf("Could not resolve the place of %s for its description: %v", task.Job.Path, err)
+		} else {
+			f.Place = place
+		}
+	}
+	for _, prop := range []struct {
+		name      string
+		templates []langTemplate
+	}{
+		{"dc:title", t.title},
+		{"dc:description", t.description},
+	} {
+		values := expandLang(prop.templates, f)
+		if len(values) == 0 {
+			continue
+		}
+		if _, err := xmp.SetLangAlt(task.Sidecar, prop.name, values, t.Overwrite); err != nil {
+			logs.warnf("Failed to write %s into %s: %v", prop.name, task.Sidecar, err)
+		}
+	}
+}
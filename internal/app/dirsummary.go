@@ -0,0 +1,65 @@
+package app
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// DirSummary counts the results of one folder, so a run over a multi-folder
+// archive shows which card or day still needs attention.
+type DirSummary struct {
+	Dir        string `json:"dir"`
+	Files      int    `json:"files"`
+	Processed  int    `json:"processed"`
+	Skipped    int    `json:"skipped"`
+	Unchanged  int    `json:"unchanged"`
+	OutOfTrack int    `json:"out_of_track"`
+	Suspicious int    `json:"suspicious"`
+	Failed     int    `json:"failed"`
+	MetaError  int    `json:"meta_errors"`
+}
+
+// NeedsAttention reports whether the folder has results that a rerun or a manual
+// check should look at.
+func (d DirSummary) NeedsAttention() bool {
+	return d.Failed > 0 || d.MetaError > 0 || d.OutOfTrack > 0 || d.Suspicious > 0
+}
+
+// SummarizeDirectories counts the results per folder, sorted by folder.
+func SummarizeDirectories(results []FileResult) []DirSummary {
+	index := make(map[string]int)
+	var dirs []DirSummary
+	for _, res := range results {
+		dir := filepath.Dir(res.Path)
+		i, ok := index[dir]
+		if !ok {
+			i = len(dirs)
+			index[dir] = i
+			dirs = append(dirs, DirSummary{Dir: dir})
+		}
+		dirs[i].add(res.Status)
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Dir < dirs[j].Dir })
+	return dirs
+}
+
+// add counts a result with status.
+func (d *DirSummary) add(status string) {
+	d.Files++
+	switch status {
+	case "processed":
+		d.Processed++
+	case "skipped":
+		d.Skipped++
+	case "unchanged":
+		d.Unchanged++
+	case "out_of_track":
+		d.OutOfTrack++
+	case "suspicious":
+		d.Suspicious++
+	case "failed":
+		d.Failed++
+	case "meta_error":
+		d.MetaError++
+	}
+}
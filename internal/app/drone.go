@@ -0,0 +1,18 @@
+package app
+
+import "github.com/nir0k/GeoRAW/internal/xmp"
+
+// writeDroneHeading passes the gimbal heading of a mirrored drone photo through
+// as exif:GPSImgDirection. Failing to write it is logged but does not fail the file.
+func writeDroneHeading(task sidecarTask, logs runLog) {
+	if !task.Mirror {
+		return
+	}
+	heading, ok := task.Job.Meta.Drone.Heading()
+	if !ok {
+		return
+	}
+	if _, err := xmp.SetGPSImgDirection(task.Sidecar, heading); err != nil {
+		logs.warnf("Failed to write image direction into %s: %v", task.Sidecar, err)
+	}
+}
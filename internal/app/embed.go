@@ -0,0 +1,16 @@
+package app
+
+import "github.com/nir0k/GeoRAW/internal/media"
+
+// embedGPS also writes the task's position into the file itself when requested
+// and the format holds it, with the writer media.EmbeddedWriter names. A failure
+// is only a warning: the sidecar already has the position.
+func embedGPS(opts Options, task sidecarTask, logs runLog) {
+	if !opts.EmbedGPS || task.Mirror || !media.SupportedEmbed(task.Job.Path) {
+		return
+	}
+	c := task.Coord
+	if err := media.WriteGPS(task.Job.Path, c.Latitude, c.Longitude, c.Altitude, task.Capture); err != nil {
+		logs.warnf("Failed to write GPS into %s: %v (the sidecar has it)", task.Job.Path, err)
+	}
+}
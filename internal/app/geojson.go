@@ -0,0 +1,106 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// GeoPoint is the position written to a file's sidecar.
+type GeoPoint struct {
+	Latitude  float64   `json:"lat"`
+	Longitude float64   `json:"lon"`
+	Altitude  *float64  `json:"alt,omitempty"`
+	Time      time.Time `json:"time"`
+	Camera    string    `json:"camera,omitempty"`
+}
+
+type geoJSONCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string          `json:"type"`
+	Geometry   geoJSONGeometry `json:"geometry"`
+	Properties geoJSONProps    `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+type geoJSONProps struct {
+	Path      string `json:"path"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Time      string `json:"time"`
+	Camera    string `json:"camera,omitempty"`
+	SeriesID  string `json:"series_id,omitempty"`
+	Estimated bool   `json:"estimated,omitempty"` // bridged across a track gap with the motion model
+}
+
+// WriteGeoJSON writes a FeatureCollection with one point per result that has a position.
+// Series IDs are read from the sidecars (georaw:SeriesID) when present.
+func WriteGeoJSON(path string, results []FileResult) (int, error) {
+	return writeGeoJSON(path, slices.Values(results))
+}
+
+func writeGeoJSON(path string, results iter.Seq[FileResult]) (int, error) {
+	coll := geoJSONCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+	for res := range results {
+		pt := res.Point
+		if pt == nil {
+			continue
+		}
+		coords := []float64{pt.Longitude, pt.Latitude}
+		if pt.Altitude != nil {
+			coords = append(coords, *pt.Altitude)
+		}
+		coll.Features = append(coll.Features, geoJSONFeature{
+			Type:     "Feature",
+			Geometry: geoJSONGeometry{Type: "Point", Coordinates: coords},
+			Properties: geoJSONProps{
+				Path:      res.Path,
+				Name:      filepath.Base(res.Path),
+				Status:    res.Status,
+				Time:      pt.Time.UTC().Format(time.RFC3339),
+				Camera:    pt.Camera,
+				SeriesID:  xmp.ReadSeriesID(xmp.SidecarPath(res.Path)),
+				Estimated: res.Estimated,
+			},
+		})
+	}
+
+	data, err := json.MarshalIndent(coll, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("encode geojson: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, fmt.Errorf("create geojson dir: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return 0, fmt.Errorf("write geojson: %w", err)
+	}
+	return len(coll.Features), nil
+}
+
+// exportGeoJSON writes opts.GeoJSONPath when requested; failures are logged, not fatal.
+func exportGeoJSON(opts Options, results iter.Seq[FileResult], logs runLog) {
+	if opts.GeoJSONPath == "" {
+		return
+	}
+	n, err := writeGeoJSON(opts.GeoJSONPath, results)
+	if err != nil {
+		logs.errorf("Failed to write GeoJSON: %v", err)
+		return
+	}
+	logs.infof("GeoJSON with %d photos written to %s", n, opts.GeoJSONPath)
+}
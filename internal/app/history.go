@@ -0,0 +1,222 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/logging"
+)
+
+// Run records are kept as <run ID>.json in this folder next to the log file,
+// the newest historyLimit of them.
+const (
+	historyDirName = "georaw-runs"
+	historyLimit   = 500
+)
+
+// RunRecord is the structured record of one run, kept next to the log file so a
+// run can be looked up later by its ID (see `georaw history`).
+type RunRecord struct {
+	RunID    string          `json:"runId"`
+	Command  string          `json:"command"` // tag, csv, series, ...
+	Started  time.Time       `json:"started"`
+	Finished time.Time       `json:"finished"`
+	Options  json.RawMessage `json:"options,omitempty"` // the options of the run, callbacks left out
+	Error    string          `json:"error,omitempty"`   // why the run failed
+	Summary  *Summary        `json:"summary,omitempty"`
+}
+
+// HistoryDir returns the folder of the run records kept next to logFile, or next
+// to the default log file when it is empty.
+func HistoryDir(logFile string) (string, error) {
+	if strings.TrimSpace(logFile) == "" {
+		def, err := DefaultLogPath()
+		if err != nil {
+			return "", err
+		}
+		logFile = def
+	}
+	return filepath.Join(filepath.Dir(logFile), historyDirName), nil
+}
+
+// RecordRun completes rec with the finish time and opts and saves it in the
+// history of logFile, removing the oldest records beyond the limit. Nothing is
+// written when the log settings disable the log file. It returns the path written.
+func RecordRun(rec RunRecord, logFile string, opts interface{}) (string, error) {
+	if logging.Current().NoFile || rec.RunID == "" {
+		return "", nil
+	}
+	rec.Finished = time.Now()
+	if opts != nil {
+		data, err := json.Marshal(optionFields(opts))
+		if err != nil {
+			return "", fmt.Errorf("encode run options: %w", err)
+		}
+		rec.Options = data
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encode run record: %w", err)
+	}
+	dir, err := HistoryDir(logFile)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create run history: %w", err)
+	}
+	path := filepath.Join(dir, rec.RunID+".json")
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", fmt.Errorf("write run record: %w", err)
+	}
+	pruneHistory(dir)
+	return path, nil
+}
+
+// recordRun saves the record of a run that got as far as logging; a failure to
+// save it is only logged.
+func recordRun(command string, opts Options, started time.Time, sum *Summary, runErr error, logs runLog) {
+	if opts.watchBatch {
+		return
+	}
+	rec := RunRecord{RunID: opts.RunID, Command: command, Started: started, Summary: sum}
+	if runErr != nil {
+		rec.Error = runErr.Error()
+	}
+	if _, err := RecordRun(rec, opts.LogFile, opts); err != nil {
+		logs.warnf("Failed to save the run record: %v", err)
+	}
+}
+
+// optionFields returns the exported fields of an options struct that JSON can
+// hold, leaving out progress and confirmation callbacks.
+func optionFields(opts interface{}) interface{} {
+	v := reflect.Indirect(reflect.ValueOf(opts))
+	if v.Kind() != reflect.Struct {
+		return opts
+	}
+	fields := make(map[string]interface{})
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		switch field.Type.Kind() {
+		case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+			continue
+		}
+		if field.IsExported() {
+			fields[field.Name] = v.Field(i).Interface()
+		}
+	}
+	return fields
+}
+
+// pruneHistory removes the oldest records beyond historyLimit. Run IDs start
+// with their start time, so the names sort oldest first.
+func pruneHistory(dir string) {
+	names, err := historyNames(dir)
+	if err != nil || len(names) <= historyLimit {
+		return
+	}
+	for _, name := range names[:len(names)-historyLimit] {
+		os.Remove(filepath.Join(dir, name))
+	}
+}
+
+// historyNames lists the record files of dir, oldest first.
+func historyNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// LoadRunRecord reads the record of a run from the history of logFile. A unique
+// prefix of the run ID is enough.
+func LoadRunRecord(logFile, runID string) (*RunRecord, error) {
+	runID = strings.TrimSuffix(strings.TrimSpace(runID), ".json")
+	if runID == "" {
+		return nil, errors.New("run ID is required")
+	}
+	dir, err := HistoryDir(logFile)
+	if err != nil {
+		return nil, err
+	}
+	names, err := historyNames(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no runs recorded in %s", dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read run history: %w", err)
+	}
+	var matches []string
+	for _, name := range names {
+		id := strings.TrimSuffix(name, ".json")
+		if id == runID {
+			matches = []string{name}
+			break
+		}
+		if strings.HasPrefix(id, runID) {
+			matches = append(matches, name)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no run %s recorded in %s", runID, dir)
+	case 1:
+		return readRunRecord(filepath.Join(dir, matches[0]))
+	default:
+		return nil, fmt.Errorf("run ID %s is ambiguous: it matches %d runs", runID, len(matches))
+	}
+}
+
+// ListRunRecords returns the recorded runs of logFile, newest first, at most
+// limit of them when limit is positive.
+func ListRunRecords(logFile string, limit int) ([]RunRecord, error) {
+	dir, err := HistoryDir(logFile)
+	if err != nil {
+		return nil, err
+	}
+	names, err := historyNames(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read run history: %w", err)
+	}
+	var records []RunRecord
+	for i := len(names) - 1; i >= 0 && (limit <= 0 || len(records) < limit); i-- {
+		rec, err := readRunRecord(filepath.Join(dir, names[i]))
+		if err != nil {
+			continue
+		}
+		records = append(records, *rec)
+	}
+	return records, nil
+}
+
+func readRunRecord(path string) (*RunRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read run record: %w", err)
+	}
+	var rec RunRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("parse run record %s: %w", path, err)
+	}
+	return &rec, nil
+}
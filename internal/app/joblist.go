@@ -0,0 +1,274 @@
+package app
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+)
+
+// jobListColumns maps the accepted CSV header names to the FileOverride fields.
+var jobListColumns = map[string]string{
+	"path": "path", "file": "path", "filename": "path",
+	"lat": "lat", "latitude": "lat",
+	"lon": "lon", "lng": "lon", "longitude": "lon",
+	"alt": "alt", "altitude": "alt", "ele": "alt",
+	"offset": "offset", "time_offset": "offset",
+	"skip": "skip",
+}
+
+// FileOverride is one entry of a job list: a photo and the settings it takes
+// instead of the run's. Only the fields that are set override anything.
+type FileOverride struct {
+	Path      string   `json:"path"`
+	Latitude  *float64 `json:"latitude,omitempty"`  // manual position, written instead of the track match
+	Longitude *float64 `json:"longitude,omitempty"` // set together with Latitude
+	Altitude  *float64 `json:"altitude,omitempty"`  // meters above sea level; only with a manual position
+	Offset    string   `json:"offset,omitempty"`    // time offset replacing the run's, e.g. -1h2m or 30s
+	Skip      bool     `json:"skip,omitempty"`      // leave the photo alone
+}
+
+// jobOverride is a parsed FileOverride.
+type jobOverride struct {
+	Where  string          // job list entry for log lines, e.g. "jobs.csv:4"
+	Coord  *gpx.Coordinate // manual position
+	Offset *time.Duration  // forced time offset
+	Skip   bool
+}
+
+// JobList gives single photos of a tagging run their own position, time offset,
+// or a skip, for the few shots the automation gets wrong. Listed files not under
+// the input are ignored; without an input, the listed files are the input.
+type JobList struct {
+	Path string // CSV or JSON job list; no overrides when empty
+
+	entries map[string]jobOverride
+	paths   []string
+}
+
+// IsZero reports whether no job list is set.
+func (j JobList) IsZero() bool {
+	return j.Path == ""
+}
+
+// load reads and checks the entries of Path.
+func (j *JobList) load() error {
+	j.Path = strings.TrimSpace(j.Path)
+	if j.Path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(j.Path)
+	if err != nil {
+		return fmt.Errorf("read job list: %w", err)
+	}
+	data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+
+	var overrides []FileOverride
+	var where []string
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &overrides); err != nil {
+			return fmt.Errorf("parse job list %s: %w", j.Path, err)
+		}
+		for i := range overrides {
+			where = append(where, fmt.Sprintf("%s entry %d", filepath.Base(j.Path), i+1))
+		}
+	} else if overrides, where, err = readJobListCSV(data, filepath.Base(j.Path)); err != nil {
+		return err
+	}
+	if len(overrides) == 0 {
+		return fmt.Errorf("job list %s lists no files", j.Path)
+	}
+
+	dir := filepath.Dir(j.Path)
+	j.entries = make(map[string]jobOverride, len(overrides))
+	for i, o := range overrides {
+		path := strings.TrimSpace(o.Path)
+		if path == "" {
+			return fmt.Errorf("%s: no file given", where[i])
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		parsed, err := o.parse()
+		if err != nil {
+			return fmt.Errorf("%s: %w", where[i], err)
+		}
+		parsed.Where = where[i]
+		key := absPath(path)
+		if prev, ok := j.entries[key]; ok {
+			return fmt.Errorf("%s: %s is already listed in %s", where[i], o.Path, prev.Where)
+		}
+		j.entries[key] = parsed
+		j.paths = append(j.paths, path)
+	}
+	return nil
+}
+
+// parse checks the override fields and converts them.
+func (o FileOverride) parse() (jobOverride, error) {
+	var parsed jobOverride
+	if (o.Latitude == nil) != (o.Longitude == nil) {
+		return parsed, errors.New("latitude and longitude must be given together")
+	}
+	if o.Latitude != nil {
+		if *o.Latitude < -90 || *o.Latitude > 90 || *o.Longitude < -180 || *o.Longitude > 180 {
+			return parsed, fmt.Errorf("position %g,%g out of range", *o.Latitude, *o.Longitude)
+		}
+		parsed.Coord = &gpx.Coordinate{Latitude: *o.Latitude, Longitude: *o.Longitude, Altitude: o.Altitude}
+	} else if o.Altitude != nil {
+		return parsed, errors.New("altitude needs a latitude and longitude")
+	}
+	if raw := strings.TrimSpace(o.Offset); raw != "" {
+		offset, err := time.ParseDuration(raw)
+		if err != nil {
+			return parsed, fmt.Errorf("invalid offset %q", o.Offset)
+		}
+		parsed.Offset = &offset
+	}
+	if o.Skip && (parsed.Coord != nil || parsed.Offset != nil) {
+		return parsed, errors.New("a skipped file takes no position or offset")
+	}
+	parsed.Skip = o.Skip
+	return parsed, nil
+}
+
+// readJobListCSV parses a job list CSV. The header names the columns (path,
+// lat, lon, alt, offset, skip, in any order); only path is required. The
+// delimiter is detected as for coordinate CSVs.
+func readJobListCSV(data []byte, name string) ([]FileOverride, []string, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = detectDelimiter(data)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse job list: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, field := range header {
+		column, ok := jobListColumns[strings.ToLower(strings.TrimSpace(field))]
+		if !ok {
+			return nil, nil, fmt.Errorf("job list %s: unknown column %q (expected path, lat, lon, alt, offset, skip)", name, field)
+		}
+		columns[column] = i
+	}
+	if _, ok := columns["path"]; !ok {
+		return nil, nil, fmt.Errorf("job list %s: the header has no path column", name)
+	}
+
+	var overrides []FileOverride
+	var where []string
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse job list: %w", err)
+		}
+		line, _ := r.FieldPos(0)
+		at := fmt.Sprintf("%s:%d", name, line)
+		field := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		number := func(column string) (*float64, error) {
+			raw := field(column)
+			if raw == "" {
+				return nil, nil
+			}
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid %s %q", at, column, raw)
+			}
+			return &v, nil
+		}
+
+		o := FileOverride{Path: field("path"), Offset: field("offset")}
+		if o.Latitude, err = number("lat"); err != nil {
+			return nil, nil, err
+		}
+		if o.Longitude, err = number("lon"); err != nil {
+			return nil, nil, err
+		}
+		if o.Altitude, err = number("alt"); err != nil {
+			return nil, nil, err
+		}
+		switch strings.ToLower(field("skip")) {
+		case "", "0", "false", "no", "n":
+		case "1", "true", "yes", "y", "x":
+			o.Skip = true
+		default:
+			return nil, nil, fmt.Errorf("%s: invalid skip %q (expected yes or no)", at, field("skip"))
+		}
+		overrides = append(overrides, o)
+		where = append(where, at)
+	}
+	return overrides, where, nil
+}
+
+// input returns the listed files as a run input.
+// automatic returns the jobs the job list leaves to the run's time offset and
+// track, the ones an automatic offset may be estimated from.
+func (j JobList) automatic(jobs []photoJob) []photoJob {
+	if len(j.entries) == 0 {
+		return jobs
+	}
+	var out []photoJob
+	for _, job := range jobs {
+		if o, ok := j.lookup(job.Path); !ok || (o.Coord == nil && o.Offset == nil) {
+			out = append(out, job)
+		}
+	}
+	return out
+}
+
+// lookup returns the override of path, if the job list has one.
+func (j JobList) lookup(path string) (jobOverride, bool) {
+	o, ok := j.entries[absPath(path)]
+	return o, ok
+}
+
+// describe summarizes the overrides for the log.
+func (j JobList) describe() string {
+	var coords, offsets, skips int
+	for _, o := range j.entries {
+		switch {
+		case o.Skip:
+			skips++
+		case o.Coord != nil:
+			coords++
+		}
+		if o.Offset != nil {
+			offsets++
+		}
+	}
+	return fmt.Sprintf("%d files, %d manual positions, %d forced offsets, %d skipped", len(j.entries), coords, offsets, skips)
+}
+
+// unmatched counts the listed files the run did not come across.
+func (j JobList) unmatched(seen map[string]bool) int {
+	n := 0
+	for key := range j.entries {
+		if !seen[key] {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// skipLivePhotoVideo reports whether path is the video half of an iPhone Live
+// Photo. Its still stands for both halves, so walks pass over it without counting
+// it as a skipped file.
+func skipLivePhotoVideo(path string, logs runLog) bool {
+	still, ok := media.LivePhotoStill(path)
+	if ok {
+		logs.debugf("Treating %s as part of the Live Photo %s", path, still)
+	}
+	return ok
+}
+
+// livePhotoTask returns the task that writes the GPS of a Live Photo still into
+// a sidecar for its video. The video keeps IMG_0001.MOV.xmp, since IMG_0001.xmp
+// belongs to the still.
+func livePhotoTask(still sidecarTask) (sidecarTask, bool) {
+	video, ok := media.LivePhotoVideo(still.Job.Path)
+	if !ok {
+		return sidecarTask{}, false
+	}
+	return sidecarTask{
+		Job:       photoJob{Path: video, Meta: still.Job.Meta, seq: still.Job.seq},
+		Capture:   still.Capture,
+		Coord:     still.Coord,
+		Sidecar:   xmp.CompanionSidecarPath(video),
+		Source:    still.Job.Path,
+		LiveStill: still.Sidecar,
+		GPSSource: still.GPSSource,
+	}, true
+}
+
+// copyLivePhotoKeywords gives the sidecar of a Live Photo video the keywords of
+// its still's sidecar. Failing to copy them is logged but does not fail the file.
+func copyLivePhotoKeywords(task sidecarTask, logs runLog) {
+	if task.LiveStill == "" {
+		return
+	}
+	keywords, err := xmp.ReadKeywords(task.LiveStill)
+	if err != nil {
+		logs.warnf("Failed to read the keywords of %s: %v", task.LiveStill, err)
+		return
+	}
+	if len(keywords) == 0 {
+		return
+	}
+	if _, err := xmp.MergeKeywords(task.Sidecar, keywords, false); err != nil && !errors.Is(err, xmp.ErrKeywordsAlreadyPresent) {
+		logs.warnf("Failed to copy keywords into %s: %v", task.Sidecar, err)
+	}
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/nir0k/GeoRAW/internal/runlock"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// LockMode selects what a run does when another GeoRAW run is writing sidecars
+// in the same folder tree.
+type LockMode string
+
+const (
+	LockRefuse LockMode = "refuse" // fail with an error naming the other run (default)
+	LockWait   LockMode = "wait"   // wait until the other run has finished
+	LockOff    LockMode = "off"    // do not lock at all
+)
+
+// ParseLockMode validates a lock mode; an empty value selects LockRefuse.
+func ParseLockMode(raw string) (LockMode, error) {
+	switch mode := LockMode(strings.ToLower(strings.TrimSpace(raw))); mode {
+	case "":
+		return LockRefuse, nil
+	case LockRefuse, LockWait, LockOff:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid lock mode %q (expected refuse, wait or off)", raw)
+	}
+}
+
+// LockInput takes the run lock of the folder that sidecars for input are written
+// to: the common root of the input, or its mirror in the sidecar folder. The
+// returned release function must be called when the run ends.
+func LockInput(ctx context.Context, inputs []string, mode LockMode, command string, infof func(string, ...interface{})) (func(), error) {
+	mode, err := ParseLockMode(string(mode))
+	if err != nil {
+		return nil, err
+	}
+	if mode == LockOff {
+		return func() {}, nil
+	}
+	root, err := media.InputRoot(inputs)
+	if err != nil {
+		return nil, err
+	}
+	dir := xmp.SidecarFolder(root)
+	if dir != root {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create sidecar folder: %w", err)
+		}
+	}
+	onWait := func(e *runlock.LockedError) {
+		infof("Waiting for %s to finish (%s)", e.Holder, e.Path)
+	}
+	lock, err := runlock.Acquire(ctx, dir, command, mode == LockWait, onWait)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := lock.Release(); err != nil {
+			infof("Release run lock: %v", err)
+		}
+	}, nil
+}
+
+// lockRun takes the run lock for opts.Inputs.
+func lockRun(ctx context.Context, opts Options, command string, logs runLog) (func(), error) {
+	return LockInput(ctx, opts.Inputs, opts.Lock, command, logs.infof)
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"io"
+
+	"github.com/nir0k/GeoRAW/internal/logging"
+)
+
+// runLog holds the level-specific logging functions used during a run.
+type runLog struct {
+	debugf func(string, ...interface{})
+	infof  func(string, ...interface{})
+	warnf  func(string, ...interface{})
+	errorf func(string, ...interface{})
+}
+
+// discardLog drops every message, for shared helpers called outside a logged run.
+func discardLog() runLog {
+	nop := func(string, ...interface{}) {}
+	return runLog{debugf: nop, infof: nop, warnf: nop, errorf: nop}
+}
+
+// openRunLog returns the run's logger, writing to the log file shared by all
+// runs and, when out is set, copying its lines there, e.g. for the GUI.
+func openRunLog(opts Options, out io.Writer) (runLog, error) {
+	l, err := logging.New(logging.Config{File: opts.LogFile, Level: opts.LogLevel, RunID: opts.RunID, Out: out})
+	if err != nil {
+		return runLog{}, err
+	}
+	return runLog{debugf: l.Debugf, infof: l.Infof, warnf: l.Warningf, errorf: l.Errorf}, nil
+}
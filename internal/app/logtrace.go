@@ -0,0 +1,165 @@
+package app
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/fsretry"
+)
+
+// Stages of a file's lifecycle named in its log lines.
+const (
+	StageCollect  = "collect"  // the file was found in the input
+	StageMetadata = "metadata" // its capture time and camera were read
+	StageMatch    = "match"    // a position was looked up for it
+	StageWrite    = "write"    // its sidecar was written
+	StageChecksum = "checksum" // its SHA-256 was computed or verified
+)
+
+// fileFieldRegex finds the stage and file fields that runLog.file adds to a line.
+var fileFieldRegex = regexp.MustCompile(`\[stage ([a-z]+)\] \[file (.+?)\] `)
+
+// file returns logs whose messages carry the stage and path of one file, e.g.
+// "[stage write] [file /photos/IMG_1234.CR3] ...", so the whole lifecycle of the
+// file can be picked out of the log with TraceFile.
+func (l runLog) file(stage, path string) runLog {
+	// The prefix goes into the format, so a % in the path must not start a verb.
+	prefix := "[stage " + stage + "] [file " + strings.ReplaceAll(path, "%", "%%") + "] "
+	with := func(logf func(string, ...interface{})) func(string, ...interface{}) {
+		return func(format string, args ...interface{}) {
+			logf(prefix+format, args...)
+		}
+	}
+	return runLog{debugf: with(l.debugf), infof: with(l.infof), warnf: with(l.warnf), errorf: with(l.errorf)}
+}
+
+// FileFilter selects the log lines of one file.
+type FileFilter struct {
+	Name  string // file name, or a path or its trailing part
+	RunID string // only lines of this run
+	Stage string // only lines of this stage
+}
+
+// Match reports whether line is logged for the file of f.
+func (f FileFilter) Match(line string) bool {
+	m := fileFieldRegex.FindStringSubmatch(line)
+	if m == nil {
+		return false
+	}
+	if f.Stage != "" && m[1] != f.Stage {
+		return false
+	}
+	if f.RunID != "" && !strings.Contains(line, "[run "+f.RunID+"] ") {
+		return false
+	}
+	return matchFileName(m[2], f.Name)
+}
+
+// matchFileName reports whether the logged path is name: the same path, the same
+// base name (case-insensitive, as cameras and Windows mix case), or a path ending
+// in name.
+func matchFileName(logged, name string) bool {
+	name = strings.TrimSpace(name)
+	if name == "" || logged == name {
+		return true
+	}
+	logged, name = filepath.ToSlash(logged), filepath.ToSlash(name)
+	if !strings.Contains(name, "/") {
+		return strings.EqualFold(logged[strings.LastIndex(logged, "/")+1:], name)
+	}
+	return strings.HasSuffix(logged, "/"+strings.TrimPrefix(name, "/"))
+}
+
+// FilterLog writes the lines of r that f matches to w and returns how many.
+func FilterLog(w io.Writer, r io.Reader, f FileFilter) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4<<20)
+	n := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !f.Match(line) {
+			continue
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, scanner.Err()
+}
+
+// TraceFile writes the lines that f matches in the log file and its rotated
+// backups, oldest first, to w and returns how many.
+func TraceFile(w io.Writer, logPath string, f FileFilter) (int, error) {
+	files, err := logFiles(logPath)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, path := range files {
+		n, err := traceLogFile(w, path, f)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("read log %s: %w", path, err)
+		}
+	}
+	return total, nil
+}
+
+// logFiles returns the rotated backups of logPath, named like
+// georaw-2024-10-16T08-15-00.000.log(.gz), in time order, followed by logPath.
+func logFiles(logPath string) ([]string, error) {
+	dir, base := filepath.Split(logPath)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "-"
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.Type().IsRegular() && strings.HasPrefix(name, prefix) &&
+			(strings.HasSuffix(name, ext) || strings.HasSuffix(name, ext+".gz")) {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(files)
+	if _, err := os.Stat(logPath); err == nil {
+		files = append(files, logPath)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no log found at %s", logPath)
+	}
+	return files, nil
+}
+
+func traceLogFile(w io.Writer, path string, f FileFilter) (int, error) {
+	var file *os.File
+	err := fsretry.Do(func() error {
+		var err error
+		file, err = os.Open(path)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return 0, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return FilterLog(w, r, f)
+}
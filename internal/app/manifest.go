@@ -0,0 +1,134 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/nir0k/GeoRAW/internal/pathnorm"
+)
+
+// WriteManifest stores the run summary as JSON, so a later run can refer back to
+// the files it wrote (see OverwriteScope.ListedIn).
+func WriteManifest(path string, sum *Summary) error {
+	data, err := json.MarshalIndent(sum, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
+
+// writeManifestFiles is WriteManifest for a summary whose files are read from
+// files one at a time instead of sum.Files, which it leaves out. The output is the
+// same as WriteManifest's with all files in Files. It returns how many files it
+// wrote.
+func writeManifestFiles(path string, sum *Summary, files iter.Seq[FileResult]) (int, error) {
+	head := *sum
+	head.Files, head.FilesOmitted = nil, 0
+	data, err := json.MarshalIndent(head, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("encode manifest: %w", err)
+	}
+	before, after, ok := bytes.Cut(data, []byte("\n  \"files\": null"))
+	if !ok {
+		return 0, fmt.Errorf("encode manifest: no files field")
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("write manifest: %w", err)
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	w.Write(before)
+	w.WriteString("\n  \"files\": [")
+	n := 0
+	for res := range files {
+		item, err := json.MarshalIndent(res, "    ", "  ")
+		if err != nil {
+			return n, fmt.Errorf("encode manifest: %w", err)
+		}
+		if n > 0 {
+			w.WriteByte(',')
+		}
+		w.WriteString("\n    ")
+		w.Write(item)
+		n++
+	}
+	if n > 0 {
+		w.WriteString("\n  ")
+	}
+	w.WriteByte(']')
+	w.Write(after)
+	w.WriteByte('\n')
+	if err := w.Flush(); err != nil {
+		return n, fmt.Errorf("write manifest: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return n, fmt.Errorf("write manifest: %w", err)
+	}
+	return n, nil
+}
+
+// ReadManifest returns the absolute paths of the files a manifest records as processed.
+func ReadManifest(path string) (map[string]bool, error) {
+	return manifestFiles(path, "processed")
+}
+
+// manifestFiles returns the absolute paths of the files a manifest records with
+// one of the statuses.
+func manifestFiles(path string, statuses ...string) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	var sum Summary
+	if err := json.Unmarshal(data, &sum); err != nil {
+		return nil, fmt.Errorf("parse manifest %s: %w", path, err)
+	}
+	files := make(map[string]bool)
+	for _, res := range sum.Files {
+		if slices.Contains(statuses, res.Status) {
+			files[absPath(res.Path)] = true
+		}
+	}
+	return files, nil
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return pathnorm.Key(abs)
+	}
+	return pathnorm.Key(filepath.Clean(path))
+}
+
+func exportManifest(opts Options, sum *Summary, logs runLog) {
+	if opts.ManifestPath == "" {
+		return
+	}
+	if err := WriteManifest(opts.ManifestPath, sum); err != nil {
+		logs.errorf("Failed to write manifest: %v", err)
+		return
+	}
+	logs.infof("Manifest with %d files written to %s", len(sum.Files), opts.ManifestPath)
+}
+
+// exportManifestFiles is exportManifest with the files read from files.
+func exportManifestFiles(opts Options, sum *Summary, files iter.Seq[FileResult], logs runLog) {
+	if opts.ManifestPath == "" {
+		return
+	}
+	n, err := writeManifestFiles(opts.ManifestPath, sum, files)
+	if err != nil {
+		logs.errorf("Failed to write manifest: %v", err)
+		return
+	}
+	logs.infof("Manifest with %d files written to %s", n, opts.ManifestPath)
+}
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/errcode"
+	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// Normalize copies GPS already embedded in photos (RAW, JPEG, HEIF, ...) into their
+// XMP sidecars. No GPX track is needed; GPXPath, TimeOffset and AutoOffset are ignored.
+func Normalize(ctx context.Context, opts Options) (*Summary, error) {
+	return normalize(ctx, opts, nil)
+}
+
+// NormalizeWithLogger is Normalize with logs piped into an in-memory buffer.
+func NormalizeWithLogger(ctx context.Context, opts Options, out io.Writer) (*Summary, error) {
+	return normalize(ctx, opts, out)
+}
+
+func normalize(ctx context.Context, opts Options, out io.Writer) (sum *Summary, err error) {
+	if err := opts.validateCommon(); err != nil {
+		return nil, err
+	}
+
+	logs, err := openRunLog(opts, out)
+	if err != nil {
+		return nil, err
+	}
+	started := time.Now()
+	defer func() { recordRun("normalize", opts, started, sum, err, logs) }()
+	release, err := lockRun(ctx, opts, "normalize", logs)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	infof := logs.infof
+
+	infof("Starting GPS normalization with input=%s recursive=%t overwrite=%t", opts.inputText(), opts.Recursive, opts.Overwrite)
+
+	progressTotal := 0
+	progressDone := 0
+	var progressMu sync.Mutex
+	step := func(done, total int) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		progressDone += done
+		progressTotal += total
+		if opts.Progress != nil && progressTotal > 0 {
+			opts.Progress(min(progressDone, progressTotal), progressTotal)
+		}
+	}
+
+	var (
+		count counters
+		clock stageClock
+		found int
+		jobs  []photoJob
+	)
+	store := newResultStore(ctx, opts, &clock, logs)
+	defer store.remove()
+	defer store.close()
+	// rawSidecars marks sidecars owned by a RAW file; a JPEG with the same base name
+	// must not write them.
+	rawSidecars := make(map[string]string)
+
+	truncated, err := walkInput(opts, logs, func(path string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		found++
+		if strings.EqualFold(filepath.Ext(path), ".xmp") || skipLivePhotoVideo(path, logs) {
+			return nil
+		}
+		step(0, 2)
+		if !media.SupportedExif(path) {
+			logs.file(StageCollect, path).warnf("Skipping unsupported file: %s", path)
+			count.skipped.Add(1)
+			store.add(FileResult{Path: path, Status: "skipped"}, found)
+			step(2, 0)
+			return nil
+		}
+		if media.SupportedRaw(path) {
+			rawSidecars[xmp.SidecarPath(path)] = path
+		}
+
+		metaStart := time.Now()
+		meta, err := media.ReadMetadata(path)
+		clock.since(path, StageMetadata, metaStart)
+		if err != nil {
+			logs.file(StageMetadata, path).warnf("Failed to read metadata for %s: %v", path, err)
+			store.add(metaFailure(path, err, &count), found)
+			step(2, 0)
+			return nil
+		}
+		if meta.GPS == nil {
+			count.skipped.Add(1)
+			store.add(FileResult{Path: path, Status: "skipped", Message: "No embedded GPS"}, found)
+			step(2, 0)
+			return nil
+		}
+		jobs = append(jobs, photoJob{Path: path, Meta: meta, seq: found})
+		step(1, 0)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == 0 {
+		return nil, errcode.New(errcode.NoFiles, nil)
+	}
+
+	tasks := make([]sidecarTask, 0, len(jobs))
+	for _, job := range jobs {
+		sidecar := xmp.SidecarPath(job.Path)
+		if owner, ok := rawSidecars[sidecar]; ok && owner != job.Path {
+			logs.file(StageMatch, job.Path).infof("Skipping %s: sidecar %s belongs to %s", job.Path, sidecar, owner)
+			count.skipped.Add(1)
+			store.addJob(job, FileResult{Path: job.Path, Status: "skipped", Message: "Sidecar belongs to RAW file"})
+			step(1, 0)
+			continue
+		}
+		capture := job.Meta.CaptureTime.UTC()
+		if !job.Meta.GPS.Time.IsZero() {
+			capture = job.Meta.GPS.Time
+		}
+		task := sidecarTask{
+			Job:       job,
+			Capture:   capture,
+			Coord:     embeddedCoordinate(job.Meta.GPS),
+			Sidecar:   sidecar,
+			Mirror:    true,
+			GPSSource: xmp.GPSSourceMirror,
+		}
+		tasks = append(tasks, task)
+		if video, ok := livePhotoTask(task); ok {
+			logs.file(StageMatch, video.Job.Path).debugf("Copying GPS of Live Photo %s to its video %s", job.Path, video.Job.Path)
+			tasks = append(tasks, video)
+			step(1, 2)
+		}
+	}
+	jobs = nil // the tasks carry what is still needed of them
+
+	record := func(task sidecarTask, res FileResult) { store.addJob(task.Job, res) }
+	tasks, err = confirmTasks(opts.Confirm, tasks, record, &count, func() { step(1, 0) })
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeSidecars(ctx, tasks, opts.Workers, func(task sidecarTask) {
+		defer step(1, 0)
+		writeStart := time.Now()
+		res := applyTask(ctx, task, opts, &count, logs)
+		clock.since(task.Job.Path, StageWrite, writeStart)
+		record(task, res)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	store.close()
+	exportGeoJSON(opts, store.all(), logs)
+	uploadPositions(ctx, opts, store.all(), logs)
+	sum = &Summary{
+		RunID:     opts.RunID,
+		Processed: int(count.processed.Load()),
+		Skipped:   int(count.skipped.Load()),
+		Unchanged: int(count.unchanged.Load()),
+		Failed:    int(count.failed.Load()),
+		MetaError: int(count.metaError.Load()),
+		Truncated: truncated,
+	}
+	store.summarize(sum)
+	summary := fmt.Sprintf("Finished. processed=%d skipped=%d unchanged=%d failed=%d meta_errors=%d", sum.Processed, sum.Skipped, sum.Unchanged, sum.Failed, sum.MetaError)
+	if opts.PrintSummary {
+		fmt.Println(summary)
+	}
+	infof("%s", summary)
+	warnTruncated(opts, sum, logs)
+	logTripStats(opts, sum, logs)
+	logTimings(opts, sum, logs)
+	return sum, nil
+}
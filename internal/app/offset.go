@@ -14,14 +14,35 @@ const (
 )
 
 type photoJob struct {
-	Path string
-	Meta media.Metadata
+	Path       string
+	Meta       media.Metadata
+	TimeSource string // fallback the capture time came from; empty for the EXIF time
+	seq        int    // position of the file in the input walk
+}
+
+// offsetEstimate is an offset detected from the photos and how well they agree on it.
+type offsetEstimate struct {
+	Offset  time.Duration
+	Samples int
+	Spread  time.Duration // interquartile range of the per-photo differences
+}
+
+// offsetSample is the difference between a photo's capture time and the nearest
+// track point.
+type offsetSample struct {
+	Capture time.Time
+	Diff    time.Duration
 }
 
 // detectOffset tries to find a consistent offset between camera time and GPX points.
-func detectOffset(track *gpx.TrackIndex, photos []photoJob) (time.Duration, int, error) {
-	var diffs []time.Duration
+func detectOffset(track *gpx.TrackIndex, photos []photoJob) (offsetEstimate, error) {
+	return estimateOffset(offsetSamples(track, photos))
+}
 
+// offsetSamples pairs every photo with the nearest track point, leaving out photos
+// further than maxAutoOffset from the track.
+func offsetSamples(track *gpx.TrackIndex, photos []photoJob) []offsetSample {
+	var samples []offsetSample
 	for _, job := range photos {
 		_, nearestTime, err := track.Nearest(job.Meta.CaptureTime)
 		if err != nil {
@@ -31,11 +52,20 @@ func detectOffset(track *gpx.TrackIndex, photos []photoJob) (time.Duration, int,
 		if absDuration(diff) > maxAutoOffset {
 			continue
 		}
-		diffs = append(diffs, diff)
+		samples = append(samples, offsetSample{Capture: job.Meta.CaptureTime.UTC(), Diff: diff})
+	}
+	return samples
+}
+
+// estimateOffset takes the median of the samples as the offset.
+func estimateOffset(samples []offsetSample) (offsetEstimate, error) {
+	diffs := make([]time.Duration, 0, len(samples))
+	for _, s := range samples {
+		diffs = append(diffs, s.Diff)
 	}
 
 	if len(diffs) == 0 {
-		return 0, 0, fmt.Errorf("unable to detect offset: no usable samples within %s window", maxAutoOffset)
+		return offsetEstimate{}, fmt.Errorf("unable to detect offset: no usable samples within %s window", maxAutoOffset)
 	}
 
 	sort.Slice(diffs, func(i, j int) bool {
@@ -50,7 +80,8 @@ func detectOffset(track *gpx.TrackIndex, photos []photoJob) (time.Duration, int,
 		median = diffs[mid]
 	}
 
-	return median, len(diffs), nil
+	spread := diffs[len(diffs)*3/4] - diffs[len(diffs)/4]
+	return offsetEstimate{Offset: median, Samples: len(diffs), Spread: spread}, nil
 }
 
 func absDuration(d time.Duration) time.Duration {
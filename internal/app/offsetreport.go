@@ -0,0 +1,155 @@
+package app
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/timefix"
+)
+
+const (
+	// driftMinEffect is how far a fitted clock drift must move the offset between the
+	// first and last photo before it is reported.
+	driftMinEffect = 30 * time.Second
+	// driftMinSamples is the number of samples needed to fit a drift at all.
+	driftMinSamples = 3
+	// confidenceSampleScale is the sample count at which offset confidence reaches
+	// half of what the spread allows.
+	confidenceSampleScale = 3
+)
+
+// DetectedOffset is a camera clock offset estimated from the track and how much it
+// can be trusted.
+type DetectedOffset struct {
+	Offset      time.Duration `json:"offset"`                // correction to pass as --time-offset
+	Samples     int           `json:"samples"`               // photos close enough to the track to vote
+	Spread      time.Duration `json:"spread"`                // interquartile range of the per-photo differences
+	Confidence  float64       `json:"confidence"`            // 0.01 to 1, from the spread and the sample count
+	DriftPerDay time.Duration `json:"driftPerDay,omitempty"` // fitted clock drift; zero when negligible
+	DriftAnchor time.Time     `json:"driftAnchor"`           // capture time (UTC) at which the drift correction is zero
+	Error       string        `json:"error,omitempty"`       // why no offset could be detected
+}
+
+// CameraOffset is the offset detected for the photos of one camera body.
+type CameraOffset struct {
+	Camera string `json:"camera"`
+	Photos int    `json:"photos"`
+	DetectedOffset
+}
+
+// OffsetReport is the result of EstimateOffset.
+type OffsetReport struct {
+	Photos  int            `json:"photos"`
+	Overall DetectedOffset `json:"overall"`
+	Cameras []CameraOffset `json:"cameras,omitempty"` // only when the photos come from more than one camera
+	// CamerasDisagree is set when the per-camera offsets differ by more than their
+	// spreads, so each camera should be tagged with its own offset.
+	CamerasDisagree bool `json:"camerasDisagree,omitempty"`
+}
+
+// EstimateOffset runs the auto-offset detection on the selected photos and reports
+// the offset overall and per camera, with the clock drift where one shows. It
+// writes nothing; the manual --time-offset is ignored.
+func EstimateOffset(ctx context.Context, opts Options) (*OffsetReport, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	jobs, track, err := readPhotosAndTrack(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &OffsetReport{Photos: len(jobs)}
+	report.Overall = detectedOffset(offsetSamples(track, jobs))
+
+	byCamera := make(map[string][]photoJob)
+	for _, job := range jobs {
+		camera := CameraName(job.Meta.CameraMake, job.Meta.CameraModel)
+		if camera == "" {
+			camera = "Unknown camera"
+		}
+		byCamera[camera] = append(byCamera[camera], job)
+	}
+	if len(byCamera) < 2 {
+		return report, nil
+	}
+	for camera, group := range byCamera {
+		report.Cameras = append(report.Cameras, CameraOffset{
+			Camera:         camera,
+			Photos:         len(group),
+			DetectedOffset: detectedOffset(offsetSamples(track, group)),
+		})
+	}
+	sort.Slice(report.Cameras, func(i, j int) bool {
+		return report.Cameras[i].Camera < report.Cameras[j].Camera
+	})
+	report.CamerasDisagree = camerasDisagree(report.Cameras)
+	return report, nil
+}
+
+// detectedOffset estimates the offset of a set of samples and fits a drift when
+// the samples span enough time for it to matter.
+func detectedOffset(samples []offsetSample) DetectedOffset {
+	estimate, err := estimateOffset(samples)
+	if err != nil {
+		return DetectedOffset{Error: err.Error()}
+	}
+	out := DetectedOffset{
+		Offset:     estimate.Offset,
+		Samples:    estimate.Samples,
+		Spread:     estimate.Spread,
+		Confidence: offsetConfidence(estimate),
+	}
+	if len(samples) < driftMinSamples {
+		return out
+	}
+
+	points := make([]timefix.Sample, 0, len(samples))
+	first, last := samples[0].Capture, samples[0].Capture
+	for _, s := range samples {
+		points = append(points, timefix.Sample{Camera: s.Capture, True: s.Capture.Add(s.Diff)})
+		if s.Capture.Before(first) {
+			first = s.Capture
+		}
+		if s.Capture.After(last) {
+			last = s.Capture
+		}
+	}
+	fit, err := timefix.Fit(points)
+	if err != nil {
+		return out
+	}
+	effect := time.Duration(float64(fit.DriftPerDay) * last.Sub(first).Hours() / 24)
+	if absDuration(effect) >= driftMinEffect && absDuration(effect) > estimate.Spread {
+		out.DriftPerDay, out.DriftAnchor = fit.DriftPerDay, fit.Anchor
+	}
+	return out
+}
+
+// offsetConfidence scores an estimate between 0.01 and 1: it halves at a spread of
+// confidenceSpreadScale and with only confidenceSampleScale samples.
+func offsetConfidence(e offsetEstimate) float64 {
+	score := halfAt(e.Spread.Seconds(), confidenceSpreadScale.Seconds()) *
+		(1 - halfAt(float64(e.Samples), confidenceSampleScale))
+	return math.Max(0.01, math.Round(score*100)/100)
+}
+
+// camerasDisagree reports whether two cameras' offsets are further apart than
+// either of their spreads and at least a minute.
+func camerasDisagree(cameras []CameraOffset) bool {
+	for i := range cameras {
+		for j := i + 1; j < len(cameras); j++ {
+			a, b := cameras[i].DetectedOffset, cameras[j].DetectedOffset
+			if a.Error != "" || b.Error != "" {
+				continue
+			}
+			tolerance := max(a.Spread, b.Spread, time.Minute)
+			if absDuration(a.Offset-b.Offset) > tolerance {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -4,42 +4,112 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
+
+	"github.com/nir0k/GeoRAW/internal/errcode"
+	"github.com/nir0k/GeoRAW/internal/timefix"
+	"github.com/nir0k/GeoRAW/internal/xmp"
 )
 
 // Options represents user-provided CLI parameters.
 type Options struct {
-	GPXPath      string
-	InputPath    string
-	Recursive    bool
-	LogLevel     string
-	LogFile      string
-	TimeOffset   time.Duration
-	AutoOffset   bool
-	Overwrite    bool
-	PrintSummary bool
-	Progress     func(done, total int)
+	GPXPath         string   // GPX file or track provider URI such as "strava:"
+	Inputs          []string // photo files, folders or glob patterns, each taken whole; see media.SplitInputs for typed input
+	Recursive       bool
+	LogLevel        string
+	LogFile         string
+	TimeOffset      time.Duration
+	TimeZone        string // IANA zone of the camera clock, TimeZoneAuto, or empty for none
+	AutoOffset      bool
+	Overwrite       bool
+	OverwriteScope  OverwriteScope        // replace existing sidecar GPS only within these limits
+	ManifestPath    string                // optional JSON summary of the run, for OverwriteScope.ListedIn later
+	Retry           RetrySource           // process only the files an earlier run's manifest records as unfinished
+	Checksums       Checksums             // record the SHA-256 of every RAW in the manifest, or verify an earlier manifest's
+	Jobs            JobList               // per-file positions, time offsets and skips; the input when Inputs is empty
+	MaxFiles        int                   // stop walking the input after this many files; 0 means no limit
+	KeepResults     int                   // per-file results kept in Summary.Files, those needing attention first; the rest only reach the manifest, GeoJSON and uploads. 0 keeps all, as Watch and CleanSidecars always do
+	ScanProgress    func(dirs, files int) // optional report of the input walk, before processing progress is known
+	MirrorGPS       bool                  // copy GPS embedded in the file into the sidecar instead of skipping it
+	Altitude        AltitudeSource        // what track and CSV altitudes represent; converted to meters above sea level
+	LinearGaps      bool                  // interpolate straight across track gaps instead of using the motion model
+	MaxSpeed        float64               // km/h between consecutive photos above which both are held back as suspicious; 0 disables
+	WriteConfidence bool                  // write georaw:GeotagConfidence into sidecars of track-matched photos
+	EmbedGPS        bool                  // also write the position into JPEG, TIFF and DNG files themselves, see media.WriteGPS
+	PairMaxGap      time.Duration         // largest capture time gap for time-based pairing (Pair only)
+	CSVPath         string                // coordinate CSV (ImportCSV only)
+	ShutterLog      string                // shutter log for the whole input (ImportShutterLog only); found per folder when empty
+	GeoJSONPath     string                // optional GeoJSON export of written positions
+	Upload          UploadTarget          // optional upload of the written positions to OpenStreetMap or uMap
+	TimeFix         timefix.Correction    // capture time correction (FixCaptureTimes only)
+	TimeFixReset    bool                  // correct from the embedded time, discarding earlier corrections
+	TimeFixEmbedded bool                  // also rewrite the capture time embedded in the files, see media.WriteCaptureTime
+	RunID           string                // identifies the run in logs and stamps; generated when empty
+	StampRun        bool                  // write georaw:LastRunID/LastRunDate into every written sidecar
+	Attribution     AttributionTemplate   // creator/copyright written to every processed sidecar
+	Description     DescriptionTemplate   // dc:description written to every processed sidecar
+	Waypoints       WaypointKeywords      // names of GPX waypoints near the written position added as keywords
+	OrphanAction    OrphanAction          // what CleanSidecars does with orphans (list by default)
+	OrphanDir       string                // destination of moved orphans (OrphanMove only)
+	Lock            LockMode              // behaviour when another run writes the same folder tree; refuse by default
+	ReadOnly        ReadOnlyPolicy        // what to do with sidecars that cannot be written because they are read-only
+	TimeFallback    TimeFallback          // where capture times missing from the EXIF are taken from
+	GPSTime         xmp.GPSTimeFormat     // how the GPS time is written into sidecars, for strict parsers
+	NoTrackCache    bool
+	Workers         int
+	PrintSummary    bool
+	Confirm         ConfirmFunc // optional review of planned writes before anything is written
+	Progress        func(done, total int)
+
+	watchBatch bool        // a batch of Watch, which records the run as a whole
+	trackCache *trackCache // the track shared by the batches of Watch
 }
 
 // Validate performs basic validation and assigns defaults where needed.
 func (o *Options) Validate() error {
 	o.GPXPath = strings.TrimSpace(o.GPXPath)
-	o.InputPath = strings.TrimSpace(o.InputPath)
+	if o.GPXPath == "" {
+		return errcode.New(errcode.GPXRequired, nil)
+	}
+	if err := o.validateCommon(); err != nil {
+		return err
+	}
+	return o.Waypoints.load(o.GPXPath)
+}
+
+// validateCommon checks the options shared by every mode, including those without a GPX track.
+func (o *Options) validateCommon() error {
 	o.LogLevel = strings.TrimSpace(o.LogLevel)
 	o.LogFile = strings.TrimSpace(o.LogFile)
-
-	if o.GPXPath == "" {
-		return fmt.Errorf("GPX path is required")
+	o.TimeZone = strings.TrimSpace(o.TimeZone)
+	o.GeoJSONPath = strings.TrimSpace(o.GeoJSONPath)
+	o.ManifestPath = strings.TrimSpace(o.ManifestPath)
+	if o.RunID == "" {
+		o.RunID = NewRunID()
+	}
+	if err := o.Jobs.load(); err != nil {
+		return err
 	}
-	if o.InputPath == "" {
-		return fmt.Errorf("input path is required")
+	if len(o.Inputs) == 0 {
+		o.Inputs = o.Jobs.paths
+	}
+
+	if len(o.Inputs) == 0 {
+		return errcode.New(errcode.InputRequired, nil)
 	}
 	if o.LogLevel == "" {
 		o.LogLevel = "info"
 	}
+	if err := o.CheckSettings(); err != nil {
+		return err
+	}
+	if o.Workers < 1 {
+		o.Workers = defaultWorkers()
+	}
 	if o.LogFile == "" {
-		defaultPath, err := defaultLogPath()
+		defaultPath, err := DefaultLogPath()
 		if err != nil {
 			return err
 		}
@@ -48,7 +118,76 @@ func (o *Options) Validate() error {
 	return nil
 }
 
-func defaultLogPath() (string, error) {
+// CheckSettings validates the settings that do not depend on the input or track:
+// time zone, altitude source, speed limit, lock mode, overwrite scope, retry and
+// checksum manifests, GPS time format, and attribution template.
+func (o *Options) CheckSettings() error {
+	if o.TimeZone != "" && !strings.EqualFold(o.TimeZone, TimeZoneAuto) {
+		if _, err := time.LoadLocation(o.TimeZone); err != nil {
+			return fmt.Errorf("unknown time zone %q: %w", o.TimeZone, err)
+		}
+	}
+	if err := o.Altitude.Validate(); err != nil {
+		return err
+	}
+	if o.MaxSpeed < 0 {
+		return fmt.Errorf("max speed must not be negative")
+	}
+	if o.MaxFiles < 0 {
+		return fmt.Errorf("max files must not be negative")
+	}
+	if o.KeepResults < 0 {
+		return fmt.Errorf("kept results must not be negative")
+	}
+	lock, err := ParseLockMode(string(o.Lock))
+	if err != nil {
+		return err
+	}
+	o.Lock = lock
+	if err := o.OverwriteScope.load(); err != nil {
+		return err
+	}
+	if err := o.Retry.load(); err != nil {
+		return err
+	}
+	if err := o.Checksums.load(); err != nil {
+		return err
+	}
+	if o.Checksums.Record && o.Checksums.Verify == "" && strings.TrimSpace(o.ManifestPath) == "" {
+		return fmt.Errorf("recording checksums needs a manifest to record them in")
+	}
+	if err := o.Description.load(); err != nil {
+		return err
+	}
+	if err := o.ReadOnly.load(); err != nil {
+		return err
+	}
+	if err := o.Upload.load(); err != nil {
+		return err
+	}
+	if err := o.TimeFallback.load(); err != nil {
+		return err
+	}
+	gpsTime, err := xmp.ParseGPSTimeFormat(string(o.GPSTime))
+	if err != nil {
+		return err
+	}
+	o.GPSTime = gpsTime
+	return o.Attribution.Validate()
+}
+
+// defaultWorkers caps parallel sidecar writes; beyond a few workers the disk is the bottleneck.
+func defaultWorkers() int {
+	n := runtime.NumCPU()
+	if n > 8 {
+		n = 8
+	}
+	return n
+}
+
+// DefaultLogPath is the log file used when none is given: georaw.log next to the
+// binary, or in the working directory under `go run`.
+func DefaultLogPath() (string, error) {
 	exe, err := os.Executable()
 	if err != nil {
 		return "", fmt.Errorf("resolve executable path: %w", err)
@@ -63,3 +202,8 @@ func defaultLogPath() (string, error) {
 	}
 	return filepath.Join(dir, "georaw.log"), nil
 }
+
+// inputText lists the inputs for log lines.
+func (o Options) inputText() string {
+	return strings.Join(o.Inputs, "; ")
+}
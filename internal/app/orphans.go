@@ -0,0 +1,265 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/pathnorm"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// OrphanAction selects what CleanSidecars does with sidecars whose photo is gone.
+type OrphanAction string
+
+const (
+	OrphanList   OrphanAction = "list" // report only (dry run)
+	OrphanMove   OrphanAction = "move"
+	OrphanDelete OrphanAction = "delete"
+)
+
+// CleanSidecars finds .xmp files under Inputs whose photo no longer exists (e.g.
+// after culling) and lists, moves (into OrphanDir, keeping their relative folders),
+// or deletes them according to OrphanAction. Listing is the default, so nothing is
+// touched unless an action is chosen. When xmp.SetSidecarDir is in effect the
+// sidecars are taken from the mirror of Inputs instead.
+func CleanSidecars(ctx context.Context, opts Options) (*Summary, error) {
+	return cleanSidecars(ctx, opts, nil)
+}
+
+// CleanSidecarsWithLogger is CleanSidecars with logs piped into an in-memory buffer.
+func CleanSidecarsWithLogger(ctx context.Context, opts Options, out io.Writer) (*Summary, error) {
+	return cleanSidecars(ctx, opts, out)
+}
+
+func cleanSidecars(ctx context.Context, opts Options, out io.Writer) (sum *Summary, err error) {
+	if err := opts.validateCommon(); err != nil {
+		return nil, err
+	}
+	opts.OrphanDir = strings.TrimSpace(opts.OrphanDir)
+	switch opts.OrphanAction {
+	case "":
+		opts.OrphanAction = OrphanList
+	case OrphanList, OrphanDelete:
+	case OrphanMove:
+		if opts.OrphanDir == "" {
+			return nil, fmt.Errorf("a destination folder is required to move orphan sidecars")
+		}
+	default:
+		return nil, fmt.Errorf("invalid orphan action %q (expected list, move or delete)", opts.OrphanAction)
+	}
+	// With a sidecar folder the sidecars are looked for in the mirror of the input,
+	// and each is matched with the photos of the folder it mirrors.
+	walkOpts := opts
+	walkOpts.Inputs = make([]string, len(opts.Inputs))
+	for i, in := range opts.Inputs {
+		if xmp.InSidecarDir(in) {
+			return nil, fmt.Errorf("input %s lies in the sidecar folder; pass the photo folders it mirrors instead", in)
+		}
+		walkOpts.Inputs[i] = xmp.SidecarFolder(in)
+	}
+
+	logs, err := openRunLog(opts, out)
+	if err != nil {
+		return nil, err
+	}
+	started := time.Now()
+	defer func() { recordRun("clean-sidecars", opts, started, sum, err, logs) }()
+	if opts.OrphanAction != OrphanList {
+		release, err := lockRun(ctx, opts, "clean-sidecars", logs)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	infof := logs.infof
+	warnf := logs.warnf
+
+	infof("Looking for orphan sidecars in input=%s recursive=%t action=%s", opts.inputText(), opts.Recursive, opts.OrphanAction)
+
+	var orphans []string
+	checked := 0
+	dirFiles := make(map[string]map[string]bool)
+	truncated, err := walkInput(walkOpts, logs, func(sidecar string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !strings.EqualFold(filepath.Ext(sidecar), ".xmp") {
+			return nil
+		}
+		checked++
+		dir := filepath.Dir(sidecar)
+		names, ok := dirFiles[dir]
+		if !ok {
+			names, err = photoNames(xmp.PhotoFolder(dir))
+			if err != nil {
+				return err
+			}
+			dirFiles[dir] = names
+		}
+		if !names[sidecarStem(sidecar)] {
+			orphans = append(orphans, sidecar)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if checked == 0 {
+		return nil, fmt.Errorf("no sidecars found")
+	}
+	infof("Checked %d sidecars, %d orphaned", checked, len(orphans))
+
+	root := ""
+	if len(orphans) > 0 {
+		root = filepath.Dir(orphans[0])
+		for _, path := range orphans[1:] {
+			root = commonDir(root, filepath.Dir(path))
+		}
+	}
+
+	var count counters
+	results := make([]FileResult, 0, len(orphans))
+	for i, path := range orphans {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		res := FileResult{Path: path}
+		switch opts.OrphanAction {
+		case OrphanList:
+			infof("Orphan sidecar: %s", path)
+			count.unchanged.Add(1)
+			res.Status, res.Message = "unchanged", "Orphan sidecar (dry run)"
+		case OrphanDelete:
+			if err := os.Remove(path); err != nil {
+				warnf("Failed to delete %s: %v", path, err)
+				count.failed.Add(1)
+				res.Status, res.Message = "failed", err.Error()
+				break
+			}
+			infof("Deleted orphan sidecar %s", path)
+			count.processed.Add(1)
+			res.Status, res.Message = "processed", "Deleted"
+		case OrphanMove:
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				rel = filepath.Base(path)
+			}
+			target := filepath.Join(opts.OrphanDir, rel)
+			if err := moveFile(path, target); err != nil {
+				warnf("Failed to move %s: %v", path, err)
+				count.failed.Add(1)
+				res.Status, res.Message = "failed", err.Error()
+				break
+			}
+			infof("Moved orphan sidecar %s -> %s", path, target)
+			count.processed.Add(1)
+			res.Status, res.Message = "processed", "Moved to "+target
+		}
+		results = append(results, res)
+		if opts.Progress != nil {
+			opts.Progress(i+1, len(orphans))
+		}
+	}
+
+	FillRelativePaths(results)
+	sum = &Summary{
+		RunID:       opts.RunID,
+		Processed:   int(count.processed.Load()),
+		Unchanged:   int(count.unchanged.Load()),
+		Failed:      int(count.failed.Load()),
+		Truncated:   truncated,
+		Directories: SummarizeDirectories(results),
+		Files:       results,
+	}
+	summary := fmt.Sprintf("Finished. checked=%d orphans=%d", checked, len(orphans))
+	switch opts.OrphanAction {
+	case OrphanList:
+		summary += " (dry run, nothing changed)"
+	case OrphanMove:
+		summary += fmt.Sprintf(" moved=%d failed=%d", sum.Processed, sum.Failed)
+	case OrphanDelete:
+		summary += fmt.Sprintf(" deleted=%d failed=%d", sum.Processed, sum.Failed)
+	}
+	if opts.PrintSummary {
+		fmt.Println(summary)
+	}
+	infof("%s", summary)
+	warnTruncated(opts, sum, logs)
+	return sum, nil
+}
+
+// photoNames returns the lower-cased, NFC-normalized names of the non-sidecar files in dir, with and
+// without their extension. The whole folder is read, so a photo counts even when the
+// input pattern only matched sidecars. A missing dir, as when a mirrored photo folder
+// was deleted, has no photos.
+func photoNames(dir string) (map[string]bool, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]bool{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", dir, err)
+	}
+	names := make(map[string]bool, len(entries)*2)
+	for _, e := range entries {
+		if e.IsDir() || strings.EqualFold(filepath.Ext(e.Name()), ".xmp") {
+			continue
+		}
+		name := strings.ToLower(pathnorm.Key(e.Name()))
+		names[name] = true
+		names[strings.TrimSuffix(name, filepath.Ext(name))] = true
+	}
+	return names, nil
+}
+
+// sidecarStem is the lower-cased name a sidecar's photo must have, with or without
+// extension: IMG_0001 for IMG_0001.xmp, IMG_0001.JPG for the companion IMG_0001.JPG.xmp.
+func sidecarStem(sidecar string) string {
+	name := strings.ToLower(pathnorm.Key(filepath.Base(sidecar)))
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// moveFile renames src to dst, copying across file systems, and never replaces dst.
+func moveFile(src, dst string) error {
+	if _, err := os.Stat(dst); err == nil {
+		return fmt.Errorf("%s already exists", dst)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("create %s: %w", filepath.Dir(dst), err)
+	}
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	var linkErr *os.LinkError
+	if !errors.As(err, &linkErr) {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	in.Close()
+	return os.Remove(src)
+}
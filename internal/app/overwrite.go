@@ -0,0 +1,129 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// OverwriteScope allows replacing GPS a sidecar already has, but only within the
+// set limits; with several set, all must agree. It narrows Overwrite for sidecars
+// and does not affect GPS embedded in the photo.
+type OverwriteScope struct {
+	AltitudeOnly bool    // replace only the altitude, keeping the recorded latitude/longitude
+	OwnOnly      bool    // replace only GPS GeoRAW wrote itself and nobody changed since
+	ListedIn     string  // replace only GPS of files the run of this manifest processed
+	FartherThan  float64 // replace only GPS more than this many meters from the new position
+
+	listed map[string]bool
+}
+
+// IsZero reports whether no scope is set.
+func (s OverwriteScope) IsZero() bool {
+	return !s.AltitudeOnly && !s.OwnOnly && s.ListedIn == "" && s.FartherThan == 0
+}
+
+// load checks the distance and reads the manifest named by ListedIn.
+func (s *OverwriteScope) load() error {
+	if s.FartherThan < 0 {
+		return fmt.Errorf("overwrite distance must not be negative, got %g m", s.FartherThan)
+	}
+	s.ListedIn = strings.TrimSpace(s.ListedIn)
+	if s.ListedIn == "" {
+		return nil
+	}
+	listed, err := ReadManifest(s.ListedIn)
+	if err != nil {
+		return err
+	}
+	s.listed = listed
+	return nil
+}
+
+// permits reports whether the existing GPS in sidecar may be replaced for photo,
+// with the reason when it may not. distance is the meters from the existing to
+// the new position, nil when the sidecar records no readable latitude/longitude.
+func (s OverwriteScope) permits(photo, sidecar string, distance *float64) (bool, string) {
+	if s.ListedIn != "" && !s.listed[absPath(photo)] {
+		return false, "not listed in " + s.ListedIn
+	}
+	if s.OwnOnly && !xmp.GPSWrittenByGeoRAW(sidecar) {
+		return false, "not written by GeoRAW"
+	}
+	if s.FartherThan > 0 && distance != nil && *distance <= s.FartherThan {
+		return false, fmt.Sprintf("%.0f m from the existing position, within %g m", *distance, s.FartherThan)
+	}
+	return true, ""
+}
+
+// describe summarizes the scope for the start-of-run log line.
+func (s OverwriteScope) describe() string {
+	var parts []string
+	if s.AltitudeOnly {
+		parts = append(parts, "altitude only")
+	}
+	if s.OwnOnly {
+		parts = append(parts, "GeoRAW-written GPS only")
+	}
+	if s.ListedIn != "" {
+		parts = append(parts, fmt.Sprintf("files in %s (%d)", s.ListedIn, len(s.listed)))
+	}
+	if s.FartherThan > 0 {
+		parts = append(parts, fmt.Sprintf("positions moving more than %g m", s.FartherThan))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// scopeRefusal is the ErrGPSAlreadyPresent of a sidecar outside the overwrite scope.
+type scopeRefusal struct {
+	reason string
+}
+
+func (e *scopeRefusal) Error() string { return xmp.ErrGPSAlreadyPresent.Error() + ": " + e.reason }
+func (e *scopeRefusal) Unwrap() error { return xmp.ErrGPSAlreadyPresent }
+
+// writeGPS writes the task's position, replacing GPS the sidecar already has only as
+// far as opts.Overwrite and opts.OverwriteScope allow. When the sidecar already has
+// GPS, task.ExistingDistance is set to how far the new position is from it; a
+// manual position from the job list always replaces it. When
+// only the altitude is replaced, task.Coord is updated to the latitude/longitude
+// the sidecar keeps.
+func writeGPS(task *sidecarTask, opts Options) (bool, error) {
+	scope := opts.OverwriteScope
+	wrote, err := xmp.MergeAndWrite(task.Sidecar, task.Coord, task.Capture, task.GPSSource, opts.GPSTime, false)
+	if !errors.Is(err, xmp.ErrGPSAlreadyPresent) {
+		return wrote, err
+	}
+	kept, hasKept, readErr := xmp.ReadGPS(task.Sidecar)
+	hasKept = hasKept && readErr == nil
+	if hasKept {
+		distance := math.Round(gpx.Haversine(kept, task.Coord))
+		task.ExistingDistance = &distance
+	}
+	if task.Manual {
+		return xmp.MergeAndWrite(task.Sidecar, task.Coord, task.Capture, task.GPSSource, opts.GPSTime, true)
+	}
+	if scope.IsZero() {
+		if !opts.Overwrite {
+			return false, err
+		}
+		return xmp.MergeAndWrite(task.Sidecar, task.Coord, task.Capture, task.GPSSource, opts.GPSTime, true)
+	}
+	if ok, why := scope.permits(task.Job.Path, task.Sidecar, task.ExistingDistance); !ok {
+		return false, &scopeRefusal{reason: why}
+	}
+	if !scope.AltitudeOnly {
+		return xmp.MergeAndWrite(task.Sidecar, task.Coord, task.Capture, task.GPSSource, opts.GPSTime, true)
+	}
+	if task.Coord.Altitude == nil {
+		return false, &scopeRefusal{reason: "no altitude to write"}
+	}
+	if hasKept {
+		task.Coord.Latitude, task.Coord.Longitude = kept.Latitude, kept.Longitude
+	}
+	return xmp.SetGPSAltitude(task.Sidecar, *task.Coord.Altitude)
+}
@@ -0,0 +1,250 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/errcode"
+	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/nir0k/GeoRAW/internal/pathnorm"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// DefaultPairMaxGap is the largest capture time difference accepted when a RAW is
+// paired with a geotagged photo by time instead of by name.
+const DefaultPairMaxGap = time.Minute
+
+// Pair copies GPS from geotagged non-RAW photos (JPEG/HEIF from a phone, a second
+// body, or the JPEG half of RAW+JPEG) into the sidecars of RAW files. A RAW is paired
+// with the photo of the same base name in its folder, otherwise with the photo
+// closest in capture time within PairMaxGap. TimeOffset is added to RAW capture
+// times to compensate for clock differences between the devices.
+func Pair(ctx context.Context, opts Options) (*Summary, error) {
+	return pair(ctx, opts, nil)
+}
+
+// PairWithLogger is Pair with logs piped into an in-memory buffer.
+func PairWithLogger(ctx context.Context, opts Options, out io.Writer) (*Summary, error) {
+	return pair(ctx, opts, out)
+}
+
+// pairSource is a geotagged photo that RAW files can borrow coordinates from.
+type pairSource struct {
+	Path string
+	Meta media.Metadata
+}
+
+func pair(ctx context.Context, opts Options, out io.Writer) (sum *Summary, err error) {
+	if err := opts.validateCommon(); err != nil {
+		return nil, err
+	}
+	if opts.PairMaxGap <= 0 {
+		opts.PairMaxGap = DefaultPairMaxGap
+	}
+
+	logs, err := openRunLog(opts, out)
+	if err != nil {
+		return nil, err
+	}
+	started := time.Now()
+	defer func() { recordRun("pair", opts, started, sum, err, logs) }()
+	release, err := lockRun(ctx, opts, "pair", logs)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	infof := logs.infof
+
+	infof("Starting GPS pairing with input=%s recursive=%t offset=%s maxGap=%s overwrite=%t", opts.inputText(), opts.Recursive, opts.TimeOffset, opts.PairMaxGap, opts.Overwrite)
+
+	progressTotal := 0
+	progressDone := 0
+	var progressMu sync.Mutex
+	step := func(done, total int) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		progressDone += done
+		progressTotal += total
+		if opts.Progress != nil && progressTotal > 0 {
+			opts.Progress(min(progressDone, progressTotal), progressTotal)
+		}
+	}
+
+	var (
+		count   counters
+		clock   stageClock
+		found   int
+		raws    []photoJob
+		sources []pairSource
+	)
+	store := newResultStore(ctx, opts, &clock, logs)
+	defer store.remove()
+	defer store.close()
+
+	truncated, err := walkInput(opts, logs, func(path string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		found++
+		if strings.EqualFold(filepath.Ext(path), ".xmp") || !media.SupportedExif(path) {
+			return nil
+		}
+		isRaw := media.SupportedRaw(path)
+		if isRaw {
+			step(0, 2)
+		}
+
+		metaLog := logs.file(StageMetadata, path)
+		metaStart := time.Now()
+		meta, timeSource, err := readMetadata(path, opts.TimeFallback)
+		clock.since(path, StageMetadata, metaStart)
+		if err != nil {
+			metaLog.warnf("Failed to read metadata for %s: %v", path, err)
+			if isRaw {
+				store.add(metaFailure(path, err, &count), found)
+				step(2, 0)
+			}
+			return nil
+		}
+		if !isRaw {
+			if meta.GPS != nil {
+				metaLog.debugf("GPS source %s (%s)", path, meta.CaptureTime.Format(time.RFC3339))
+				sources = append(sources, pairSource{Path: path, Meta: meta})
+			}
+			return nil
+		}
+		if meta.GPS != nil && !opts.Overwrite {
+			metaLog.infof("Skipping %s: GPS already embedded by the camera (use --overwrite-gps to replace)", path)
+			count.unchanged.Add(1)
+			store.add(FileResult{Path: path, Status: "unchanged", Message: "GPS embedded in file"}, found)
+			step(2, 0)
+			return nil
+		}
+		if shift, ok := applyTimeShift(path, &meta); ok {
+			metaLog.debugf("Using corrected capture time of %s (shift %s)", path, shift)
+		}
+		if timeSource != "" {
+			metaLog.warnf("No capture time in the EXIF of %s; using %s from its %s", path, meta.CaptureTime.Format(time.DateTime), timeSource)
+		}
+		raws = append(raws, photoJob{Path: path, Meta: meta, TimeSource: timeSource, seq: found})
+		step(1, 0)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == 0 {
+		return nil, errcode.New(errcode.NoFiles, nil)
+	}
+	if len(raws) == 0 {
+		return nil, errcode.New(errcode.NoRAWFiles, nil)
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no geotagged JPEG/HEIF photos found to copy GPS from")
+	}
+	infof("Found %d RAW files and %d geotagged photos", len(raws), len(sources))
+
+	byName := make(map[string]pairSource, len(sources))
+	for _, src := range sources {
+		byName[pathnorm.PairKey(src.Path)] = src
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].Meta.CaptureTime.Before(sources[j].Meta.CaptureTime)
+	})
+
+	tasks := make([]sidecarTask, 0, len(raws))
+	unpaired := 0
+	for _, job := range raws {
+		capture := job.Meta.CaptureTime.Add(opts.TimeOffset)
+		src, ok := byName[pathnorm.PairKey(job.Path)]
+		if !ok {
+			src, ok = nearestSource(sources, capture, opts.PairMaxGap)
+		}
+		if !ok {
+			logs.file(StageMatch, job.Path).warnf("No geotagged photo within %s of %s", opts.PairMaxGap, job.Path)
+			unpaired++
+			count.skipped.Add(1)
+			store.addJob(job, FileResult{Path: job.Path, Status: "skipped", Message: "No paired photo with GPS"})
+			step(1, 0)
+			continue
+		}
+		tasks = append(tasks, sidecarTask{
+			Job:       job,
+			Capture:   capture.UTC(),
+			Coord:     embeddedCoordinate(src.Meta.GPS),
+			Sidecar:   xmp.SidecarPath(job.Path),
+			Source:    src.Path,
+			GPSSource: xmp.GPSSourceMirror,
+		})
+	}
+	raws, sources = nil, nil // the tasks carry what is still needed of them
+
+	record := func(task sidecarTask, res FileResult) { store.addJob(task.Job, res) }
+	tasks, err = confirmTasks(opts.Confirm, tasks, record, &count, func() { step(1, 0) })
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeSidecars(ctx, tasks, opts.Workers, func(task sidecarTask) {
+		defer step(1, 0)
+		writeStart := time.Now()
+		res := applyTask(ctx, task, opts, &count, logs)
+		clock.since(task.Job.Path, StageWrite, writeStart)
+		record(task, res)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	store.close()
+	exportGeoJSON(opts, store.all(), logs)
+	uploadPositions(ctx, opts, store.all(), logs)
+	sum = &Summary{
+		RunID:     opts.RunID,
+		Processed: int(count.processed.Load()),
+		Skipped:   int(count.skipped.Load()),
+		Unchanged: int(count.unchanged.Load()),
+		Failed:    int(count.failed.Load()),
+		MetaError: int(count.metaError.Load()),
+		Truncated: truncated,
+	}
+	store.summarize(sum)
+	summary := fmt.Sprintf("Finished. processed=%d unchanged=%d skipped=%d unpaired=%d failed=%d meta_errors=%d", sum.Processed, sum.Unchanged, sum.Skipped, unpaired, sum.Failed, sum.MetaError)
+	if opts.PrintSummary {
+		fmt.Println(summary)
+	}
+	infof("%s", summary)
+	warnTruncated(opts, sum, logs)
+	logTripStats(opts, sum, logs)
+	logTimings(opts, sum, logs)
+	return sum, nil
+}
+
+// nearestSource returns the source closest to ts if it lies within maxGap.
+// sources must be sorted by capture time.
+func nearestSource(sources []pairSource, ts time.Time, maxGap time.Duration) (pairSource, bool) {
+	idx := sort.Search(len(sources), func(i int) bool {
+		return !sources[i].Meta.CaptureTime.Before(ts)
+	})
+	best := -1
+	var bestGap time.Duration
+	for _, i := range []int{idx - 1, idx} {
+		if i < 0 || i >= len(sources) {
+			continue
+		}
+		gap := absDuration(sources[i].Meta.CaptureTime.Sub(ts))
+		if best == -1 || gap < bestGap {
+			best, bestGap = i, gap
+		}
+	}
+	if best == -1 || bestGap > maxGap {
+		return pairSource{}, false
+	}
+	return sources[best], true
+}
@@ -0,0 +1,24 @@
+package app
+
+import (
+	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// preserveGPano copies the GPano properties of a panorama JPEG or HEIF into the
+// sidecar written for it, so viewers and Street View uploads that prefer the
+// sidecar still see a panorama. GPano data the sidecar already has is kept.
+func preserveGPano(task sidecarTask, logs runLog) {
+	if media.SupportedRaw(task.Job.Path) {
+		return
+	}
+	props, err := media.ReadGPano(task.Job.Path)
+	if err != nil || props == nil {
+		return
+	}
+	if _, err := xmp.SetGPano(task.Sidecar, props, false); err != nil {
+		logs.warnf("Failed to copy panorama metadata into %s: %v", task.Sidecar, err)
+		return
+	}
+	logs.debugf("Copied GPano metadata (projection %q) of %s into %s", props["GPano:ProjectionType"], task.Job.Path, task.Sidecar)
+}
@@ -0,0 +1,152 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// Bounds of a track replay: the frame count a default bucket aims for, the most
+// frames any bucket may produce, and the track kept before and after the photos.
+const (
+	playbackFrames    = 300
+	maxPlaybackFrames = 3000
+	playbackPadding   = 30 * time.Minute
+)
+
+// PlaybackPhoto is a photo popping up during a track replay.
+type PlaybackPhoto struct {
+	Path     string    `json:"path"`
+	RelPath  string    `json:"relPath,omitempty"`
+	Time     time.Time `json:"time"`               // capture time with time zone and offset applied, UTC
+	Position *GeoPoint `json:"position,omitempty"` // track position at Time; nil when the track has none
+	Tagged   *GeoPoint `json:"tagged,omitempty"`   // position already in the photo's sidecar
+	Distance *float64  `json:"distance,omitempty"` // meters between Tagged and Position
+}
+
+// PlaybackFrame is one time bucket of a track replay.
+type PlaybackFrame struct {
+	Time     time.Time       `json:"time"`               // start of the bucket, UTC
+	Position *GeoPoint       `json:"position,omitempty"` // track position at Time; nil in gaps between routes
+	Photos   []PlaybackPhoto `json:"photos,omitempty"`   // photos taken within the bucket
+}
+
+// Playback replays the track of a day in time buckets with the photos taken in
+// each, for reviewing a trip and spotting photos with wrong times.
+type Playback struct {
+	Bucket     time.Duration   `json:"bucket"`
+	Offset     time.Duration   `json:"offset"` // offset applied to capture times, including auto-detection
+	Start      time.Time       `json:"start"`
+	End        time.Time       `json:"end"`
+	Frames     []PlaybackFrame `json:"frames"`
+	OutOfTrack int             `json:"outOfTrack"` // photos outside the replayed span
+}
+
+// TrackPlayback reads the selected photos and the track, applies the time zone and
+// offset a run would use, and cuts the part of the track around the photos into
+// buckets of bucket (or about playbackFrames buckets when zero). Nothing is written.
+func TrackPlayback(ctx context.Context, opts Options, bucket time.Duration) (*Playback, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if bucket < 0 {
+		return nil, fmt.Errorf("playback bucket must not be negative")
+	}
+	jobs, track, err := readPhotosAndTrack(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	offset := opts.TimeOffset
+	if offset == 0 && opts.AutoOffset {
+		if detected, err := detectOffset(track, jobs); err == nil {
+			offset = detected.Offset
+		}
+	}
+
+	photos := make([]PlaybackPhoto, 0, len(jobs))
+	rel := make([]FileResult, 0, len(jobs))
+	var first, last time.Time
+	for _, job := range jobs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		photo := PlaybackPhoto{Path: job.Path, Time: job.Meta.CaptureTime.Add(offset).UTC()}
+		if first.IsZero() || photo.Time.Before(first) {
+			first = photo.Time
+		}
+		if photo.Time.After(last) {
+			last = photo.Time
+		}
+		if coord, err := track.CoordinateAt(photo.Time); err == nil {
+			photo.Position = playbackPoint(coord, photo.Time)
+		}
+		if coord, ok, err := xmp.ReadGPS(xmp.SidecarPath(job.Path)); err == nil && ok {
+			photo.Tagged = &GeoPoint{Latitude: coord.Latitude, Longitude: coord.Longitude, Altitude: coord.Altitude}
+			if photo.Position != nil {
+				d := math.Round(gpx.Haversine(coord, gpx.Coordinate{Latitude: photo.Position.Latitude, Longitude: photo.Position.Longitude}))
+				photo.Distance = &d
+			}
+		}
+		photos = append(photos, photo)
+		rel = append(rel, FileResult{Path: job.Path})
+	}
+	FillRelativePaths(rel)
+	for i := range photos {
+		photos[i].RelPath = rel[i].RelPath
+	}
+	sort.SliceStable(photos, func(i, j int) bool { return photos[i].Time.Before(photos[j].Time) })
+
+	pb := &Playback{Offset: offset}
+	pb.Start, pb.End = track.Bounds()
+	if from, to := first.Add(-playbackPadding), last.Add(playbackPadding); from.Before(pb.End) && to.After(pb.Start) {
+		pb.Start, pb.End = maxTime(pb.Start, from), minTime(pb.End, to)
+	}
+	span := pb.End.Sub(pb.Start)
+	if bucket == 0 {
+		bucket = span / playbackFrames
+	}
+	bucket = max(bucket, span/maxPlaybackFrames, time.Second).Round(time.Second)
+	pb.Bucket = bucket
+
+	next := 0
+	for t := pb.Start; !t.After(pb.End); t = t.Add(bucket) {
+		frame := PlaybackFrame{Time: t}
+		if coord, err := track.CoordinateAt(t); err == nil {
+			frame.Position = playbackPoint(coord, t)
+		}
+		for ; next < len(photos) && photos[next].Time.Before(t.Add(bucket)); next++ {
+			if photos[next].Time.Before(pb.Start) {
+				pb.OutOfTrack++
+				continue
+			}
+			frame.Photos = append(frame.Photos, photos[next])
+		}
+		pb.Frames = append(pb.Frames, frame)
+	}
+	pb.OutOfTrack += len(photos) - next
+	return pb, nil
+}
+
+// playbackPoint is the GeoPoint of a track position at t.
+func playbackPoint(c gpx.Coordinate, t time.Time) *GeoPoint {
+	return &GeoPoint{Latitude: c.Latitude, Longitude: c.Longitude, Altitude: c.Altitude, Time: t}
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
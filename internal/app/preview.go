@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// errPreviewed stops a run at the confirmation step once the plan has been captured.
+var errPreviewed = errors.New("preview complete")
+
+// PreviewItem compares the position a run would write with the one already stored
+// in the photo's sidecar.
+type PreviewItem struct {
+	Path     string    `json:"path"`
+	RelPath  string    `json:"relPath,omitempty"`
+	Current  *GeoPoint `json:"current,omitempty"` // position in the existing sidecar, if any
+	Planned  *GeoPoint `json:"planned"`
+	Distance float64   `json:"distance"` // meters between Current and Planned
+}
+
+// Preview runs the geotagging workflow up to the point of writing and returns the
+// planned positions next to the existing ones. Nothing is written.
+func Preview(ctx context.Context, opts Options) ([]PreviewItem, error) {
+	return preview(ctx, opts, nil)
+}
+
+// PreviewWithLogger is Preview with the log lines also written to out.
+func PreviewWithLogger(ctx context.Context, opts Options, out io.Writer) ([]PreviewItem, error) {
+	return preview(ctx, opts, out)
+}
+
+func preview(ctx context.Context, opts Options, out io.Writer) ([]PreviewItem, error) {
+	var plan []PlannedWrite
+	opts.Confirm = func(p []PlannedWrite) ([]PlannedWrite, error) {
+		plan = p
+		return nil, errPreviewed
+	}
+	opts.GeoJSONPath = ""
+	opts.Upload = UploadTarget{}
+	opts.PrintSummary = false
+	if _, err := run(ctx, opts, out); err != nil && !errors.Is(err, errPreviewed) {
+		return nil, err
+	}
+
+	items := make([]PreviewItem, 0, len(plan))
+	rel := make([]FileResult, 0, len(plan))
+	for _, p := range plan {
+		item := PreviewItem{Path: p.Path, Planned: p.Point}
+		if coord, ok, err := xmp.ReadGPS(xmp.SidecarPath(p.Path)); err == nil && ok {
+			item.Current = &GeoPoint{Latitude: coord.Latitude, Longitude: coord.Longitude, Altitude: coord.Altitude}
+			if p.Point != nil {
+				item.Distance = math.Round(gpx.Haversine(coord, gpx.Coordinate{Latitude: p.Point.Latitude, Longitude: p.Point.Longitude}))
+			}
+		}
+		items = append(items, item)
+		rel = append(rel, FileResult{Path: p.Path})
+	}
+	FillRelativePaths(rel)
+	for i := range items {
+		items[i].RelPath = rel[i].RelPath
+	}
+	return items, nil
+}
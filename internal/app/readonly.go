@@ -0,0 +1,113 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/fsretry"
+	"github.com/nir0k/GeoRAW/internal/media"
+)
+
+// ReadOnlyPolicy decides what happens when a sidecar cannot be written because
+// it or its folder is read-only. By default the file fails and is flagged
+// read-only in the results.
+type ReadOnlyPolicy struct {
+	Clear     bool   // clear the read-only attribute of the sidecar and write it anyway
+	DivertDir string // write the sidecar under this folder instead, mirroring the input folders
+}
+
+// IsZero reports whether read-only sidecars simply fail.
+func (p ReadOnlyPolicy) IsZero() bool {
+	return !p.Clear && p.DivertDir == ""
+}
+
+// load checks that at most one way of handling read-only sidecars is chosen.
+func (p *ReadOnlyPolicy) load() error {
+	p.DivertDir = strings.TrimSpace(p.DivertDir)
+	if p.Clear && p.DivertDir != "" {
+		return fmt.Errorf("clearing the read-only attribute and diverting read-only sidecars cannot be combined")
+	}
+	return nil
+}
+
+// IsReadOnly reports whether err is a write refused because the file or its
+// folder is read-only or not writable by the user.
+func IsReadOnly(err error) bool {
+	return errors.Is(err, fs.ErrPermission)
+}
+
+// CountReadOnly counts the results flagged by FailedResult as read-only.
+func CountReadOnly(results []FileResult) int {
+	n := 0
+	for _, res := range results {
+		if res.ReadOnly {
+			n++
+		}
+	}
+	return n
+}
+
+// writeGPSReadOnly retries a GPS write that failed on a read-only sidecar as the
+// policy allows. A diverted write moves task.Sidecar, so the later steps of the
+// task follow it. It returns the original error when the policy does not help.
+func writeGPSReadOnly(task *sidecarTask, opts Options, writeErr error, logs runLog) (bool, error) {
+	policy := opts.ReadOnly
+	switch {
+	case policy.Clear:
+		info, err := os.Stat(task.Sidecar)
+		if err != nil || info.Mode().Perm()&0o200 != 0 {
+			return false, writeErr // the folder is read-only, not the sidecar
+		}
+		if err := fsretry.Do(func() error { return os.Chmod(task.Sidecar, info.Mode().Perm()|0o200) }); err != nil {
+			return false, fmt.Errorf("clear read-only attribute: %w", err)
+		}
+		logs.warnf("Cleared the read-only attribute of %s", task.Sidecar)
+	case policy.DivertDir != "":
+		diverted, err := divertSidecar(task.Sidecar, opts)
+		if err != nil {
+			return false, err
+		}
+		logs.warnf("%s is read-only; writing %s instead", task.Sidecar, diverted)
+		task.Sidecar = diverted
+	default:
+		return false, writeErr
+	}
+	return writeGPS(task, opts)
+}
+
+// divertSidecar returns the mirror of sidecar below the divert folder. The first
+// time, the read-only sidecar is copied there so the merge keeps its data.
+func divertSidecar(sidecar string, opts Options) (string, error) {
+	abs, err := filepath.Abs(sidecar)
+	if err != nil {
+		return "", fmt.Errorf("resolve sidecar path: %w", err)
+	}
+	rel := strings.TrimPrefix(abs, filepath.VolumeName(abs))
+	if root, err := media.InputRoot(opts.Inputs); err == nil {
+		if r, err := filepath.Rel(root, abs); err == nil && r != ".." && !strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+			rel = r
+		}
+	}
+	diverted := filepath.Join(opts.ReadOnly.DivertDir, rel)
+	if _, err := os.Stat(diverted); err == nil {
+		return diverted, nil
+	}
+	data, err := fsretry.ReadFile(sidecar)
+	if os.IsNotExist(err) {
+		return diverted, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read read-only sidecar: %w", err)
+	}
+	if err := fsretry.Do(func() error { return os.MkdirAll(filepath.Dir(diverted), 0o755) }); err != nil {
+		return "", fmt.Errorf("create divert folder: %w", err)
+	}
+	if err := fsretry.WriteFile(diverted, data, 0o644); err != nil {
+		return "", fmt.Errorf("copy read-only sidecar: %w", err)
+	}
+	return diverted, nil
+}
@@ -0,0 +1,40 @@
+package app
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// FillRelativePaths sets RelPath on every result relative to the deepest folder
+// shared by all results, so files with the same name in different folders stay
+// distinguishable in reports.
+func FillRelativePaths(results []FileResult) {
+	if len(results) == 0 {
+		return
+	}
+	root := filepath.Dir(results[0].Path)
+	for _, res := range results[1:] {
+		root = commonDir(root, filepath.Dir(res.Path))
+	}
+	for i := range results {
+		rel, err := filepath.Rel(root, results[i].Path)
+		if err != nil {
+			rel = results[i].Path
+		}
+		results[i].RelPath = filepath.ToSlash(rel)
+	}
+}
+
+// commonDir returns the longest directory prefix shared by a and b.
+func commonDir(a, b string) string {
+	for {
+		if a == b || strings.HasPrefix(b, strings.TrimSuffix(a, string(filepath.Separator))+string(filepath.Separator)) {
+			return a
+		}
+		parent := filepath.Dir(a)
+		if parent == a {
+			return a
+		}
+		a = parent
+	}
+}
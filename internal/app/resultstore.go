@@ -0,0 +1,279 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"iter"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// resultStore takes the results of a run as they finish, so a run over
+// hundreds of thousands of files does not hold all of them. The folder breakdown,
+// the counts of the summary, the trip positions and the stage timings are updated
+// as results come in. The results themselves stay in memory up to keep, those
+// needing attention first; the others go to a temporary file, which the manifest,
+// GeoJSON and upload read back. A keep of zero holds every result. It is safe for
+// concurrent use.
+type resultStore struct {
+	keep   int
+	clock  *stageClock
+	checks Checksums
+	logs   runLog
+
+	mu        sync.Mutex
+	kept      []storedResult
+	evictable []int // indexes into kept of results not needing attention
+	spill     *os.File
+	spillBuf  *bufio.Writer
+	spillEnc  *json.Encoder
+	spilled   int
+	spillErr  error
+	root      string // deepest folder shared by all results, for RelPath
+	dirs      map[string]*DirSummary
+	trip      []tripPoint
+	timings   timingSamples
+
+	transient, readOnly, verified, mismatch int
+
+	hash chan storedResult // results waiting for their checksum; nil without checksums
+	wg   sync.WaitGroup
+}
+
+// storedResult is a result with the position of its file in the walk, which
+// orders the kept results.
+type storedResult struct {
+	seq int
+	res FileResult
+}
+
+// newResultStore returns a store for the results of a run with opts. With
+// checksums, files are hashed by opts.Workers goroutines as their results come in.
+func newResultStore(ctx context.Context, opts Options, clock *stageClock, logs runLog) *resultStore {
+	s := &resultStore{
+		keep:   opts.KeepResults,
+		clock:  clock,
+		checks: opts.Checksums,
+		logs:   logs,
+		dirs:   make(map[string]*DirSummary),
+	}
+	if opts.Checksums.IsZero() {
+		return s
+	}
+	logs.infof("Computing the SHA-256 of every file as it is done")
+	s.hash = make(chan storedResult)
+	for range max(1, opts.Workers) {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for r := range s.hash {
+				if ctx.Err() == nil {
+					checkFile(&r.res, s.checks, logs.file(StageChecksum, r.res.Path))
+				}
+				s.finish(r)
+			}
+		}()
+	}
+	return s
+}
+
+// add takes the final result of the file at position seq of the walk.
+func (s *resultStore) add(res FileResult, seq int) {
+	r := storedResult{seq: seq, res: res}
+	r.res.Timings = s.clock.take(res.Path)
+	if s.hash != nil && needsChecksum(res) {
+		s.hash <- r
+		return
+	}
+	s.finish(r)
+}
+
+// addJob is add for the result of a job, which notes the fallback its capture
+// time came from.
+func (s *resultStore) addJob(job photoJob, res FileResult) {
+	res.TimeSource = job.TimeSource
+	s.add(res, job.seq)
+}
+
+// close waits for the checksums still being computed; no result may be added
+// after it.
+func (s *resultStore) close() {
+	if s.hash != nil {
+		close(s.hash)
+		s.wg.Wait()
+		s.hash = nil
+	}
+}
+
+// remove deletes the temporary file of the spilled results.
+func (s *resultStore) remove() {
+	if s.spill != nil {
+		s.spill.Close()
+		os.Remove(s.spill.Name())
+		s.spill = nil
+	}
+}
+
+// finish counts a result and keeps or spills it.
+func (s *resultStore) finish(r storedResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := &r.res
+	dir := filepath.Dir(res.Path)
+	if s.root == "" {
+		s.root = dir
+	} else {
+		s.root = commonDir(s.root, dir)
+	}
+	d := s.dirs[dir]
+	if d == nil {
+		d = &DirSummary{Dir: dir}
+		s.dirs[dir] = d
+	}
+	d.add(res.Status)
+	if res.Transient {
+		s.transient++
+	}
+	if res.ReadOnly {
+		s.readOnly++
+	}
+	switch res.Checksum {
+	case ChecksumVerified:
+		s.verified++
+	case ChecksumMismatch:
+		s.mismatch++
+	}
+	if pt, ok := tripPointOf(*res); ok {
+		s.trip = append(s.trip, pt)
+	}
+	s.timings.add(res.Timings)
+
+	attention := res.needsAttention()
+	switch {
+	case s.keep <= 0 || len(s.kept) < s.keep:
+		if !attention {
+			s.evictable = append(s.evictable, len(s.kept))
+		}
+		s.kept = append(s.kept, r)
+	case attention && len(s.evictable) > 0:
+		// Make room by spilling a result that needs no attention.
+		i := s.evictable[len(s.evictable)-1]
+		s.evictable = s.evictable[:len(s.evictable)-1]
+		s.spillResult(s.kept[i].res)
+		s.kept[i] = r
+	default:
+		s.spillResult(r.res)
+	}
+}
+
+// spillResult appends res to the temporary file; mu must be held. A result
+// that cannot be written is still counted, and the error is reported once.
+func (s *resultStore) spillResult(res FileResult) {
+	s.spilled++
+	if s.spillErr != nil {
+		return
+	}
+	if s.spill == nil {
+		s.spill, s.spillErr = os.CreateTemp("", "georaw-results-*.jsonl")
+		if s.spillErr != nil {
+			s.logs.errorf("Failed to create a file for the results beyond %d; they are left out of the manifest: %v", s.keep, s.spillErr)
+			return
+		}
+		s.spillBuf = bufio.NewWriterSize(s.spill, 64*1024)
+		s.spillEnc = json.NewEncoder(s.spillBuf)
+	}
+	if s.spillErr = s.spillEnc.Encode(res); s.spillErr != nil {
+		s.logs.errorf("Failed to write a result to %s; later ones are left out of the manifest: %v", s.spill.Name(), s.spillErr)
+	}
+}
+
+// files returns the kept results in walk order, with their relative paths.
+func (s *resultStore) files() []FileResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sort.Slice(s.kept, func(i, j int) bool { return s.kept[i].seq < s.kept[j].seq })
+	s.evictable = nil
+	files := make([]FileResult, len(s.kept))
+	for i, r := range s.kept {
+		files[i] = s.withRelPath(r.res)
+	}
+	return files
+}
+
+// all yields the kept results in walk order and then the spilled ones, in the
+// order they finished. A spilled result that cannot be read back ends the
+// sequence, with the error logged.
+func (s *resultStore) all() iter.Seq[FileResult] {
+	return func(yield func(FileResult) bool) {
+		for _, res := range s.files() {
+			if !yield(res) {
+				return
+			}
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.spill == nil || s.spillErr != nil {
+			return
+		}
+		if err := s.spillBuf.Flush(); err != nil {
+			s.logs.errorf("Failed to read back the results beyond %d: %v", s.keep, err)
+			return
+		}
+		if _, err := s.spill.Seek(0, 0); err != nil {
+			s.logs.errorf("Failed to read back the results beyond %d: %v", s.keep, err)
+			return
+		}
+		dec := json.NewDecoder(bufio.NewReaderSize(s.spill, 64*1024))
+		for dec.More() {
+			var res FileResult
+			if err := dec.Decode(&res); err != nil {
+				s.logs.errorf("Failed to read back the results beyond %d: %v", s.keep, err)
+				return
+			}
+			if !yield(s.withRelPath(res)) {
+				return
+			}
+		}
+	}
+}
+
+// withRelPath sets RelPath like FillRelativePaths over all results; mu must be held.
+func (s *resultStore) withRelPath(res FileResult) FileResult {
+	rel, err := filepath.Rel(s.root, res.Path)
+	if err != nil {
+		rel = res.Path
+	}
+	res.RelPath = filepath.ToSlash(rel)
+	return res
+}
+
+// directories returns the folder breakdown, sorted by folder.
+func (s *resultStore) directories() []DirSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dirs := make([]DirSummary, 0, len(s.dirs))
+	for _, d := range s.dirs {
+		dirs = append(dirs, *d)
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Dir < dirs[j].Dir })
+	return dirs
+}
+
+// summarize fills the fields of sum that come from the results.
+func (s *resultStore) summarize(sum *Summary) {
+	sum.Files = s.files()
+	sum.Directories = s.directories()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sum.FilesOmitted = s.spilled
+	sum.Transient = s.transient
+	sum.ReadOnly = s.readOnly
+	sum.ChecksumsVerified = s.verified
+	sum.ChecksumMismatch = s.mismatch
+	sum.Stats = computeTripStats(s.trip)
+	sum.Timings = s.timings.stats()
+}
@@ -0,0 +1,39 @@
+package app
+
+import "strings"
+
+// retryStatuses are the results a later run can fix: a write error, a photo outside
+// the track (e.g. before the track was completed), or unreadable metadata.
+var retryStatuses = []string{"failed", "out_of_track", "meta_error"}
+
+// RetrySource limits a run to the files an earlier run could not finish, as
+// recorded in the summary it wrote with --manifest.
+type RetrySource struct {
+	Manifest string // summary JSON of the earlier run; every file is processed when empty
+
+	files map[string]bool
+}
+
+// IsZero reports whether no retry manifest is set.
+func (r RetrySource) IsZero() bool {
+	return r.Manifest == ""
+}
+
+// load reads the files to retry from Manifest.
+func (r *RetrySource) load() error {
+	r.Manifest = strings.TrimSpace(r.Manifest)
+	if r.Manifest == "" {
+		return nil
+	}
+	files, err := manifestFiles(r.Manifest, retryStatuses...)
+	if err != nil {
+		return err
+	}
+	r.files = files
+	return nil
+}
+
+// includes reports whether path should be processed.
+func (r RetrySource) includes(path string) bool {
+	return r.Manifest == "" || r.files[absPath(path)]
+}
@@ -0,0 +1,28 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// NewRunID returns an identifier for one run: its start time plus random bits,
+// e.g. 20241016-081500-3fa9c1.
+func NewRunID() string {
+	var b [3]byte
+	_, _ = rand.Read(b[:])
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(b[:])
+}
+
+// stampRun records the run in a sidecar it wrote when opts.StampRun is set.
+// Failing to stamp is logged but does not fail the file.
+func stampRun(opts Options, sidecar string, logs runLog) {
+	if !opts.StampRun {
+		return
+	}
+	if _, err := xmp.SetLastRun(sidecar, opts.RunID, time.Now()); err != nil {
+		logs.warnf("Failed to stamp run ID into %s: %v", sidecar, err)
+	}
+}
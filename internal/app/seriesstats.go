@@ -0,0 +1,39 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SeriesStats summarizes how a series run grouped and classified the frames,
+// as feedback when tuning the detection.
+type SeriesStats struct {
+	Groups   int         `json:"groups"`   // candidate groups, short and rejected ones included
+	Sizes    map[int]int `json:"sizes"`    // groups per number of frames
+	HDR      int         `json:"hdr"`      // groups classified as HDR brackets
+	Panorama int         `json:"panorama"` // groups classified as panorama sources
+	Rejected int         `json:"rejected"` // groups long enough but not detected as a series
+	TooShort int         `json:"tooShort"` // groups with fewer frames than a series needs
+	Kept     int         `json:"kept"`     // series kept from the keywords of already tagged frames
+	MinLen   int         `json:"minLen"`   // frames a series needs
+}
+
+// String formats the statistics for the end-of-run summary.
+func (s *SeriesStats) String() string {
+	sizes := make([]int, 0, len(s.Sizes))
+	for size := range s.Sizes {
+		sizes = append(sizes, size)
+	}
+	sort.Ints(sizes)
+	hist := make([]string, len(sizes))
+	for i, size := range sizes {
+		hist[i] = fmt.Sprintf("%d×%d", s.Sizes[size], size)
+	}
+	text := fmt.Sprintf("Detection: %d candidate group(s) (count×frames: %s); %d HDR, %d panorama, %d rejected, %d too short (under %d frames)",
+		s.Groups, strings.Join(hist, ", "), s.HDR, s.Panorama, s.Rejected, s.TooShort, s.MinLen)
+	if s.Kept > 0 {
+		text += fmt.Sprintf("; %d kept from existing keywords", s.Kept)
+	}
+	return text
+}
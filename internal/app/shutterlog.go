@@ -0,0 +1,428 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+	"github.com/nir0k/GeoRAW/internal/media"
+)
+
+// shutterLine is one shutter release in a shutter log.
+type shutterLine struct {
+	Line  int
+	Frame int // frame number the line starts with; -1 when it has none
+	Coord gpx.Coordinate
+	NoFix bool      // the logger had no position for this shot
+	Time  time.Time // fix time of NMEA RMC sentences; zero otherwise
+}
+
+// ImportShutterLog writes sidecars from shutter logs: .log/.txt files some field
+// cameras and GPS loggers write with one line per shutter release. Lines are
+// matched to the RAW files by sequence: a line starting with a frame number goes
+// to the photo with that file number, other lines go to the photos in file
+// number order. Without opts.ShutterLog every folder is matched with the log
+// found in it.
+func ImportShutterLog(ctx context.Context, opts Options) (*Summary, error) {
+	return importShutterLog(ctx, opts, nil)
+}
+
+// ImportShutterLogWithLogger is ImportShutterLog with logs piped into an in-memory buffer.
+func ImportShutterLogWithLogger(ctx context.Context, opts Options, out io.Writer) (*Summary, error) {
+	return importShutterLog(ctx, opts, out)
+}
+
+func importShutterLog(ctx context.Context, opts Options, out io.Writer) (sum *Summary, err error) {
+	opts.ShutterLog = strings.TrimSpace(opts.ShutterLog)
+	if len(opts.Inputs) == 0 && opts.ShutterLog != "" {
+		opts.Inputs = []string{filepath.Dir(opts.ShutterLog)}
+	}
+	if err := opts.validateCommon(); err != nil {
+		return nil, err
+	}
+
+	logs, err := openRunLog(opts, out)
+	if err != nil {
+		return nil, err
+	}
+	started := time.Now()
+	defer func() { recordRun("shutter-log", opts, started, sum, err, logs) }()
+	release, err := lockRun(ctx, opts, "shutter-log", logs)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	logs.infof("Starting shutter log import with log=%s input=%s recursive=%t overwrite=%t", opts.ShutterLog, opts.inputText(), opts.Recursive, opts.Overwrite)
+
+	// Photos and candidate logs per folder, or all photos for an explicit log.
+	photos := make(map[string][]string)
+	logFiles := make(map[string][]string)
+	var dirs []string
+	truncated, err := walkInput(opts, logs, func(path string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		dir := filepath.Dir(path)
+		if opts.ShutterLog != "" {
+			dir = ""
+		}
+		switch ext := strings.ToLower(filepath.Ext(path)); {
+		case ext == ".log" || ext == ".txt":
+			logFiles[dir] = append(logFiles[dir], path)
+			return nil
+		case !media.SupportedRaw(path):
+			return nil
+		}
+		if _, ok := photos[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		photos[dir] = append(photos[dir], path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no RAW files found to process")
+	}
+	sort.Strings(dirs)
+
+	var rows []importedRow
+	for _, dir := range dirs {
+		logPath := opts.ShutterLog
+		var lines []shutterLine
+		var err error
+		if logPath != "" {
+			lines, err = readShutterLog(logPath)
+		} else {
+			logPath, lines, err = findShutterLog(logFiles[dir])
+		}
+		if err == nil && len(lines) == 0 {
+			err = fmt.Errorf("%s has no shutter lines", logPath)
+		}
+		if err != nil {
+			if logPath == "" {
+				err = fmt.Errorf("no shutter log in %s", dir)
+			}
+			for _, path := range photos[dir] {
+				rows = append(rows, importedRow{Path: path, Err: err, Skip: logPath == "", Where: "shutter log"})
+			}
+			continue
+		}
+		logs.infof("Matching %d shutter lines of %s with %d RAW files", len(lines), logPath, len(photos[dir]))
+		rows = append(rows, matchShutterLog(logPath, lines, photos[dir], logs)...)
+	}
+	return writeImported(ctx, opts, logs, rows, truncated)
+}
+
+// findShutterLog picks the shutter log among a folder's .log/.txt files. Files
+// without shutter lines are passed over; several logs are an error.
+func findShutterLog(candidates []string) (string, []shutterLine, error) {
+	var found []string
+	var lines []shutterLine
+	for _, path := range candidates {
+		parsed, err := readShutterLog(path)
+		if err != nil || len(parsed) == 0 {
+			continue
+		}
+		found = append(found, path)
+		lines = parsed
+	}
+	switch len(found) {
+	case 0:
+		return "", nil, nil
+	case 1:
+		return found[0], lines, nil
+	default:
+		names := make([]string, len(found))
+		for i, path := range found {
+			names[i] = filepath.Base(path)
+		}
+		return found[0], nil, fmt.Errorf("several shutter logs (%s); pass the one to use with --log", strings.Join(names, ", "))
+	}
+}
+
+// matchShutterLog pairs the lines of one log with its photos. RAW files sharing
+// a folder and base name are one shot. Lines with frame numbers go to the shot
+// with that file number; otherwise the line count must equal the shot count, as
+// a shot deleted from the card would shift every later photo.
+func matchShutterLog(logPath string, lines []shutterLine, paths []string, logs runLog) []importedRow {
+	type shot struct {
+		frame int
+		paths []string
+	}
+	byName := make(map[string]*shot)
+	var shots []*shot
+	for _, path := range paths {
+		key := strings.ToLower(strings.TrimSuffix(path, filepath.Ext(path)))
+		s, ok := byName[key]
+		if !ok {
+			s = &shot{frame: fileNumber(filepath.Base(key))}
+			byName[key] = s
+			shots = append(shots, s)
+		}
+		s.paths = append(s.paths, path)
+	}
+	sort.SliceStable(shots, func(i, j int) bool {
+		if shots[i].frame != shots[j].frame {
+			return shots[i].frame < shots[j].frame
+		}
+		return shots[i].paths[0] < shots[j].paths[0]
+	})
+
+	base := filepath.Base(logPath)
+	row := func(path string, line shutterLine) importedRow {
+		r := importedRow{
+			Path:   path,
+			Where:  fmt.Sprintf("%s line %d", base, line.Line),
+			Source: fmt.Sprintf("%s:%d", base, line.Line),
+			Coord:  line.Coord,
+			Time:   line.Time,
+		}
+		if line.NoFix {
+			r.Err, r.Skip = fmt.Errorf("no GPS fix for this shot in %s", base), true
+		}
+		return r
+	}
+
+	var rows []importedRow
+	if lines[0].Frame >= 0 {
+		byFrame := make(map[int]shutterLine, len(lines))
+		for _, line := range lines {
+			byFrame[line.Frame] = line
+		}
+		matched := 0
+		for _, s := range shots {
+			line, ok := byFrame[s.frame]
+			for _, path := range s.paths {
+				if !ok {
+					rows = append(rows, importedRow{Path: path, Err: fmt.Errorf("no line for frame %d in %s", s.frame, base), Skip: true, Where: base})
+					continue
+				}
+				rows = append(rows, row(path, line))
+			}
+			if ok {
+				matched++
+			}
+		}
+		if unused := len(lines) - matched; unused > 0 {
+			logs.infof("%d lines of %s match no photo (deleted shots or another folder)", unused, base)
+		}
+		return rows
+	}
+
+	if len(lines) != len(shots) {
+		err := fmt.Errorf("%s has %d shutter lines for %d shots; add frame numbers to the log or remove the photos it does not cover", base, len(lines), len(shots))
+		for _, s := range shots {
+			for _, path := range s.paths {
+				rows = append(rows, importedRow{Path: path, Err: err, Where: base})
+			}
+		}
+		return rows
+	}
+	for i, s := range shots {
+		for _, path := range s.paths {
+			rows = append(rows, row(path, lines[i]))
+		}
+	}
+	return rows
+}
+
+// fileNumber returns the number a camera file name ends with (IMG_0042 is 42),
+// or -1 when it has none.
+func fileNumber(name string) int {
+	i := len(name)
+	for i > 0 && name[i-1] >= '0' && name[i-1] <= '9' {
+		i--
+	}
+	n, err := strconv.Atoi(name[i:])
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// readShutterLog parses a shutter log. Lines are NMEA GGA or RMC sentences, or
+// "[frame] lat lon [alt]" separated by commas, semicolons, tabs or spaces; a
+// first field without a decimal point followed by two numbers is a frame
+// number. When a log has both GGA and RMC sentences, the GGA lines count as the
+// shots. Blank lines, # comments and a header line are skipped.
+func readShutterLog(path string) ([]shutterLine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read shutter log: %w", err)
+	}
+	data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+
+	var plain, gga, rmc []shutterLine
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.HasPrefix(text, "$") {
+			kind, line, err := parseNMEA(text)
+			if err != nil {
+				return nil, fmt.Errorf("%s line %d: %w", filepath.Base(path), n, err)
+			}
+			line.Line = n
+			switch kind {
+			case "GGA":
+				gga = append(gga, line)
+			case "RMC":
+				rmc = append(rmc, line)
+			}
+			continue
+		}
+		line, err := parseShutterFields(text)
+		if err != nil {
+			if n == 1 {
+				continue // header
+			}
+			return nil, fmt.Errorf("%s line %d: %w", filepath.Base(path), n, err)
+		}
+		line.Line = n
+		plain = append(plain, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read shutter log: %w", err)
+	}
+
+	switch {
+	case len(plain) > 0 && len(gga)+len(rmc) > 0:
+		return nil, fmt.Errorf("%s mixes NMEA sentences with coordinate lines", filepath.Base(path))
+	case len(gga) > 0:
+		return gga, nil
+	case len(rmc) > 0:
+		return rmc, nil
+	}
+	for _, line := range plain[1:] {
+		if (line.Frame >= 0) != (plain[0].Frame >= 0) {
+			return nil, fmt.Errorf("%s line %d: frame numbers on some lines only", filepath.Base(path), line.Line)
+		}
+	}
+	return plain, nil
+}
+
+// parseShutterFields parses a "[frame] lat lon [alt]" line.
+func parseShutterFields(text string) (shutterLine, error) {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\t' || r == ' '
+	})
+	line := shutterLine{Frame: -1}
+	if len(fields) >= 3 && !strings.ContainsAny(fields[0], ".eE") {
+		if frame, err := strconv.Atoi(fields[0]); err == nil && frame >= 0 {
+			line.Frame = frame
+			fields = fields[1:]
+		}
+	}
+	if len(fields) < 2 {
+		return line, fmt.Errorf("expected [frame] lat lon [alt]")
+	}
+	lat, latErr := strconv.ParseFloat(fields[0], 64)
+	lon, lonErr := strconv.ParseFloat(fields[1], 64)
+	if latErr != nil || lonErr != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return line, fmt.Errorf("invalid latitude/longitude %q %q", fields[0], fields[1])
+	}
+	line.Coord = gpx.Coordinate{Latitude: lat, Longitude: lon}
+	if len(fields) > 2 {
+		if alt, err := strconv.ParseFloat(fields[2], 64); err == nil {
+			line.Coord.Altitude = &alt
+		}
+	}
+	return line, nil
+}
+
+// parseNMEA parses a GGA or RMC sentence, checking its checksum when it has one.
+// Other sentence types return an empty kind.
+func parseNMEA(text string) (string, shutterLine, error) {
+	line := shutterLine{Frame: -1}
+	body := strings.TrimPrefix(text, "$")
+	if data, sum, ok := strings.Cut(body, "*"); ok {
+		want, err := strconv.ParseUint(strings.TrimSpace(sum), 16, 8)
+		if err != nil {
+			return "", line, fmt.Errorf("invalid NMEA checksum %q", sum)
+		}
+		var got byte
+		for i := 0; i < len(data); i++ {
+			got ^= data[i]
+		}
+		if uint64(got) != want {
+			return "", line, fmt.Errorf("NMEA checksum mismatch")
+		}
+		body = data
+	}
+	fields := strings.Split(body, ",")
+	if len(fields[0]) < 5 {
+		return "", line, fmt.Errorf("invalid NMEA sentence %q", fields[0])
+	}
+	kind := fields[0][len(fields[0])-3:]
+	var latField, lonField int
+	switch kind {
+	case "GGA":
+		if len(fields) < 10 {
+			return "", line, fmt.Errorf("short GGA sentence")
+		}
+		latField, lonField = 2, 4
+		line.NoFix = fields[6] == "" || fields[6] == "0"
+		if alt, err := strconv.ParseFloat(fields[9], 64); err == nil {
+			line.Coord.Altitude = &alt
+		}
+	case "RMC":
+		if len(fields) < 10 {
+			return "", line, fmt.Errorf("short RMC sentence")
+		}
+		latField, lonField = 3, 5
+		line.NoFix = fields[2] != "A"
+		if ts, err := time.Parse("020106 150405", fields[9]+" "+strings.SplitN(fields[1], ".", 2)[0]); err == nil {
+			line.Time = ts.UTC()
+		}
+	default:
+		return "", line, nil
+	}
+	if line.NoFix {
+		return kind, line, nil
+	}
+	lat, err := nmeaDegrees(fields[latField], fields[latField+1], 2)
+	if err != nil {
+		return "", line, err
+	}
+	lon, err := nmeaDegrees(fields[lonField], fields[lonField+1], 3)
+	if err != nil {
+		return "", line, err
+	}
+	line.Coord.Latitude, line.Coord.Longitude = lat, lon
+	return kind, line, nil
+}
+
+// nmeaDegrees converts an NMEA (d)ddmm.mmmm value with its hemisphere to degrees.
+func nmeaDegrees(value, hemisphere string, degreeDigits int) (float64, error) {
+	if len(value) < degreeDigits+2 {
+		return 0, fmt.Errorf("invalid NMEA coordinate %q", value)
+	}
+	deg, err1 := strconv.Atoi(value[:degreeDigits])
+	minutes, err2 := strconv.ParseFloat(value[degreeDigits:], 64)
+	if err1 != nil || err2 != nil || minutes >= 60 {
+		return 0, fmt.Errorf("invalid NMEA coordinate %q", value)
+	}
+	v := float64(deg) + minutes/60
+	switch hemisphere {
+	case "N", "E":
+	case "S", "W":
+		v = -v
+	default:
+		return 0, fmt.Errorf("invalid NMEA hemisphere %q", hemisphere)
+	}
+	return v, nil
+}
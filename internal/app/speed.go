@@ -0,0 +1,80 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+)
+
+// DefaultMaxSpeed is the speed guard threshold in km/h used by the CLI and GUI: fast
+// enough for cars and trains, well below what a GPS glitch or a wrong clock implies.
+const DefaultMaxSpeed = 300
+
+// holdImplausible sorts the track-matched tasks by capture time and holds back both
+// photos of every consecutive pair whose positions imply moving faster than maxKmh.
+// Held photos are recorded as suspicious instead of being written and reported
+// through done. A maxKmh of zero disables the guard.
+func holdImplausible(tasks []sidecarTask, maxKmh float64, record taskResult, count *counters, logs runLog, done func()) []sidecarTask {
+	if maxKmh <= 0 {
+		return tasks
+	}
+	var matched []int
+	for i, task := range tasks {
+		if !task.Mirror {
+			matched = append(matched, i)
+		}
+	}
+	sort.SliceStable(matched, func(a, b int) bool {
+		return tasks[matched[a]].Capture.Before(tasks[matched[b]].Capture)
+	})
+
+	reasons := make(map[int]string)
+	for k := 1; k < len(matched); k++ {
+		prev, cur := tasks[matched[k-1]], tasks[matched[k]]
+		kmh := impliedSpeed(prev, cur)
+		if kmh <= maxKmh {
+			continue
+		}
+		logs.file(StageMatch, cur.Job.Path).warnf("Implausible speed of %.0f km/h between %s and %s (limit %.0f km/h)", kmh, prev.Job.Path, cur.Job.Path, maxKmh)
+		if _, ok := reasons[matched[k-1]]; !ok {
+			reasons[matched[k-1]] = fmt.Sprintf("Implies %.0f km/h to %s", kmh, cur.Job.Path)
+		}
+		reasons[matched[k]] = fmt.Sprintf("Implies %.0f km/h from %s", kmh, prev.Job.Path)
+	}
+	if len(reasons) == 0 {
+		return tasks
+	}
+
+	out := tasks[:0]
+	for i, task := range tasks {
+		reason, held := reasons[i]
+		if !held {
+			out = append(out, task)
+			continue
+		}
+		count.suspicious.Add(1)
+		record(task, FileResult{
+			Path:       task.Job.Path,
+			Status:     "suspicious",
+			Message:    reason,
+			Point:      taskPoint(task),
+			Confidence: task.Confidence,
+			Estimated:  task.Estimated,
+		})
+		done()
+	}
+	return out
+}
+
+// impliedSpeed is the straight-line speed in km/h between two matched positions.
+// Photos less than a second apart count as a second, so bursts stay plausible.
+func impliedSpeed(a, b sidecarTask) float64 {
+	meters := gpx.Haversine(a.Coord, b.Coord)
+	elapsed := b.Capture.Sub(a.Capture)
+	if elapsed < time.Second {
+		elapsed = time.Second
+	}
+	return meters / elapsed.Seconds() * 3.6
+}
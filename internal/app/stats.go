@@ -0,0 +1,102 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+)
+
+// sessionGap splits shooting sessions: time between photos further apart than this
+// does not count as active shooting time.
+const sessionGap = 30 * time.Minute
+
+// TripStats summarizes the positions a run resolved.
+type TripStats struct {
+	Photos      int       `json:"photos"`                // photos with a written or confirmed position
+	DistanceKm  float64   `json:"distanceKm"`            // straight-line distance between consecutive photos
+	PhotosPerKm float64   `json:"photosPerKm,omitempty"` // zero when the photos were all taken in one spot
+	ActiveHours float64   `json:"activeHours"`           // time between photos, leaving out gaps over 30 minutes
+	Sessions    int       `json:"sessions"`              // runs of photos separated by such gaps
+	First       time.Time `json:"first"`
+	Last        time.Time `json:"last"`
+}
+
+// tripPoint is what the trip statistics need of a result's position.
+type tripPoint struct {
+	time     time.Time
+	lat, lon float64
+}
+
+// tripPointOf returns the position of a processed or unchanged result.
+func tripPointOf(res FileResult) (tripPoint, bool) {
+	if res.Point == nil || (res.Status != "processed" && res.Status != "unchanged") {
+		return tripPoint{}, false
+	}
+	return tripPoint{time: res.Point.Time, lat: res.Point.Latitude, lon: res.Point.Longitude}, true
+}
+
+// ComputeTripStats walks the processed and unchanged results in capture order. It
+// returns nil when no result has a position.
+func ComputeTripStats(results []FileResult) *TripStats {
+	var points []tripPoint
+	for _, res := range results {
+		if pt, ok := tripPointOf(res); ok {
+			points = append(points, pt)
+		}
+	}
+	return computeTripStats(points)
+}
+
+// computeTripStats is ComputeTripStats over the positions alone; it sorts points.
+func computeTripStats(points []tripPoint) *TripStats {
+	if len(points) == 0 {
+		return nil
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].time.Before(points[j].time) })
+
+	stats := &TripStats{Photos: len(points), Sessions: 1, First: points[0].time, Last: points[len(points)-1].time}
+	var active time.Duration
+	for i := 1; i < len(points); i++ {
+		prev, cur := points[i-1], points[i]
+		stats.DistanceKm += gpx.Haversine(gpx.Coordinate{Latitude: prev.lat, Longitude: prev.lon}, gpx.Coordinate{Latitude: cur.lat, Longitude: cur.lon}) / 1000
+		if gap := cur.time.Sub(prev.time); gap > sessionGap {
+			stats.Sessions++
+		} else {
+			active += gap
+		}
+	}
+	stats.ActiveHours = active.Hours()
+	if stats.DistanceKm >= 0.01 {
+		stats.PhotosPerKm = float64(stats.Photos) / stats.DistanceKm
+	}
+	return stats
+}
+
+// String formats the statistics for the end-of-run summary.
+func (s *TripStats) String() string {
+	perKm := "-"
+	if s.PhotosPerKm > 0 {
+		perKm = fmt.Sprintf("%.1f", s.PhotosPerKm)
+	}
+	return fmt.Sprintf("Trip: %.1f km between %d photos (%s photos/km), %.1f h shooting in %d session(s)",
+		s.DistanceKm, s.Photos, perKm, s.ActiveHours, s.Sessions)
+}
+
+// reportTripStats fills sum.Stats and prints and logs them.
+func reportTripStats(opts Options, sum *Summary, logs runLog) {
+	sum.Stats = ComputeTripStats(sum.Files)
+	logTripStats(opts, sum, logs)
+}
+
+// logTripStats prints and logs sum.Stats, if any.
+func logTripStats(opts Options, sum *Summary, logs runLog) {
+	if sum.Stats == nil {
+		return
+	}
+	if opts.PrintSummary {
+		fmt.Println(sum.Stats)
+	}
+	logs.infof("%s", sum.Stats)
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/media"
+)
+
+// Sources the capture time of a photo without one in its EXIF can be taken from.
+const (
+	TimeSourceMtime    = "mtime"    // the file's modification time
+	TimeSourceSibling  = "sibling"  // the capture time of the JPEG/HEIF of the same name
+	TimeSourceFilename = "filename" // a date and time in the file name, e.g. IMG_20240712_101530
+)
+
+var timeSources = []string{TimeSourceFilename, TimeSourceSibling, TimeSourceMtime}
+
+// TimeFallback lists, in the order they are tried, where the capture time of a
+// photo whose EXIF records none is taken from. Without sources such photos fail
+// as meta_error.
+type TimeFallback struct {
+	Sources  []string
+	Patterns []string // file name layouts such as "CAM1_%Y-%m-%d_%H%M%S" (media.DefaultFilenameTimePatterns when empty); imply the filename source
+
+	patterns []media.FilenameTimePattern
+}
+
+// IsZero reports whether no fallback is configured.
+func (f TimeFallback) IsZero() bool {
+	return len(f.Sources) == 0
+}
+
+// load checks the sources.
+func (f *TimeFallback) load() error {
+	var sources []string
+	for _, s := range f.Sources {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			continue
+		}
+		valid := false
+		for _, known := range timeSources {
+			valid = valid || s == known
+		}
+		if !valid {
+			return fmt.Errorf("unknown time fallback %q (expected %s)", s, strings.Join(timeSources, ", "))
+		}
+		sources = append(sources, s)
+	}
+	if len(sources) == 0 && len(f.Patterns) > 0 {
+		sources = []string{TimeSourceFilename}
+	}
+	f.Sources = sources
+	var err error
+	f.patterns, err = media.ParseFilenameTimePatterns(f.Patterns)
+	return err
+}
+
+// readMetadata reads the metadata of path and, when it has no capture time,
+// takes one from the first fallback source that yields it. The source used is
+// returned, empty when the EXIF time was found.
+func readMetadata(path string, fallback TimeFallback) (media.Metadata, string, error) {
+	meta, err := media.ReadMetadata(path)
+	if !errors.Is(err, media.ErrNoCaptureTime) || fallback.IsZero() {
+		return meta, "", err
+	}
+	for _, source := range fallback.Sources {
+		var t time.Time
+		switch source {
+		case TimeSourceFilename:
+			t, _ = media.FilenameTime(path, fallback.patterns)
+		case TimeSourceSibling:
+			t = siblingTime(path)
+		case TimeSourceMtime:
+			t = mtime(path)
+		}
+		if !t.IsZero() {
+			meta.CaptureTime = t
+			return meta, source, nil
+		}
+	}
+	return meta, "", fmt.Errorf("%w (tried %s)", err, strings.Join(fallback.Sources, ", "))
+}
+
+// MetadataStatus is the status of a file whose metadata could not be read:
+// skipped when the decoder crashed on it in an earlier run (media.ErrSuspect),
+// meta_error otherwise.
+func MetadataStatus(err error) string {
+	if errors.Is(err, media.ErrSuspect) {
+		return "skipped"
+	}
+	return "meta_error"
+}
+
+// metaFailure counts and records a file whose metadata could not be read.
+func metaFailure(path string, err error, count *counters) FileResult {
+	status := MetadataStatus(err)
+	if status == "skipped" {
+		count.skipped.Add(1)
+	} else {
+		count.metaError.Add(1)
+	}
+	return FailedResult(path, status, err)
+}
+
+// siblingTime returns the capture time of the JPEG or HEIF with the base name of
+// path in its folder, or zero.
+func siblingTime(path string) time.Time {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, ext := range []string{".jpg", ".jpeg", ".heic", ".heif", ".hif"} {
+		for _, candidate := range []string{base + ext, base + strings.ToUpper(ext)} {
+			if candidate == path {
+				continue
+			}
+			if meta, err := media.ReadMetadata(candidate); err == nil {
+				return meta.CaptureTime
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// mtime returns the modification time of path as a wall-clock time like the
+// EXIF ones, assuming the camera clock ran in the computer's time zone, or zero.
+func mtime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	t := info.ModTime().Local()
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
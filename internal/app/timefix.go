@@ -0,0 +1,178 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/errcode"
+	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// FixCaptureTimes applies opts.TimeFix to the capture time of every RAW in the
+// input. The corrected time is written to the sidecar as exif:DateTimeOriginal
+// together with the total shift from the embedded time (georaw:TimeShift), so
+// corrections build on earlier ones (unless opts.TimeFixReset is set) and later
+// geotagging runs use the corrected time. The RAW files themselves are only
+// modified when opts.TimeFixEmbedded is set.
+func FixCaptureTimes(ctx context.Context, opts Options) (*Summary, error) {
+	return fixCaptureTimes(ctx, opts, nil)
+}
+
+// FixCaptureTimesWithLogger is FixCaptureTimes with logs piped into an in-memory buffer.
+func FixCaptureTimesWithLogger(ctx context.Context, opts Options, out io.Writer) (*Summary, error) {
+	return fixCaptureTimes(ctx, opts, out)
+}
+
+func fixCaptureTimes(ctx context.Context, opts Options, out io.Writer) (sum *Summary, err error) {
+	if err := opts.validateCommon(); err != nil {
+		return nil, err
+	}
+	if err := opts.TimeFix.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.TimeFix.IsZero() && !opts.TimeFixReset {
+		return nil, fmt.Errorf("no time correction given")
+	}
+
+	logs, err := openRunLog(opts, out)
+	if err != nil {
+		return nil, err
+	}
+	started := time.Now()
+	defer func() { recordRun("timefix", opts, started, sum, err, logs) }()
+	release, err := lockRun(ctx, opts, "timefix", logs)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	infof := logs.infof
+
+	infof("Correcting capture times (%s) for input=%s recursive=%t reset=%t embedded=%t", opts.TimeFix, opts.inputText(), opts.Recursive, opts.TimeFixReset, opts.TimeFixEmbedded)
+
+	var (
+		count counters
+		clock stageClock
+		paths []string
+	)
+	store := newResultStore(ctx, opts, &clock, logs)
+	defer store.remove()
+	defer store.close()
+	truncated, err := walkInput(opts, logs, func(path string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !strings.EqualFold(filepath.Ext(path), ".xmp") && !skipLivePhotoVideo(path, logs) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, errcode.New(errcode.NoFiles, nil)
+	}
+
+	for i, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if opts.Progress != nil {
+			opts.Progress(i, len(paths))
+		}
+		if !media.SupportedRaw(path) {
+			count.skipped.Add(1)
+			store.add(FileResult{Path: path, Status: "skipped", Message: "Not a RAW file"}, i)
+			continue
+		}
+		meta, err := media.ReadMetadata(path)
+		if err != nil {
+			logs.file(StageMetadata, path).warnf("Failed to read metadata for %s: %v", path, err)
+			store.add(metaFailure(path, err, &count), i)
+			continue
+		}
+
+		sidecar := xmp.SidecarPath(path)
+		var previous time.Duration
+		if !opts.TimeFixReset {
+			previous, _ = xmp.ReadTimeShift(sidecar)
+		}
+		corrected := opts.TimeFix.Apply(meta.CaptureTime.Add(previous))
+		shift := corrected.Sub(meta.CaptureTime)
+		if _, err := xmp.SetCaptureTime(sidecar, corrected, shift); err != nil {
+			logs.file(StageWrite, path).errorf("Failed to write corrected time for %s: %v", path, err)
+			count.failed.Add(1)
+			store.add(FailedResult(path, "failed", err), i)
+			continue
+		}
+		written := logs.file(StageWrite, path)
+		if opts.TimeFixEmbedded {
+			if err := writeEmbeddedTime(path, sidecar, corrected, opts.TimeFix.ZoneOffset(corrected)); err != nil {
+				written.errorf("Failed to write corrected time into %s: %v (the sidecar has it)", path, err)
+				count.failed.Add(1)
+				store.add(FailedResult(path, "failed", err), i)
+				continue
+			}
+		}
+		stampRun(opts, sidecar, written)
+		written.infof("Capture time of %s: %s -> %s (total shift %s)", path, meta.CaptureTime.Format(time.DateTime), corrected.Format(time.DateTime), shift)
+		count.processed.Add(1)
+		store.add(FileResult{
+			Path:    path,
+			Status:  "processed",
+			Message: fmt.Sprintf("%s (shift %s)", corrected.Format(time.DateTime), shift),
+		}, i)
+	}
+
+	if opts.Progress != nil {
+		opts.Progress(len(paths), len(paths))
+	}
+
+	store.close()
+	sum = &Summary{
+		RunID:     opts.RunID,
+		Processed: int(count.processed.Load()),
+		Skipped:   int(count.skipped.Load()),
+		Failed:    int(count.failed.Load()),
+		MetaError: int(count.metaError.Load()),
+		Truncated: truncated,
+	}
+	store.summarize(sum)
+	summary := fmt.Sprintf("Finished. corrected=%d skipped=%d failed=%d meta_errors=%d", sum.Processed, sum.Skipped, sum.Failed, sum.MetaError)
+	if opts.PrintSummary {
+		fmt.Println(summary)
+	}
+	infof("%s", summary)
+	warnTruncated(opts, sum, logs)
+	return sum, nil
+}
+
+// writeEmbeddedTime writes the corrected time into the file itself. The sidecar
+// already holds it with the shift from the old embedded time; once the file has
+// the corrected time, the recorded shift drops to zero so it is not applied
+// twice.
+func writeEmbeddedTime(path, sidecar string, corrected time.Time, offset string) error {
+	if err := media.WriteCaptureTime(path, corrected, offset); err != nil {
+		return err
+	}
+	if _, err := xmp.SetCaptureTime(sidecar, corrected, 0); err != nil {
+		return fmt.Errorf("the file has the corrected time but resetting the sidecar shift failed, so remove georaw:TimeShift from %s: %w", sidecar, err)
+	}
+	return nil
+}
+
+// applyTimeShift replaces the embedded capture time with the corrected one recorded
+// in the photo's sidecar by FixCaptureTimes, if any.
+func applyTimeShift(path string, meta *media.Metadata) (time.Duration, bool) {
+	shift, ok := xmp.ReadTimeShift(xmp.SidecarPath(path))
+	if !ok {
+		return 0, false
+	}
+	meta.CaptureTime = meta.CaptureTime.Add(shift)
+	return shift, true
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	_ "time/tzdata" // zone rules for systems without a zoneinfo database (Windows)
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+)
+
+// TimeZoneAuto infers the camera time zone from the start of the GPX track.
+const TimeZoneAuto = "auto"
+
+// loadTimeZone resolves a user supplied zone name. Empty means capture times are used as-is.
+func loadTimeZone(name string, track *gpx.TrackIndex) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, nil
+	}
+	if strings.EqualFold(name, TimeZoneAuto) {
+		start, _ := track.Bounds()
+		coord, err := track.CoordinateAt(start)
+		if err != nil {
+			return nil, fmt.Errorf("infer time zone: %w", err)
+		}
+		name = nearestZone(coord.Latitude, coord.Longitude)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("load time zone %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// applyTimeZone reinterprets the camera's wall-clock capture times in loc, so each
+// photo gets the UTC offset (including DST) that was in effect when it was taken.
+// During the repeated hour after a DST switch the first occurrence is assumed.
+func applyTimeZone(jobs []photoJob, loc *time.Location) {
+	for i := range jobs {
+		jobs[i].Meta.CaptureTime = wallClockIn(jobs[i].Meta.CaptureTime, loc)
+	}
+}
+
+// wallClockIn returns the UTC time of the wall-clock time t read in loc.
+func wallClockIn(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc).UTC()
+}
+
+// zoneAnchor is a reference city for a time zone.
+type zoneAnchor struct {
+	name     string
+	lat, lon float64
+}
+
+// zoneAnchors is a coarse lookup table: the zone of the nearest anchor is assumed.
+// It is accurate away from borders; use an explicit zone name otherwise.
+var zoneAnchors = []zoneAnchor{
+	{"Atlantic/Reykjavik", 64.15, -21.94},
+	{"Atlantic/Azores", 37.74, -25.67},
+	{"Atlantic/Canary", 28.12, -15.43},
+	{"Europe/Lisbon", 38.72, -9.14},
+	{"Europe/Dublin", 53.35, -6.26},
+	{"Europe/London", 51.51, -0.13},
+	{"Europe/Madrid", 40.42, -3.70},
+	{"Europe/Paris", 48.86, 2.35},
+	{"Europe/Brussels", 50.85, 4.35},
+	{"Europe/Amsterdam", 52.37, 4.90},
+	{"Europe/Zurich", 47.38, 8.54},
+	{"Europe/Berlin", 52.52, 13.40},
+	{"Europe/Rome", 41.90, 12.50},
+	{"Europe/Vienna", 48.21, 16.37},
+	{"Europe/Prague", 50.08, 14.44},
+	{"Europe/Budapest", 47.50, 19.04},
+	{"Europe/Warsaw", 52.23, 21.01},
+	{"Europe/Belgrade", 44.79, 20.45},
+	{"Europe/Oslo", 59.91, 10.75},
+	{"Europe/Copenhagen", 55.68, 12.57},
+	{"Europe/Stockholm", 59.33, 18.07},
+	{"Europe/Helsinki", 60.17, 24.94},
+	{"Europe/Tallinn", 59.44, 24.75},
+	{"Europe/Riga", 56.95, 24.11},
+	{"Europe/Vilnius", 54.69, 25.28},
+	{"Europe/Minsk", 53.90, 27.57},
+	{"Europe/Kyiv", 50.45, 30.52},
+	{"Europe/Bucharest", 44.43, 26.10},
+	{"Europe/Sofia", 42.70, 23.32},
+	{"Europe/Athens", 37.98, 23.73},
+	{"Europe/Istanbul", 41.01, 28.98},
+	{"Europe/Moscow", 55.76, 37.62},
+	{"Asia/Tbilisi", 41.72, 44.79},
+	{"Asia/Yerevan", 40.18, 44.51},
+	{"Asia/Baku", 40.41, 49.87},
+	{"Asia/Jerusalem", 31.77, 35.21},
+	{"Asia/Riyadh", 24.71, 46.68},
+	{"Asia/Dubai", 25.20, 55.27},
+	{"Asia/Tehran", 35.69, 51.39},
+	{"Asia/Yekaterinburg", 56.84, 60.60},
+	{"Asia/Tashkent", 41.30, 69.24},
+	{"Asia/Almaty", 43.24, 76.89},
+	{"Asia/Karachi", 24.86, 67.01},
+	{"Asia/Kolkata", 28.61, 77.21},
+	{"Asia/Kathmandu", 27.72, 85.32},
+	{"Asia/Dhaka", 23.81, 90.41},
+	{"Asia/Novosibirsk", 55.03, 82.92},
+	{"Asia/Krasnoyarsk", 56.01, 92.89},
+	{"Asia/Irkutsk", 52.29, 104.28},
+	{"Asia/Bangkok", 13.76, 100.50},
+	{"Asia/Singapore", 1.35, 103.82},
+	{"Asia/Jakarta", -6.21, 106.85},
+	{"Asia/Shanghai", 39.90, 116.40},
+	{"Asia/Hong_Kong", 22.32, 114.17},
+	{"Asia/Taipei", 25.03, 121.57},
+	{"Asia/Manila", 14.60, 120.98},
+	{"Asia/Seoul", 37.57, 126.98},
+	{"Asia/Tokyo", 35.68, 139.69},
+	{"Asia/Vladivostok", 43.12, 131.89},
+	{"Africa/Casablanca", 33.57, -7.59},
+	{"Africa/Lagos", 6.52, 3.38},
+	{"Africa/Cairo", 30.04, 31.24},
+	{"Africa/Nairobi", -1.29, 36.82},
+	{"Africa/Johannesburg", -26.20, 28.05},
+	{"America/St_Johns", 47.56, -52.71},
+	{"America/Halifax", 44.65, -63.57},
+	{"America/New_York", 40.71, -74.01},
+	{"America/Toronto", 43.65, -79.38},
+	{"America/Chicago", 41.88, -87.63},
+	{"America/Denver", 39.74, -104.99},
+	{"America/Phoenix", 33.45, -112.07},
+	{"America/Los_Angeles", 34.05, -118.24},
+	{"America/Vancouver", 49.28, -123.12},
+	{"America/Anchorage", 61.22, -149.90},
+	{"Pacific/Honolulu", 21.31, -157.86},
+	{"America/Mexico_City", 19.43, -99.13},
+	{"America/Bogota", 4.71, -74.07},
+	{"America/Lima", -12.05, -77.04},
+	{"America/Santiago", -33.45, -70.67},
+	{"America/Argentina/Buenos_Aires", -34.60, -58.38},
+	{"America/Sao_Paulo", -23.55, -46.63},
+	{"Australia/Perth", -31.95, 115.86},
+	{"Australia/Adelaide", -34.93, 138.60},
+	{"Australia/Brisbane", -27.47, 153.03},
+	{"Australia/Sydney", -33.87, 151.21},
+	{"Australia/Melbourne", -37.81, 144.96},
+	{"Pacific/Auckland", -36.85, 174.76},
+}
+
+// nearestZone returns the zone of the closest anchor by great-circle distance.
+func nearestZone(lat, lon float64) string {
+	best := "UTC"
+	bestDist := math.Inf(1)
+	for _, z := range zoneAnchors {
+		if d := gpx.Haversine(gpx.Coordinate{Latitude: lat, Longitude: lon}, gpx.Coordinate{Latitude: z.lat, Longitude: z.lon}); d < bestDist {
+			best, bestDist = z.name, d
+		}
+	}
+	return best
+}
@@ -0,0 +1,187 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StageTimings are the milliseconds a file spent in each stage of a run; a stage
+// the file did not reach is zero.
+type StageTimings struct {
+	MetadataMs float64 `json:"metadataMs,omitempty"` // reading the capture time and camera, time fallbacks included
+	MatchMs    float64 `json:"matchMs,omitempty"`    // looking up the position on the track and converting its altitude
+	WriteMs    float64 `json:"writeMs,omitempty"`    // writing the sidecar, with everything written along with the GPS
+}
+
+// TimingStats are the percentiles of the stage timings of a run's files, to tell
+// whether decoding, track lookups or the disk hold a run up.
+type TimingStats struct {
+	Metadata *StagePercentiles `json:"metadata,omitempty"`
+	Match    *StagePercentiles `json:"match,omitempty"`
+	Write    *StagePercentiles `json:"write,omitempty"`
+}
+
+// StagePercentiles summarize the milliseconds the files that reached a stage
+// spent in it.
+type StagePercentiles struct {
+	Files   int     `json:"files"`
+	TotalMs float64 `json:"totalMs"`
+	P50Ms   float64 `json:"p50Ms"`
+	P90Ms   float64 `json:"p90Ms"`
+	P99Ms   float64 `json:"p99Ms"`
+	MaxMs   float64 `json:"maxMs"`
+}
+
+// stageClock collects the stage timings of a run's files by path; it is safe
+// for concurrent use.
+type stageClock struct {
+	mu    sync.Mutex
+	files map[string]*StageTimings
+}
+
+// since adds the time from start to now to the stage of path, one of
+// StageMetadata, StageMatch and StageWrite.
+func (c *stageClock) since(path, stage string, start time.Time) {
+	// Rounded to microseconds, but never to zero, so a stage that was reached
+	// is still counted.
+	ms := max(math.Round(float64(time.Since(start))/1e3)/1e3, 0.001)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.files == nil {
+		c.files = make(map[string]*StageTimings)
+	}
+	t := c.files[path]
+	if t == nil {
+		t = &StageTimings{}
+		c.files[path] = t
+	}
+	switch stage {
+	case StageMetadata:
+		t.MetadataMs += ms
+	case StageMatch:
+		t.MatchMs += ms
+	case StageWrite:
+		t.WriteMs += ms
+	}
+}
+
+// take returns the timings of path and forgets them, or nil when there are none.
+func (c *stageClock) take(path string) *StageTimings {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := c.files[path]
+	delete(c.files, path)
+	return t
+}
+
+// ComputeTimingStats returns the percentiles of the timings in results, or nil
+// when no result has any.
+func ComputeTimingStats(results []FileResult) *TimingStats {
+	var samples timingSamples
+	for _, res := range results {
+		samples.add(res.Timings)
+	}
+	return samples.stats()
+}
+
+// timingSamples collects the stage timings of files one at a time.
+type timingSamples struct {
+	metadata, match, write []float64
+}
+
+func (s *timingSamples) add(t *StageTimings) {
+	if t == nil {
+		return
+	}
+	if t.MetadataMs > 0 {
+		s.metadata = append(s.metadata, t.MetadataMs)
+	}
+	if t.MatchMs > 0 {
+		s.match = append(s.match, t.MatchMs)
+	}
+	if t.WriteMs > 0 {
+		s.write = append(s.write, t.WriteMs)
+	}
+}
+
+// stats returns the percentiles of the samples, or nil when there are none.
+func (s *timingSamples) stats() *TimingStats {
+	if len(s.metadata)+len(s.match)+len(s.write) == 0 {
+		return nil
+	}
+	return &TimingStats{
+		Metadata: stagePercentiles(s.metadata),
+		Match:    stagePercentiles(s.match),
+		Write:    stagePercentiles(s.write),
+	}
+}
+
+func stagePercentiles(ms []float64) *StagePercentiles {
+	if len(ms) == 0 {
+		return nil
+	}
+	sort.Float64s(ms)
+	p := &StagePercentiles{
+		Files: len(ms),
+		P50Ms: nearestRank(ms, 0.50),
+		P90Ms: nearestRank(ms, 0.90),
+		P99Ms: nearestRank(ms, 0.99),
+		MaxMs: ms[len(ms)-1],
+	}
+	for _, v := range ms {
+		p.TotalMs += v
+	}
+	p.TotalMs = math.Round(p.TotalMs*1e3) / 1e3
+	return p
+}
+
+// nearestRank returns the q-quantile of sorted values by the nearest-rank method,
+// which always picks a value that was measured.
+func nearestRank(sorted []float64, q float64) float64 {
+	i := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return sorted[max(0, min(i, len(sorted)-1))]
+}
+
+// String formats the percentiles for the end-of-run summary.
+func (s *TimingStats) String() string {
+	var parts []string
+	for _, stage := range []struct {
+		name string
+		p    *StagePercentiles
+	}{{StageMetadata, s.Metadata}, {StageMatch, s.Match}, {StageWrite, s.Write}} {
+		if stage.p != nil {
+			parts = append(parts, stage.name+" "+stage.p.String())
+		}
+	}
+	return "Timings per file: " + strings.Join(parts, "; ")
+}
+
+func (p *StagePercentiles) String() string {
+	return fmt.Sprintf("p50=%s p90=%s p99=%s max=%s (%s over %d files)",
+		msText(p.P50Ms), msText(p.P90Ms), msText(p.P99Ms), msText(p.MaxMs), msText(p.TotalMs), p.Files)
+}
+
+func msText(ms float64) string {
+	return time.Duration(ms * float64(time.Millisecond)).Round(time.Microsecond).String()
+}
+
+// reportTimings fills sum.Timings and prints and logs them.
+func reportTimings(opts Options, sum *Summary, logs runLog) {
+	sum.Timings = ComputeTimingStats(sum.Files)
+	logTimings(opts, sum, logs)
+}
+
+// logTimings prints and logs sum.Timings, if any.
+func logTimings(opts Options, sum *Summary, logs runLog) {
+	if sum.Timings == nil {
+		return
+	}
+	if opts.PrintSummary {
+		fmt.Println(sum.Timings)
+	}
+	logs.infof("%s", sum.Timings)
+}
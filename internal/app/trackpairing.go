@@ -0,0 +1,215 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+)
+
+// trackPairingMargin widens the span of each track when the camera time zone is
+// not known, since capture times are then wall-clock times up to 14 hours off UTC.
+const trackPairingMargin = 14 * time.Hour
+
+// TrackPairing proposes a track of a GPX library for a photo folder. A folder
+// no track covers has an empty Track.
+type TrackPairing struct {
+	Folder     string    `json:"folder"`
+	Track      string    `json:"track,omitempty"`
+	Photos     int       `json:"photos"`  // photos in the folder with a capture time
+	Covered    int       `json:"covered"` // of them, those taken within the track's span
+	PhotoStart time.Time `json:"photoStart"`
+	PhotoEnd   time.Time `json:"photoEnd"`
+	TrackStart time.Time `json:"trackStart"`
+	TrackEnd   time.Time `json:"trackEnd"`
+	Error      string    `json:"error,omitempty"` // why the folder's photos could not be read
+}
+
+// libraryTrack is a GPX file of a track library with its time span.
+type libraryTrack struct {
+	path       string
+	index      *gpx.TrackIndex
+	start, end time.Time
+}
+
+// PairTracks proposes, for each photo folder, the GPX files of library whose
+// time span covers some of its photos, most photos first. A folder spanning
+// several tracks, such as one GPX per day of a trip, is paired with each; runs
+// of its pairings in turn tag each photo from the track that covers it. opts
+// supplies Recursive, TimeZone (which may be auto), TimeOffset and NoTrackCache.
+// Without a time zone, capture times are matched within trackPairingMargin of a
+// track.
+func PairTracks(ctx context.Context, folders []string, library string, opts Options) ([]TrackPairing, error) {
+	tracks, err := loadTrackLibrary(ctx, library, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var pairings []TrackPairing
+	for _, folder := range folders {
+		folder = strings.TrimSpace(folder)
+		if folder == "" {
+			continue
+		}
+		folderOpts := opts
+		folderOpts.Inputs = []string{folder}
+		folderOpts.MaxFiles = 0
+		folderOpts.ScanProgress = nil
+		jobs, err := readPhotos(ctx, folderOpts)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err != nil {
+			pairings = append(pairings, TrackPairing{Folder: folder, Error: err.Error()})
+			continue
+		}
+		pairings = append(pairings, pairFolder(folder, jobs, tracks, opts)...)
+	}
+	return pairings, nil
+}
+
+// pairFolder returns the pairings of one folder's photos, most covered first, or
+// a pairing without a track when none covers them.
+func pairFolder(folder string, jobs []photoJob, tracks []libraryTrack, opts Options) []TrackPairing {
+	base := TrackPairing{Folder: folder, Photos: len(jobs)}
+	for _, job := range jobs {
+		t := job.Meta.CaptureTime
+		if base.PhotoStart.IsZero() || t.Before(base.PhotoStart) {
+			base.PhotoStart = t
+		}
+		if t.After(base.PhotoEnd) {
+			base.PhotoEnd = t
+		}
+	}
+
+	var pairings []TrackPairing
+	for _, track := range tracks {
+		loc, err := loadTimeZone(opts.TimeZone, track.index)
+		if err != nil {
+			continue
+		}
+		margin := time.Duration(0)
+		if loc == nil {
+			margin = trackPairingMargin
+		}
+		from, to := track.start.Add(-margin), track.end.Add(margin)
+		covered := 0
+		for _, job := range jobs {
+			t := job.Meta.CaptureTime
+			if loc != nil {
+				t = wallClockIn(t, loc)
+			}
+			t = t.Add(opts.TimeOffset)
+			if !t.Before(from) && !t.After(to) {
+				covered++
+			}
+		}
+		if covered == 0 {
+			continue
+		}
+		p := base
+		p.Track, p.Covered, p.TrackStart, p.TrackEnd = track.path, covered, track.start, track.end
+		pairings = append(pairings, p)
+	}
+	if len(pairings) == 0 {
+		return []TrackPairing{base}
+	}
+	sort.SliceStable(pairings, func(i, j int) bool {
+		if pairings[i].Covered != pairings[j].Covered {
+			return pairings[i].Covered > pairings[j].Covered
+		}
+		return pairings[i].TrackStart.Before(pairings[j].TrackStart)
+	})
+	return pairings
+}
+
+// loadTrackLibrary loads every GPX file (.gpx or .gpx.gz) under library, in the
+// track cache unless opts.NoTrackCache is set. Files that fail to load are left out.
+func loadTrackLibrary(ctx context.Context, library string, opts Options) ([]libraryTrack, error) {
+	library = strings.TrimSpace(library)
+	if library == "" {
+		return nil, fmt.Errorf("track library folder is required")
+	}
+	cacheDir := ""
+	if !opts.NoTrackCache {
+		cacheDir, _ = gpx.DefaultCacheDir()
+	}
+	var tracks []libraryTrack
+	failed := 0
+	err := filepath.WalkDir(library, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		name := strings.ToLower(d.Name())
+		if d.IsDir() || !(strings.HasSuffix(name, ".gpx") || strings.HasSuffix(name, ".gpx.gz")) {
+			return nil
+		}
+		index, _, err := gpx.LoadTrackCached(path, cacheDir)
+		if err != nil {
+			failed++
+			return nil
+		}
+		start, end := index.Bounds()
+		tracks = append(tracks, libraryTrack{path: path, index: index, start: start, end: end})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan track library: %w", err)
+	}
+	if len(tracks) == 0 {
+		if failed > 0 {
+			return nil, fmt.Errorf("none of the %d GPX files in %s could be loaded", failed, library)
+		}
+		return nil, fmt.Errorf("no GPX files found in %s", library)
+	}
+	return tracks, nil
+}
+
+// MergeSummaries combines the summaries of runs over overlapping inputs, such as
+// one folder tagged from several tracks in turn. A file keeps the result of the
+// run that geotagged it, otherwise that of the last run; the counts are
+// recomputed from the merged results.
+func MergeSummaries(sums []*Summary) *Summary {
+	index := make(map[string]int)
+	var files []FileResult
+	merged := &Summary{}
+	for _, s := range sums {
+		if s == nil {
+			continue
+		}
+		merged.Truncated = merged.Truncated || s.Truncated
+		for _, res := range s.Files {
+			i, ok := index[res.Path]
+			switch {
+			case !ok:
+				index[res.Path] = len(files)
+				files = append(files, res)
+			case files[i].Status != "processed":
+				files[i] = res
+			}
+		}
+	}
+	merged.Files = files
+	merged.Directories = SummarizeDirectories(files)
+	for _, d := range merged.Directories {
+		merged.Processed += d.Processed
+		merged.Skipped += d.Skipped
+		merged.Unchanged += d.Unchanged
+		merged.OutOfTrack += d.OutOfTrack
+		merged.Suspicious += d.Suspicious
+		merged.Failed += d.Failed
+		merged.MetaError += d.MetaError
+	}
+	merged.Transient = CountTransient(files)
+	merged.ReadOnly = CountReadOnly(files)
+	merged.Stats = ComputeTripStats(files)
+	return merged
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+	_ "github.com/nir0k/GeoRAW/internal/strava" // registers the strava: track provider
+	"github.com/nir0k/GeoRAW/internal/track"
+)
+
+// loadTrack resolves opts.GPXPath through the registered track providers, passing
+// the capture time range of jobs for providers that download data. The batches of
+// Watch reuse the track of their trackCache while it covers their frames.
+func loadTrack(ctx context.Context, opts Options, jobs []photoJob, logs runLog) (*gpx.TrackIndex, error) {
+	if c := opts.trackCache; c != nil {
+		return c.load(ctx, opts, jobs, logs)
+	}
+	return resolveTrack(ctx, opts, jobs, logs)
+}
+
+// resolveTrack loads the track of opts.GPXPath for jobs.
+func resolveTrack(ctx context.Context, opts Options, jobs []photoJob, logs runLog) (*gpx.TrackIndex, error) {
+	req := track.Request{URI: opts.GPXPath}
+	for _, job := range jobs {
+		ts := job.Meta.CaptureTime
+		if req.From.IsZero() || ts.Before(req.From) {
+			req.From = ts
+		}
+		if ts.After(req.To) {
+			req.To = ts
+		}
+	}
+	if !opts.NoTrackCache {
+		if dir, err := gpx.DefaultCacheDir(); err != nil {
+			logs.warnf("GPX cache disabled: %v", err)
+		} else {
+			req.CacheDir = dir
+		}
+	}
+
+	t, err := track.Load(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if opts.LinearGaps {
+		t.SetInterpolation(gpx.InterpolateLinear)
+	}
+	start, end := t.Bounds()
+	logs.infof("Track loaded from %s (%s provider) with %d points (%s .. %s)", opts.GPXPath, track.Scheme(opts.GPXPath), t.PointCount(), start.Format(time.RFC3339), end.Format(time.RFC3339))
+	if untimed := t.Untimed(); untimed.Total() > 0 {
+		logs.warnf("GPX track has %d points without timestamps: %d interpolated, %d skipped", untimed.Total(), untimed.Interpolated, untimed.Dropped)
+	}
+	return t, nil
+}
+
+// trackCache keeps the track between the batches of Watch, so providers that
+// download data, like Strava, are not asked again for every new photo.
+type trackCache struct {
+	refresh time.Duration // reload after this long even when the track covers every frame
+	track   *gpx.TrackIndex
+	loc     *time.Location // camera time zone for the track, nil when none is set
+	loaded  time.Time
+}
+
+// load returns the cached track, or resolves it again when it is older than
+// refresh or a frame of jobs falls past its end, as a live track may have
+// grown since.
+func (c *trackCache) load(ctx context.Context, opts Options, jobs []photoJob, logs runLog) (*gpx.TrackIndex, error) {
+	if c.track != nil && time.Since(c.loaded) < c.refresh && c.covers(opts, jobs) {
+		return c.track, nil
+	}
+	t, err := resolveTrack(ctx, opts, jobs, logs)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := loadTimeZone(opts.TimeZone, t)
+	if err != nil {
+		return nil, err
+	}
+	c.track, c.loc, c.loaded = t, loc, time.Now()
+	return t, nil
+}
+
+// covers reports whether the capture time of every job, with the time zone and
+// offset applied as the run will, is no later than the end of the track.
+func (c *trackCache) covers(opts Options, jobs []photoJob) bool {
+	_, end := c.track.Bounds()
+	for _, job := range jobs {
+		ts := job.Meta.CaptureTime
+		if c.loc != nil {
+			ts = wallClockIn(ts, c.loc)
+		}
+		offset := opts.TimeOffset
+		if override, _ := opts.Jobs.lookup(job.Path); override.Offset != nil {
+			offset = *override.Offset
+		}
+		if ts.Add(offset).After(end) {
+			return false
+		}
+	}
+	return true
+}
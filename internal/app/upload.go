@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"iter"
+	"path/filepath"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/share"
+)
+
+// UploadTarget shares the positions a run wrote: as a GPS trace on OpenStreetMap
+// or as a layer of a uMap map. Credentials come from the environment, see
+// share.EnvOSMToken and share.EnvUMapSession.
+type UploadTarget struct {
+	Spec       string // osm, osm:API_URL or umap:MAP_URL; nothing is uploaded when empty
+	Visibility string // visibility of an OSM trace: private (default), public, trackable or identifiable
+
+	target share.Target // parsed by load
+}
+
+// IsZero reports whether no upload is configured.
+func (u UploadTarget) IsZero() bool {
+	return strings.TrimSpace(u.Spec) == ""
+}
+
+// load parses the target.
+func (u *UploadTarget) load() error {
+	target, err := share.ParseTarget(u.Spec, u.Visibility)
+	if err != nil {
+		return err
+	}
+	u.target = target
+	return nil
+}
+
+// uploadPositions sends the written positions to opts.Upload when requested;
+// failures are logged, not fatal.
+func uploadPositions(ctx context.Context, opts Options, results iter.Seq[FileResult], logs runLog) {
+	target := opts.Upload.target
+	if target.IsZero() {
+		return
+	}
+	var points []share.Point
+	for res := range results {
+		if res.Point == nil || res.Status == "suspicious" {
+			continue
+		}
+		points = append(points, share.Point{
+			Name:      filepath.Base(res.Path),
+			Latitude:  res.Point.Latitude,
+			Longitude: res.Point.Longitude,
+			Altitude:  res.Point.Altitude,
+			Time:      res.Point.Time,
+		})
+	}
+	if len(points) == 0 {
+		logs.infof("No photo positions to upload to %s", target.Service)
+		return
+	}
+	where, err := share.Upload(ctx, target, "GeoRAW run "+opts.RunID, points)
+	if err != nil {
+		logs.errorf("Failed to upload photo positions: %v", err)
+		return
+	}
+	logs.infof("Uploaded %d photo positions to %s", len(points), where)
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/media"
+)
+
+// scanLogInterval is how often a long input walk is reported in the log.
+const scanLogInterval = 10 * time.Second
+
+// InputWalk is the input of a run as WalkInput walks it.
+type InputWalk struct {
+	Inputs       []string
+	Recursive    bool
+	MaxFiles     int                   // files after which the walk stops; 0 means no limit
+	ScanProgress func(dirs, files int) // optional, called as folders are read
+}
+
+// WalkInput calls fn for every file of the input, logging the progress of long
+// walks through infof and passing it to w.ScanProgress. It reports whether
+// w.MaxFiles cut the walk short, which is also logged through warnf.
+func WalkInput(w InputWalk, infof, warnf func(string, ...interface{}), fn func(path string) error) (bool, error) {
+	start := time.Now()
+	lastLog := start
+	walk := media.WalkOptions{
+		MaxFiles: w.MaxFiles,
+		Progress: func(p media.WalkProgress) {
+			if w.ScanProgress != nil {
+				w.ScanProgress(p.Dirs, p.Files)
+			}
+			switch {
+			case p.Done:
+				if time.Since(start) >= scanLogInterval {
+					infof("Scanned %d folders and found %d files in %s", p.Dirs, p.Files, time.Since(start).Round(time.Second))
+				}
+			case time.Since(lastLog) >= scanLogInterval:
+				lastLog = time.Now()
+				infof("Scanning input: %d folders, %d files found so far", p.Dirs, p.Files)
+			}
+		},
+	}
+	p, err := media.WalkFilesWith(w.Inputs, w.Recursive, walk, fn)
+	if p.Truncated {
+		warnf("Stopped scanning the input at the limit of %d files (--max-files); the remaining files were not processed", w.MaxFiles)
+	}
+	return p.Truncated, err
+}
+
+// walkInput is WalkInput over the input of opts, logging to logs.
+func walkInput(opts Options, logs runLog, fn func(path string) error) (bool, error) {
+	w := InputWalk{Inputs: opts.Inputs, Recursive: opts.Recursive, MaxFiles: opts.MaxFiles, ScanProgress: opts.ScanProgress}
+	return WalkInput(w, logs.infof, logs.warnf, fn)
+}
+
+// warnTruncated repeats after the summary that the input was cut short, so it is
+// not lost in a long log.
+func warnTruncated(opts Options, sum *Summary, logs runLog) {
+	if sum.Truncated {
+		logs.warnf("Only the first %d files of the input were processed; raise --max-files or narrow the input to process the rest", opts.MaxFiles)
+	}
+}
@@ -0,0 +1,259 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/errcode"
+	"github.com/nir0k/GeoRAW/internal/media"
+)
+
+// Defaults of WatchOptions.
+const (
+	DefaultWatchInterval = 2 * time.Second
+	DefaultWatchSettle   = time.Second
+	DefaultTrackWait     = 2 * time.Minute
+	DefaultTrackRefresh  = 5 * time.Minute
+)
+
+// watchRetryInterval is the least time between attempts at a frame that waits
+// for the track, as every attempt logs why it failed.
+const watchRetryInterval = 10 * time.Second
+
+// WatchOptions configure live geotagging of a capture folder.
+type WatchOptions struct {
+	Interval  time.Duration // how often the folder is polled; DefaultWatchInterval when zero
+	Settle    time.Duration // a new file is tagged once its size and time stayed the same this long, as tethering tools write in pieces; DefaultWatchSettle when zero
+	TrackWait time.Duration // how long a frame beyond the end of the track waits for a live track to catch up; DefaultTrackWait when zero
+	// TrackRefresh is how long the track is reused before it is loaded again;
+	// DefaultTrackRefresh when zero. A frame past its end reloads it at once.
+	TrackRefresh time.Duration
+	OnResult     func(FileResult)
+}
+
+// watchedFile is a new file of the capture folder that is not done yet.
+type watchedFile struct {
+	size     int64
+	mod      time.Time
+	changed  time.Time // when size or mod last changed
+	deadline time.Time // when the file is given up on; set by its first attempt
+	retryAt  time.Time // when to try again after an attempt left it waiting
+}
+
+// Watch geotags new photos as they arrive in opts.Inputs, e.g. the capture
+// folder of a tethering tool. The folder is polled every Interval; each batch of
+// files that settled is tagged like a normal run. The track is loaded once and
+// reused; it is loaded again after TrackRefresh, or as soon as a frame falls past
+// its end, so a live track that a logger keeps appending to stays current without
+// downloading from a provider for every batch. Frames beyond the end of the track
+// are retried until TrackWait has passed. Files already in
+// the folder when watching starts are left alone. Watch returns when ctx is done,
+// with the results of the files it handled, each of which was also passed to
+// OnResult as it was done.
+func Watch(ctx context.Context, opts Options, w WatchOptions, out io.Writer) (sum *Summary, err error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if !opts.Upload.IsZero() {
+		return nil, errors.New("uploading positions is not supported while watching")
+	}
+	if w.Interval <= 0 {
+		w.Interval = DefaultWatchInterval
+	}
+	if w.Settle <= 0 {
+		w.Settle = DefaultWatchSettle
+	}
+	if w.TrackWait <= 0 {
+		w.TrackWait = DefaultTrackWait
+	}
+	if w.TrackRefresh <= 0 {
+		w.TrackRefresh = DefaultTrackRefresh
+	}
+
+	logs, err := openRunLog(opts, out)
+	if err != nil {
+		return nil, err
+	}
+	started := time.Now()
+	defer func() { recordRun("watch", opts, started, sum, err, logs) }()
+
+	existing, err := listWatched(opts)
+	if err != nil {
+		return nil, err
+	}
+	logs.infof("Watching %s for new photos every %s (GPX=%s, %d files already there are left alone)", opts.inputText(), w.Interval, opts.GPXPath, len(existing))
+	if opts.AutoOffset && opts.TimeOffset == 0 {
+		logs.infof("Automatic offset detection is off while watching, since single frames cannot estimate it; set a time offset if the camera clock is off")
+	}
+
+	batch := opts
+	batch.AutoOffset = false
+	batch.PrintSummary = false
+	batch.ManifestPath, batch.GeoJSONPath = "", ""
+	batch.KeepResults = 0 // without a manifest, results beyond the limit would be lost
+	batch.Progress, batch.ScanProgress, batch.Confirm = nil, nil, nil
+	batch.watchBatch = true
+	batch.trackCache = &trackCache{refresh: w.TrackRefresh}
+
+	done := existing
+	pending := make(map[string]*watchedFile)
+	var results []FileResult
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return finishWatch(opts, results, pending, logs), nil
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		if err := pollWatched(opts, done, pending, now); err != nil {
+			logs.warnf("Failed to scan %s: %v", opts.inputText(), err)
+			continue
+		}
+		var ready []string
+		for path, f := range pending {
+			if now.Sub(f.changed) >= w.Settle && !now.Before(f.retryAt) {
+				ready = append(ready, path)
+			}
+		}
+		if len(ready) == 0 {
+			continue
+		}
+		sort.Strings(ready)
+		for _, path := range ready {
+			if f := pending[path]; f.deadline.IsZero() {
+				f.deadline = now.Add(w.TrackWait)
+			}
+		}
+
+		batch.Inputs = ready
+		batchSum, err := run(ctx, batch, out)
+		if ctx.Err() != nil {
+			return finishWatch(opts, results, pending, logs), nil
+		}
+		byPath := make(map[string]FileResult)
+		if err != nil {
+			logs.warnf("Tagging %d new photos failed, trying again: %v", len(ready), err)
+		} else {
+			for _, res := range batchSum.Files {
+				byPath[absPath(res.Path)] = res
+			}
+		}
+		for _, path := range ready {
+			f := pending[path]
+			res, ok := byPath[absPath(path)]
+			waiting := !ok || res.Status == "out_of_track" || res.Status == "meta_error"
+			if waiting && now.Before(f.deadline) {
+				// The live track may not have caught up yet, or the tethering tool
+				// was still writing the file.
+				f.retryAt = now.Add(min(max(w.Interval, watchRetryInterval), f.deadline.Sub(now)))
+				continue
+			}
+			delete(pending, path)
+			done[path] = true
+			if !ok {
+				if err == nil {
+					continue // passed over by the run, like a Live Photo video
+				}
+				res = FailedResult(path, "failed", err)
+				if errcode.Of(err) == errcode.NoRAWFiles {
+					// No file of the batch had readable metadata; report why.
+					if _, metaErr := media.ReadMetadata(path); metaErr != nil {
+						res = FailedResult(path, MetadataStatus(metaErr), metaErr)
+					}
+				}
+			}
+			results = append(results, res)
+			if w.OnResult != nil {
+				w.OnResult(res)
+			}
+		}
+	}
+}
+
+// listWatched returns the RAW files in the watched folder.
+func listWatched(opts Options) (map[string]bool, error) {
+	files := make(map[string]bool)
+	err := media.WalkFiles(opts.Inputs, opts.Recursive, func(path string) error {
+		if media.SupportedRaw(path) {
+			files[path] = true
+		}
+		return nil
+	})
+	return files, err
+}
+
+// pollWatched adds the RAW files that appeared since the last poll to pending and
+// notes which pending files are still being written.
+func pollWatched(opts Options, done map[string]bool, pending map[string]*watchedFile, now time.Time) error {
+	files, err := listWatched(opts)
+	if err != nil {
+		return err
+	}
+	for path := range files {
+		if done[path] {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		f, ok := pending[path]
+		if !ok {
+			pending[path] = &watchedFile{size: info.Size(), mod: info.ModTime(), changed: now}
+			continue
+		}
+		if f.size != info.Size() || !f.mod.Equal(info.ModTime()) {
+			f.size, f.mod, f.changed = info.Size(), info.ModTime(), now
+		}
+	}
+	for path := range pending {
+		if !files[path] {
+			delete(pending, path)
+		}
+	}
+	return nil
+}
+
+// finishWatch summarizes the files a watch handled and writes the manifest and
+// GeoJSON of the whole session.
+func finishWatch(opts Options, results []FileResult, pending map[string]*watchedFile, logs runLog) *Summary {
+	if len(pending) > 0 {
+		logs.warnf("Stopped watching with %d new photos not tagged yet", len(pending))
+	}
+	FillRelativePaths(results)
+	sum := &Summary{
+		RunID:       opts.RunID,
+		Transient:   CountTransient(results),
+		ReadOnly:    CountReadOnly(results),
+		Directories: SummarizeDirectories(results),
+		Files:       results,
+	}
+	for _, d := range sum.Directories {
+		sum.Processed += d.Processed
+		sum.Skipped += d.Skipped
+		sum.Unchanged += d.Unchanged
+		sum.OutOfTrack += d.OutOfTrack
+		sum.Suspicious += d.Suspicious
+		sum.Failed += d.Failed
+		sum.MetaError += d.MetaError
+	}
+	exportGeoJSON(opts, slices.Values(results), logs)
+	exportManifest(opts, sum, logs)
+	summary := fmt.Sprintf("Stopped watching. processed=%d skipped=%d unchanged=%d out_of_track=%d suspicious=%d failed=%d meta_errors=%d", sum.Processed, sum.Skipped, sum.Unchanged, sum.OutOfTrack, sum.Suspicious, sum.Failed, sum.MetaError)
+	if opts.PrintSummary {
+		fmt.Println(summary)
+	}
+	logs.infof("%s", summary)
+	reportTripStats(opts, sum, logs)
+	reportTimings(opts, sum, logs)
+	return sum
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+	"github.com/nir0k/GeoRAW/internal/track"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// WaypointKeywords adds the names of GPX waypoints near a photo's written
+// position as keywords of its sidecar, turning points of interest marked on the
+// logger (e.g. "Refuge du Goûter") into searchable tags.
+type WaypointKeywords struct {
+	Radius float64 // meters from a waypoint within which its name is added; 0 disables
+	Path   string  // GPX file with the waypoints; the track file when empty
+
+	waypoints []gpx.Waypoint // read by load
+}
+
+// IsZero reports whether waypoint keywords are off.
+func (w WaypointKeywords) IsZero() bool {
+	return w.Radius == 0
+}
+
+// load reads the named waypoints of Path, or of the track file trackPath.
+func (w *WaypointKeywords) load(trackPath string) error {
+	w.Path = strings.TrimSpace(w.Path)
+	if w.Radius < 0 {
+		return fmt.Errorf("waypoint radius must not be negative, got %g m", w.Radius)
+	}
+	if w.IsZero() {
+		if w.Path != "" {
+			return fmt.Errorf("waypoint file %s needs a waypoint radius", w.Path)
+		}
+		return nil
+	}
+	path := w.Path
+	if path == "" {
+		info, err := os.Stat(trackPath)
+		if track.Scheme(trackPath) != track.FileScheme || err != nil || info.IsDir() {
+			return fmt.Errorf("track %s is not a GPX file; name the GPX file with the waypoints", trackPath)
+		}
+		path = trackPath
+	}
+	waypoints, err := gpx.LoadWaypoints(path)
+	if err != nil {
+		return fmt.Errorf("read waypoints: %w", err)
+	}
+	if len(waypoints) == 0 {
+		return fmt.Errorf("%s has no named waypoints", path)
+	}
+	w.waypoints = waypoints
+	return nil
+}
+
+// applyWaypointKeywords adds the names of the waypoints near the written position
+// to the keywords of a sidecar the run processed. Failing to write them is logged
+// but does not fail the file.
+func applyWaypointKeywords(opts Options, task sidecarTask, logs runLog) {
+	w := opts.Waypoints
+	if len(w.waypoints) == 0 {
+		return
+	}
+	near := gpx.WaypointsNear(w.waypoints, task.Coord, w.Radius)
+	if len(near) == 0 {
+		return
+	}
+	names := make([]string, 0, len(near))
+	for _, wpt := range near {
+		logs.debugf("%s is %.0f m from waypoint %q", task.Job.Path, wpt.Distance, wpt.Name)
+		names = append(names, wpt.Name)
+	}
+	if _, err := xmp.MergeKeywords(task.Sidecar, names, false); err != nil && !errors.Is(err, xmp.ErrKeywordsAlreadyPresent) {
+		logs.warnf("Failed to add waypoint keywords to %s: %v", task.Sidecar, err)
+		return
+	}
+	logs.infof("Tagged %s with nearby waypoints: %s", task.Job.Path, strings.Join(names, ", "))
+}
@@ -0,0 +1,241 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/errcode"
+	"github.com/nir0k/GeoRAW/internal/fsretry"
+	"github.com/nir0k/GeoRAW/internal/gpx"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// sidecarTask is a resolved photo waiting for its sidecar to be written.
+type sidecarTask struct {
+	Job        photoJob
+	Capture    time.Time
+	Coord      gpx.Coordinate
+	Sidecar    string
+	Mirror     bool          // Coord comes from the file's embedded GPS rather than the track
+	Source     string        // photo, CSV row, shutter log line or job list entry the coordinate was copied from, if any
+	Confidence float64       // geotagConfidence of a track-matched position, 0 otherwise
+	Estimated  bool          // Coord was bridged across a track gap with the motion model
+	LiveStill  string        // sidecar of the Live Photo still whose keywords this video's sidecar copies
+	Manual     bool          // Coord was set by hand in the job list and replaces GPS the sidecar has
+	GPSSource  xmp.GPSSource // where Coord came from, recorded with it in the sidecar
+
+	ExistingDistance *float64 // meters from the GPS the sidecar already had to Coord, set by writeGPS
+}
+
+// taskResult records the result of a task that is not written.
+type taskResult func(task sidecarTask, res FileResult)
+
+// counters tracks per-status totals; it is safe for concurrent use.
+type counters struct {
+	processed  atomic.Int64
+	skipped    atomic.Int64
+	unchanged  atomic.Int64
+	outTrack   atomic.Int64
+	suspicious atomic.Int64
+	failed     atomic.Int64
+	metaError  atomic.Int64
+}
+
+// writeSidecars runs write for every task using up to workers goroutines.
+// Tasks sharing a sidecar path are handled in order by a single worker, so two
+// photos that map to the same sidecar never write it concurrently.
+func writeSidecars(ctx context.Context, tasks []sidecarTask, workers int, write func(sidecarTask)) error {
+	var order []string
+	byPath := make(map[string][]sidecarTask)
+	for _, task := range tasks {
+		if _, ok := byPath[task.Sidecar]; !ok {
+			order = append(order, task.Sidecar)
+		}
+		byPath[task.Sidecar] = append(byPath[task.Sidecar], task)
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(order) {
+		workers = len(order)
+	}
+
+	queue := make(chan []sidecarTask)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range queue {
+				for _, task := range batch {
+					if ctx.Err() != nil {
+						return
+					}
+					write(task)
+				}
+			}
+		}()
+	}
+
+	var err error
+feed:
+	for _, path := range order {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			break feed
+		case queue <- byPath[path]:
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if err == nil {
+		err = ctx.Err()
+	}
+	return err
+}
+
+// applyTask writes the GPS sidecar for one task, updates count, and returns the file result.
+func applyTask(ctx context.Context, task sidecarTask, opts Options, count *counters, logs runLog) FileResult {
+	logs = logs.file(StageWrite, task.Job.Path)
+	infof, errorf := logs.infof, logs.errorf
+	wrote, err := writeGPS(&task, opts)
+	if IsReadOnly(err) {
+		wrote, err = writeGPSReadOnly(&task, opts, err, logs)
+	}
+	job, capture, coord, sidecarPath := task.Job, task.Capture, task.Coord, task.Sidecar
+	if errors.Is(err, xmp.ErrGPSAlreadyPresent) {
+		message := "GPS already present"
+		var refused *scopeRefusal
+		if errors.As(err, &refused) {
+			infof("Skipping already geotagged sidecar %s: %s", sidecarPath, refused.reason)
+			message += " (" + refused.reason + ")"
+		} else {
+			infof("Skipping already geotagged sidecar %s%s (use --overwrite-gps to replace)", sidecarPath, distanceText(task.ExistingDistance))
+		}
+		count.unchanged.Add(1)
+		return FileResult{
+			Path:             job.Path,
+			Status:           "unchanged",
+			Message:          message,
+			ExistingDistance: task.ExistingDistance,
+		}
+	}
+	if err != nil {
+		if IsReadOnly(err) && opts.ReadOnly.IsZero() {
+			errorf("Failed to write sidecar for %s: %v (read-only; use --clear-readonly or --readonly-dir)", job.Path, err)
+		} else {
+			errorf("Failed to write sidecar for %s: %v", job.Path, err)
+		}
+		count.failed.Add(1)
+		return FailedResult(job.Path, "failed", SidecarError(job.Path, err))
+	}
+
+	verb := "Geotagged"
+	switch {
+	case task.Mirror:
+		verb = "Mirrored embedded GPS of"
+	case task.Source != "":
+		verb = "Copied GPS from " + task.Source + " to"
+	}
+	infof("%s %s (%s %s, %s) -> %s [lat=%.6f lon=%.6f alt=%v]%s",
+		verb,
+		job.Path,
+		job.Meta.CameraMake,
+		job.Meta.CameraModel,
+		capture.Format(time.RFC3339),
+		sidecarPath,
+		coord.Latitude,
+		coord.Longitude,
+		altText(coord.Altitude),
+		distanceText(task.ExistingDistance),
+	)
+	point := taskPoint(task)
+	if !wrote {
+		count.unchanged.Add(1)
+		return FileResult{
+			Path:       job.Path,
+			Status:     "unchanged",
+			Message:    "Sidecar existed",
+			Point:      point,
+			Confidence: task.Confidence,
+			Estimated:  task.Estimated,
+
+			ExistingDistance: task.ExistingDistance,
+		}
+	}
+	applyAttribution(opts, task, logs)
+	writeDroneHeading(task, logs)
+	preserveGPano(task, logs)
+	copyLivePhotoKeywords(task, logs)
+	applyDescription(ctx, opts, task, logs)
+	applyWaypointKeywords(opts, task, logs)
+	writeConfidence(opts, task, logs)
+	embedGPS(opts, task, logs)
+	stampRun(opts, sidecarPath, logs)
+	count.processed.Add(1)
+	return FileResult{
+		Path:       job.Path,
+		Status:     "processed",
+		Message:    sidecarPath,
+		Point:      point,
+		Confidence: task.Confidence,
+		Estimated:  task.Estimated,
+
+		ExistingDistance: task.ExistingDistance,
+	}
+}
+
+// taskPoint describes the position a task writes.
+func taskPoint(task sidecarTask) *GeoPoint {
+	return &GeoPoint{
+		Latitude:  task.Coord.Latitude,
+		Longitude: task.Coord.Longitude,
+		Altitude:  task.Coord.Altitude,
+		Time:      task.Capture,
+		Camera:    strings.TrimSpace(task.Job.Meta.CameraMake + " " + task.Job.Meta.CameraModel),
+	}
+}
+
+// FailedResult records a failure with the code of its error, flagging I/O errors
+// that stayed transient through every retry so they can be told apart from
+// permanent ones, and writes refused on read-only files.
+func FailedResult(path, status string, err error) FileResult {
+	res := FileResult{
+		Path:      path,
+		Status:    status,
+		Message:   err.Error(),
+		Transient: fsretry.Failed(err),
+		ReadOnly:  IsReadOnly(err),
+	}
+	if coded, ok := errcode.As(err); ok {
+		res.Code, res.Params = coded.Code, coded.Params
+	}
+	return res
+}
+
+// SidecarError gives a failed write of the sidecar of path its code.
+func SidecarError(path string, err error) error {
+	code := errcode.SidecarWrite
+	if IsReadOnly(err) {
+		code = errcode.SidecarReadOnly
+	}
+	return errcode.Wrap(err, code, errcode.Params{"path": path})
+}
+
+// CountTransient counts the results flagged by FailedResult as transient.
+func CountTransient(results []FileResult) int {
+	n := 0
+	for _, res := range results {
+		if res.Transient {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,247 @@
+// Package doctor runs the self-checks behind `georaw doctor`: the external tools,
+// folders and settings a run depends on, each with a suggested fix when it fails.
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/nir0k/GeoRAW/internal/logging"
+	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/nir0k/GeoRAW/internal/strava"
+	"github.com/nir0k/GeoRAW/internal/track"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// minExiftool is the oldest exiftool release the EXIF viewer is used with.
+const minExiftool = 12.0
+
+// Status is the outcome of a check.
+type Status int
+
+const (
+	OK Status = iota
+	Warn
+	Fail
+	Skipped
+)
+
+func (s Status) String() string {
+	switch s {
+	case OK:
+		return "ok"
+	case Warn:
+		return "warn"
+	case Fail:
+		return "fail"
+	default:
+		return "skip"
+	}
+}
+
+// Check is the result of one self-check.
+type Check struct {
+	Name   string
+	Status Status
+	Detail string
+	Fix    string // what to do about a warning or failure
+}
+
+// Run performs every check for the given options. The input folders and the track
+// are only checked when opts names them.
+func Run(ctx context.Context, opts app.Options) []Check {
+	return []Check{
+		checkExiftool(ctx),
+		checkSettings(opts),
+		checkLogFile(opts.LogFile),
+		checkTargets(opts.Inputs),
+		checkTrack(ctx, opts.GPXPath),
+	}
+}
+
+// Failed counts the checks that failed.
+func Failed(checks []Check) int {
+	n := 0
+	for _, c := range checks {
+		if c.Status == Fail {
+			n++
+		}
+	}
+	return n
+}
+
+func checkExiftool(ctx context.Context) Check {
+	c := Check{Name: "exiftool"}
+	exe, err := exec.LookPath("exiftool")
+	if err != nil {
+		c.Status = Warn
+		c.Detail = "not found in PATH; tagging works without it, but `georaw exif`, the GUI EXIF viewer, --embedded capture time fixes, --embed-gps and --embed-keywords do not"
+		if media.EmbeddedWriter() == "exiv2" {
+			c.Detail = "not found in PATH; tagging, --embedded capture time fixes, --embed-gps and --embed-keywords (built-in exiv2) work without it, but `georaw exif` and the GUI EXIF viewer do not"
+		}
+		c.Fix = "install exiftool (apt install libimage-exiftool-perl, brew install exiftool, choco install exiftool) and make sure it is in PATH"
+		return c
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, exe, "-ver").Output()
+	if err != nil {
+		c.Status = Fail
+		c.Detail = fmt.Sprintf("%s does not run: %v", exe, err)
+		c.Fix = "reinstall exiftool; on Windows rename exiftool(-k).exe to exiftool.exe"
+		return c
+	}
+	ver := strings.TrimSpace(string(out))
+	c.Detail = fmt.Sprintf("%s at %s", ver, exe)
+	if media.EmbeddedWriter() == "exiv2" {
+		c.Detail += "; --embedded, --embed-gps and --embed-keywords use the built-in exiv2 first"
+	}
+	if v, err := strconv.ParseFloat(ver, 64); err != nil || v < minExiftool {
+		c.Status = Warn
+		c.Fix = fmt.Sprintf("update exiftool to %.2f or newer", minExiftool)
+	}
+	return c
+}
+
+func checkSettings(opts app.Options) Check {
+	c := Check{Name: "settings"}
+	if err := opts.CheckSettings(); err != nil {
+		c.Status = Fail
+		c.Detail = err.Error()
+		c.Fix = "correct the flag named in the error"
+		return c
+	}
+	c.Detail = "flags are valid"
+	return c
+}
+
+// checkLogFile opens the log file for appending, removing it again if the check
+// created it.
+func checkLogFile(path string) Check {
+	c := Check{Name: "log file"}
+	settings, err := logging.SettingsFromEnv()
+	if err != nil {
+		c.Status = Fail
+		c.Detail = err.Error()
+		c.Fix = "correct or unset the variable"
+		return c
+	}
+	if settings.NoFile {
+		c.Detail = "disabled by " + logging.EnvNoFile + "; runs log to stderr"
+		return c
+	}
+	path = strings.TrimSpace(path)
+	if path == "" {
+		def, err := app.DefaultLogPath()
+		if err != nil {
+			c.Status = Fail
+			c.Detail = err.Error()
+			c.Fix = "pass --log-file with a writable path"
+			return c
+		}
+		path = def
+	}
+	_, statErr := os.Stat(path)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		c.Status = Fail
+		c.Detail = fmt.Sprintf("cannot write %s: %v", path, err)
+		c.Fix = "pass --log-file with a writable path, or run GeoRAW from a folder you can write to"
+		return c
+	}
+	f.Close()
+	if errors.Is(statErr, os.ErrNotExist) {
+		os.Remove(path)
+	}
+	c.Detail = path + " is writable"
+	return c
+}
+
+// checkTargets makes sure sidecars can be created where the inputs' sidecars go.
+func checkTargets(inputs []string) Check {
+	c := Check{Name: "target folder"}
+	if len(inputs) == 0 {
+		c.Status = Skipped
+		c.Detail = "no input given; pass -i to check it"
+		return c
+	}
+	root, err := media.InputRoot(inputs)
+	if err != nil {
+		c.Status = Fail
+		c.Detail = err.Error()
+		c.Fix = "check the -i value"
+		return c
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		c.Status = Fail
+		c.Detail = fmt.Sprintf("%s is not an existing folder", root)
+		c.Fix = "check the -i value; network shares must be mounted"
+		return c
+	}
+	dir := xmp.SidecarFolder(root)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		c.Status = Fail
+		c.Detail = fmt.Sprintf("cannot create %s: %v", dir, err)
+		c.Fix = "choose a writable --sidecar-dir"
+		return c
+	}
+	probe, err := os.CreateTemp(dir, ".georaw-doctor-*")
+	if err != nil {
+		c.Status = Fail
+		c.Detail = fmt.Sprintf("cannot write sidecars in %s: %v", dir, err)
+		c.Fix = "fix the folder permissions, or keep sidecars elsewhere with --sidecar-dir"
+		return c
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	files, err := media.CollectFiles(inputs, false)
+	if err != nil {
+		c.Status = Warn
+		c.Detail = fmt.Sprintf("%s is writable, but listing the input failed: %v", dir, err)
+		c.Fix = "check the -i value"
+		return c
+	}
+	c.Detail = fmt.Sprintf("%s is writable; %d files match the input (without recursing)", dir, len(files))
+	return c
+}
+
+// checkTrack loads the track, or for Strava only checks that credentials are set.
+func checkTrack(ctx context.Context, uri string) Check {
+	c := Check{Name: "track"}
+	if strings.TrimSpace(uri) == "" {
+		c.Status = Skipped
+		c.Detail = "no track given; pass --gpx to check it"
+		return c
+	}
+	if track.Scheme(uri) == strava.Scheme {
+		if err := strava.CredentialsFromEnv().Validate(); err != nil {
+			c.Status = Fail
+			c.Detail = err.Error()
+			c.Fix = fmt.Sprintf("set %s, %s and %s", strava.EnvClientID, strava.EnvClientSecret, strava.EnvRefreshToken)
+			return c
+		}
+		c.Detail = "Strava credentials are set (not contacted)"
+		return c
+	}
+	t, err := track.Load(ctx, track.Request{URI: uri})
+	if err != nil {
+		c.Status = Fail
+		c.Detail = err.Error()
+		c.Fix = "check that --gpx names a GPX/FIT file, archive or Garmin export folder with timed track points"
+		return c
+	}
+	start, end := t.Bounds()
+	c.Detail = fmt.Sprintf("%d points from %s to %s", t.PointCount(), start.Format(time.RFC3339), end.Format(time.RFC3339))
+	if untimed := t.Untimed(); untimed.Dropped > 0 {
+		c.Status = Warn
+		c.Fix = fmt.Sprintf("%d points without timestamps were skipped; re-export the track with times if photos fall in those gaps", untimed.Dropped)
+	}
+	return c
+}
@@ -0,0 +1,113 @@
+// Package errcode gives the errors a user can run into a stable code such as
+// GEORAW-E012 and the values they were raised with. Scripts branch on the code
+// instead of matching message text, and the GUI renders the message in the
+// user's language from the code and its parameters.
+package errcode
+
+import (
+	"errors"
+	"strings"
+)
+
+// Code identifies a kind of error. Codes are never reused or renumbered.
+type Code string
+
+// Run setup.
+const (
+	InputRequired Code = "GEORAW-E001" // no input path given
+	GPXRequired   Code = "GEORAW-E002" // no GPX path given
+	NoFiles       Code = "GEORAW-E003" // the input holds no files
+	NoRAWFiles    Code = "GEORAW-E004" // the input holds no readable RAW files
+	RetryNotFound Code = "GEORAW-E005" // none of the files to retry are in the input
+	Aborted       Code = "GEORAW-E006" // the user declined at the confirmation prompt
+)
+
+// Tracks and matching.
+const (
+	TrackEmpty    Code = "GEORAW-E010" // the GPX file has no track points
+	TrackUntimed  Code = "GEORAW-E011" // the track has no timestamped points
+	OutOfTrack    Code = "GEORAW-E012" // capture time outside GPX coverage
+	BetweenRoutes Code = "GEORAW-E013" // capture time in the gap between two recorded routes
+)
+
+// Photo metadata.
+const (
+	MetadataUnreadable Code = "GEORAW-E020" // the RAW could not be opened or decoded
+	NoCaptureTime      Code = "GEORAW-E021" // the RAW records no capture time
+	SuspectFile        Code = "GEORAW-E022" // skipped: the decoder crashed on the RAW in an earlier run
+)
+
+// Sidecars.
+const (
+	SidecarWrite    Code = "GEORAW-E030" // writing the sidecar failed
+	SidecarReadOnly Code = "GEORAW-E031" // the sidecar or its folder is read-only
+)
+
+// Params are the values an error was raised with, by the names its message
+// templates use. Wrap adds the cause as "cause".
+type Params map[string]string
+
+// Error is an error with a code. Its text is the English message of the code
+// with the parameters filled in, prefixed with the code.
+type Error struct {
+	Code   Code
+	Params Params
+	Err    error // cause, if any
+}
+
+// New returns an error of code.
+func New(code Code, params Params) *Error {
+	return &Error{Code: code, Params: params}
+}
+
+// Wrap returns an error of code caused by err, which errors.Is and errors.As see
+// through. The text of err is passed to the message as the "cause" parameter.
+func Wrap(err error, code Code, params Params) *Error {
+	all := Params{"cause": err.Error()}
+	for name, value := range params {
+		all[name] = value
+	}
+	return &Error{Code: code, Params: all, Err: err}
+}
+
+func (e *Error) Error() string {
+	return string(e.Code) + ": " + Format("", e.Code, e.Params)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// As returns the outermost coded error in err's chain.
+func As(err error) (*Error, bool) {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded, true
+	}
+	return nil, false
+}
+
+// Of returns the code of err, or "" when it has none.
+func Of(err error) Code {
+	if coded, ok := As(err); ok {
+		return coded.Code
+	}
+	return ""
+}
+
+// Format renders the message of code in lang with params. Placeholders without a
+// value are left as they are.
+func Format(lang string, code Code, params Params) string {
+	msg, ok := message(lang, code)
+	if !ok {
+		return string(code)
+	}
+	if len(params) == 0 {
+		return msg
+	}
+	pairs := make([]string, 0, 2*len(params))
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(msg)
+}
@@ -0,0 +1,90 @@
+package errcode
+
+import "strings"
+
+// english holds the message of every code. Placeholders in braces are filled
+// from the Params of the error.
+var english = map[Code]string{
+	InputRequired: "input path is required",
+	GPXRequired:   "GPX path is required",
+	NoFiles:       "no files found to process",
+	NoRAWFiles:    "no RAW files to process",
+	RetryNotFound: "none of the {count} files to retry from {manifest} were found in {input}",
+	Aborted:       "aborted at confirmation, nothing was written",
+
+	TrackEmpty:    "the GPX file contains no track points",
+	TrackUntimed:  "the track contains no timestamped points",
+	OutOfTrack:    "capture time {time} is outside the GPX coverage ({start} to {end})",
+	BetweenRoutes: "capture time {time} falls between two recorded routes",
+
+	MetadataUnreadable: "cannot read the metadata of {path}: {cause}",
+	NoCaptureTime:      "capture time not found in the metadata of {path}",
+	SuspectFile:        "skipped {path}: reading its metadata crashed the decoder on {since}; it is read again once the file changes, or with --force (Retry files that crashed the decoder before, in the GUI)",
+
+	SidecarWrite:    "cannot write the sidecar of {path}: {cause}",
+	SidecarReadOnly: "the sidecar of {path} or its folder is read-only: {cause}",
+}
+
+// german is the German translation.
+var german = map[Code]string{
+	InputRequired: "ein Eingabepfad ist erforderlich",
+	GPXRequired:   "ein GPX-Pfad ist erforderlich",
+	NoFiles:       "keine Dateien zum Verarbeiten gefunden",
+	NoRAWFiles:    "keine RAW-Dateien zum Verarbeiten",
+	RetryNotFound: "keine der {count} Dateien aus {manifest} für den erneuten Versuch wurde in {input} gefunden",
+	Aborted:       "bei der Bestätigung abgebrochen, nichts wurde geschrieben",
+
+	TrackEmpty:    "die GPX-Datei enthält keine Trackpunkte",
+	TrackUntimed:  "der Track enthält keine Punkte mit Zeitstempel",
+	OutOfTrack:    "Aufnahmezeit {time} liegt außerhalb des GPX-Tracks ({start} bis {end})",
+	BetweenRoutes: "Aufnahmezeit {time} liegt zwischen zwei aufgezeichneten Routen",
+
+	MetadataUnreadable: "die Metadaten von {path} können nicht gelesen werden: {cause}",
+	NoCaptureTime:      "keine Aufnahmezeit in den Metadaten von {path} gefunden",
+	SuspectFile:        "{path} übersprungen: das Lesen der Metadaten hat den Decoder am {since} abstürzen lassen; sie wird erneut gelesen, sobald sich die Datei ändert, oder mit --force (in der GUI: Retry files that crashed the decoder before)",
+
+	SidecarWrite:    "die Sidecar-Datei von {path} kann nicht geschrieben werden: {cause}",
+	SidecarReadOnly: "die Sidecar-Datei von {path} oder ihr Ordner ist schreibgeschützt: {cause}",
+}
+
+// catalogs are the translations by lower-case language tag; a translation may
+// leave codes out, which then keep their English message.
+var catalogs = map[string]map[Code]string{
+	"en": english,
+	"de": german,
+}
+
+// Messages returns the message of every code in lang, a language tag such as
+// "de" or "pt-BR" whose base language is used when the region has no catalog
+// of its own. Codes without a translation, and every code of an unknown or empty
+// lang, get their English message.
+func Messages(lang string) map[Code]string {
+	catalog := catalogOf(lang)
+	msgs := make(map[Code]string, len(english))
+	for code, msg := range english {
+		if translated, ok := catalog[code]; ok {
+			msg = translated
+		}
+		msgs[code] = msg
+	}
+	return msgs
+}
+
+// message returns the message of code in lang, falling back to English.
+func message(lang string, code Code) (string, bool) {
+	if msg, ok := catalogOf(lang)[code]; ok {
+		return msg, true
+	}
+	msg, ok := english[code]
+	return msg, ok
+}
+
+// catalogOf returns the translation for lang, or nil when there is none.
+func catalogOf(lang string) map[Code]string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if catalog, ok := catalogs[lang]; ok {
+		return catalog
+	}
+	base, _, _ := strings.Cut(lang, "-")
+	return catalogs[base]
+}
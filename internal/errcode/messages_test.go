@@ -0,0 +1,68 @@
+package errcode
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestFormatTranslates(t *testing.T) {
+	params := Params{"path": "a.cr3"}
+	want := "keine Aufnahmezeit in den Metadaten von a.cr3 gefunden"
+	for _, lang := range []string{"de", "DE", " de-AT "} {
+		if got := Format(lang, NoCaptureTime, params); got != want {
+			t.Errorf("Format(%q) = %q, want %q", lang, got, want)
+		}
+	}
+}
+
+func TestFormatFallsBackToEnglish(t *testing.T) {
+	params := Params{"path": "a.cr3"}
+	want := "capture time not found in the metadata of a.cr3"
+	for _, lang := range []string{"", "fr", "pt-BR", "zz-de"} {
+		if got := Format(lang, NoCaptureTime, params); got != want {
+			t.Errorf("Format(%q) = %q, want %q", lang, got, want)
+		}
+	}
+
+	// A catalog that leaves a code out keeps the English message for it.
+	catalogs["xx"] = map[Code]string{NoFiles: "translated"}
+	defer delete(catalogs, "xx")
+	if got := Format("xx", NoCaptureTime, params); got != want {
+		t.Errorf("Format(xx) of an untranslated code = %q, want %q", got, want)
+	}
+	msgs := Messages("xx-YY")
+	if msgs[NoFiles] != "translated" || msgs[NoCaptureTime] != english[NoCaptureTime] {
+		t.Errorf("Messages(xx-YY) = %q, %q", msgs[NoFiles], msgs[NoCaptureTime])
+	}
+	if len(msgs) != len(english) {
+		t.Errorf("Messages(xx-YY) has %d codes, want %d", len(msgs), len(english))
+	}
+
+	if got := Format("de", Code("GEORAW-E999"), nil); got != "GEORAW-E999" {
+		t.Errorf("Format of an unknown code = %q", got)
+	}
+}
+
+// TestCatalogsKeepPlaceholders guards translations against dropping or renaming
+// the parameters of a message.
+func TestCatalogsKeepPlaceholders(t *testing.T) {
+	placeholder := regexp.MustCompile(`\{[a-z]+\}`)
+	names := func(msg string) []string {
+		found := placeholder.FindAllString(msg, -1)
+		slices.Sort(found)
+		return found
+	}
+	for lang, catalog := range catalogs {
+		for code, msg := range catalog {
+			en, ok := english[code]
+			if !ok {
+				t.Errorf("%s: %s has no English message", lang, code)
+				continue
+			}
+			if got, want := names(msg), names(en); !slices.Equal(got, want) {
+				t.Errorf("%s: %s uses %v, English uses %v", lang, code, got, want)
+			}
+		}
+	}
+}
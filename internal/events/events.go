@@ -0,0 +1,113 @@
+// Package events carries the progress, input scan and log stream of a run to
+// whatever shows it — the GUI frontend, a terminal progress line, a server — so
+// the workflows report through one implementation regardless of the frontend.
+package events
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// Kind tells what an Event reports.
+type Kind string
+
+const (
+	KindProgress Kind = "progress" // Current of Total files processed
+	KindScan     Kind = "scan"     // Dirs and Files found while the input is walked
+	KindLog      Kind = "log"      // one log line in Line
+)
+
+// Event is one update of a run.
+type Event struct {
+	Kind    Kind
+	Context string // which workflow reports, e.g. "gps" or "series"
+	Current int
+	Total   int
+	Dirs    int
+	Files   int
+	Line    string
+}
+
+// Sink receives the events of a bus. Emit is called from the goroutines of the
+// run, one event at a time.
+type Sink interface {
+	Emit(Event)
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(Event)
+
+// Emit calls f.
+func (f SinkFunc) Emit(e Event) { f(e) }
+
+// Bus fans the events of one run out to its sinks. It is nil-safe: reporting to
+// a nil bus does nothing. Its methods match the Progress and ScanProgress
+// callbacks of the workflow options, and it is an io.Writer for their logs.
+type Bus struct {
+	context string
+
+	mu      sync.Mutex
+	sinks   []Sink
+	partial []byte // log output after the last newline
+}
+
+// NewBus returns a bus reporting as context to sinks; nil sinks are ignored.
+func NewBus(context string, sinks ...Sink) *Bus {
+	b := &Bus{context: context}
+	for _, s := range sinks {
+		if s != nil {
+			b.sinks = append(b.sinks, s)
+		}
+	}
+	return b
+}
+
+// Progress reports done of total files; a run without a known total is not
+// reported.
+func (b *Bus) Progress(done, total int) {
+	if b == nil || total <= 0 {
+		return
+	}
+	b.emit(Event{Kind: KindProgress, Current: done, Total: total})
+}
+
+// Scan reports the folders and files found while the input is still walked.
+func (b *Bus) Scan(dirs, files int) {
+	if b == nil {
+		return
+	}
+	b.emit(Event{Kind: KindScan, Dirs: dirs, Files: files})
+}
+
+// Write splits log output into lines and emits a log event for each complete one.
+func (b *Bus) Write(p []byte) (int, error) {
+	if b == nil {
+		return len(p), nil
+	}
+	b.mu.Lock()
+	b.partial = append(b.partial, p...)
+	var lines []string
+	for {
+		i := bytes.IndexByte(b.partial, '\n')
+		if i < 0 {
+			break
+		}
+		lines = append(lines, strings.TrimSuffix(string(b.partial[:i]), "\r"))
+		b.partial = b.partial[i+1:]
+	}
+	b.mu.Unlock()
+	for _, line := range lines {
+		b.emit(Event{Kind: KindLog, Line: line})
+	}
+	return len(p), nil
+}
+
+func (b *Bus) emit(e Event) {
+	e.Context = b.context
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, s := range b.sinks {
+		s.Emit(e)
+	}
+}
@@ -0,0 +1,346 @@
+package events
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logMemoryLimit is how much log text a Log keeps in memory; older lines are
+// moved to a spill file so a run over hundreds of thousands of files does not
+// grow the log without bound.
+const logMemoryLimit = 4 << 20
+
+// Levels of a Record.
+const (
+	LevelDebug   = "debug"
+	LevelInfo    = "info"
+	LevelWarning = "warning"
+	LevelError   = "error"
+)
+
+// levelRegex finds the level the logger writes into each line.
+var levelRegex = regexp.MustCompile(`\b(TRACE|DEBUG|INFO|WARN|WARNING|ERROR|FATAL)\b`)
+
+// Record is one log line with the level it names and the time it was logged.
+type Record struct {
+	Seq   int       `json:"seq"` // position in the log, from 0
+	Time  time.Time `json:"time"`
+	Level string    `json:"level"` // one of the Level constants, empty when the line names none
+	Line  string    `json:"line"`
+}
+
+// newRecord parses the level of line.
+func newRecord(seq int, at time.Time, line string) Record {
+	r := Record{Seq: seq, Time: at, Line: line}
+	switch levelRegex.FindString(line) {
+	case "TRACE", "DEBUG":
+		r.Level = LevelDebug
+	case "INFO":
+		r.Level = LevelInfo
+	case "WARN", "WARNING":
+		r.Level = LevelWarning
+	case "ERROR", "FATAL":
+		r.Level = LevelError
+	}
+	return r
+}
+
+// Query selects the records of a Log; zero fields do not filter.
+type Query struct {
+	Levels []string          // only records of these levels
+	Text   string            // only records containing this text, ignoring case
+	From   time.Time         // only records logged at or after From
+	To     time.Time         // only records logged before To
+	Match  func(string) bool // only lines Match accepts
+	Offset int               // skip this many matching records
+	Limit  int               // return at most this many; 0 returns all
+	Tail   bool              // count Offset back from the newest record
+}
+
+func (q Query) match(r Record, text string) bool {
+	if len(q.Levels) > 0 {
+		found := false
+		for _, level := range q.Levels {
+			found = found || strings.EqualFold(level, r.Level)
+		}
+		if !found {
+			return false
+		}
+	}
+	if !q.From.IsZero() && r.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !r.Time.Before(q.To) {
+		return false
+	}
+	if text != "" && !strings.Contains(strings.ToLower(r.Line), text) {
+		return false
+	}
+	return q.Match == nil || q.Match(r.Line)
+}
+
+// Page is the result of a Query.
+type Page struct {
+	Records []Record `json:"records"`
+	Offset  int      `json:"offset"`  // matching records before the first of Records
+	Total   int      `json:"total"`   // matching records, the ones before Offset and after Limit included
+	Lines   int      `json:"lines"`   // records in the log
+	Dropped int      `json:"dropped"` // oldest records lost because the spill file failed
+}
+
+// Log is a sink that keeps the log records of runs for querying or saving them
+// later. The newest records stay in memory, at most logMemoryLimit bytes of
+// text; older ones are spilled to a temporary file. It is safe for concurrent
+// use.
+type Log struct {
+	mu      sync.Mutex
+	records []Record // newest records, oldest first
+	size    int      // bytes of text in records, newlines included
+	seq     int      // Seq of the next record
+	spill   *os.File // older records as "UNIXNANO\tLINE"; nil until the memory limit is first reached
+	spilled int      // number of records in spill
+	dropped int      // records lost because the spill file failed
+	err     error    // first spill failure
+}
+
+// Emit appends a log line; other events are ignored.
+func (l *Log) Emit(e Event) {
+	if e.Kind != KindLog {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.add(time.Now(), e.Line)
+}
+
+// add appends a record logged at the given time; l.mu must be held.
+func (l *Log) add(at time.Time, line string) {
+	l.records = append(l.records, newRecord(l.seq, at, line))
+	l.seq++
+	l.size += len(line) + 1
+	if l.size > logMemoryLimit {
+		l.spillOldest()
+	}
+}
+
+// spillOldest moves the older half of the records in memory to the spill file.
+func (l *Log) spillOldest() {
+	keep := len(l.records)
+	for size := l.size; keep > 0 && size > logMemoryLimit/2; keep-- {
+		size -= len(l.records[len(l.records)-keep].Line) + 1
+	}
+	n := len(l.records) - keep
+	if l.spill == nil && l.err == nil {
+		l.spill, l.err = os.CreateTemp("", "georaw-log-*.log")
+	}
+	if l.err == nil {
+		w := bufio.NewWriter(l.spill)
+		for _, r := range l.records[:n] {
+			w.WriteString(strconv.FormatInt(r.Time.UnixNano(), 10))
+			w.WriteByte('\t')
+			w.WriteString(r.Line)
+			w.WriteByte('\n')
+		}
+		l.err = w.Flush()
+	}
+	for _, r := range l.records[:n] {
+		l.size -= len(r.Line) + 1
+	}
+	if l.err == nil {
+		l.spilled += n
+	} else {
+		// A failed spill file cannot be trusted, so what it held is lost too.
+		l.dropped += l.spilled + n
+		l.spilled = 0
+	}
+	l.records = append([]Record(nil), l.records[n:]...)
+}
+
+// readSpill calls fn for each spilled record, oldest first.
+func (l *Log) readSpill(fn func(Record) error) error {
+	if l.spill == nil || l.err != nil {
+		return nil
+	}
+	if _, err := l.spill.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("read spilled log: %w", err)
+	}
+	defer l.spill.Seek(0, io.SeekEnd)
+	scanner := bufio.NewScanner(l.spill)
+	scanner.Buffer(make([]byte, 64*1024), logMemoryLimit)
+	seq := 0
+	for scanner.Scan() {
+		stamp, line, _ := strings.Cut(scanner.Text(), "\t")
+		nanos, _ := strconv.ParseInt(stamp, 10, 64)
+		if err := fn(newRecord(seq, time.Unix(0, nanos), line)); err != nil {
+			return err
+		}
+		seq++
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read spilled log: %w", err)
+	}
+	return nil
+}
+
+// Query returns the page of records q selects, oldest first. Spilled records
+// are read back from disk, so every record logged since the last Reset can be
+// found.
+func (l *Log) Query(q Query) (Page, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	text := strings.ToLower(q.Text)
+	if q.Tail {
+		total, err := l.scan(q, text, 0, 0, nil)
+		if err != nil {
+			return Page{}, err
+		}
+		end := max(total-q.Offset, 0)
+		start := 0
+		if q.Limit > 0 {
+			start = max(end-q.Limit, 0)
+		}
+		if end == start {
+			return Page{Records: []Record{}, Offset: start, Total: total, Lines: l.spilled + len(l.records), Dropped: l.dropped}, nil
+		}
+		q.Offset, q.Limit = start, end-start
+	}
+	page := Page{Records: []Record{}, Offset: q.Offset, Lines: l.spilled + len(l.records), Dropped: l.dropped}
+	total, err := l.scan(q, text, q.Offset, q.Limit, &page.Records)
+	if err != nil {
+		return Page{}, err
+	}
+	page.Total = total
+	return page, nil
+}
+
+// scan counts the records q selects and appends the limit of them from offset
+// on to out when it is set.
+func (l *Log) scan(q Query, text string, offset, limit int, out *[]Record) (int, error) {
+	total := 0
+	add := func(r Record) error {
+		if !q.match(r, text) {
+			return nil
+		}
+		if out != nil && total >= offset && (limit <= 0 || len(*out) < limit) {
+			*out = append(*out, r)
+		}
+		total++
+		return nil
+	}
+	if err := l.readSpill(add); err != nil {
+		return 0, err
+	}
+	for _, r := range l.records {
+		add(r)
+	}
+	return total, nil
+}
+
+// String returns the records kept in memory, after a note on the ones spilled
+// to disk when there are any.
+func (l *Log) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var b strings.Builder
+	b.Grow(l.size + 128)
+	if l.spilled > 0 {
+		fmt.Fprintf(&b, "[%d earlier lines are in %s; save the log to get all of them]\n", l.spilled, l.spill.Name())
+	}
+	if l.dropped > 0 {
+		fmt.Fprintf(&b, "[%d earlier lines were dropped: %v]\n", l.dropped, l.err)
+	}
+	for _, r := range l.records {
+		b.WriteString(r.Line)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// WriteTo writes every line, the spilled ones included, to w.
+func (l *Log) WriteTo(w io.Writer) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	bw := bufio.NewWriter(w)
+	var total int64
+	write := func(r Record) error {
+		n, err := bw.WriteString(r.Line)
+		total += int64(n)
+		if err == nil {
+			err = bw.WriteByte('\n')
+			total++
+		}
+		return err
+	}
+	if err := l.readSpill(write); err != nil {
+		return total, err
+	}
+	for _, r := range l.records {
+		if err := write(r); err != nil {
+			return total, err
+		}
+	}
+	return total, bw.Flush()
+}
+
+// Save writes every record, the spilled ones included, with the time it was
+// logged, for LoadLog to read back.
+func (l *Log) Save(w io.Writer) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	bw := bufio.NewWriter(w)
+	write := func(r Record) error {
+		bw.WriteString(strconv.FormatInt(r.Time.UnixNano(), 10))
+		bw.WriteByte('\t')
+		bw.WriteString(r.Line)
+		return bw.WriteByte('\n')
+	}
+	if err := l.readSpill(write); err != nil {
+		return err
+	}
+	for _, r := range l.records {
+		if err := write(r); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// LoadLog reads a log written by Log.Save.
+func LoadLog(r io.Reader) (*Log, error) {
+	l := &Log{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), logMemoryLimit)
+	for scanner.Scan() {
+		stamp, line, ok := strings.Cut(scanner.Text(), "\t")
+		nanos, err := strconv.ParseInt(stamp, 10, 64)
+		if !ok || err != nil {
+			l.Reset()
+			return nil, fmt.Errorf("read saved log: malformed record %q", scanner.Text())
+		}
+		l.add(time.Unix(0, nanos), line) // l is not shared yet, so l.mu is not needed
+	}
+	if err := scanner.Err(); err != nil {
+		l.Reset()
+		return nil, fmt.Errorf("read saved log: %w", err)
+	}
+	return l, nil
+}
+
+// Reset drops every record and removes the spill file.
+func (l *Log) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records, l.size, l.seq, l.spilled, l.dropped, l.err = nil, 0, 0, 0, 0, nil
+	if l.spill != nil {
+		l.spill.Close()
+		os.Remove(l.spill.Name())
+		l.spill = nil
+	}
+}
@@ -0,0 +1,57 @@
+package events
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// terminalInterval limits how often the progress line is redrawn.
+const terminalInterval = 100 * time.Millisecond
+
+// Terminal is a sink that draws the progress of a run on one line of a terminal,
+// such as stderr, redrawn in place. Log lines are ignored.
+type Terminal struct {
+	w io.Writer
+
+	mu    sync.Mutex
+	last  time.Time
+	width int // length of the line drawn last, to blank what a shorter one leaves
+}
+
+// NewTerminal returns a sink drawing to w.
+func NewTerminal(w io.Writer) *Terminal {
+	return &Terminal{w: w}
+}
+
+// Emit redraws the progress line; the line is finished when every file is done.
+func (t *Terminal) Emit(e Event) {
+	var line string
+	done := false
+	switch e.Kind {
+	case KindScan:
+		line = fmt.Sprintf("%s: scanning, %d files in %d folders", e.Context, e.Files, e.Dirs)
+	case KindProgress:
+		line = fmt.Sprintf("%s: %d/%d files (%d%%)", e.Context, e.Current, e.Total, e.Current*100/e.Total)
+		done = e.Current >= e.Total
+	default:
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	if !done && now.Sub(t.last) < terminalInterval {
+		return
+	}
+	t.last = now
+	pad := max(t.width-len(line), 0)
+	t.width = len(line)
+	fmt.Fprint(t.w, "\r"+line+strings.Repeat(" ", pad))
+	if done {
+		fmt.Fprintln(t.w)
+		t.width = 0
+	}
+}
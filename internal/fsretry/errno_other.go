@@ -0,0 +1,22 @@
+//go:build !windows
+
+package fsretry
+
+import (
+	"errors"
+	"syscall"
+)
+
+func transientErrno(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	switch errno {
+	case syscall.EAGAIN, syscall.EINTR, syscall.EBUSY, syscall.EIO,
+		syscall.ETIMEDOUT, syscall.ESTALE, syscall.ECONNRESET, syscall.ECONNABORTED,
+		syscall.ENETDOWN, syscall.ENETUNREACH, syscall.EHOSTDOWN, syscall.EHOSTUNREACH:
+		return true
+	}
+	return false
+}
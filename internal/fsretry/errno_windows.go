@@ -0,0 +1,33 @@
+//go:build windows
+
+package fsretry
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Win32 error codes of busy files and interrupted network shares.
+const (
+	errorSharingViolation = 32
+	errorLockViolation    = 33
+	errorNetworkBusy      = 54
+	errorBadNetResp       = 58
+	errorUnexpNetErr      = 59
+	errorNetnameDeleted   = 64
+	errorSemTimeout       = 121
+	errorNetworkUnreach   = 1231
+)
+
+func transientErrno(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	switch errno {
+	case errorSharingViolation, errorLockViolation, errorNetworkBusy, errorBadNetResp,
+		errorUnexpNetErr, errorNetnameDeleted, errorSemTimeout, errorNetworkUnreach:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+// Package fsretry retries file operations that fail with transient errors, as
+// network file systems (SMB, NFS) produce when a share stalls for a moment.
+package fsretry
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// Policy controls how often and how patiently an operation is retried.
+type Policy struct {
+	Attempts int           // total tries; 1 or less disables retrying
+	Delay    time.Duration // wait before the first retry, doubled for each further one
+}
+
+// DefaultPolicy is used until SetPolicy is called.
+var DefaultPolicy = Policy{Attempts: 3, Delay: 200 * time.Millisecond}
+
+var (
+	mu     sync.RWMutex
+	policy = DefaultPolicy
+)
+
+// SetPolicy replaces the process-wide retry policy.
+func SetPolicy(p Policy) {
+	mu.Lock()
+	policy = p
+	mu.Unlock()
+}
+
+// Do runs op until it succeeds, fails with a permanent error, or the attempts of the
+// policy are used up. An error that stayed transient is wrapped in *TransientError.
+func Do(op func() error) error {
+	mu.RLock()
+	p := policy
+	mu.RUnlock()
+
+	delay := p.Delay
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || !IsTransient(err) {
+			return err
+		}
+		if attempt >= p.Attempts {
+			return &TransientError{Attempts: attempt, Err: err}
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// ReadFile is os.ReadFile with retries.
+func ReadFile(path string) ([]byte, error) {
+	var data []byte
+	err := Do(func() error {
+		var err error
+		data, err = os.ReadFile(path)
+		return err
+	})
+	return data, err
+}
+
+// WriteFile is os.WriteFile with retries.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	return Do(func() error {
+		return os.WriteFile(path, data, perm)
+	})
+}
+
+// Open is os.Open with retries.
+func Open(path string) (*os.File, error) {
+	var file *os.File
+	err := Do(func() error {
+		var err error
+		file, err = os.Open(path)
+		return err
+	})
+	return file, err
+}
+
+// TransientError is an operation that still failed with a transient error after
+// all attempts; retrying later may succeed.
+type TransientError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *TransientError) Error() string { return e.Err.Error() }
+func (e *TransientError) Unwrap() error { return e.Err }
+
+// IsTransient reports whether err is a temporary failure worth retrying: timeouts,
+// busy or locked files, and dropped network connections.
+func IsTransient(err error) bool {
+	var timeout interface{ Timeout() bool }
+	if errors.As(err, &timeout) && timeout.Timeout() {
+		return true
+	}
+	return transientErrno(err)
+}
+
+// Failed reports whether err is, or wraps, a *TransientError.
+func Failed(err error) bool {
+	var te *TransientError
+	return errors.As(err, &te)
+}
@@ -0,0 +1,182 @@
+// Package garmin reads activity tracks from a Garmin Connect bulk export (the
+// "Export Your Data" archive, extracted): FIT and GPX files, loose or inside the
+// UploadedFiles zip archives, selected by the activity summaries in its JSON files.
+package garmin
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+)
+
+// activityIDRegex finds the activity ID in uploaded file names such as
+// "jane@example.com_12345678901.fit" or "12345678901_ACTIVITY.fit".
+var activityIDRegex = regexp.MustCompile(`\d{6,}`)
+
+// summary is one activity of a summarizedActivities JSON file.
+type summary struct {
+	ID       int64   `json:"activityId"`
+	StartGMT float64 `json:"startTimeGmt"` // milliseconds since the Unix epoch
+	Duration float64 `json:"duration"`     // milliseconds
+}
+
+// activityFile is a track file found in the export, loose or inside a zip.
+type activityFile struct {
+	name string
+	open func() (io.ReadCloser, error)
+}
+
+// LoadExport builds a track from the activities in dir that overlap [from, to].
+// Files whose activity is listed in the export's summaries are selected by those
+// summaries without being read; other files are decoded and kept when their points
+// overlap the range. A zero range keeps every activity. Each activity stays a
+// separate piece of the track.
+func LoadExport(ctx context.Context, dir string, from, to time.Time) (*gpx.TrackIndex, error) {
+	if from.IsZero() && to.IsZero() {
+		from, to = time.Unix(0, 0), time.Now().Add(24*time.Hour)
+	}
+	var files []activityFile
+	var closers []io.Closer
+	defer func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}()
+	summaries := make(map[int64]summary)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		name := strings.ToLower(d.Name())
+		switch {
+		case strings.HasSuffix(name, "summarizedactivities.json"):
+			if err := readSummaries(path, summaries); err != nil {
+				return err
+			}
+		case strings.HasSuffix(name, ".fit"), strings.HasSuffix(name, ".gpx"):
+			files = append(files, activityFile{name: d.Name(), open: func() (io.ReadCloser, error) { return os.Open(path) }})
+		case strings.HasSuffix(name, ".zip"):
+			zr, err := zip.OpenReader(path)
+			if err != nil {
+				return fmt.Errorf("open %s: %w", path, err)
+			}
+			closers = append(closers, zr)
+			for _, f := range zr.File {
+				entry := strings.ToLower(f.Name)
+				if strings.HasSuffix(entry, ".fit") || strings.HasSuffix(entry, ".gpx") {
+					files = append(files, activityFile{name: filepath.Base(f.Name), open: f.Open})
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no FIT or GPX activity files found in %s", dir)
+	}
+
+	var pieces [][]gpx.Point
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if id, ok := fileActivityID(f.name); ok {
+			if s, known := summaries[id]; known && !s.overlaps(from, to) {
+				continue
+			}
+		}
+		points, err := readActivity(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.name, err)
+		}
+		if len(points) == 0 || points[len(points)-1].Time.Before(from) || points[0].Time.After(to) {
+			continue
+		}
+		pieces = append(pieces, points)
+	}
+	if len(pieces) == 0 {
+		return nil, fmt.Errorf("no Garmin activities with GPS between %s and %s", from.Format(time.DateOnly), to.Format(time.DateOnly))
+	}
+	return gpx.NewTrack(pieces)
+}
+
+func (s summary) overlaps(from, to time.Time) bool {
+	start := time.UnixMilli(int64(s.StartGMT))
+	end := start.Add(time.Duration(s.Duration) * time.Millisecond)
+	return !end.Before(from) && !start.After(to)
+}
+
+// readSummaries adds the activities of a summarizedActivities file to out. The file
+// is a list of objects holding a summarizedActivitiesExport array.
+func readSummaries(path string, out map[int64]summary) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	var doc []struct {
+		Activities []summary `json:"summarizedActivitiesExport"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	for _, part := range doc {
+		for _, s := range part.Activities {
+			if s.ID != 0 && s.StartGMT > 0 {
+				out[s.ID] = s
+			}
+		}
+	}
+	return nil
+}
+
+func fileActivityID(name string) (int64, bool) {
+	var id int64
+	m := activityIDRegex.FindString(name)
+	if m == "" {
+		return 0, false
+	}
+	if _, err := fmt.Sscan(m, &id); err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// readActivity decodes one FIT or GPX file, with its points in time order.
+func readActivity(f activityFile) ([]gpx.Point, error) {
+	rc, err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(f.name), ".gpx") {
+		return gpx.ParsePoints(data)
+	}
+	points, err := decodeFIT(data)
+	if err != nil && len(points) == 0 {
+		return nil, err
+	}
+	return points, nil
+}
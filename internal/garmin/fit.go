@@ -0,0 +1,218 @@
+package garmin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+)
+
+// FIT constants used to pull positions out of activity files. Only record
+// messages are interpreted; everything else is skipped by size.
+const (
+	fitRecordMessage   = 20
+	fitFieldTimestamp  = 253
+	fitFieldLatitude   = 0
+	fitFieldLongitude  = 1
+	fitFieldAltitude   = 2
+	fitFieldEnhancedAl = 78
+)
+
+// fitEpoch is the FIT time origin, 1989-12-31T00:00:00Z.
+var fitEpoch = time.Date(1989, 12, 31, 0, 0, 0, 0, time.UTC)
+
+var errNotFIT = errors.New("not a FIT file")
+
+type fitField struct {
+	num  byte
+	size byte
+}
+
+type fitDefinition struct {
+	global    uint16
+	order     binary.ByteOrder
+	fields    []fitField
+	devLength int
+}
+
+// decodeFIT returns the timed positions of the record messages in a FIT file.
+// Chained files (several FIT files back to back) are read in order.
+func decodeFIT(data []byte) ([]gpx.Point, error) {
+	var points []gpx.Point
+	for len(data) > 0 {
+		if len(data) < 12 {
+			if len(points) > 0 {
+				break
+			}
+			return nil, errNotFIT
+		}
+		headerSize := int(data[0])
+		if headerSize < 12 || len(data) < headerSize || !bytes.Equal(data[8:12], []byte(".FIT")) {
+			if len(points) > 0 {
+				break
+			}
+			return nil, errNotFIT
+		}
+		dataSize := int(binary.LittleEndian.Uint32(data[4:8]))
+		end := headerSize + dataSize
+		if end > len(data) {
+			end = len(data) // truncated file: read what is there
+		}
+		pts, err := decodeFITRecords(data[headerSize:end])
+		points = append(points, pts...)
+		if err != nil {
+			return points, err
+		}
+		data = data[min(end+2, len(data)):] // skip the file CRC
+	}
+	return points, nil
+}
+
+func decodeFITRecords(data []byte) ([]gpx.Point, error) {
+	var (
+		points []gpx.Point
+		defs   [16]*fitDefinition
+		lastTS uint32
+	)
+	for pos := 0; pos < len(data); {
+		header := data[pos]
+		pos++
+
+		local := header & 0x0F
+		compressedTS := header&0x80 != 0
+		if compressedTS {
+			local = (header >> 5) & 0x03
+		} else if header&0x40 != 0 {
+			def, n, err := readFITDefinition(data[pos:], header&0x20 != 0)
+			if err != nil {
+				return points, err
+			}
+			defs[local] = def
+			pos += n
+			continue
+		}
+
+		def := defs[local]
+		if def == nil {
+			return points, fmt.Errorf("fit: data message without definition at byte %d", pos-1)
+		}
+		size := def.devLength
+		for _, f := range def.fields {
+			size += int(f.size)
+		}
+		if pos+size > len(data) {
+			return points, fmt.Errorf("fit: truncated message at byte %d", pos-1)
+		}
+		msg := data[pos : pos+size]
+		pos += size
+
+		var (
+			ts           uint32
+			hasTS        bool
+			lat, lon     int32
+			hasPos       bool
+			alt          float64
+			hasAlt       bool
+			offset       int
+			latOK, lonOK bool
+		)
+		if compressedTS {
+			delta := uint32(header & 0x1F)
+			ts = lastTS&^0x1F | delta
+			if delta < lastTS&0x1F {
+				ts += 0x20
+			}
+			hasTS = true
+		}
+		for _, f := range def.fields {
+			raw := msg[offset : offset+int(f.size)]
+			offset += int(f.size)
+			switch {
+			case f.num == fitFieldTimestamp && f.size == 4:
+				if v := def.order.Uint32(raw); v != math.MaxUint32 {
+					ts, hasTS = v, true
+				}
+			case def.global != fitRecordMessage:
+			case f.num == fitFieldLatitude && f.size == 4:
+				lat = int32(def.order.Uint32(raw))
+				latOK = lat != math.MaxInt32
+			case f.num == fitFieldLongitude && f.size == 4:
+				lon = int32(def.order.Uint32(raw))
+				lonOK = lon != math.MaxInt32
+			case f.num == fitFieldAltitude && f.size == 2 && !hasAlt:
+				if v := def.order.Uint16(raw); v != math.MaxUint16 {
+					alt, hasAlt = float64(v)/5-500, true
+				}
+			case f.num == fitFieldEnhancedAl && f.size == 4:
+				if v := def.order.Uint32(raw); v != math.MaxUint32 {
+					alt, hasAlt = float64(v)/5-500, true
+				}
+			}
+		}
+		hasPos = latOK && lonOK
+		if hasTS {
+			lastTS = ts
+		}
+		if def.global != fitRecordMessage || !hasTS || !hasPos {
+			continue
+		}
+		pt := gpx.Point{
+			Time: fitEpoch.Add(time.Duration(ts) * time.Second),
+			Coord: gpx.Coordinate{
+				Latitude:  semicircles(lat),
+				Longitude: semicircles(lon),
+			},
+		}
+		if hasAlt {
+			v := alt
+			pt.Coord.Altitude = &v
+		}
+		points = append(points, pt)
+	}
+	return points, nil
+}
+
+// readFITDefinition parses a definition message and returns it with its length.
+func readFITDefinition(data []byte, devFields bool) (*fitDefinition, int, error) {
+	if len(data) < 5 {
+		return nil, 0, fmt.Errorf("fit: truncated definition")
+	}
+	def := &fitDefinition{order: binary.LittleEndian}
+	if data[1] == 1 {
+		def.order = binary.BigEndian
+	}
+	def.global = def.order.Uint16(data[2:4])
+	count := int(data[4])
+	n := 5 + count*3
+	if len(data) < n {
+		return nil, 0, fmt.Errorf("fit: truncated definition")
+	}
+	for i := 0; i < count; i++ {
+		f := data[5+i*3:]
+		def.fields = append(def.fields, fitField{num: f[0], size: f[1]})
+	}
+	if devFields {
+		if len(data) < n+1 {
+			return nil, 0, fmt.Errorf("fit: truncated definition")
+		}
+		devCount := int(data[n])
+		n++
+		if len(data) < n+devCount*3 {
+			return nil, 0, fmt.Errorf("fit: truncated definition")
+		}
+		for i := 0; i < devCount; i++ {
+			def.devLength += int(data[n+i*3+1])
+		}
+		n += devCount * 3
+	}
+	return def, n, nil
+}
+
+// semicircles converts a FIT angle to degrees.
+func semicircles(v int32) float64 {
+	return float64(v) * 180 / (1 << 31)
+}
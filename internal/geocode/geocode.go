@@ -0,0 +1,223 @@
+// Package geocode resolves coordinates to place names with the OpenStreetMap
+// Nominatim service and renders small maps from OpenStreetMap tiles or another
+// XYZ tile server. Answers and tiles are cached on disk, so a position is only
+// looked up once and maps of visited areas also render offline.
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/version"
+)
+
+const (
+	reverseURL     = "https://nominatim.openstreetmap.org/reverse"
+	requestTimeout = 15 * time.Second
+	// requestInterval keeps to the Nominatim usage policy of one request per second.
+	requestInterval = time.Second
+	// cachePrecision rounds coordinates for the cache key: 4 decimals are about 11 m.
+	cachePrecision = 4
+	placesFile     = "places.json"
+)
+
+// Attribution must be shown next to place names and maps from this package.
+const Attribution = "© OpenStreetMap contributors"
+
+// Place is the address of a position, from the most to the least specific part.
+type Place struct {
+	Name        string `json:"name"` // short display name, e.g. "Old Town, Prague, Czechia"
+	Locality    string `json:"locality,omitempty"`
+	Region      string `json:"region,omitempty"`
+	Country     string `json:"country,omitempty"`
+	CountryCode string `json:"countryCode,omitempty"`
+}
+
+// DefaultCacheDir returns the per-user folder used for cached places and tiles.
+func DefaultCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve cache dir: %w", err)
+	}
+	return filepath.Join(base, "georaw", "geocode"), nil
+}
+
+// Resolver looks up places and map tiles, sharing one cache folder. It is safe
+// for concurrent use.
+type Resolver struct {
+	cacheDir string
+	http     *http.Client
+
+	mu     sync.Mutex
+	places map[string]Place // nil until the cache file was read
+	last   time.Time        // time of the last Nominatim request
+	tiles  TileSettings
+}
+
+// NewResolver returns a resolver caching in cacheDir, with the tile settings
+// saved there; an empty cacheDir disables the cache.
+func NewResolver(cacheDir string) *Resolver {
+	return &Resolver{cacheDir: cacheDir, http: &http.Client{Timeout: requestTimeout}, tiles: loadTileSettings(cacheDir)}
+}
+
+// Reverse returns the place at lat/lon.
+func (r *Resolver) Reverse(ctx context.Context, lat, lon float64) (Place, error) {
+	key := fmt.Sprintf("%.*f,%.*f", cachePrecision, lat, cachePrecision, lon)
+	r.mu.Lock()
+	r.loadPlaces()
+	place, ok := r.places[key]
+	r.mu.Unlock()
+	if ok {
+		return place, nil
+	}
+
+	q := url.Values{
+		"format":         {"jsonv2"},
+		"lat":            {fmt.Sprintf("%.6f", lat)},
+		"lon":            {fmt.Sprintf("%.6f", lon)},
+		"zoom":           {"16"},
+		"addressdetails": {"1"},
+	}
+	var resp struct {
+		Error   string            `json:"error"`
+		Name    string            `json:"name"`
+		Display string            `json:"display_name"`
+		Address map[string]string `json:"address"`
+	}
+	if err := r.throttle(ctx); err != nil {
+		return Place{}, err
+	}
+	body, err := r.get(ctx, reverseURL+"?"+q.Encode())
+	if err != nil {
+		return Place{}, fmt.Errorf("reverse geocode: %w", err)
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Place{}, fmt.Errorf("reverse geocode: decode response: %w", err)
+	}
+	if resp.Error != "" {
+		// Nominatim answers positions at sea or outside any area this way.
+		place = Place{}
+	} else {
+		place = placeFromAddress(resp.Name, resp.Display, resp.Address)
+	}
+
+	r.mu.Lock()
+	r.places[key] = place
+	r.savePlaces()
+	r.mu.Unlock()
+	return place, nil
+}
+
+// placeFromAddress builds a short name from the address parts: the named feature
+// or neighbourhood, the locality, and the country.
+func placeFromAddress(name, display string, addr map[string]string) Place {
+	p := Place{
+		Locality:    first(addr, "city", "town", "village", "hamlet", "municipality"),
+		Region:      first(addr, "state", "region", "county"),
+		Country:     addr["country"],
+		CountryCode: strings.ToUpper(addr["country_code"]),
+	}
+	local := name
+	if local == "" {
+		local = first(addr, "suburb", "neighbourhood", "quarter", "city_district")
+	}
+	var parts []string
+	for _, part := range []string{local, p.Locality, p.Country} {
+		if part != "" && (len(parts) == 0 || parts[len(parts)-1] != part) {
+			parts = append(parts, part)
+		}
+	}
+	p.Name = strings.Join(parts, ", ")
+	if p.Name == "" {
+		p.Name = display
+	}
+	return p
+}
+
+func first(addr map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if v := addr[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// throttle waits as needed to keep Nominatim requests to one per requestInterval.
+func (r *Resolver) throttle(ctx context.Context) error {
+	r.mu.Lock()
+	wait := time.Until(r.last.Add(requestInterval))
+	r.last = time.Now().Add(max(wait, 0))
+	r.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// get fetches a URL identifying GeoRAW, as the OpenStreetMap usage policies ask.
+func (r *Resolver) get(ctx context.Context, target string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "GeoRAW/"+version.Version+" (+https://github.com/nir0k/GeoRAW)")
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+}
+
+// loadPlaces reads the place cache on first use. Callers hold r.mu.
+func (r *Resolver) loadPlaces() {
+	if r.places != nil {
+		return
+	}
+	r.places = make(map[string]Place)
+	if r.cacheDir == "" {
+		return
+	}
+	if data, err := os.ReadFile(filepath.Join(r.cacheDir, placesFile)); err == nil {
+		_ = json.Unmarshal(data, &r.places)
+	}
+}
+
+// savePlaces writes the place cache; failures only cost a later lookup. Callers
+// hold r.mu.
+func (r *Resolver) savePlaces() {
+	if r.cacheDir == "" {
+		return
+	}
+	data, err := json.Marshal(r.places)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(r.cacheDir, 0o755); err != nil {
+		return
+	}
+	path := filepath.Join(r.cacheDir, placesFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, path)
+}
@@ -0,0 +1,137 @@
+package geocode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"time"
+)
+
+const (
+	tileSize = 256
+	// tileMaxAge is how long a cached tile is used before it is downloaded again.
+	tileMaxAge = 30 * 24 * time.Hour
+	// MaxZoom is the most detailed zoom level of the OpenStreetMap tiles.
+	MaxZoom = 19
+)
+
+// StaticMap renders a width×height PNG of the map tiles around lat/lon at zoom,
+// with a marker at the position. Offline, tiles missing from the cache are left
+// blank; a map without any cached tile is an error.
+func (r *Resolver) StaticMap(ctx context.Context, lat, lon float64, width, height, zoom int) ([]byte, error) {
+	return r.render(ctx, lat, lon, width, height, zoom, true)
+}
+
+// BaseMap renders the map of StaticMap without the marker, for overlays placed
+// with Project.
+func (r *Resolver) BaseMap(ctx context.Context, lat, lon float64, width, height, zoom int) ([]byte, error) {
+	return r.render(ctx, lat, lon, width, height, zoom, false)
+}
+
+func (r *Resolver) render(ctx context.Context, lat, lon float64, width, height, zoom int, marker bool) ([]byte, error) {
+	if width <= 0 || height <= 0 || width > 4*tileSize || height > 4*tileSize {
+		return nil, fmt.Errorf("map size %dx%d out of range", width, height)
+	}
+	zoom = min(max(zoom, 0), r.MapMaxZoom())
+	n := 1 << zoom
+	cx, cy := Project(lat, lon, zoom)
+	left, top := int(math.Floor(cx))-width/2, int(math.Floor(cy))-height/2
+
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.RGBA{0xe5, 0xe3, 0xdf, 0xff}), image.Point{}, draw.Src)
+	drawn, missing := 0, error(nil)
+	for ty := floorDiv(top, tileSize); ty <= floorDiv(top+height-1, tileSize); ty++ {
+		if ty < 0 || ty >= n {
+			continue
+		}
+		for tx := floorDiv(left, tileSize); tx <= floorDiv(left+width-1, tileSize); tx++ {
+			tile, err := r.tile(ctx, zoom, ((tx%n)+n)%n, ty)
+			if errors.Is(err, ErrTileNotCached) {
+				missing = err
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			drawn++
+			at := image.Pt(tx*tileSize-left, ty*tileSize-top)
+			draw.Draw(canvas, image.Rectangle{Min: at, Max: at.Add(image.Pt(tileSize, tileSize))}, tile, tile.Bounds().Min, draw.Src)
+		}
+	}
+	if drawn == 0 && missing != nil {
+		return nil, missing
+	}
+	if marker {
+		drawMarker(canvas, int(math.Floor(cx))-left, int(math.Floor(cy))-top)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, canvas); err != nil {
+		return nil, fmt.Errorf("encode map: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Project converts a position to the Web Mercator pixel coordinates of the map
+// at zoom, as used by StaticMap.
+func Project(lat, lon float64, zoom int) (float64, float64) {
+	scale := float64(tileSize) * math.Exp2(float64(zoom))
+	lat = math.Max(-85.0511, math.Min(85.0511, lat))
+	phi := lat * math.Pi / 180
+	x := (lon + 180) / 360 * scale
+	y := (1 - math.Log(math.Tan(phi)+1/math.Cos(phi))/math.Pi) / 2 * scale
+	return x, y
+}
+
+// Unproject converts Web Mercator pixel coordinates at zoom back to a position.
+func Unproject(x, y float64, zoom int) (lat, lon float64) {
+	scale := float64(tileSize) * math.Exp2(float64(zoom))
+	lon = x/scale*360 - 180
+	lat = math.Atan(math.Sinh(math.Pi*(1-2*y/scale))) * 180 / math.Pi
+	return lat, lon
+}
+
+// FitZoom returns the largest zoom, up to maxZoom, at which the box between the
+// two corners fits into width×height pixels.
+func FitZoom(minLat, minLon, maxLat, maxLon float64, width, height, maxZoom int) int {
+	for z := maxZoom; z > 0; z-- {
+		x0, y0 := Project(maxLat, minLon, z)
+		x1, y1 := Project(minLat, maxLon, z)
+		if x1-x0 <= float64(width) && y1-y0 <= float64(height) {
+			return z
+		}
+	}
+	return 0
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+// drawMarker draws a red dot with a white ring centered at x, y.
+func drawMarker(img draw.Image, x, y int) {
+	const outer, inner = 8, 6
+	ring := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	dot := color.RGBA{0xd9, 0x30, 0x25, 0xff}
+	for dy := -outer; dy <= outer; dy++ {
+		for dx := -outer; dx <= outer; dx++ {
+			d := dx*dx + dy*dy
+			switch {
+			case d <= inner*inner:
+				img.Set(x+dx, y+dy, dot)
+			case d <= outer*outer:
+				img.Set(x+dx, y+dy, ring)
+			}
+		}
+	}
+}
@@ -0,0 +1,358 @@
+package geocode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // tile servers such as aerial imagery serve JPEG
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	tileSettingsFile = "tiles.json"
+	// maxPrefetchTiles bounds one prefetch, so a mistyped zoom does not hammer
+	// even a server that allows offline use.
+	maxPrefetchTiles = 2500
+)
+
+// ErrTileNotCached is returned in offline mode for tiles that were never downloaded.
+var ErrTileNotCached = errors.New("tile not in the offline cache")
+
+// TileSource is an XYZ tile server that maps are rendered from.
+type TileSource struct {
+	Name        string `json:"name"`        // names the cache folder; "osm" is OpenStreetMap
+	URL         string `json:"url"`         // template with {z}, {x} and {y}
+	Attribution string `json:"attribution"` // shown next to the maps
+	MaxZoom     int    `json:"maxZoom"`
+}
+
+// OpenStreetMap is the default tile source.
+var OpenStreetMap = TileSource{
+	Name:        "osm",
+	URL:         "https://tile.openstreetmap.org/{z}/{x}/{y}.png",
+	Attribution: Attribution,
+	MaxZoom:     MaxZoom,
+}
+
+// TileSettings selects the tile source of a resolver and whether it may download.
+type TileSettings struct {
+	Source  TileSource `json:"source"`
+	Offline bool       `json:"offline"` // render from cached tiles only, never downloading
+}
+
+// TileCache counts the cached tiles of a tile source.
+type TileCache struct {
+	Tiles int   `json:"tiles"`
+	Bytes int64 `json:"bytes"`
+}
+
+// Validate checks a custom source and fills in its defaults: the name from the
+// server's host and the OpenStreetMap zoom limit.
+func (s *TileSource) Validate() error {
+	s.Name = strings.TrimSpace(s.Name)
+	s.URL = strings.TrimSpace(s.URL)
+	s.Attribution = strings.TrimSpace(s.Attribution)
+	if s.URL == "" || s.URL == OpenStreetMap.URL {
+		*s = OpenStreetMap
+		return nil
+	}
+	for _, p := range []string{"{z}", "{x}", "{y}"} {
+		if !strings.Contains(s.URL, p) {
+			return fmt.Errorf("tile URL must contain {z}, {x} and {y}")
+		}
+	}
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("tile URL must be an http or https address")
+	}
+	if s.Name == "" {
+		s.Name = u.Hostname()
+	}
+	s.Name = sanitizeName(s.Name)
+	if s.Name == OpenStreetMap.Name {
+		return fmt.Errorf("tile source name %q is reserved for OpenStreetMap", s.Name)
+	}
+	if s.Attribution == "" {
+		s.Attribution = "Map tiles: " + u.Hostname()
+	}
+	if s.MaxZoom == 0 {
+		s.MaxZoom = MaxZoom
+	}
+	if s.MaxZoom < 1 || s.MaxZoom > 22 {
+		return fmt.Errorf("tile source max zoom must be between 1 and 22")
+	}
+	return nil
+}
+
+// sanitizeName keeps a tile source name usable as a folder name.
+func sanitizeName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return strings.Trim(b.String(), "._")
+}
+
+func (s TileSource) tileURL(z, x, y int) string {
+	return strings.NewReplacer("{z}", fmt.Sprint(z), "{x}", fmt.Sprint(x), "{y}", fmt.Sprint(y)).Replace(s.URL)
+}
+
+// tileDir is the cache folder of the source's tiles. OpenStreetMap keeps the
+// folder it had before other sources were supported.
+func (r *Resolver) tileDir(s TileSource) string {
+	if r.cacheDir == "" {
+		return ""
+	}
+	if s.Name == OpenStreetMap.Name {
+		return filepath.Join(r.cacheDir, "tiles")
+	}
+	return filepath.Join(r.cacheDir, "tiles", "sources", s.Name)
+}
+
+// TileSettings returns the tile source and offline mode in use.
+func (r *Resolver) TileSettings() TileSettings {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tiles
+}
+
+// SetTileSettings switches the tile source or offline mode and saves the choice
+// in the cache folder, where later resolvers pick it up.
+func (r *Resolver) SetTileSettings(s TileSettings) error {
+	if err := s.Source.Validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.tiles = s
+	r.mu.Unlock()
+	if r.cacheDir == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(r.cacheDir, 0o755); err != nil {
+		return fmt.Errorf("save tile settings: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(r.cacheDir, tileSettingsFile), data, 0o644); err != nil {
+		return fmt.Errorf("save tile settings: %w", err)
+	}
+	return nil
+}
+
+// loadTileSettings reads the saved tile settings, keeping OpenStreetMap online
+// when there are none or they no longer validate.
+func loadTileSettings(cacheDir string) TileSettings {
+	def := TileSettings{Source: OpenStreetMap}
+	if cacheDir == "" {
+		return def
+	}
+	data, err := os.ReadFile(filepath.Join(cacheDir, tileSettingsFile))
+	if err != nil {
+		return def
+	}
+	var s TileSettings
+	if json.Unmarshal(data, &s) != nil || s.Source.Validate() != nil {
+		return def
+	}
+	return s
+}
+
+// MapMaxZoom is the most detailed zoom of the current tile source.
+func (r *Resolver) MapMaxZoom() int {
+	return r.TileSettings().Source.MaxZoom
+}
+
+// MapAttribution is the credit to show next to maps of the current tile source.
+func (r *Resolver) MapAttribution() string {
+	return r.TileSettings().Source.Attribution
+}
+
+// tile returns a map tile of the current source, from the cache when it is recent
+// enough. A stale cached tile is still used when the download fails, and offline
+// only cached tiles are used.
+func (r *Resolver) tile(ctx context.Context, z, x, y int) (image.Image, error) {
+	settings := r.TileSettings()
+	data, err := r.tileData(ctx, settings, z, x, y)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("map tile %d/%d/%d: %w", z, x, y, err)
+	}
+	return img, nil
+}
+
+// tileData returns the encoded tile. Tiles are cached as .png whatever their
+// format; decoding sniffs it.
+func (r *Resolver) tileData(ctx context.Context, settings TileSettings, z, x, y int) ([]byte, error) {
+	var cached string
+	var stale []byte
+	if dir := r.tileDir(settings.Source); dir != "" {
+		cached = filepath.Join(dir, fmt.Sprint(z), fmt.Sprint(x), fmt.Sprintf("%d.png", y))
+		if info, err := os.Stat(cached); err == nil {
+			if data, err := os.ReadFile(cached); err == nil {
+				if settings.Offline || time.Since(info.ModTime()) < tileMaxAge {
+					return data, nil
+				}
+				stale = data
+			}
+		}
+	}
+	if settings.Offline {
+		return nil, fmt.Errorf("map tile %d/%d/%d: %w", z, x, y, ErrTileNotCached)
+	}
+
+	data, err := r.get(ctx, settings.Source.tileURL(z, x, y))
+	if err != nil {
+		if stale != nil && ctx.Err() == nil {
+			return stale, nil
+		}
+		return nil, fmt.Errorf("map tile %d/%d/%d: %w", z, x, y, err)
+	}
+	if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("map tile %d/%d/%d: %w", z, x, y, err)
+	}
+	if cached != "" {
+		if err := os.MkdirAll(filepath.Dir(cached), 0o755); err == nil {
+			_ = os.WriteFile(cached, data, 0o644)
+		}
+	}
+	return data, nil
+}
+
+// TileCacheStats counts the cached tiles of the current tile source.
+func (r *Resolver) TileCacheStats() (TileCache, error) {
+	var stats TileCache
+	dir := r.tileDir(r.TileSettings().Source)
+	if dir == "" {
+		return stats, nil
+	}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() && d.Name() == "sources" && filepath.Dir(path) == dir {
+			return filepath.SkipDir // other sources' tiles below the OpenStreetMap folder
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".png") {
+			if info, err := d.Info(); err == nil {
+				stats.Tiles++
+				stats.Bytes += info.Size()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, fmt.Errorf("read tile cache: %w", err)
+	}
+	return stats, nil
+}
+
+// ClearTileCache deletes the cached tiles of the current tile source.
+func (r *Resolver) ClearTileCache() error {
+	source := r.TileSettings().Source
+	dir := r.tileDir(source)
+	if dir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("clear tile cache: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() && e.Name() == "sources" && source.Name == OpenStreetMap.Name {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+			return fmt.Errorf("clear tile cache: %w", err)
+		}
+	}
+	return nil
+}
+
+// PrefetchTiles downloads the tiles covering the area at zooms minZoom through
+// maxZoom into the cache, so maps of it render offline. Tiles already cached and
+// recent are skipped. It reports progress after each tile and returns the number
+// of tiles downloaded. OpenStreetMap's tile usage policy forbids bulk downloads,
+// so it needs a custom source that allows offline use; OpenStreetMap tiles are
+// only cached as maps show them.
+func (r *Resolver) PrefetchTiles(ctx context.Context, minLat, minLon, maxLat, maxLon float64, minZoom, maxZoom int, progress func(done, total int)) (int, error) {
+	settings := r.TileSettings()
+	if settings.Source.Name == OpenStreetMap.Name {
+		return 0, fmt.Errorf("OpenStreetMap's tile policy forbids downloading tiles in bulk; pick a tile server that allows offline use")
+	}
+	if settings.Offline {
+		return 0, fmt.Errorf("switch offline mode off to download tiles")
+	}
+	if r.cacheDir == "" {
+		return 0, fmt.Errorf("no tile cache folder")
+	}
+	maxZoom = min(maxZoom, settings.Source.MaxZoom)
+	minZoom = max(minZoom, 0)
+	if minZoom > maxZoom {
+		return 0, fmt.Errorf("min zoom %d is above max zoom %d", minZoom, maxZoom)
+	}
+
+	type span struct{ z, x0, x1, y0, y1 int }
+	var spans []span
+	total := 0
+	for z := minZoom; z <= maxZoom; z++ {
+		left, top := Project(maxLat, minLon, z)
+		right, bottom := Project(minLat, maxLon, z)
+		last := 1<<z - 1
+		s := span{z,
+			min(max(int(left)/tileSize, 0), last), min(max(int(right)/tileSize, 0), last),
+			min(max(int(top)/tileSize, 0), last), min(max(int(bottom)/tileSize, 0), last)}
+		spans = append(spans, s)
+		total += (s.x1 - s.x0 + 1) * (s.y1 - s.y0 + 1)
+	}
+	if total > maxPrefetchTiles {
+		return 0, fmt.Errorf("the area needs %d tiles at zoom %d–%d, more than %d; lower the max zoom or pick a smaller area", total, minZoom, maxZoom, maxPrefetchTiles)
+	}
+
+	done, fetched := 0, 0
+	dir := r.tileDir(settings.Source)
+	for _, s := range spans {
+		for x := s.x0; x <= s.x1; x++ {
+			for y := s.y0; y <= s.y1; y++ {
+				if err := ctx.Err(); err != nil {
+					return fetched, err
+				}
+				path := filepath.Join(dir, fmt.Sprint(s.z), fmt.Sprint(x), fmt.Sprintf("%d.png", y))
+				if info, err := os.Stat(path); err != nil || time.Since(info.ModTime()) >= tileMaxAge {
+					if _, err := r.tileData(ctx, settings, s.z, x, y); err != nil {
+						return fetched, err
+					}
+					fetched++
+				}
+				done++
+				if progress != nil {
+					progress(done, total)
+				}
+			}
+		}
+	}
+	return fetched, nil
+}
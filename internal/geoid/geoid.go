@@ -0,0 +1,134 @@
+// Package geoid reads geoid height grids in the GeographicLib PGM format (e.g.
+// egm96-5.pgm or egm2008-2_5.pgm from geographiclib.sourceforge.io) to convert
+// heights above the WGS84 ellipsoid into heights above mean sea level.
+package geoid
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Grid is an open geoid grid. Values are read from the file on demand, so even the
+// large 1-minute grids need no memory beyond the header.
+type Grid struct {
+	file   *os.File
+	data   int64 // offset of the first sample
+	width  int   // samples per row, covering longitudes [0, 360)
+	height int   // rows from 90°N to 90°S, both included
+	offset float64
+	scale  float64
+}
+
+// Open reads the header of a GeographicLib PGM geoid grid.
+func Open(path string) (*Grid, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open geoid grid: %w", err)
+	}
+	g, err := readHeader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("geoid grid %s: %w", path, err)
+	}
+	return g, nil
+}
+
+func readHeader(f *os.File) (*Grid, error) {
+	r := bufio.NewReader(f)
+	g := &Grid{file: f, scale: math.NaN(), offset: math.NaN()}
+	magic, err := r.ReadString('\n')
+	if err != nil || strings.TrimSpace(magic) != "P5" {
+		return nil, fmt.Errorf("not a binary PGM file")
+	}
+	pos := int64(len(magic))
+	var fields []int
+	for len(fields) < 3 {
+		line, err := r.ReadString('\n')
+		pos += int64(len(line))
+		if err != nil {
+			return nil, fmt.Errorf("truncated header")
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#"):
+			key, value, _ := strings.Cut(strings.TrimSpace(line[1:]), " ")
+			switch key {
+			case "Offset":
+				g.offset, _ = strconv.ParseFloat(strings.TrimSpace(value), 64)
+			case "Scale":
+				g.scale, _ = strconv.ParseFloat(strings.TrimSpace(value), 64)
+			}
+		default:
+			for _, tok := range strings.Fields(line) {
+				n, err := strconv.Atoi(tok)
+				if err != nil {
+					return nil, fmt.Errorf("invalid header value %q", tok)
+				}
+				fields = append(fields, n)
+			}
+		}
+	}
+	g.width, g.height = fields[0], fields[1]
+	if math.IsNaN(g.offset) || math.IsNaN(g.scale) {
+		return nil, fmt.Errorf("header has no Offset/Scale comments; not a GeographicLib geoid grid")
+	}
+	if fields[2] != 65535 || g.width < 2 || g.height < 2 || (g.height-1)*2 != g.width {
+		return nil, fmt.Errorf("unexpected grid layout %dx%d (max %d)", g.width, g.height, fields[2])
+	}
+	g.data = pos
+	if info, err := f.Stat(); err == nil && info.Size() < g.data+int64(g.width*g.height*2) {
+		return nil, fmt.Errorf("file is truncated")
+	}
+	return g, nil
+}
+
+// Close releases the grid file.
+func (g *Grid) Close() error {
+	return g.file.Close()
+}
+
+// Separation returns the geoid height N in meters at a position, bilinearly
+// interpolated: mean sea level lies N meters above the ellipsoid.
+func (g *Grid) Separation(lat, lon float64) (float64, error) {
+	step := 360 / float64(g.width)
+	lon = math.Mod(lon, 360)
+	if lon < 0 {
+		lon += 360
+	}
+	fx := lon / step
+	fy := (90 - math.Max(-90, math.Min(90, lat))) / step
+	x0, y0 := int(fx), int(fy)
+	if y0 >= g.height-1 {
+		y0 = g.height - 2
+	}
+	dx, dy := fx-float64(x0), fy-float64(y0)
+	x1 := (x0 + 1) % g.width
+	x0 %= g.width
+
+	var v [4]float64
+	for i, p := range [4][2]int{{x0, y0}, {x1, y0}, {x0, y0 + 1}, {x1, y0 + 1}} {
+		raw, err := g.sample(p[0], p[1])
+		if err != nil {
+			return 0, err
+		}
+		v[i] = g.offset + g.scale*float64(raw)
+	}
+	top := v[0] + dx*(v[1]-v[0])
+	bottom := v[2] + dx*(v[3]-v[2])
+	return top + dy*(bottom-top), nil
+}
+
+func (g *Grid) sample(x, y int) (uint16, error) {
+	var buf [2]byte
+	off := g.data + int64(y*g.width+x)*2
+	if _, err := g.file.ReadAt(buf[:], off); err != nil {
+		return 0, fmt.Errorf("read geoid grid: %w", err)
+	}
+	return binary.BigEndian.Uint16(buf[:]), nil
+}
@@ -0,0 +1,94 @@
+package gpx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// appleHealthCreator is the creator attribute of workout route files in an Apple
+// Health export (apple_health_export/workout-routes/route_*.gpx).
+const appleHealthCreator = "Apple Health Export"
+
+// healthTimeLayouts covers the timestamp variants found in workout routes across iOS
+// versions: UTC with or without fractions, numeric offsets with or without a colon,
+// the "2006-01-02 15:04:05 -0700" form of export.xml, and zone-less times (UTC).
+var healthTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+type healthGPX struct {
+	Tracks []struct {
+		Segments []struct {
+			Points []healthPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+type healthPoint struct {
+	Lat  float64  `xml:"lat,attr"`
+	Lon  float64  `xml:"lon,attr"`
+	Ele  *float64 `xml:"ele"`
+	Time string   `xml:"time"`
+}
+
+// isHealthRouteEntry reports whether a zip entry is a workout route of a Health export.
+func isHealthRouteEntry(name string) bool {
+	return strings.EqualFold(path.Base(path.Dir(name)), "workout-routes")
+}
+
+// isAppleHealthRoute reports whether a GPX document was written by the Health export.
+func isAppleHealthRoute(data []byte) bool {
+	head := data
+	if len(head) > 2048 {
+		head = head[:2048]
+	}
+	return bytes.Contains(head, []byte(`creator="`+appleHealthCreator))
+}
+
+// parseHealthRoute reads a workout route with a lenient timestamp parser; points
+// whose time cannot be read are treated like untimed track points.
+func parseHealthRoute(data []byte) ([]trackPoint, UntimedStats, error) {
+	var doc healthGPX
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, UntimedStats{}, fmt.Errorf("parse workout route: %w", err)
+	}
+
+	var points []trackPoint
+	var untimed UntimedStats
+	for _, track := range doc.Tracks {
+		for _, segment := range track.Segments {
+			segPoints := make([]trackPoint, 0, len(segment.Points))
+			for _, pt := range segment.Points {
+				tp := trackPoint{
+					coord: Coordinate{Latitude: pt.Lat, Longitude: pt.Lon, Altitude: pt.Ele},
+					time:  parseHealthTime(pt.Time),
+				}
+				segPoints = append(segPoints, tp)
+			}
+			points = append(points, fillSegmentTimes(segPoints, &untimed)...)
+		}
+	}
+	return points, untimed, nil
+}
+
+func parseHealthTime(raw string) time.Time {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}
+	}
+	for _, layout := range healthTimeLayouts {
+		if ts, err := time.Parse(layout, raw); err == nil {
+			return ts.UTC()
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,85 @@
+package gpx
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// readGPXDocs returns the GPX documents stored at path. Gzip files (.gpx.gz) are
+// decompressed; zip archives must contain exactly one .gpx file, unless they are an
+// Apple Health export, whose workout routes are all returned. The format is detected
+// from the content, not the extension.
+func readGPXDocs(path string) ([][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read gpx: %w", err)
+	}
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("open gzip: %w", err)
+		}
+		defer zr.Close()
+		out, err := io.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("decompress gzip: %w", err)
+		}
+		return [][]byte{out}, nil
+	case bytes.HasPrefix(data, zipMagic):
+		return readZippedGPX(data)
+	default:
+		return [][]byte{data}, nil
+	}
+}
+
+func readZippedGPX(data []byte) ([][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open zip: %w", err)
+	}
+	var found, routes []*zip.File
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !strings.EqualFold(path.Ext(f.Name), ".gpx") {
+			continue
+		}
+		found = append(found, f)
+		if isHealthRouteEntry(f.Name) {
+			routes = append(routes, f)
+		}
+	}
+	switch {
+	case len(routes) > 0:
+		found = routes
+	case len(found) == 0:
+		return nil, fmt.Errorf("zip archive contains no .gpx file")
+	case len(found) > 1:
+		return nil, fmt.Errorf("zip archive contains %d .gpx files; extract the one to use", len(found))
+	}
+
+	docs := make([][]byte, 0, len(found))
+	for _, f := range found {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open %s in zip: %w", f.Name, err)
+		}
+		out, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read %s in zip: %w", f.Name, err)
+		}
+		docs = append(docs, out)
+	}
+	return docs, nil
+}
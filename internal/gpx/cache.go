@@ -0,0 +1,179 @@
+package gpx
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cacheMagic identifies GeoRAW track cache files and their layout version.
+var cacheMagic = [8]byte{'G', 'R', 'W', 'T', 'R', 'K', '0', '4'}
+
+// Flags stored in byte 32 of each cached point record; the HDOP follows as a float32.
+const (
+	cacheHasAltitude = 1 << iota
+	cachePieceStart
+)
+
+// Layout sizes: the header is the magic, the point count and the two untimed
+// counters; every point is a fixed-size record.
+const (
+	cacheHeaderSize = 8 + 8 + 2*4
+	cacheRecordSize = 37
+)
+
+// DefaultCacheDir returns the per-user folder used for cached track indexes.
+func DefaultCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve cache dir: %w", err)
+	}
+	return filepath.Join(base, "georaw", "tracks"), nil
+}
+
+// LoadTrackCached behaves like LoadTrack but reuses a binary index stored in cacheDir,
+// keyed by the SHA-256 of the GPX content. It reports whether the cache was used.
+// Cache read or write problems never fail the load; the GPX is parsed instead.
+func LoadTrackCached(path, cacheDir string) (*TrackIndex, bool, error) {
+	if cacheDir == "" {
+		ti, err := LoadTrack(path)
+		return ti, false, err
+	}
+
+	key, err := fileHash(path)
+	if err != nil {
+		return nil, false, err
+	}
+	cachePath := filepath.Join(cacheDir, key+".trk")
+
+	if ti, err := readTrackCache(cachePath); err == nil {
+		return ti, true, nil
+	}
+
+	ti, err := LoadTrack(path)
+	if err != nil {
+		return nil, false, err
+	}
+	_ = writeTrackCache(cachePath, ti)
+	return ti, false, nil
+}
+
+func fileHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open gpx: %w", err)
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("hash gpx: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func readTrackCache(path string) (*TrackIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReaderSize(file, 64*1024)
+
+	var magic [8]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return nil, err
+	}
+	if magic != cacheMagic {
+		return nil, errors.New("unknown track cache format")
+	}
+	var count uint64
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+	// A truncated or corrupt file must not size the allocation below.
+	if count == 0 || count > math.MaxInt32 || info.Size() < cacheHeaderSize || uint64(info.Size()-cacheHeaderSize) != count*cacheRecordSize {
+		return nil, errors.New("invalid track cache size")
+	}
+	var untimed [2]uint32
+	if err := binary.Read(r, binary.LittleEndian, &untimed); err != nil {
+		return nil, err
+	}
+
+	points := make([]trackPoint, count)
+	var rec [cacheRecordSize]byte
+	for i := range points {
+		if _, err := io.ReadFull(r, rec[:]); err != nil {
+			return nil, err
+		}
+		points[i].time = time.Unix(0, int64(binary.LittleEndian.Uint64(rec[0:8]))).UTC()
+		points[i].coord.Latitude = math.Float64frombits(binary.LittleEndian.Uint64(rec[8:16]))
+		points[i].coord.Longitude = math.Float64frombits(binary.LittleEndian.Uint64(rec[16:24]))
+		if rec[32]&cacheHasAltitude != 0 {
+			alt := math.Float64frombits(binary.LittleEndian.Uint64(rec[24:32]))
+			points[i].coord.Altitude = &alt
+		}
+		points[i].pieceStart = rec[32]&cachePieceStart != 0
+		points[i].hdop = float64(math.Float32frombits(binary.LittleEndian.Uint32(rec[33:37])))
+	}
+	return &TrackIndex{
+		points:  points,
+		untimed: UntimedStats{Interpolated: int(untimed[0]), Dropped: int(untimed[1])},
+	}, nil
+}
+
+func writeTrackCache(path string, ti *TrackIndex) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".trk-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriterSize(tmp, 64*1024)
+	w.Write(cacheMagic[:])
+	binary.Write(w, binary.LittleEndian, uint64(len(ti.points)))
+	binary.Write(w, binary.LittleEndian, [2]uint32{uint32(ti.untimed.Interpolated), uint32(ti.untimed.Dropped)})
+	var rec [cacheRecordSize]byte
+	for _, pt := range ti.points {
+		binary.LittleEndian.PutUint64(rec[0:8], uint64(pt.time.UnixNano()))
+		binary.LittleEndian.PutUint64(rec[8:16], math.Float64bits(pt.coord.Latitude))
+		binary.LittleEndian.PutUint64(rec[16:24], math.Float64bits(pt.coord.Longitude))
+		rec[32] = 0
+		binary.LittleEndian.PutUint64(rec[24:32], 0)
+		if pt.coord.Altitude != nil {
+			binary.LittleEndian.PutUint64(rec[24:32], math.Float64bits(*pt.coord.Altitude))
+			rec[32] |= cacheHasAltitude
+		}
+		if pt.pieceStart {
+			rec[32] |= cachePieceStart
+		}
+		binary.LittleEndian.PutUint32(rec[33:37], math.Float32bits(float32(pt.hdop)))
+		if _, err := w.Write(rec[:]); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
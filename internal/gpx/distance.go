@@ -0,0 +1,13 @@
+package gpx
+
+import "math"
+
+// Haversine returns the great-circle distance between a and b in meters,
+// ignoring altitude.
+func Haversine(a, b Coordinate) float64 {
+	const rad = math.Pi / 180
+	dLat := (b.Latitude - a.Latitude) * rad
+	dLon := (b.Longitude - a.Longitude) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(a.Latitude*rad)*math.Cos(b.Latitude*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * math.Atan2(math.Sqrt(h), math.Sqrt(1-h)) * earthRadiusMeters
+}
@@ -0,0 +1,103 @@
+package gpx
+
+import (
+	"math"
+	"time"
+)
+
+// Gaps in this range are bridged with the motion model; shorter gaps are close
+// enough to straight, longer ones say nothing about the path taken.
+const (
+	minMotionGap = 30 * time.Second
+	maxMotionGap = 15 * time.Minute
+	// velocitySpan is the longest interval between two points used to measure the
+	// speed and heading on entering or leaving a gap.
+	velocitySpan = 2 * time.Minute
+	// walkingPace (m/s) keeps slow movement across a gap from being capped to nothing.
+	walkingPace = 1.5
+)
+
+// Interpolation selects how positions between two recorded points are computed.
+type Interpolation int
+
+const (
+	InterpolateMotion Interpolation = iota // motion model across moderate gaps, straight lines elsewhere
+	InterpolateLinear                      // straight lines everywhere
+)
+
+// SetInterpolation selects the interpolation used by MatchAt and CoordinateAt.
+func (ti *TrackIndex) SetInterpolation(m Interpolation) {
+	ti.interpolation = m
+}
+
+// motionEstimate bridges the gap before point idx with a cubic Hermite curve
+// through both ends whose tangents are the velocities measured just before the gap
+// and just after it. The path keeps the entry and exit headings, around a switchback
+// or along a curving road, instead of cutting straight across. Tangent speeds are
+// capped at three times the straight-line speed across the gap plus walking pace,
+// so a stop inside the gap does not fling the curve outward. It reports false when
+// the gap is outside the modelled range or a velocity cannot be measured.
+func (ti *TrackIndex) motionEstimate(idx int, progress float64) (float64, float64, bool) {
+	if ti.interpolation != InterpolateMotion || idx < 2 || idx+1 >= len(ti.points) {
+		return 0, 0, false
+	}
+	before, prev, next, after := ti.points[idx-2], ti.points[idx-1], ti.points[idx], ti.points[idx+1]
+	gap := next.time.Sub(prev.time)
+	if gap < minMotionGap || gap > maxMotionGap || prev.pieceStart || after.pieceStart {
+		return 0, 0, false
+	}
+
+	origin := prev.coord
+	px, py := localMeters(origin, next.coord)
+	ex, ey, ok := velocity(origin, before, prev)
+	if !ok {
+		return 0, 0, false
+	}
+	xx, xy, ok := velocity(origin, next, after)
+	if !ok {
+		return 0, 0, false
+	}
+
+	seconds := gap.Seconds()
+	limit := 3*math.Hypot(px, py)/seconds + walkingPace
+	ex, ey = capSpeed(ex, ey, limit)
+	xx, xy = capSpeed(xx, xy, limit)
+
+	s := progress
+	h10 := s*s*s - 2*s*s + s
+	h01 := -2*s*s*s + 3*s*s
+	h11 := s*s*s - s*s
+	x := h10*seconds*ex + h01*px + h11*seconds*xx
+	y := h10*seconds*ey + h01*py + h11*seconds*xy
+
+	lat := origin.Latitude + y/earthRadiusMeters*180/math.Pi
+	lon := origin.Longitude + x/(earthRadiusMeters*math.Cos(origin.Latitude*math.Pi/180))*180/math.Pi
+	return lat, lon, true
+}
+
+// velocity is the velocity in m/s (east, north) between two consecutive points,
+// measured in the local frame around origin.
+func velocity(origin Coordinate, a, b trackPoint) (float64, float64, bool) {
+	dt := b.time.Sub(a.time)
+	if dt <= 0 || dt > velocitySpan {
+		return 0, 0, false
+	}
+	ax, ay := localMeters(origin, a.coord)
+	bx, by := localMeters(origin, b.coord)
+	return (bx - ax) / dt.Seconds(), (by - ay) / dt.Seconds(), true
+}
+
+// localMeters projects c onto a flat east/north plane centered on origin.
+func localMeters(origin, c Coordinate) (float64, float64) {
+	const rad = math.Pi / 180
+	x := (c.Longitude - origin.Longitude) * rad * math.Cos(origin.Latitude*rad) * earthRadiusMeters
+	y := (c.Latitude - origin.Latitude) * rad * earthRadiusMeters
+	return x, y
+}
+
+func capSpeed(vx, vy, limit float64) (float64, float64) {
+	if speed := math.Hypot(vx, vy); speed > limit {
+		return vx * limit / speed, vy * limit / speed
+	}
+	return vx, vy
+}
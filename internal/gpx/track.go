@@ -6,6 +6,7 @@ import (
 	"sort"
 	"time"
 
+	"github.com/nir0k/GeoRAW/internal/errcode"
 	gogpx "github.com/tkrajina/gpxgo/gpx"
 )
 
@@ -21,24 +22,91 @@ type Coordinate struct {
 
 // TrackIndex keeps GPX points sorted by timestamp for quick lookups.
 type TrackIndex struct {
-	points []trackPoint
+	points        []trackPoint
+	untimed       UntimedStats
+	interpolation Interpolation
 }
 
 type trackPoint struct {
 	coord Coordinate
 	time  time.Time
+	// pieceStart marks the first point of a separately recorded piece (one workout
+	// of a Health export); positions are not interpolated across the gap before it.
+	pieceStart bool
+	hdop       float64 // horizontal dilution of precision, 0 when not recorded
 }
 
-// LoadTrack parses a GPX file and prepares the lookup index.
+// Match describes how a position was derived from the track, for judging how far
+// it can be trusted.
+type Match struct {
+	Gap      time.Duration // time between the recorded points around the timestamp, 0 on an exact hit
+	Distance float64       // meters from the position to the nearer recorded point
+	HDOP     float64       // worst horizontal dilution of those points, 0 when not recorded
+	// Estimated marks a position bridged across a gap with the motion model instead
+	// of a straight line (see SetInterpolation).
+	Estimated bool
+}
+
+// LoadTrack parses a GPX file and prepares the lookup index. Gzip-compressed
+// files and zip archives holding a single GPX are decompressed first; an Apple
+// Health export.zip contributes all of its workout routes.
 func LoadTrack(path string) (*TrackIndex, error) {
-	parsed, err := gogpx.ParseFile(path)
+	docs, err := readGPXDocs(path)
 	if err != nil {
-		return nil, fmt.Errorf("parse gpx: %w", err)
+		return nil, err
 	}
 
-	collected := collectPoints(parsed)
+	var collected []trackPoint
+	var untimed UntimedStats
+	for _, data := range docs {
+		points, stats, err := parseGPXDoc(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(docs) > 1 && len(points) > 0 {
+			points[0].pieceStart = true
+		}
+		collected = append(collected, points...)
+		untimed.Interpolated += stats.Interpolated
+		untimed.Dropped += stats.Dropped
+	}
 	if len(collected) == 0 {
-		return nil, fmt.Errorf("gpx file contains no track points")
+		if untimed.Total() > 0 {
+			return nil, errcode.New(errcode.TrackUntimed, nil)
+		}
+		return nil, errcode.New(errcode.TrackEmpty, nil)
+	}
+
+	sort.Slice(collected, func(i, j int) bool {
+		return collected[i].time.Before(collected[j].time)
+	})
+
+	return &TrackIndex{points: collected, untimed: untimed}, nil
+}
+
+// Point is a timed position from a source other than a GPX file.
+type Point struct {
+	Time  time.Time
+	Coord Coordinate
+}
+
+// NewTrack builds a lookup index from separately recorded pieces, such as
+// activities downloaded from a service; positions are not interpolated across
+// the gaps between pieces. Points without a time are ignored.
+func NewTrack(pieces [][]Point) (*TrackIndex, error) {
+	var collected []trackPoint
+	for _, piece := range pieces {
+		start := true
+		for _, pt := range piece {
+			if pt.Time.IsZero() {
+				continue
+			}
+			collected = append(collected, trackPoint{coord: pt.Coord, time: pt.Time.UTC(), pieceStart: start && len(pieces) > 1})
+			start = false
+		}
+	}
+	if len(collected) == 0 {
+		return nil, errcode.New(errcode.TrackUntimed, nil)
 	}
 
 	sort.Slice(collected, func(i, j int) bool {
@@ -48,15 +116,61 @@ func LoadTrack(path string) (*TrackIndex, error) {
 	return &TrackIndex{points: collected}, nil
 }
 
+// ParsePoints returns the timed points of one GPX document, for sources that mix
+// GPX files with other formats.
+func ParsePoints(data []byte) ([]Point, error) {
+	points, _, err := parseGPXDoc(data)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Point, len(points))
+	for i, pt := range points {
+		out[i] = Point{Time: pt.time, Coord: pt.coord}
+	}
+	return out, nil
+}
+
+// parseGPXDoc returns the timed points of one GPX document, falling back to its
+// routes when it has no track.
+func parseGPXDoc(data []byte) ([]trackPoint, UntimedStats, error) {
+	if isAppleHealthRoute(data) {
+		return parseHealthRoute(data)
+	}
+	parsed, err := gogpx.ParseBytes(data)
+	if err != nil {
+		return nil, UntimedStats{}, fmt.Errorf("parse gpx: %w", err)
+	}
+
+	points, untimed := collectPoints(parsed)
+	if len(points) == 0 && untimed.Total() == 0 {
+		// Planning tools often export a route instead of a recorded track.
+		points, untimed = collectRoutePoints(parsed)
+		if len(points) == 0 && untimed.Total() > 0 {
+			return nil, untimed, fmt.Errorf("gpx route has no timestamps; at least its first and last points need a <time>")
+		}
+	}
+	return points, untimed, nil
+}
+
 // CoordinateAt returns an interpolated coordinate for the provided timestamp.
 func (ti *TrackIndex) CoordinateAt(ts time.Time) (Coordinate, error) {
+	coord, _, err := ti.MatchAt(ts)
+	return coord, err
+}
+
+// MatchAt is CoordinateAt that also reports how the coordinate was interpolated.
+func (ti *TrackIndex) MatchAt(ts time.Time) (Coordinate, Match, error) {
 	if len(ti.points) == 0 {
-		return Coordinate{}, fmt.Errorf("no track points loaded")
+		return Coordinate{}, Match{}, fmt.Errorf("no track points loaded")
 	}
 	target := ts.UTC()
 
 	if target.Before(ti.points[0].time) || target.After(ti.points[len(ti.points)-1].time) {
-		return Coordinate{}, fmt.Errorf("%w: %s", ErrTimestampOutOfBounds, target.Format(time.RFC3339))
+		return Coordinate{}, Match{}, errcode.Wrap(ErrTimestampOutOfBounds, errcode.OutOfTrack, errcode.Params{
+			"time":  target.Format(time.RFC3339),
+			"start": ti.points[0].time.Format(time.RFC3339),
+			"end":   ti.points[len(ti.points)-1].time.Format(time.RFC3339),
+		})
 	}
 
 	idx := sort.Search(len(ti.points), func(i int) bool {
@@ -64,26 +178,35 @@ func (ti *TrackIndex) CoordinateAt(ts time.Time) (Coordinate, error) {
 	})
 
 	if idx == len(ti.points) {
-		return ti.points[len(ti.points)-1].coord, nil
-	}
-	if ti.points[idx].time.Equal(target) {
-		return ti.points[idx].coord, nil
+		last := ti.points[len(ti.points)-1]
+		return last.coord, Match{HDOP: last.hdop}, nil
 	}
-	if idx == 0 {
-		return ti.points[0].coord, nil
+	if ti.points[idx].time.Equal(target) || idx == 0 {
+		return ti.points[idx].coord, Match{HDOP: ti.points[idx].hdop}, nil
 	}
 
 	prev := ti.points[idx-1]
 	next := ti.points[idx]
+	if next.pieceStart {
+		return Coordinate{}, Match{}, errcode.Wrap(ErrTimestampOutOfBounds, errcode.BetweenRoutes, errcode.Params{"time": target.Format(time.RFC3339)})
+	}
 
+	match := Match{Gap: next.time.Sub(prev.time), HDOP: max(prev.hdop, next.hdop)}
 	total := next.time.Sub(prev.time).Seconds()
 	if total <= 0 {
-		return prev.coord, nil
+		return prev.coord, match, nil
 	}
 
 	progress := target.Sub(prev.time).Seconds() / total
+	match.Distance = min(progress, 1-progress) * approxDistance(prev.coord, next.coord) * earthRadiusMeters
 	lat := prev.coord.Latitude + progress*(next.coord.Latitude-prev.coord.Latitude)
 	lon := prev.coord.Longitude + progress*(next.coord.Longitude-prev.coord.Longitude)
+	if elat, elon, ok := ti.motionEstimate(idx, progress); ok {
+		lat, lon = elat, elon
+		est := Coordinate{Latitude: lat, Longitude: lon}
+		match.Estimated = true
+		match.Distance = min(approxDistance(prev.coord, est), approxDistance(est, next.coord)) * earthRadiusMeters
+	}
 
 	var alt *float64
 	if prev.coord.Altitude != nil && next.coord.Altitude != nil {
@@ -101,7 +224,7 @@ func (ti *TrackIndex) CoordinateAt(ts time.Time) (Coordinate, error) {
 		Latitude:  lat,
 		Longitude: lon,
 		Altitude:  alt,
-	}, nil
+	}, match, nil
 }
 
 // Nearest returns the nearest track point and its timestamp for a given time.
@@ -146,32 +269,75 @@ func (ti *TrackIndex) Bounds() (time.Time, time.Time) {
 	return ti.points[0].time, ti.points[len(ti.points)-1].time
 }
 
+// Area returns the corners of the box around every track point.
+func (ti *TrackIndex) Area() (minLat, minLon, maxLat, maxLon float64) {
+	if len(ti.points) == 0 {
+		return 0, 0, 0, 0
+	}
+	minLat, minLon = ti.points[0].coord.Latitude, ti.points[0].coord.Longitude
+	maxLat, maxLon = minLat, minLon
+	for _, p := range ti.points[1:] {
+		minLat, maxLat = min(minLat, p.coord.Latitude), max(maxLat, p.coord.Latitude)
+		minLon, maxLon = min(minLon, p.coord.Longitude), max(maxLon, p.coord.Longitude)
+	}
+	return minLat, minLon, maxLat, maxLon
+}
+
 // PointCount returns number of GPX points indexed.
 func (ti *TrackIndex) PointCount() int {
 	return len(ti.points)
 }
 
-func collectPoints(doc *gogpx.GPX) []trackPoint {
+// Untimed reports how points without timestamps were handled while loading.
+func (ti *TrackIndex) Untimed() UntimedStats {
+	return ti.untimed
+}
+
+func collectPoints(doc *gogpx.GPX) ([]trackPoint, UntimedStats) {
 	points := make([]trackPoint, 0)
+	var untimed UntimedStats
 
 	for _, track := range doc.Tracks {
 		for _, segment := range track.Segments {
-			for _, pt := range segment.Points {
-				coord := Coordinate{
-					Latitude:  pt.GetLatitude(),
-					Longitude: pt.GetLongitude(),
-				}
-				if ele := pt.GetElevation(); ele.NotNull() {
-					val := ele.Value()
-					coord.Altitude = &val
-				}
-				points = append(points, trackPoint{
-					coord: coord,
-					time:  pt.Timestamp.UTC(),
-				})
-			}
+			points = append(points, fillSegmentTimes(convertPoints(segment.Points), &untimed)...)
 		}
 	}
 
-	return points
+	return points, untimed
+}
+
+// collectRoutePoints reads <rte> points the same way as track segments: each route
+// needs timed points, and untimed points between them are spread by distance.
+func collectRoutePoints(doc *gogpx.GPX) ([]trackPoint, UntimedStats) {
+	points := make([]trackPoint, 0)
+	var untimed UntimedStats
+
+	for _, route := range doc.Routes {
+		points = append(points, fillSegmentTimes(convertPoints(route.Points), &untimed)...)
+	}
+
+	return points, untimed
+}
+
+func convertPoints(src []gogpx.GPXPoint) []trackPoint {
+	out := make([]trackPoint, 0, len(src))
+	for _, pt := range src {
+		coord := Coordinate{
+			Latitude:  pt.GetLatitude(),
+			Longitude: pt.GetLongitude(),
+		}
+		if ele := pt.GetElevation(); ele.NotNull() {
+			val := ele.Value()
+			coord.Altitude = &val
+		}
+		tp := trackPoint{coord: coord}
+		if hdop := pt.HorizontalDilution; hdop.NotNull() {
+			tp.hdop = hdop.Value()
+		}
+		if !pt.Timestamp.IsZero() {
+			tp.time = pt.Timestamp.UTC()
+		}
+		out = append(out, tp)
+	}
+	return out
 }
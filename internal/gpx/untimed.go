@@ -0,0 +1,81 @@
+package gpx
+
+import (
+	"math"
+	"time"
+)
+
+// UntimedStats reports how track points without a <time> element were handled.
+type UntimedStats struct {
+	// Interpolated points lay between two timed points of the same segment and
+	// received a timestamp proportional to the distance travelled.
+	Interpolated int
+	// Dropped points had no timed neighbour on one side and were ignored.
+	Dropped int
+}
+
+// Total returns the number of points that had no timestamp.
+func (s UntimedStats) Total() int {
+	return s.Interpolated + s.Dropped
+}
+
+// fillSegmentTimes assigns timestamps to untimed points of a single segment.
+// Points before the first or after the last timed point are dropped.
+func fillSegmentTimes(points []trackPoint, stats *UntimedStats) []trackPoint {
+	out := make([]trackPoint, 0, len(points))
+	prevTimed := -1
+	for i, pt := range points {
+		if pt.time.IsZero() {
+			continue
+		}
+		if prevTimed == -1 {
+			stats.Dropped += i
+		} else if i-prevTimed > 1 {
+			out = append(out, interpolateGap(points[prevTimed:i+1])...)
+			stats.Interpolated += i - prevTimed - 1
+		}
+		out = append(out, pt)
+		prevTimed = i
+	}
+	if prevTimed == -1 {
+		stats.Dropped += len(points)
+	} else {
+		stats.Dropped += len(points) - prevTimed - 1
+	}
+	return out
+}
+
+// interpolateGap returns the inner points of gap with times spread by distance
+// between gap[0] and gap[len(gap)-1], which are both timed.
+func interpolateGap(gap []trackPoint) []trackPoint {
+	dist := make([]float64, len(gap))
+	for i := 1; i < len(gap); i++ {
+		dist[i] = dist[i-1] + approxDistance(gap[i-1].coord, gap[i].coord)
+	}
+	start, end := gap[0].time, gap[len(gap)-1].time
+	span := end.Sub(start)
+	inner := gap[1 : len(gap)-1]
+	out := make([]trackPoint, len(inner))
+	for i, pt := range inner {
+		frac := float64(i+1) / float64(len(gap)-1)
+		if total := dist[len(dist)-1]; total > 0 {
+			frac = dist[i+1] / total
+		}
+		pt.time = start.Add(time.Duration(frac * float64(span)))
+		out[i] = pt
+	}
+	return out
+}
+
+// earthRadiusMeters converts approxDistance results to meters.
+const earthRadiusMeters = 6371000
+
+// approxDistance returns an equirectangular distance in radians, which is enough for
+// apportioning time between closely spaced points.
+func approxDistance(a, b Coordinate) float64 {
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	x := (b.Longitude - a.Longitude) * math.Pi / 180 * math.Cos((lat1+lat2)/2)
+	y := lat2 - lat1
+	return math.Hypot(x, y)
+}
@@ -0,0 +1,65 @@
+package gpx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	gogpx "github.com/tkrajina/gpxgo/gpx"
+)
+
+// Waypoint is a named point of interest marked in a GPX file.
+type Waypoint struct {
+	Name  string
+	Coord Coordinate
+}
+
+// LoadWaypoints returns the named waypoints of a GPX file, which may be
+// compressed like the files LoadTrack reads. Unnamed waypoints are left out.
+func LoadWaypoints(path string) ([]Waypoint, error) {
+	docs, err := readGPXDocs(path)
+	if err != nil {
+		return nil, err
+	}
+	var out []Waypoint
+	for _, data := range docs {
+		if isAppleHealthRoute(data) {
+			continue
+		}
+		parsed, err := gogpx.ParseBytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse gpx: %w", err)
+		}
+		for _, wpt := range parsed.Waypoints {
+			name := strings.TrimSpace(wpt.Name)
+			if name == "" {
+				continue
+			}
+			coord := Coordinate{Latitude: wpt.GetLatitude(), Longitude: wpt.GetLongitude()}
+			if ele := wpt.GetElevation(); ele.NotNull() {
+				alt := ele.Value()
+				coord.Altitude = &alt
+			}
+			out = append(out, Waypoint{Name: name, Coord: coord})
+		}
+	}
+	return out, nil
+}
+
+// NearWaypoint is a waypoint found close to a position.
+type NearWaypoint struct {
+	Waypoint
+	Distance float64 // meters from the position
+}
+
+// WaypointsNear returns the waypoints within radius meters of c, nearest first.
+func WaypointsNear(waypoints []Waypoint, c Coordinate, radius float64) []NearWaypoint {
+	var found []NearWaypoint
+	for _, wpt := range waypoints {
+		if d := Haversine(c, wpt.Coord); d <= radius {
+			found = append(found, NearWaypoint{Waypoint: wpt, Distance: d})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Distance < found[j].Distance })
+	return found
+}
@@ -1,7 +1,6 @@
 package gui
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -15,11 +14,20 @@ import (
 	"time"
 
 	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/nir0k/GeoRAW/internal/errcode"
+	"github.com/nir0k/GeoRAW/internal/events"
+	"github.com/nir0k/GeoRAW/internal/media"
 	"github.com/nir0k/GeoRAW/internal/series"
+	"github.com/nir0k/GeoRAW/internal/timefix"
 	"github.com/nir0k/GeoRAW/internal/version"
+	"github.com/nir0k/GeoRAW/internal/xmp"
 	wruntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+// resultLimit caps the per-file results sent to the frontend; larger runs list
+// the files needing attention first, and the counts stay complete.
+const resultLimit = 20000
+
 // Backend is bound to the Wails frontend.
 type Backend struct {
 	ctx context.Context
@@ -27,12 +35,21 @@ type Backend struct {
 	mu      sync.Mutex
 	cancel  context.CancelFunc
 	running bool
-	logBuf  *bytes.Buffer
+	logs    *events.Log // log of the current or last run
+
+	profileDir string
+	heatCells  [][]string // files of each cell of the last heatmap, for HeatmapCellFiles
 }
 
-// OnStartup stores the Wails context.
+// OnStartup stores the Wails context and restores the log of the last run.
 func (b *Backend) OnStartup(ctx context.Context) {
 	b.ctx = ctx
+	go b.restoreLastLog()
+}
+
+// OnShutdown removes the spill file of the in-memory log.
+func (b *Backend) OnShutdown(context.Context) {
+	b.ClearLogs()
 }
 
 func (b *Backend) currentCtx() (context.Context, error) {
@@ -58,6 +75,26 @@ func (b *Backend) Cancel() error {
 	return nil
 }
 
+// beginRun starts a run that Cancel can stop, failing while another one is
+// running. end must be called once the run is over.
+func (b *Backend) beginRun(ctx context.Context) (runCtx context.Context, end func(), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.running {
+		return nil, nil, errors.New("already running")
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	b.running = true
+	b.cancel = cancel
+	return runCtx, func() {
+		cancel()
+		b.mu.Lock()
+		b.running = false
+		b.cancel = nil
+		b.mu.Unlock()
+	}, nil
+}
+
 // PickGPX opens a file dialog filtered to GPX files.
 func (b *Backend) PickGPX() (string, error) {
 	ctx, err := b.currentCtx()
@@ -67,7 +104,7 @@ func (b *Backend) PickGPX() (string, error) {
 	return wruntime.OpenFileDialog(ctx, wruntime.OpenDialogOptions{
 		Title: "Select GPX file",
 		Filters: []wruntime.FileFilter{
-			{DisplayName: "GPX", Pattern: "*.gpx"},
+			{DisplayName: "GPX", Pattern: "*.gpx;*.gpx.gz;*.zip"},
 		},
 	})
 }
@@ -103,8 +140,8 @@ func (b *Backend) PickFiles() ([]string, error) {
 func (b *Backend) ClearLogs() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	if b.logBuf != nil {
-		b.logBuf.Reset()
+	if b.logs != nil {
+		b.logs.Reset()
 	}
 }
 
@@ -112,10 +149,72 @@ func (b *Backend) ClearLogs() {
 func (b *Backend) GetLogs() (string, error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	if b.logBuf == nil {
+	if b.logs == nil {
 		return "", nil
 	}
-	return b.logBuf.String(), nil
+	return b.logs.String(), nil
+}
+
+// logPageLimit caps the records one QueryLogs call returns.
+const logPageLimit = 2000
+
+// LogRequest selects a page of the log for the log window.
+type LogRequest struct {
+	Levels []string `json:"levels"` // debug, info, warning, error; empty shows all
+	Text   string   `json:"text"`   // substring, ignoring case
+	File   string   `json:"file"`   // photo name or trailing path, as for georaw log --file
+	From   string   `json:"from"`   // local time such as 2024-10-16T08:15, or RFC 3339
+	To     string   `json:"to"`
+	Offset int      `json:"offset"`
+	Limit  int      `json:"limit"`
+	Tail   bool     `json:"tail"` // count Offset back from the newest record
+}
+
+// QueryLogs returns the records of the whole log, spilled ones included, that
+// req selects, so long runs can be browsed page by page.
+func (b *Backend) QueryLogs(req LogRequest) (events.Page, error) {
+	b.mu.Lock()
+	logs := b.logs
+	b.mu.Unlock()
+	if logs == nil {
+		return events.Page{Records: []events.Record{}}, nil
+	}
+	q := events.Query{Levels: req.Levels, Text: strings.TrimSpace(req.Text), Offset: req.Offset, Limit: req.Limit, Tail: req.Tail}
+	var err error
+	if q.From, err = parseLogTime(req.From); err != nil {
+		return events.Page{}, err
+	}
+	if q.To, err = parseLogTime(req.To); err != nil {
+		return events.Page{}, err
+	}
+	if name := strings.TrimSpace(req.File); name != "" {
+		q.Match = app.FileFilter{Name: name}.Match
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	if q.Limit <= 0 || q.Limit > logPageLimit {
+		q.Limit = logPageLimit
+	}
+	return logs.Query(q)
+}
+
+// parseLogTime reads a time of the log window filter; empty is the zero time.
+func parseLogTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02T15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", value)
+	}
+	return t, nil
 }
 
 // SaveLog asks for a path and writes the in-memory log to disk.
@@ -124,11 +223,10 @@ func (b *Backend) SaveLog() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	logStr, err := b.GetLogs()
-	if err != nil {
-		return "", err
-	}
-	if strings.TrimSpace(logStr) == "" {
+	b.mu.Lock()
+	logs := b.logs
+	b.mu.Unlock()
+	if logs == nil || strings.TrimSpace(logs.String()) == "" {
 		return "", errors.New("log is empty")
 	}
 
@@ -146,7 +244,15 @@ func (b *Backend) SaveLog() (string, error) {
 	if target == "" {
 		return "", nil
 	}
-	if err := os.WriteFile(target, []byte(logStr), 0o644); err != nil {
+	file, err := os.Create(target)
+	if err != nil {
+		return "", err
+	}
+	if _, err := logs.WriteTo(file); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
 		return "", err
 	}
 	return target, nil
@@ -157,6 +263,13 @@ func (b *Backend) Version() string {
 	return version.Version
 }
 
+// ErrorMessages returns the message templates of the error codes in lang (the
+// browser's language, e.g. "de-DE"), for showing the coded failures of a run in
+// the user's language.
+func (b *Backend) ErrorMessages(lang string) map[errcode.Code]string {
+	return errcode.Messages(lang)
+}
+
 // OpenFolder opens a directory in the system file manager.
 func (b *Backend) OpenFolder(path string) error {
 	path = strings.TrimSpace(path)
@@ -186,8 +299,45 @@ type ProcessRequest struct {
 	Recursive  bool   `json:"recursive"`
 	LogLevel   string `json:"logLevel"`
 	TimeOffset string `json:"timeOffset"`
+	TimeZone   string `json:"timeZone"`
 	AutoOffset bool   `json:"autoOffset"`
 	Overwrite  bool   `json:"overwrite"`
+	MirrorGPS  bool   `json:"mirrorGps"`
+	SidecarDir string `json:"sidecarDir"` // keep sidecars in this mirrored folder instead of next to the photos
+	Force      bool   `json:"force"`      // read files again that crashed the metadata decoder in an earlier run
+	Notify     bool   `json:"notify"`     // raise a desktop notification when the run ends
+}
+
+// options converts the request into workflow options.
+func (req ProcessRequest) options() (app.Options, error) {
+	offset, err := parseOffset(req.TimeOffset)
+	if err != nil {
+		return app.Options{}, err
+	}
+	return app.Options{
+		GPXPath:      req.GPXPath,
+		Inputs:       media.SplitInputs(req.InputPath),
+		Recursive:    req.Recursive,
+		LogLevel:     req.LogLevel,
+		LogFile:      "",
+		TimeOffset:   offset,
+		TimeZone:     req.TimeZone,
+		AutoOffset:   req.AutoOffset,
+		Overwrite:    req.Overwrite,
+		MirrorGPS:    req.MirrorGPS,
+		MaxSpeed:     app.DefaultMaxSpeed,
+		PrintSummary: false,
+	}, nil
+}
+
+// TimeShiftRequest asks for the capture times of the selected photos to be corrected.
+type TimeShiftRequest struct {
+	InputPath  string `json:"inputPath"`
+	Recursive  bool   `json:"recursive"`
+	LogLevel   string `json:"logLevel"`
+	Shift      string `json:"shift"` // e.g. +1h or -00:02:30
+	SidecarDir string `json:"sidecarDir"`
+	Embedded   bool   `json:"embedded"` // also rewrite the capture time in the files themselves (with exiftool or the built-in exiv2)
 }
 
 // SeriesRequest represents user input for series tagging from the GUI.
@@ -199,7 +349,17 @@ type SeriesRequest struct {
 	Mode       string `json:"mode"`
 	Prefix     string `json:"prefix"`
 	StartIndex int    `json:"startIndex"`
+	IDTemplate string `json:"idTemplate"`
 	ExtraTags  string `json:"extraTags"`
+	Pick       string `json:"pick"`
+	Position   bool   `json:"position"`
+	SyncPairs  bool   `json:"syncPairs"`
+	Labels     bool   `json:"labels"` // write the default color labels (series.DefaultLabels)
+	DryRun     bool   `json:"dryRun"` // report detection statistics without writing anything
+	KeepTagged bool   `json:"keepTagged"`
+	SidecarDir string `json:"sidecarDir"` // keep sidecars in this mirrored folder instead of next to the photos
+	Force      bool   `json:"force"`      // read files again that crashed the metadata decoder in an earlier run
+	Notify     bool   `json:"notify"`     // raise a desktop notification when the run ends
 }
 
 // Process executes the geotagging workflow using existing CLI logic.
@@ -209,91 +369,175 @@ func (b *Backend) Process(req ProcessRequest) (*app.Summary, error) {
 		return nil, err
 	}
 
-	b.mu.Lock()
-	if b.running {
-		b.mu.Unlock()
-		return nil, errors.New("already running")
+	runCtx, end, err := b.beginRun(ctx)
+	if err != nil {
+		return nil, err
 	}
-	runCtx, cancel := context.WithCancel(ctx)
-	b.running = true
-	b.cancel = cancel
-	b.mu.Unlock()
+	defer end()
 
-	progress := newProgressEmitter(ctx, "gps")
+	bus := b.newRunEvents(ctx, "gps")
 
-	defer func() {
-		b.mu.Lock()
-		if b.cancel != nil {
-			b.cancel()
-		}
-		b.running = false
-		b.cancel = nil
-		b.mu.Unlock()
-	}()
+	opts, err := req.options()
+	if err != nil {
+		return nil, err
+	}
+	if err := useSidecarDir(req.SidecarDir, opts.Inputs); err != nil {
+		return nil, err
+	}
+	opts.Progress = bus.Progress
+	opts.ScanProgress = bus.Scan
+	opts.KeepResults = resultLimit
+	media.RetrySuspects(req.Force)
+	defer media.RetrySuspects(false)
 
-	offset, err := parseOffset(req.TimeOffset)
+	session := b.startProfiling("gps", bus)
+	defer session.Stop()
+
+	sum, err := app.RunWithLogger(runCtx, opts, bus)
+	app.CompactFiles(sum, resultLimit)
+	b.saveLastRun("gps", "GPS tagging", sum, err)
+	b.notifyCompletion(req.Notify, "GeoRAW: GPS tagging", sum, err)
+	return sum, err
+}
+
+// Watch geotags new photos as a tethering tool saves them to the input folder,
+// until Cancel. Each tagged photo is sent to the frontend as a "geotagged" event
+// and, with Notify, raises a desktop notification of its own.
+func (b *Backend) Watch(req ProcessRequest) (*app.Summary, error) {
+	ctx, err := b.currentCtx()
 	if err != nil {
 		return nil, err
 	}
 
-	// Attach in-memory log buffer
-	buf := &bytes.Buffer{}
-	b.mu.Lock()
-	b.logBuf = buf
-	b.mu.Unlock()
+	runCtx, end, err := b.beginRun(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
 
-	opts := app.Options{
-		GPXPath:      req.GPXPath,
-		InputPath:    req.InputPath,
-		Recursive:    req.Recursive,
-		LogLevel:     req.LogLevel,
-		LogFile:      "",
-		TimeOffset:   offset,
-		AutoOffset:   req.AutoOffset,
-		Overwrite:    req.Overwrite,
-		PrintSummary: false,
-		Progress: func(done, total int) {
-			progress.update(done, total)
+	bus := b.newRunEvents(ctx, "gps")
+
+	opts, err := req.options()
+	if err != nil {
+		return nil, err
+	}
+	if err := useSidecarDir(req.SidecarDir, opts.Inputs); err != nil {
+		return nil, err
+	}
+	media.RetrySuspects(req.Force)
+	defer media.RetrySuspects(false)
+
+	sum, err := app.Watch(runCtx, opts, app.WatchOptions{
+		OnResult: func(res app.FileResult) {
+			wruntime.EventsEmit(ctx, "geotagged", res)
+			if req.Notify {
+				_ = desktopNotify("GeoRAW: "+filepath.Base(res.Path), watchMessage(res))
+			}
 		},
+	}, bus)
+	app.CompactFiles(sum, resultLimit)
+	b.saveLastRun("gps", "Live geotagging", sum, err)
+	return sum, err
+}
+
+// CheckCoverage compares the photos' capture times with the GPX track so the UI
+// can warn before a run that would leave most photos out of track.
+func (b *Backend) CheckCoverage(req ProcessRequest) (*app.Coverage, error) {
+	ctx, err := b.currentCtx()
+	if err != nil {
+		return nil, err
+	}
+	opts, err := req.options()
+	if err != nil {
+		return nil, err
+	}
+	if err := useSidecarDir(req.SidecarDir, opts.Inputs); err != nil {
+		return nil, err
+	}
+	return app.CheckCoverage(ctx, opts)
+}
+
+// PreviewGPS resolves positions for the request without writing anything and
+// returns them next to the coordinates already in each sidecar.
+func (b *Backend) PreviewGPS(req ProcessRequest) ([]app.PreviewItem, error) {
+	ctx, err := b.currentCtx()
+	if err != nil {
+		return nil, err
 	}
 
-	return app.RunWithLogger(runCtx, opts, buf)
+	runCtx, end, err := b.beginRun(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	bus := b.newRunEvents(ctx, "gps")
+
+	opts, err := req.options()
+	if err != nil {
+		return nil, err
+	}
+	if err := useSidecarDir(req.SidecarDir, opts.Inputs); err != nil {
+		return nil, err
+	}
+	opts.Progress = bus.Progress
+	opts.ScanProgress = bus.Scan
+	return app.PreviewWithLogger(runCtx, opts, bus)
 }
 
-// ProcessSeries executes the series tagging workflow.
-func (b *Backend) ProcessSeries(req SeriesRequest) (*app.Summary, error) {
+// ShiftCaptureTimes writes corrected capture times for the selected photos into
+// their sidecars; later GPS runs use the corrected times.
+func (b *Backend) ShiftCaptureTimes(req TimeShiftRequest) (*app.Summary, error) {
 	ctx, err := b.currentCtx()
 	if err != nil {
 		return nil, err
 	}
 
-	b.mu.Lock()
-	if b.running {
-		b.mu.Unlock()
-		return nil, errors.New("already running")
+	runCtx, end, err := b.beginRun(ctx)
+	if err != nil {
+		return nil, err
 	}
-	runCtx, cancel := context.WithCancel(ctx)
-	b.running = true
-	b.cancel = cancel
-	b.mu.Unlock()
+	defer end()
 
-	progress := newProgressEmitter(ctx, "series")
+	bus := b.newRunEvents(ctx, "gps")
 
-	defer func() {
-		b.mu.Lock()
-		if b.cancel != nil {
-			b.cancel()
-		}
-		b.running = false
-		b.cancel = nil
-		b.mu.Unlock()
-	}()
+	shift, err := parseOffset(req.Shift)
+	if err != nil {
+		return nil, err
+	}
 
-	// Attach in-memory log buffer
-	buf := &bytes.Buffer{}
-	b.mu.Lock()
-	b.logBuf = buf
-	b.mu.Unlock()
+	opts := app.Options{
+		Inputs:          media.SplitInputs(req.InputPath),
+		Recursive:       req.Recursive,
+		LogLevel:        req.LogLevel,
+		TimeFix:         timefix.Correction{Offset: shift},
+		TimeFixEmbedded: req.Embedded,
+		ScanProgress:    bus.Scan,
+		Progress:        bus.Progress,
+	}
+	if err := useSidecarDir(req.SidecarDir, opts.Inputs); err != nil {
+		return nil, err
+	}
+	sum, err := app.FixCaptureTimesWithLogger(runCtx, opts, bus)
+	app.CompactFiles(sum, resultLimit)
+	b.saveLastRun("gps", "Capture time shift", sum, err)
+	return sum, err
+}
+
+// ProcessSeries executes the series tagging workflow.
+func (b *Backend) ProcessSeries(req SeriesRequest) (*app.Summary, error) {
+	ctx, err := b.currentCtx()
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, end, err := b.beginRun(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	bus := b.newRunEvents(ctx, "series")
 
 	mode := series.Mode(strings.ToLower(strings.TrimSpace(req.Mode)))
 	if mode == "" {
@@ -301,22 +545,60 @@ func (b *Backend) ProcessSeries(req SeriesRequest) (*app.Summary, error) {
 	}
 
 	opts := series.Options{
-		InputPath:    req.InputPath,
-		Recursive:    req.Recursive,
-		LogLevel:     req.LogLevel,
-		LogFile:      "",
-		Overwrite:    req.Overwrite,
-		Mode:         mode,
-		Prefix:       req.Prefix,
-		StartIndex:   req.StartIndex,
-		ExtraTags:    req.ExtraTags,
-		PrintSummary: false,
-		Progress: func(done, total int) {
-			progress.update(done, total)
-		},
+		Inputs:        media.SplitInputs(req.InputPath),
+		Recursive:     req.Recursive,
+		LogLevel:      req.LogLevel,
+		LogFile:       "",
+		Overwrite:     req.Overwrite,
+		Mode:          mode,
+		Prefix:        req.Prefix,
+		StartIndex:    req.StartIndex,
+		IDTemplate:    req.IDTemplate,
+		ExtraTags:     req.ExtraTags,
+		Pick:          series.PickMode(strings.ToLower(strings.TrimSpace(req.Pick))),
+		WritePosition: req.Position,
+		SyncPairs:     req.SyncPairs,
+		DryRun:        req.DryRun,
+		KeepTagged:    req.KeepTagged,
+		PrintSummary:  false,
+		ScanProgress:  bus.Scan,
+		Progress:      bus.Progress,
+	}
+
+	if req.Labels {
+		opts.Labels = series.DefaultLabels
+	}
+	if err := useSidecarDir(req.SidecarDir, opts.Inputs); err != nil {
+		return nil, err
 	}
+	media.RetrySuspects(req.Force)
+	defer media.RetrySuspects(false)
+
+	session := b.startProfiling("series", bus)
+	defer session.Stop()
 
-	return series.RunWithLogger(runCtx, opts, buf)
+	title := "Series tagging"
+	if req.DryRun {
+		title = "Series detection (dry run)"
+	}
+	sum, err := series.RunWithLogger(runCtx, opts, bus)
+	app.CompactFiles(sum, resultLimit)
+	b.saveLastRun("series", title, sum, err)
+	b.notifyCompletion(req.Notify, "GeoRAW: series tagging", sum, err)
+	return sum, err
+}
+
+// useSidecarDir points the sidecar reads and writes of a run into dir, mirroring
+// the folders below its inputs. An empty dir keeps sidecars next to the photos.
+func useSidecarDir(dir string, inputs []string) error {
+	if dir = strings.TrimSpace(dir); dir == "" {
+		return xmp.SetSidecarDir("", "")
+	}
+	root, err := media.InputRoot(inputs)
+	if err != nil {
+		return err
+	}
+	return xmp.SetSidecarDir(root, dir)
 }
 
 // parseOffset accepts human-friendly strings like "1h30m", "01:30:00", "-15m", "+90s".
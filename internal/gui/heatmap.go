@@ -0,0 +1,168 @@
+package gui
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/geocode"
+	"github.com/nir0k/GeoRAW/internal/metacache"
+)
+
+// Size of the heatmap, its cells in map pixels, and the closest zoom used for a
+// library shot in one spot.
+const (
+	heatmapWidth    = 800
+	heatmapHeight   = 480
+	heatmapCellSize = 16
+	heatmapMaxZoom  = 15
+)
+
+// HeatCell is a square of the heatmap with the photos taken inside it.
+type HeatCell struct {
+	X     int `json:"x"` // left edge in map pixels
+	Y     int `json:"y"` // top edge in map pixels
+	Count int `json:"count"`
+}
+
+// Heatmap is the density of photo positions in a library over a map.
+type Heatmap struct {
+	Root        string          `json:"root"`
+	Stats       metacache.Stats `json:"stats"`
+	Width       int             `json:"width"`
+	Height      int             `json:"height"`
+	CellSize    int             `json:"cellSize"`
+	Map         string          `json:"map,omitempty"` // PNG as a data URL; empty when the tiles could not be loaded
+	MapError    string          `json:"mapError,omitempty"`
+	Attribution string          `json:"attribution"`
+	Cells       []HeatCell      `json:"cells"` // index i is the cell of HeatmapCellFiles(i)
+	MaxCount    int             `json:"maxCount"`
+}
+
+// ScanHeatmap scans root recursively for photos with GPS (in the sidecar or the
+// file) and bins their positions into cells over a map fitted to them. Positions
+// are cached per library, so later scans only read changed files.
+func (b *Backend) ScanHeatmap(root string) (*Heatmap, error) {
+	ctx, err := b.currentCtx()
+	if err != nil {
+		return nil, err
+	}
+	root = strings.TrimSpace(root)
+	if root == "" {
+		return nil, errors.New("library folder is empty")
+	}
+	if info, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("stat %s: %w", root, err)
+	} else if !info.IsDir() {
+		return nil, errors.New("library path is not a folder")
+	}
+
+	runCtx, end, err := b.beginRun(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+	cacheDir, _ := metacache.DefaultCacheDir()
+	photos, stats, err := metacache.Scan(runCtx, root, cacheDir, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	hm := &Heatmap{
+		Root:        root,
+		Stats:       stats,
+		Width:       heatmapWidth,
+		Height:      heatmapHeight,
+		CellSize:    heatmapCellSize,
+		Attribution: sharedGeocoder().MapAttribution(),
+	}
+	var files [][]string
+	if len(photos) > 0 {
+		files = binPhotos(runCtx, hm, photos)
+	}
+	b.mu.Lock()
+	b.heatCells = files
+	b.mu.Unlock()
+	return hm, nil
+}
+
+// binPhotos fits the map to the photos, renders it, and fills hm.Cells. It returns
+// the files of each cell.
+func binPhotos(ctx context.Context, hm *Heatmap, photos []metacache.Photo) [][]string {
+	minLat, minLon := math.Inf(1), math.Inf(1)
+	maxLat, maxLon := math.Inf(-1), math.Inf(-1)
+	for _, p := range photos {
+		minLat, maxLat = math.Min(minLat, p.Latitude), math.Max(maxLat, p.Latitude)
+		minLon, maxLon = math.Min(minLon, p.Longitude), math.Max(maxLon, p.Longitude)
+	}
+	view := fitMapView(minLat, minLon, maxLat, maxLon, hm.Width, hm.Height, 2*hm.CellSize, min(heatmapMaxZoom, sharedGeocoder().MapMaxZoom()))
+
+	index := make(map[[2]int]int)
+	var files [][]string
+	for _, p := range photos {
+		x, y := geocode.Project(p.Latitude, p.Longitude, view.Zoom)
+		key := [2]int{int((x - view.Left) / float64(hm.CellSize)), int((y - view.Top) / float64(hm.CellSize))}
+		i, ok := index[key]
+		if !ok {
+			i = len(hm.Cells)
+			index[key] = i
+			hm.Cells = append(hm.Cells, HeatCell{X: key[0] * hm.CellSize, Y: key[1] * hm.CellSize})
+			files = append(files, nil)
+		}
+		hm.Cells[i].Count++
+		hm.MaxCount = max(hm.MaxCount, hm.Cells[i].Count)
+		files[i] = append(files[i], p.Path)
+	}
+	for _, f := range files {
+		sort.Strings(f)
+	}
+
+	if png, err := sharedGeocoder().BaseMap(ctx, view.CenterLat, view.CenterLon, hm.Width, hm.Height, view.Zoom); err != nil {
+		hm.MapError = err.Error()
+	} else {
+		hm.Map = "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+	}
+	return files
+}
+
+// mapView is a base map fitted to an area, with the map pixel of its top left
+// corner at Zoom.
+type mapView struct {
+	Zoom                 int
+	CenterLat, CenterLon float64
+	Left, Top            float64
+}
+
+// fitMapView fits a width×height map to the area, keeping margin pixels free
+// around it, at most at maxZoom.
+func fitMapView(minLat, minLon, maxLat, maxLon float64, width, height, margin, maxZoom int) mapView {
+	zoom := geocode.FitZoom(minLat, minLon, maxLat, maxLon, width-margin, height-margin, maxZoom)
+	x0, y0 := geocode.Project(maxLat, minLon, zoom)
+	x1, y1 := geocode.Project(minLat, maxLon, zoom)
+	centerLat, centerLon := geocode.Unproject((x0+x1)/2, (y0+y1)/2, zoom)
+
+	// The same origin StaticMap uses, so positions line up with the tiles.
+	cx, cy := geocode.Project(centerLat, centerLon, zoom)
+	return mapView{
+		Zoom:      zoom,
+		CenterLat: centerLat,
+		CenterLon: centerLon,
+		Left:      math.Floor(cx) - float64(width/2),
+		Top:       math.Floor(cy) - float64(height/2),
+	}
+}
+
+// HeatmapCellFiles returns the photos of a cell of the last ScanHeatmap result.
+func (b *Backend) HeatmapCellFiles(cell int) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if cell < 0 || cell >= len(b.heatCells) {
+		return nil, fmt.Errorf("no heatmap cell %d; scan the library again", cell)
+	}
+	return b.heatCells[cell], nil
+}
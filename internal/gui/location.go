@@ -0,0 +1,96 @@
+package gui
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/nir0k/GeoRAW/internal/geocode"
+	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// Size and zoom of the map shown in the EXIF viewer.
+const (
+	locationMapWidth  = 320
+	locationMapHeight = 180
+	locationMapZoom   = 14
+)
+
+var (
+	geocoderOnce sync.Once
+	geocoder     *geocode.Resolver
+)
+
+// sharedGeocoder returns the resolver used by every GUI lookup, so they share one
+// cache and one request rate limit.
+func sharedGeocoder() *geocode.Resolver {
+	geocoderOnce.Do(func() {
+		dir, _ := geocode.DefaultCacheDir()
+		geocoder = geocode.NewResolver(dir)
+	})
+	return geocoder
+}
+
+// PhotoLocation is the position of a photo with its place name and a small map.
+// Place and Map stay empty when the lookup failed; MapError or PlaceError says why.
+type PhotoLocation struct {
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Source      string  `json:"source"` // "sidecar" or "embedded"
+	Place       string  `json:"place,omitempty"`
+	PlaceError  string  `json:"placeError,omitempty"`
+	Map         string  `json:"map,omitempty"` // PNG as a data URL
+	MapError    string  `json:"mapError,omitempty"`
+	Attribution string  `json:"attribution"`
+}
+
+// LocatePhoto returns the position of a photo, from its sidecar or else its own
+// EXIF, with the resolved place name and a map around it. A photo without GPS
+// returns nil.
+func (b *Backend) LocatePhoto(path string) (*PhotoLocation, error) {
+	ctx, err := b.currentCtx()
+	if err != nil {
+		ctx = context.Background()
+	}
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, fmt.Errorf("path is empty")
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	resolver := sharedGeocoder()
+	loc := &PhotoLocation{Attribution: geocode.Attribution}
+	if credit := resolver.MapAttribution(); credit != geocode.Attribution {
+		loc.Attribution += " · " + credit
+	}
+	if sidecar, err := findSidecar(path); err == nil {
+		if coord, ok, err := xmp.ReadGPS(sidecar); err == nil && ok {
+			loc.Latitude, loc.Longitude, loc.Source = coord.Latitude, coord.Longitude, "sidecar"
+		}
+	}
+	if loc.Source == "" {
+		meta, err := media.ReadMetadata(path)
+		if err != nil || meta.GPS == nil {
+			return nil, nil
+		}
+		loc.Latitude, loc.Longitude, loc.Source = meta.GPS.Latitude, meta.GPS.Longitude, "embedded"
+	}
+
+	if place, err := resolver.Reverse(ctx, loc.Latitude, loc.Longitude); err != nil {
+		loc.PlaceError = err.Error()
+	} else {
+		loc.Place = place.Name
+	}
+	if png, err := resolver.StaticMap(ctx, loc.Latitude, loc.Longitude, locationMapWidth, locationMapHeight, locationMapZoom); err != nil {
+		loc.MapError = err.Error()
+	} else {
+		loc.Map = "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+	}
+	return loc, nil
+}
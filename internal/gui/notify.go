@@ -0,0 +1,83 @@
+package gui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+)
+
+// toastScript shows a Windows toast. Title and body come from the environment so
+// file names in error messages never need shell quoting.
+const toastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+[Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] | Out-Null
+$title = [Security.SecurityElement]::Escape($env:GEORAW_NOTIFY_TITLE)
+$body = [Security.SecurityElement]::Escape($env:GEORAW_NOTIFY_BODY)
+$xml = New-Object Windows.Data.Xml.Dom.XmlDocument
+$xml.LoadXml("<toast><visual><binding template='ToastGeneric'><text>$title</text><text>$body</text></binding></visual></toast>")
+$toast = New-Object Windows.UI.Notifications.ToastNotification $xml
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('GeoRAW').Show($toast)
+`
+
+// notifyCompletion raises a desktop notification describing how a run ended.
+// Failures to notify are ignored; the result is already shown in the window.
+func (b *Backend) notifyCompletion(enabled bool, title string, sum *app.Summary, runErr error) {
+	if !enabled {
+		return
+	}
+	_ = desktopNotify(title, completionMessage(sum, runErr))
+}
+
+func completionMessage(sum *app.Summary, runErr error) string {
+	switch {
+	case errors.Is(runErr, context.Canceled):
+		return "Cancelled"
+	case runErr != nil:
+		return "Failed: " + runErr.Error()
+	case sum == nil:
+		return "Finished"
+	}
+	return fmt.Sprintf("Finished: %d processed, %d unchanged, %d skipped, %d out of track, %d suspicious, %d failed, %d metadata errors",
+		sum.Processed, sum.Unchanged, sum.Skipped, sum.OutOfTrack, sum.Suspicious, sum.Failed, sum.MetaError)
+}
+
+// watchMessage describes how a photo tagged while watching ended.
+func watchMessage(res app.FileResult) string {
+	switch {
+	case res.Status == "processed" && res.Point != nil:
+		return fmt.Sprintf("Tagged at %.6f, %.6f", res.Point.Latitude, res.Point.Longitude)
+	case res.Message != "":
+		return res.Status + ": " + res.Message
+	}
+	return res.Status
+}
+
+// desktopNotify uses the platform's own notifier: a toast on Windows,
+// Notification Center on macOS and libnotify (notify-send) elsewhere.
+func desktopNotify(title, body string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", toastScript)
+		cmd.Env = append(os.Environ(), "GEORAW_NOTIFY_TITLE="+title, "GEORAW_NOTIFY_BODY="+body)
+		hideWindow(cmd)
+	case "darwin":
+		cmd = exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, body)
+	default:
+		cmd = exec.Command("notify-send", "--app-name=GeoRAW", title, body)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
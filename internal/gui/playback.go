@@ -0,0 +1,86 @@
+package gui
+
+import (
+	"encoding/base64"
+	"math"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+)
+
+// Size of the replay map and the closest zoom used for a track in one spot.
+const (
+	playbackWidth   = 800
+	playbackHeight  = 480
+	playbackMaxZoom = 17
+)
+
+// PlaybackRequest asks for a replay of the GPS tab's track and photos.
+type PlaybackRequest struct {
+	ProcessRequest
+	Bucket string `json:"bucket"` // time per replay step, e.g. 1m; empty picks one
+}
+
+// PlaybackView is a track replay with the map to draw it on. Positions are drawn
+// at their Web Mercator pixel at Zoom, less Left and Top.
+type PlaybackView struct {
+	*app.Playback
+	Width       int     `json:"width"`
+	Height      int     `json:"height"`
+	Zoom        int     `json:"zoom"`
+	Left        float64 `json:"left"`
+	Top         float64 `json:"top"`
+	Map         string  `json:"map,omitempty"` // PNG as a data URL; empty when the tiles could not be loaded
+	MapError    string  `json:"mapError,omitempty"`
+	Attribution string  `json:"attribution"`
+}
+
+// TrackPlayback cuts the track around the selected photos into time steps with
+// the photos taken in each, over a map fitted to the track. Nothing is written.
+func (b *Backend) TrackPlayback(req PlaybackRequest) (*PlaybackView, error) {
+	ctx, err := b.currentCtx()
+	if err != nil {
+		return nil, err
+	}
+	opts, err := req.options()
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := parseOffset(req.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	pb, err := app.TrackPlayback(ctx, opts, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &PlaybackView{Playback: pb, Width: playbackWidth, Height: playbackHeight, Attribution: sharedGeocoder().MapAttribution()}
+	minLat, minLon := math.Inf(1), math.Inf(1)
+	maxLat, maxLon := math.Inf(-1), math.Inf(-1)
+	extend := func(p *app.GeoPoint) {
+		if p == nil {
+			return
+		}
+		minLat, maxLat = math.Min(minLat, p.Latitude), math.Max(maxLat, p.Latitude)
+		minLon, maxLon = math.Min(minLon, p.Longitude), math.Max(maxLon, p.Longitude)
+	}
+	for _, frame := range pb.Frames {
+		extend(frame.Position)
+		for _, photo := range frame.Photos {
+			extend(photo.Position)
+		}
+	}
+	if math.IsInf(minLat, 1) {
+		view.MapError = "the track has no positions around the photos"
+		return view, nil
+	}
+
+	fit := fitMapView(minLat, minLon, maxLat, maxLon, view.Width, view.Height, 32, min(playbackMaxZoom, sharedGeocoder().MapMaxZoom()))
+	view.Zoom, view.Left, view.Top = fit.Zoom, fit.Left, fit.Top
+	if png, err := sharedGeocoder().BaseMap(ctx, fit.CenterLat, fit.CenterLon, view.Width, view.Height, fit.Zoom); err != nil {
+		view.MapError = err.Error()
+	} else {
+		view.Map = "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+	}
+	return view, nil
+}
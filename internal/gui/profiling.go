@@ -0,0 +1,56 @@
+package gui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/profiling"
+)
+
+// ToggleProfiling switches CPU/heap profiling for subsequent runs on or off.
+// It is not exposed in the UI; the frontend binds it to a hidden shortcut.
+// The returned folder is empty when profiling was turned off.
+func (b *Backend) ToggleProfiling() (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.profileDir != "" {
+		b.profileDir = ""
+		return "", nil
+	}
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	dir := filepath.Join(base, "georaw", "profiles")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create profile folder: %w", err)
+	}
+	b.profileDir = dir
+	return dir, nil
+}
+
+// startProfiling begins profiling a run into a timestamped folder when enabled.
+// A profile that cannot be started is reported to the run's log, which goes on
+// without it.
+func (b *Backend) startProfiling(kind string, log io.Writer) *profiling.Session {
+	b.mu.Lock()
+	dir := b.profileDir
+	b.mu.Unlock()
+	if dir == "" {
+		return nil
+	}
+	runDir := filepath.Join(dir, kind+"-"+time.Now().Format("20060102-150405"))
+	session, err := profiling.Start(profiling.Config{
+		Dir:       runDir,
+		TracePath: filepath.Join(runDir, "trace.out"),
+	})
+	if err != nil {
+		fmt.Fprintf(log, "Profiling is on but could not be started in %s: %v\n", runDir, err)
+		return nil
+	}
+	fmt.Fprintf(log, "Profiling this run into %s\n", runDir)
+	return session
+}
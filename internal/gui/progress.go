@@ -3,40 +3,43 @@ package gui
 import (
 	"context"
 
+	"github.com/nir0k/GeoRAW/internal/events"
 	wruntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-// progressEmitter sends progress updates to the frontend.
-// It is nil-safe: calling methods on a nil pointer is a no-op.
-type progressEmitter struct {
-	ctx     context.Context
-	context string
+// frontendSink forwards progress and scan events to the frontend. Log lines are
+// not sent; the frontend reads them from the log with QueryLogs.
+type frontendSink struct {
+	ctx context.Context
 }
 
-func newProgressEmitter(ctx context.Context, context string) *progressEmitter {
-	if ctx == nil {
-		return nil
+func (s frontendSink) Emit(e events.Event) {
+	switch e.Kind {
+	case events.KindProgress:
+		wruntime.EventsEmit(s.ctx, "progress", map[string]any{
+			"context": e.Context,
+			"current": e.Current,
+			"total":   e.Total,
+		})
+	case events.KindScan:
+		wruntime.EventsEmit(s.ctx, "scan", map[string]any{
+			"context": e.Context,
+			"dirs":    e.Dirs,
+			"files":   e.Files,
+		})
 	}
-	return &progressEmitter{
-		ctx:     ctx,
-		context: context,
-	}
-}
-
-func (p *progressEmitter) update(current, total int) {
-	if p == nil || total <= 0 {
-		return
-	}
-	p.emit(current, total)
 }
 
-func (p *progressEmitter) emit(current, total int) {
-	if p == nil {
-		return
+// newRunEvents starts a fresh log, dropping the one of the last run with its
+// spill file, and returns the bus a run reports its progress, scan and log lines
+// to.
+func (b *Backend) newRunEvents(ctx context.Context, context string) *events.Bus {
+	log := &events.Log{}
+	b.mu.Lock()
+	if b.logs != nil {
+		b.logs.Reset()
 	}
-	wruntime.EventsEmit(p.ctx, "progress", map[string]any{
-		"context": p.context,
-		"current": current,
-		"total":   total,
-	})
+	b.logs = log
+	b.mu.Unlock()
+	return events.NewBus(context, frontendSink{ctx: ctx}, log)
 }
@@ -0,0 +1,199 @@
+package gui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/nir0k/GeoRAW/internal/series"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// SelectionStats summarizes the photos selected in the EXIF browser.
+type SelectionStats struct {
+	Files       int            `json:"files"`
+	Unreadable  int            `json:"unreadable"` // files whose metadata could not be read
+	NoTime      int            `json:"noTime"`     // files without an EXIF capture time
+	WithGPS     int            `json:"withGps"`    // GPS in the sidecar or embedded in the file
+	WithSidecar int            `json:"withSidecar"`
+	First       *time.Time     `json:"first,omitempty"` // earliest capture time
+	Last        *time.Time     `json:"last,omitempty"`  // latest capture time
+	Cameras     map[string]int `json:"cameras"`         // files per camera
+	Keywords    map[string]int `json:"keywords"`        // files per sidecar keyword
+}
+
+// BatchResult reports a batch action on the photos selected in the EXIF browser.
+type BatchResult struct {
+	Changed   int      `json:"changed"`
+	Unchanged int      `json:"unchanged"`
+	Failed    []string `json:"failed,omitempty"` // "file: error" per failed photo
+}
+
+// SeriesMatrixRequest asks for the frames selected in the EXIF browser to be
+// compared per series.
+type SeriesMatrixRequest struct {
+	Paths      []string `json:"paths"`
+	ID         string   `json:"id"`     // only this series; every series when empty
+	Prefix     string   `json:"prefix"` // series tab prefix and template the IDs were tagged with
+	IDTemplate string   `json:"idTemplate"`
+}
+
+// selectionPaths cleans the selected paths, dropping blanks and duplicates.
+func selectionPaths(paths []string) ([]string, error) {
+	seen := make(map[string]bool, len(paths))
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	switch {
+	case len(out) == 0:
+		return nil, errors.New("no files selected")
+	case len(out) > maxTreeEntries:
+		return nil, fmt.Errorf("too many files selected (%d, at most %d)", len(out), maxTreeEntries)
+	}
+	return out, nil
+}
+
+// ReadSelection reads the metadata and sidecars of the selected photos and
+// returns their combined stats.
+func (b *Backend) ReadSelection(paths []string) (*SelectionStats, error) {
+	paths, err := selectionPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+	stats := &SelectionStats{Files: len(paths), Cameras: map[string]int{}, Keywords: map[string]int{}}
+	for _, path := range paths {
+		hasGPS := false
+		if sidecar, err := findSidecar(path); err == nil {
+			stats.WithSidecar++
+			_, hasGPS, _ = xmp.ReadGPS(sidecar)
+			keywords, _ := xmp.ReadKeywords(sidecar)
+			for _, k := range keywords {
+				stats.Keywords[k]++
+			}
+		}
+		meta, err := media.ReadMetadata(path)
+		switch {
+		case errors.Is(err, media.ErrNoCaptureTime):
+			stats.NoTime++
+		case err != nil:
+			stats.Unreadable++
+			if hasGPS {
+				stats.WithGPS++
+			}
+			continue
+		default:
+			t := meta.CaptureTime
+			if stats.First == nil || t.Before(*stats.First) {
+				stats.First = &t
+			}
+			if stats.Last == nil || t.After(*stats.Last) {
+				stats.Last = &t
+			}
+		}
+		if hasGPS || meta.GPS != nil {
+			stats.WithGPS++
+		}
+		camera := app.CameraName(meta.CameraMake, meta.CameraModel)
+		if camera == "" {
+			camera = "Unknown camera"
+		}
+		stats.Cameras[camera]++
+	}
+	return stats, nil
+}
+
+// CompareSeries returns the key exposure fields of the selected frames side by
+// side, one matrix per series found in their sidecar keywords.
+func (b *Backend) CompareSeries(req SeriesMatrixRequest) ([]series.Matrix, error) {
+	paths, err := selectionPaths(req.Paths)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err := b.currentCtx()
+	if err != nil {
+		ctx = context.Background()
+	}
+	opts := series.Options{Inputs: paths, Prefix: req.Prefix, IDTemplate: req.IDTemplate}
+	return series.CompareSeries(ctx, opts, req.ID)
+}
+
+// AddKeywords merges keywords into the sidecars of the selected photos,
+// creating sidecars that do not exist yet.
+func (b *Backend) AddKeywords(paths []string, keywords []string) (*BatchResult, error) {
+	var tags []string
+	for _, k := range keywords {
+		if k = strings.TrimSpace(k); k != "" {
+			tags = append(tags, k)
+		}
+	}
+	if len(tags) == 0 {
+		return nil, errors.New("no keywords given")
+	}
+	return b.batch(paths, func(path string) (bool, error) {
+		sidecar, err := findSidecar(path)
+		if err != nil {
+			sidecar = xmp.SidecarPath(path)
+		}
+		wrote, err := xmp.MergeKeywords(sidecar, tags, false)
+		if errors.Is(err, xmp.ErrKeywordsAlreadyPresent) {
+			return false, nil
+		}
+		return wrote, err
+	})
+}
+
+// StripGPS removes the GPS from the sidecars of the selected photos. GPS
+// embedded in the files themselves is left alone.
+func (b *Backend) StripGPS(paths []string) (*BatchResult, error) {
+	return b.batch(paths, func(path string) (bool, error) {
+		sidecar, err := findSidecar(path)
+		if err != nil {
+			return false, nil
+		}
+		return xmp.RemoveGPS(sidecar)
+	})
+}
+
+// batch applies a sidecar edit to every selected photo. It refuses to run next to
+// a workflow that may be writing the same sidecars.
+func (b *Backend) batch(paths []string, apply func(path string) (bool, error)) (*BatchResult, error) {
+	paths, err := selectionPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+	_, end, err := b.beginRun(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	res := &BatchResult{}
+	for _, path := range paths {
+		changed, err := apply(path)
+		switch {
+		case err != nil:
+			res.Failed = append(res.Failed, filepath.Base(path)+": "+err.Error())
+		case changed:
+			res.Changed++
+		default:
+			res.Unchanged++
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,118 @@
+package gui
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/nir0k/GeoRAW/internal/events"
+)
+
+// Files of the last run in the session folder.
+const (
+	lastRunFile = "last-run.json"
+	lastLogFile = "last-run.log"
+)
+
+// LastRun records how the last run of the GUI ended, so its results survive
+// closing the app.
+type LastRun struct {
+	Context  string       `json:"context"` // tab the run belongs to: gps or series
+	Title    string       `json:"title"`
+	Finished time.Time    `json:"finished"`
+	Error    string       `json:"error,omitempty"`
+	Summary  *app.Summary `json:"summary,omitempty"`
+}
+
+// sessionDir is where the last run is kept, in the user cache folder.
+func sessionDir() string {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	return filepath.Join(base, "georaw", "session")
+}
+
+// saveLastRun writes the summary and log of a run that just ended, replacing
+// the previous run's. Failing to save is ignored; the result is still shown.
+func (b *Backend) saveLastRun(context, title string, sum *app.Summary, runErr error) {
+	run := LastRun{Context: context, Title: title, Finished: time.Now(), Summary: sum}
+	if runErr != nil {
+		run.Error = runErr.Error()
+	}
+	dir := sessionDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return
+	}
+	b.mu.Lock()
+	logs := b.logs
+	b.mu.Unlock()
+	if logs != nil {
+		_ = writeFileAtomic(filepath.Join(dir, lastLogFile), logs.Save)
+	} else {
+		os.Remove(filepath.Join(dir, lastLogFile))
+	}
+	_ = writeFileAtomic(filepath.Join(dir, lastRunFile), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(run)
+	})
+}
+
+// writeFileAtomic writes path through a temporary file, so a crash never leaves
+// half of it.
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".session-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LastRun returns the run saved before this launch or during it, nil when there
+// is none.
+func (b *Backend) LastRun() (*LastRun, error) {
+	data, err := os.ReadFile(filepath.Join(sessionDir(), lastRunFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var run LastRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, nil // written by an incompatible version; start afresh
+	}
+	return &run, nil
+}
+
+// restoreLastLog loads the saved log of the last run into the log window,
+// unless a run has started meanwhile.
+func (b *Backend) restoreLastLog() {
+	f, err := os.Open(filepath.Join(sessionDir(), lastLogFile))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	logs, err := events.LoadLog(f)
+	if err != nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.logs != nil || b.running {
+		logs.Reset()
+		return
+	}
+	b.logs = logs
+}
@@ -0,0 +1,58 @@
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// OpenSidecar opens the XMP sidecar of a photo with the system's default editor
+// and returns its path. A photo without a sidecar is reported, never given one.
+func (b *Backend) OpenSidecar(path string) (string, error) {
+	sidecar, err := findSidecar(path)
+	if err != nil {
+		return "", err
+	}
+	return sidecar, openInEditor(sidecar).Start()
+}
+
+// RevealSidecar shows the XMP sidecar of a photo selected in the file manager and
+// returns its path.
+func (b *Backend) RevealSidecar(path string) (string, error) {
+	sidecar, err := findSidecar(path)
+	if err != nil {
+		return "", err
+	}
+	return sidecar, reveal(sidecar)
+}
+
+// findSidecar returns the existing sidecar of path: path itself for an .xmp file,
+// otherwise IMG_0001.xmp or the companion IMG_0001.JPG.xmp, preferring the
+// companion form for non-RAW files.
+func findSidecar(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "", errors.New("path is empty")
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	candidates := []string{xmp.SidecarPath(path), xmp.CompanionSidecarPath(path)}
+	switch {
+	case strings.EqualFold(filepath.Ext(path), ".xmp"):
+		candidates = []string{path}
+	case !media.SupportedRaw(path):
+		candidates[0], candidates[1] = candidates[1], candidates[0]
+	}
+	for _, c := range candidates {
+		if info, err := os.Stat(c); err == nil && !info.IsDir() {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("%s has no sidecar yet (looked for %s)", filepath.Base(path), strings.Join(candidates, ", "))
+}
@@ -0,0 +1,35 @@
+//go:build !windows
+
+package gui
+
+import (
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// openInEditor opens a file with the default text editor on macOS and the
+// default application for its type elsewhere.
+func openInEditor(path string) *exec.Cmd {
+	if runtime.GOOS == "darwin" {
+		return exec.Command("open", "-t", path)
+	}
+	return exec.Command("xdg-open", path)
+}
+
+// reveal selects path in the file manager. On Linux it asks the file manager over
+// D-Bus (org.freedesktop.FileManager1) and falls back to opening the folder.
+func reveal(path string) error {
+	if runtime.GOOS == "darwin" {
+		return exec.Command("open", "-R", path).Start()
+	}
+	uri := (&url.URL{Scheme: "file", Path: path}).String()
+	err := exec.Command("dbus-send", "--session", "--dest=org.freedesktop.FileManager1", "--type=method_call",
+		"/org/freedesktop/FileManager1", "org.freedesktop.FileManager1.ShowItems",
+		"array:string:"+uri, "string:").Run()
+	if err == nil {
+		return nil
+	}
+	return exec.Command("xdg-open", filepath.Dir(path)).Start()
+}
@@ -0,0 +1,19 @@
+package gui
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// openInEditor opens a file with the application associated with its type.
+func openInEditor(path string) *exec.Cmd {
+	return exec.Command("rundll32", "url.dll,FileProtocolHandler", path)
+}
+
+// reveal selects path in Explorer. The command line is passed verbatim, since
+// Explorer does not understand /select, inside a quoted argument.
+func reveal(path string) error {
+	cmd := exec.Command("explorer")
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: `explorer /select,"` + path + `"`}
+	return cmd.Start()
+}
@@ -0,0 +1,96 @@
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/geocode"
+	"github.com/nir0k/GeoRAW/internal/gpx"
+)
+
+// Zoom levels downloaded for a track by default: from the one fitting the
+// whole track into the heatmap to about a street map.
+const prefetchMaxZoom = 15
+
+// MapTiles reports where the GUI maps come from and how many tiles are cached.
+type MapTiles struct {
+	geocode.TileSettings
+	Cache geocode.TileCache `json:"cache"`
+}
+
+// TilePrefetchRequest asks for the map tiles around a GPX track to be downloaded.
+type TilePrefetchRequest struct {
+	GPXPath string `json:"gpxPath"`
+	MaxZoom int    `json:"maxZoom"` // prefetchMaxZoom when zero
+}
+
+// MapTiles returns the tile source, offline mode and tile cache of the maps.
+func (b *Backend) MapTiles() (*MapTiles, error) {
+	resolver := sharedGeocoder()
+	stats, err := resolver.TileCacheStats()
+	if err != nil {
+		return nil, err
+	}
+	return &MapTiles{TileSettings: resolver.TileSettings(), Cache: stats}, nil
+}
+
+// SetMapTiles switches the tile source or offline mode of every map and keeps the
+// choice for later launches. An empty URL selects OpenStreetMap.
+func (b *Backend) SetMapTiles(settings geocode.TileSettings) (*MapTiles, error) {
+	if err := sharedGeocoder().SetTileSettings(settings); err != nil {
+		return nil, err
+	}
+	return b.MapTiles()
+}
+
+// ClearMapTiles deletes the cached tiles of the current tile source.
+func (b *Backend) ClearMapTiles() (*MapTiles, error) {
+	if err := sharedGeocoder().ClearTileCache(); err != nil {
+		return nil, err
+	}
+	return b.MapTiles()
+}
+
+// PrefetchMapTiles downloads the tiles around a GPX track into the cache, so maps
+// of the trip render offline. It is refused for OpenStreetMap, see
+// geocode.Resolver.PrefetchTiles.
+func (b *Backend) PrefetchMapTiles(req TilePrefetchRequest) (*MapTiles, error) {
+	ctx, err := b.currentCtx()
+	if err != nil {
+		return nil, err
+	}
+	path := strings.TrimSpace(req.GPXPath)
+	if path == "" {
+		return nil, errors.New("pick the GPX track to download the map for")
+	}
+	track, err := gpx.LoadTrack(path)
+	if err != nil {
+		return nil, err
+	}
+	if track.PointCount() == 0 {
+		return nil, fmt.Errorf("%s has no track points", path)
+	}
+	maxZoom := req.MaxZoom
+	if maxZoom <= 0 {
+		maxZoom = prefetchMaxZoom
+	}
+
+	runCtx, end, err := b.beginRun(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	bus := b.newRunEvents(ctx, "heatmap")
+
+	resolver := sharedGeocoder()
+	minLat, minLon, maxLat, maxLon := track.Area()
+	minZoom := geocode.FitZoom(minLat, minLon, maxLat, maxLon, heatmapWidth, heatmapHeight, maxZoom)
+	fetched, err := resolver.PrefetchTiles(runCtx, minLat, minLon, maxLat, maxLon, minZoom, maxZoom, bus.Progress)
+	fmt.Fprintf(bus, "Downloaded %d map tiles for %s (zoom %d–%d)\n", fetched, path, minZoom, maxZoom)
+	if err != nil {
+		return nil, err
+	}
+	return b.MapTiles()
+}
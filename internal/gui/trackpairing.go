@@ -0,0 +1,131 @@
+package gui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+)
+
+// TrackPairingRequest asks for photo folders to be paired with the GPX files of
+// a track library. The embedded settings apply to every pairing; their GPXPath
+// and Inputs are ignored.
+type TrackPairingRequest struct {
+	ProcessRequest
+	Folders []string `json:"folders"`
+	Library string   `json:"library"`
+}
+
+// PairingJob is a confirmed folder and track pairing to run.
+type PairingJob struct {
+	Folder string `json:"folder"`
+	Track  string `json:"track"`
+}
+
+// PairedRunRequest runs confirmed pairings in turn with shared settings.
+type PairedRunRequest struct {
+	ProcessRequest
+	Jobs []PairingJob `json:"jobs"`
+}
+
+// PairedJobResult reports one pairing of a paired run.
+type PairedJobResult struct {
+	PairingJob
+	Processed int    `json:"processed"`
+	Error     string `json:"error,omitempty"`
+}
+
+// PairedRunResult reports every pairing of a paired run and their merged summary.
+type PairedRunResult struct {
+	Jobs    []PairedJobResult `json:"jobs"`
+	Summary *app.Summary      `json:"summary"`
+}
+
+// ProposeTrackPairings reads the capture times of the folders and proposes the
+// library tracks covering them, without writing anything.
+func (b *Backend) ProposeTrackPairings(req TrackPairingRequest) ([]app.TrackPairing, error) {
+	ctx, err := b.currentCtx()
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Folders) == 0 {
+		return nil, errors.New("no photo folders selected")
+	}
+	opts, err := req.options()
+	if err != nil {
+		return nil, err
+	}
+	return app.PairTracks(ctx, req.Folders, req.Library, opts)
+}
+
+// RunTrackPairings runs the confirmed pairings one after another as a single
+// cancellable run. A failing pairing is reported in its result and the next one
+// still runs; only cancelling the run returns an error.
+func (b *Backend) RunTrackPairings(req PairedRunRequest) (*PairedRunResult, error) {
+	ctx, err := b.currentCtx()
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Jobs) == 0 {
+		return nil, errors.New("no pairings selected")
+	}
+	opts, err := req.options()
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, end, err := b.beginRun(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	bus := b.newRunEvents(ctx, "gps")
+
+	opts.Progress = bus.Progress
+	opts.ScanProgress = bus.Scan
+
+	session := b.startProfiling("gps", bus)
+	defer session.Stop()
+
+	res := &PairedRunResult{}
+	var sums []*app.Summary
+	for i, job := range req.Jobs {
+		if runCtx.Err() != nil {
+			break
+		}
+		fmt.Fprintf(bus, "Pairing %d/%d: %s with %s\n", i+1, len(req.Jobs), job.Folder, job.Track)
+		jobOpts := opts
+		jobOpts.Inputs = []string{job.Folder}
+		jobOpts.GPXPath = job.Track
+		sum, err := app.RunWithLogger(runCtx, jobOpts, bus)
+		out := PairedJobResult{PairingJob: job}
+		if sum != nil {
+			out.Processed = sum.Processed
+			sums = append(sums, sum)
+		}
+		if err != nil {
+			out.Error = err.Error()
+		}
+		res.Jobs = append(res.Jobs, out)
+	}
+	runErr := runCtx.Err()
+	outcome := runErr
+	if outcome == nil {
+		failed := 0
+		for _, job := range res.Jobs {
+			if job.Error != "" {
+				failed++
+			}
+		}
+		if failed > 0 {
+			outcome = fmt.Errorf("%d of %d pairings failed", failed, len(res.Jobs))
+		}
+	}
+
+	res.Summary = app.MergeSummaries(sums)
+	app.CompactFiles(res.Summary, resultLimit)
+	b.saveLastRun("gps", "Paired runs", res.Summary, outcome)
+	b.notifyCompletion(req.Notify, "GeoRAW: paired runs", res.Summary, outcome)
+	return res, runErr
+}
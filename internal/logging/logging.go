@@ -0,0 +1,188 @@
+// Package logging writes the logs of runs. Every run gets its own Logger with
+// its level, run ID and an optional copy of its lines (the GUI keeps them in an
+// in-memory events.Log), while all runs logging to one file share a single
+// rotated file logger, so concurrent runs neither interleave partial writes nor
+// rotate the file from under each other.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nir0k/logger"
+)
+
+// Levels by increasing severity; a Logger drops the lines below its level.
+const (
+	levelTrace = iota
+	levelDebug
+	levelInfo
+	levelWarning
+	levelError
+	levelFatal
+)
+
+// levelNames are the accepted level settings.
+var levelNames = map[string]int{
+	"trace":   levelTrace,
+	"debug":   levelDebug,
+	"info":    levelInfo,
+	"warn":    levelWarning,
+	"warning": levelWarning,
+	"error":   levelError,
+	"fatal":   levelFatal,
+}
+
+// levelLabels name the levels in the copied lines, where events.Log finds them.
+var levelLabels = [...]string{"TRACE", "DEBUG", "INFO", "WARNING", "ERROR", "FATAL"}
+
+// Config describes the Logger of one run.
+type Config struct {
+	File  string    // log file shared with other runs; nothing is written to disk when empty or Settings.NoFile is set
+	Level string    // lowest level logged, to the file and Out alike; info when empty
+	RunID string    // prefixed to every line as "[run ID] ", so one run can be picked out of the file
+	Out   io.Writer // optional copy of the run's lines, e.g. an events.Bus
+}
+
+// sharedFile is the rotated logger of one log file. Writes are serialized, as
+// runs log from many goroutines.
+type sharedFile struct {
+	mu  sync.Mutex
+	log *logger.Logger
+}
+
+// files and settings are guarded by filesMu.
+var (
+	filesMu  sync.Mutex
+	files    = make(map[string]*sharedFile)
+	settings = DefaultSettings
+)
+
+// openFile returns the logger of path, created on first use and kept for the
+// runs that follow.
+func openFile(path string) (*sharedFile, error) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	filesMu.Lock()
+	defer filesMu.Unlock()
+	if f, ok := files[path]; ok {
+		return f, nil
+	}
+	// The file logs every level; each run filters its own lines.
+	l, err := logger.NewLogger(logger.LogConfig{
+		FilePath:       path,
+		Format:         "standard",
+		FileLevel:      "trace",
+		ConsoleLevel:   "fatal",
+		EnableRotation: true,
+		RotationConfig: logger.RotationConfig{
+			MaxSize:    settings.MaxSize,
+			MaxBackups: settings.MaxBackups,
+			MaxAge:     settings.MaxAge,
+			Compress:   settings.Compress,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	f := &sharedFile{log: l}
+	files[path] = f
+	return f, nil
+}
+
+// write logs msg at level.
+func (f *sharedFile) write(level int, msg string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch level {
+	case levelTrace:
+		f.log.Tracef("%s", msg)
+	case levelDebug:
+		f.log.Debugf("%s", msg)
+	case levelInfo:
+		f.log.Infof("%s", msg)
+	case levelWarning:
+		f.log.Warningf("%s", msg)
+	default:
+		f.log.Errorf("%s", msg)
+	}
+}
+
+// Logger logs the lines of one run. It is safe for concurrent use.
+type Logger struct {
+	level  int
+	prefix string
+	file   *sharedFile
+	out    *log.Logger
+}
+
+// New returns the logger of a run. Runs given the same file share its rotated
+// logger for the life of the process. With Settings.NoFile the lines go to stderr
+// instead of the file.
+func New(cfg Config) (*Logger, error) {
+	name := strings.ToLower(strings.TrimSpace(cfg.Level))
+	if name == "" {
+		name = "info"
+	}
+	level, ok := levelNames[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown log level %q (expected trace, debug, info, warning, error or fatal)", cfg.Level)
+	}
+	l := &Logger{level: level}
+	if cfg.RunID != "" {
+		l.prefix = "[run " + cfg.RunID + "] "
+	}
+	out := cfg.Out
+	switch file := strings.TrimSpace(cfg.File); {
+	case Current().NoFile:
+		out = os.Stderr
+		if cfg.Out != nil {
+			out = io.MultiWriter(cfg.Out, os.Stderr)
+		}
+	case file != "":
+		shared, err := openFile(file)
+		if err != nil {
+			return nil, err
+		}
+		l.file = shared
+	}
+	if out != nil {
+		l.out = log.New(out, "", 0)
+	}
+	return l, nil
+}
+
+// Tracef logs at trace level.
+func (l *Logger) Tracef(format string, args ...interface{}) { l.logf(levelTrace, format, args...) }
+
+// Debugf logs at debug level.
+func (l *Logger) Debugf(format string, args ...interface{}) { l.logf(levelDebug, format, args...) }
+
+// Infof logs at info level.
+func (l *Logger) Infof(format string, args ...interface{}) { l.logf(levelInfo, format, args...) }
+
+// Warningf logs at warning level.
+func (l *Logger) Warningf(format string, args ...interface{}) { l.logf(levelWarning, format, args...) }
+
+// Errorf logs at error level.
+func (l *Logger) Errorf(format string, args ...interface{}) { l.logf(levelError, format, args...) }
+
+func (l *Logger) logf(level int, format string, args ...interface{}) {
+	if level < l.level {
+		return
+	}
+	msg := l.prefix + fmt.Sprintf(format, args...)
+	if l.file != nil {
+		l.file.write(level, msg)
+	}
+	if l.out != nil {
+		l.out.Printf("%s [%s] %s", time.Now().Format("2006-01-02 15:04:05"), levelLabels[level], msg)
+	}
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment variables that change the log settings, for the GUI and for
+// containers where flags are awkward; CLI flags take their defaults from them.
+const (
+	EnvMaxSize    = "GEORAW_LOG_MAX_SIZE"    // megabytes
+	EnvMaxBackups = "GEORAW_LOG_MAX_BACKUPS" // rotated files kept
+	EnvMaxAge     = "GEORAW_LOG_MAX_AGE"     // days
+	EnvCompress   = "GEORAW_LOG_COMPRESS"    // true or false
+	EnvNoFile     = "GEORAW_NO_LOG_FILE"     // true to log to stderr only
+)
+
+// Settings decide how the log files are rotated, or that none is written.
+type Settings struct {
+	MaxSize    int  // rotate a log file when it reaches this many megabytes
+	MaxBackups int  // rotated files to keep; 0 keeps all of them
+	MaxAge     int  // days to keep rotated files; 0 keeps them regardless of age
+	Compress   bool // gzip rotated files
+	NoFile     bool // write no log file; runs log to stderr instead
+}
+
+// DefaultSettings rotate at 25 MB and keep five compressed backups for 30 days.
+var DefaultSettings = Settings{MaxSize: 25, MaxBackups: 5, MaxAge: 30, Compress: true}
+
+// Validate rejects sizes and counts that make no sense.
+func (s Settings) Validate() error {
+	switch {
+	case s.MaxSize < 1:
+		return fmt.Errorf("log max size must be at least 1 MB")
+	case s.MaxBackups < 0:
+		return fmt.Errorf("log max backups must not be negative")
+	case s.MaxAge < 0:
+		return fmt.Errorf("log max age must not be negative")
+	}
+	return nil
+}
+
+// SettingsFromEnv returns DefaultSettings with the environment variables that are
+// set applied.
+func SettingsFromEnv() (Settings, error) {
+	s := DefaultSettings
+	for _, v := range []struct {
+		name string
+		dst  *int
+	}{{EnvMaxSize, &s.MaxSize}, {EnvMaxBackups, &s.MaxBackups}, {EnvMaxAge, &s.MaxAge}} {
+		raw := strings.TrimSpace(os.Getenv(v.name))
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return s, fmt.Errorf("%s: invalid number %q", v.name, raw)
+		}
+		*v.dst = n
+	}
+	for _, v := range []struct {
+		name string
+		dst  *bool
+	}{{EnvCompress, &s.Compress}, {EnvNoFile, &s.NoFile}} {
+		raw := strings.TrimSpace(os.Getenv(v.name))
+		if raw == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return s, fmt.Errorf("%s: invalid boolean %q", v.name, raw)
+		}
+		*v.dst = b
+	}
+	return s, s.Validate()
+}
+
+// Configure sets how the log files of later runs are written. Files already
+// opened keep the rotation they were opened with.
+func Configure(s Settings) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	filesMu.Lock()
+	defer filesMu.Unlock()
+	settings = s
+	return nil
+}
+
+// Current returns the settings in use.
+func Current() Settings {
+	filesMu.Lock()
+	defer filesMu.Unlock()
+	return settings
+}
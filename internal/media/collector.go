@@ -1,56 +1,145 @@
 package media
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/pathnorm"
 )
 
-// CollectFiles resolves the input path into a list of files to process.
-// It supports direct file paths, directories, and glob patterns.
-func CollectFiles(input string, recursive bool) ([]string, error) {
-	inputs := splitInputs(input)
+// CollectFiles resolves the inputs into a list of files to process. Each input is
+// a file, a directory, or a glob pattern (including `**`), taken whole: a path
+// that exists as written is never expanded as a pattern.
+func CollectFiles(inputs []string, recursive bool) ([]string, error) {
+	var results []string
+	err := WalkFiles(inputs, recursive, func(path string) error {
+		results = append(results, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// WalkFiles resolves the input like CollectFiles but calls fn for every file as soon
+// as it is found, so callers can start processing before a large walk completes.
+// Each path is reported once. A non-nil error from fn stops the walk and is returned.
+func WalkFiles(inputs []string, recursive bool, fn func(path string) error) error {
+	_, err := WalkFilesWith(inputs, recursive, WalkOptions{}, fn)
+	return err
+}
+
+// DefaultProgressInterval is how often WalkFilesWith reports progress by default.
+const DefaultProgressInterval = 500 * time.Millisecond
+
+// WalkOptions limit a walk and report its progress. The zero value walks
+// everything silently.
+type WalkOptions struct {
+	MaxFiles         int                // stop after this many files; 0 means no limit
+	Progress         func(WalkProgress) // called while walking and once when done
+	ProgressInterval time.Duration      // least time between progress calls; DefaultProgressInterval when zero
+}
+
+// WalkProgress counts what a walk has seen so far.
+type WalkProgress struct {
+	Dirs      int  // folders read
+	Files     int  // files reported to the callback
+	Truncated bool // the walk stopped at WalkOptions.MaxFiles
+	Done      bool // the walk finished; this is the last call
+}
+
+// errWalkLimit stops the walk once MaxFiles files were reported.
+var errWalkLimit = errors.New("file limit reached")
+
+// WalkFilesWith is WalkFiles with a file limit and progress reports. When the
+// limit is reached the walk stops without an error and the returned progress has
+// Truncated set.
+func WalkFilesWith(inputs []string, recursive bool, opts WalkOptions, fn func(path string) error) (WalkProgress, error) {
+	var state WalkProgress
 	if len(inputs) == 0 {
-		return nil, fmt.Errorf("input path is empty")
+		return state, fmt.Errorf("input path is empty")
 	}
 
-	unique := make(map[string]struct{})
-	var results []string
+	interval := opts.ProgressInterval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+	lastReport := time.Now()
+	report := func() {
+		if opts.Progress != nil && time.Since(lastReport) >= interval {
+			lastReport = time.Now()
+			opts.Progress(state)
+		}
+	}
+	enterDir := func() {
+		state.Dirs++
+		report()
+	}
 
-	addFile := func(path string) {
-		if _, exists := unique[path]; !exists {
-			unique[path] = struct{}{}
-			results = append(results, path)
+	unique := make(map[string]struct{})
+	addFile := func(path string) error {
+		key := pathnorm.Key(path)
+		if _, exists := unique[key]; exists {
+			return nil
 		}
+		if opts.MaxFiles > 0 && state.Files >= opts.MaxFiles {
+			state.Truncated = true
+			return errWalkLimit
+		}
+		unique[key] = struct{}{}
+		state.Files++
+		report()
+		return fn(path)
 	}
 
+	err := walkInputs(inputs, recursive, enterDir, addFile)
+	if errors.Is(err, errWalkLimit) {
+		err = nil
+	}
+	state.Done = true
+	if opts.Progress != nil {
+		opts.Progress(state)
+	}
+	return state, err
+}
+
+func walkInputs(inputs []string, recursive bool, enterDir func(), addFile func(string) error) error {
 	for _, in := range inputs {
 		matches, err := expandInput(in)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		for _, candidate := range matches {
 			info, err := os.Stat(candidate)
 			if err != nil {
-				return nil, fmt.Errorf("stat %s: %w", candidate, err)
+				return fmt.Errorf("stat %s: %w", candidate, err)
 			}
 			if info.IsDir() {
-				err = walkDir(candidate, recursive, addFile)
-				if err != nil {
-					return nil, err
+				if err := walkDir(candidate, recursive, enterDir, addFile); err != nil {
+					return err
 				}
 				continue
 			}
-			addFile(candidate)
+			if err := addFile(candidate); err != nil {
+				return err
+			}
 		}
 	}
 
-	return results, nil
+	return nil
 }
 
-func splitInputs(raw string) []string {
+// SplitInputs splits an input typed into one field, as the GUI takes it, into
+// its entries: separated by ';' or newlines, with surrounding spaces trimmed.
+// Inputs that come as a list already, like repeated flags or file lists, must
+// not be joined and split again.
+func SplitInputs(raw string) []string {
 	raw = strings.TrimSpace(raw)
 	if raw == "" {
 		return nil
@@ -68,8 +157,8 @@ func splitInputs(raw string) []string {
 }
 
 func expandInput(input string) ([]string, error) {
-	if containsGlob(input) {
-		matches, err := filepath.Glob(input)
+	if containsGlob(input) && !exists(input) {
+		matches, err := globFiles(input)
 		if err != nil {
 			return nil, fmt.Errorf("expand glob: %w", err)
 		}
@@ -81,31 +170,77 @@ func expandInput(input string) ([]string, error) {
 	return []string{input}, nil
 }
 
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
 func containsGlob(path string) bool {
 	return strings.ContainsAny(path, "*?[")
 }
 
-func walkDir(root string, recursive bool, add func(string)) error {
+func walkDir(root string, recursive bool, enterDir func(), add func(string) error) error {
 	if recursive {
 		return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
 			if err != nil {
 				return err
 			}
+			if d.IsDir() {
+				enterDir()
+				return nil
+			}
 			if d.Type().IsRegular() {
-				add(path)
+				return add(path)
 			}
 			return nil
 		})
 	}
 
+	enterDir()
 	entries, err := os.ReadDir(root)
 	if err != nil {
 		return fmt.Errorf("read dir %s: %w", root, err)
 	}
 	for _, entry := range entries {
 		if entry.Type().IsRegular() {
-			add(filepath.Join(root, entry.Name()))
+			if err := add(filepath.Join(root, entry.Name())); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
+
+// InputRoot returns the deepest folder containing everything the inputs can match:
+// the folder itself, a file's folder, or the wildcard-free start of a glob,
+// combined over all inputs.
+func InputRoot(inputs []string) (string, error) {
+	root := ""
+	for _, in := range inputs {
+		dir := in
+		if containsGlob(in) && !exists(in) {
+			dir, _ = splitGlob(in)
+		} else if info, err := os.Stat(in); err != nil || !info.IsDir() {
+			dir = filepath.Dir(in)
+		}
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return "", fmt.Errorf("resolve %s: %w", dir, err)
+		}
+		if root == "" {
+			root = abs
+			continue
+		}
+		for root != abs && !strings.HasPrefix(abs, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator)) {
+			parent := filepath.Dir(root)
+			if parent == root {
+				break
+			}
+			root = parent
+		}
+	}
+	if root == "" {
+		return "", fmt.Errorf("input path is empty")
+	}
+	return root, nil
+}
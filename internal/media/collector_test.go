@@ -0,0 +1,67 @@
+package media
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWalkFilesNormalization checks that a name present in both Unicode
+// normalizations, as after copying between macOS and Linux, is reported once.
+func TestWalkFilesNormalization(t *testing.T) {
+	dir := t.TempDir()
+	nfc := filepath.Join(dir, "Caf\u00e9.CR3")
+	nfd := filepath.Join(dir, "Cafe\u0301.CR3")
+	other := filepath.Join(dir, "Cafe.CR3")
+	for _, path := range []string{nfc, nfd, other} {
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := CollectFiles([]string{dir}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("CollectFiles = %q, want Café.CR3 once and Cafe.CR3", files)
+	}
+
+	files, err = CollectFiles([]string{nfd, nfc}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0] != nfd {
+		t.Errorf("CollectFiles of both forms = %q, want only %q", files, nfd)
+	}
+}
+
+// TestCollectFilesLiteralNames checks that listed names are taken whole: not
+// split on ';', trimmed or expanded as patterns.
+func TestCollectFilesLiteralNames(t *testing.T) {
+	dir := t.TempDir()
+	var inputs []string
+	for _, name := range []string{"a;b.CR3", " lead.CR3", "[1].CR3"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, path)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "1.CR3"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := CollectFiles(inputs, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(inputs) {
+		t.Fatalf("CollectFiles = %q, want %q", files, inputs)
+	}
+	for i := range inputs {
+		if files[i] != inputs[i] {
+			t.Errorf("CollectFiles[%d] = %q, want %q", i, files[i], inputs[i])
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package media
+
+import (
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// droneXMPLimit is how far into a drone photo its XMP packet is looked for; DJI
+// writes it next to the first IFD.
+const droneXMPLimit = 4 << 20
+
+var (
+	droneAttrRegex    = regexp.MustCompile(`drone-dji:(\w+)\s*=\s*"([^"]*)"`)
+	droneElementRegex = regexp.MustCompile(`<drone-dji:(\w+)>\s*([^<]*?)\s*</drone-dji:\w+>`)
+)
+
+// DroneTelemetry is the flight data DJI drones record in the XMP packet of their
+// photos (drone-dji namespace). Fields are nil when not recorded.
+type DroneTelemetry struct {
+	Latitude         *float64
+	Longitude        *float64
+	AbsoluteAltitude *float64 // meters above sea level
+	RelativeAltitude *float64 // meters above the take-off point
+	GimbalYaw        *float64 // degrees, 0 is north
+	GimbalPitch      *float64 // degrees, -90 is straight down
+	GimbalRoll       *float64
+	FlightYaw        *float64 // heading of the aircraft
+}
+
+// Heading returns the direction the camera pointed in degrees from north: the
+// gimbal yaw, or the aircraft's heading when the gimbal yaw is missing.
+func (d *DroneTelemetry) Heading() (float64, bool) {
+	if d == nil {
+		return 0, false
+	}
+	yaw := d.GimbalYaw
+	if yaw == nil {
+		yaw = d.FlightYaw
+	}
+	if yaw == nil {
+		return 0, false
+	}
+	return math.Mod(math.Mod(*yaw, 360)+360, 360), true
+}
+
+// position returns the drone's GPS fix, nil when it has none.
+func (d *DroneTelemetry) position() *GPSPosition {
+	if d == nil || d.Latitude == nil || d.Longitude == nil || (*d.Latitude == 0 && *d.Longitude == 0) {
+		return nil
+	}
+	return &GPSPosition{
+		Latitude:  *d.Latitude,
+		Longitude: *d.Longitude,
+		Altitude:  d.AbsoluteAltitude,
+	}
+}
+
+// isDroneCamera reports whether make/model is a DJI aircraft camera, including
+// the Hasselblad cameras of the Mavic series.
+func isDroneCamera(make, model string) bool {
+	if strings.EqualFold(make, "DJI") {
+		return true
+	}
+	model = strings.ToUpper(model)
+	return strings.EqualFold(make, "Hasselblad") && (strings.HasPrefix(model, "L1D") || strings.HasPrefix(model, "L2D") || strings.HasPrefix(model, "L3D"))
+}
+
+// readDroneTelemetry finds the drone-dji properties in the XMP packet of a drone
+// photo. It returns nil when the file has none.
+func readDroneTelemetry(file *os.File) *DroneTelemetry {
+	buf := make([]byte, droneXMPLimit)
+	n, err := file.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return nil
+	}
+	return parseDroneTelemetry(string(buf[:n]))
+}
+
+func parseDroneTelemetry(data string) *DroneTelemetry {
+	var d DroneTelemetry
+	found := false
+	set := func(name, value string) {
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return
+		}
+		var field **float64
+		switch name {
+		case "GpsLatitude":
+			field = &d.Latitude
+		case "GpsLongitude", "GpsLongtitude": // older firmware misspells it
+			field = &d.Longitude
+		case "AbsoluteAltitude":
+			field = &d.AbsoluteAltitude
+		case "RelativeAltitude":
+			field = &d.RelativeAltitude
+		case "GimbalYawDegree":
+			field = &d.GimbalYaw
+		case "GimbalPitchDegree":
+			field = &d.GimbalPitch
+		case "GimbalRollDegree":
+			field = &d.GimbalRoll
+		case "FlightYawDegree":
+			field = &d.FlightYaw
+		default:
+			return
+		}
+		*field = &v
+		found = true
+	}
+	for _, m := range droneAttrRegex.FindAllStringSubmatch(data, -1) {
+		set(m[1], m[2])
+	}
+	for _, m := range droneElementRegex.FindAllStringSubmatch(data, -1) {
+		set(m[1], m[2])
+	}
+	if !found {
+		return nil
+	}
+	return &d
+}
@@ -0,0 +1,172 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// embedTag is a tag written into a file, named the exiv2 way.
+type embedTag struct {
+	Key, Value string
+	Add        bool // add Value to the list the tag holds instead of replacing it
+	Optional   bool // skip the tag when the format cannot hold its family, e.g. IPTC
+}
+
+// nativeWrite writes tags into a file without exiftool. It is set when GeoRAW
+// is built with the exiv2 tag (see embed_exiv2.go) and nil otherwise.
+var nativeWrite func(path string, tags []embedTag) error
+
+// embedExt lists the formats GPS and keywords are written into.
+var embedExt = map[string]bool{".jpg": true, ".jpeg": true, ".tif": true, ".tiff": true, ".dng": true}
+
+// SupportedEmbed reports whether WriteGPS and AddKeywords write into the file:
+// JPEG, TIFF and DNG. Other RAW formats keep their metadata in sidecars only.
+func SupportedEmbed(path string) bool {
+	return embedExt[strings.ToLower(filepath.Ext(path))]
+}
+
+// EmbeddedWriter names what writes into files: "exiv2" when GeoRAW is built
+// with it, otherwise "exiftool".
+func EmbeddedWriter() string {
+	if nativeWrite != nil {
+		return "exiv2"
+	}
+	return "exiftool"
+}
+
+// WriteGPS writes the position and its UTC time t into the EXIF of the file,
+// replacing GPS it had. A nil alt leaves the altitude alone.
+func WriteGPS(path string, lat, lon float64, alt *float64, t time.Time) error {
+	t = t.UTC()
+	latRef, lonRef := "N", "E"
+	if lat < 0 {
+		latRef = "S"
+	}
+	if lon < 0 {
+		lonRef = "W"
+	}
+	tags := []embedTag{
+		{Key: "Exif.GPSInfo.GPSVersionID", Value: "2 3 0 0"},
+		{Key: "Exif.GPSInfo.GPSLatitudeRef", Value: latRef},
+		{Key: "Exif.GPSInfo.GPSLatitude", Value: exifDegrees(lat)},
+		{Key: "Exif.GPSInfo.GPSLongitudeRef", Value: lonRef},
+		{Key: "Exif.GPSInfo.GPSLongitude", Value: exifDegrees(lon)},
+		{Key: "Exif.GPSInfo.GPSMapDatum", Value: "WGS-84"},
+		{Key: "Exif.GPSInfo.GPSDateStamp", Value: t.Format("2006:01:02")},
+		{Key: "Exif.GPSInfo.GPSTimeStamp", Value: fmt.Sprintf("%d/1 %d/1 %d/1", t.Hour(), t.Minute(), t.Second())},
+	}
+	args := []string{
+		fmt.Sprintf("-GPSLatitude=%.8f", math.Abs(lat)), "-GPSLatitudeRef=" + latRef,
+		fmt.Sprintf("-GPSLongitude=%.8f", math.Abs(lon)), "-GPSLongitudeRef=" + lonRef,
+		"-GPSMapDatum=WGS-84",
+		"-GPSDateStamp=" + t.Format("2006:01:02"), "-GPSTimeStamp=" + t.Format("15:04:05"),
+	}
+	if alt != nil {
+		ref := "0"
+		if *alt < 0 {
+			ref = "1"
+		}
+		tags = append(tags,
+			embedTag{Key: "Exif.GPSInfo.GPSAltitudeRef", Value: ref},
+			embedTag{Key: "Exif.GPSInfo.GPSAltitude", Value: fmt.Sprintf("%d/100", int64(math.Round(math.Abs(*alt)*100)))},
+		)
+		args = append(args, fmt.Sprintf("-GPSAltitude=%.2f", math.Abs(*alt)), "-GPSAltitudeRef#="+ref)
+	}
+	return writeEmbedded(path, tags, args)
+}
+
+// AddKeywords adds the keywords the file does not have yet to its XMP dc:subject
+// and, where the format holds IPTC, its IPTC keywords.
+func AddKeywords(path string, keywords []string) error {
+	var tags []embedTag
+	var args []string
+	for _, k := range keywords {
+		tags = append(tags,
+			embedTag{Key: "Xmp.dc.subject", Value: k, Add: true},
+			embedTag{Key: "Iptc.Application2.Keywords", Value: k, Add: true, Optional: true},
+		)
+		args = append(args, "-XMP-dc:Subject-="+k, "-XMP-dc:Subject+="+k, "-IPTC:Keywords-="+k, "-IPTC:Keywords+="+k)
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	return writeEmbedded(path, tags, args)
+}
+
+// exifDegrees formats a coordinate as the degrees, minutes and seconds
+// rationals of an EXIF GPS tag, dropping the sign the reference tag carries.
+func exifDegrees(v float64) string {
+	v = math.Abs(v)
+	deg := int64(v)
+	mins := int64((v - float64(deg)) * 60)
+	secs := int64(math.Round(((v-float64(deg))*60 - float64(mins)) * 60 * 10000))
+	if secs >= 60*10000 {
+		secs -= 60 * 10000
+		mins++
+	}
+	if mins >= 60 {
+		mins -= 60
+		deg++
+	}
+	return fmt.Sprintf("%d/1 %d/1 %d/10000", deg, mins, secs)
+}
+
+// writeEmbedded writes tags into the file with the built-in exiv2 when
+// available, and with exiftool and args otherwise or when exiv2 cannot write
+// the file. The file's modification time is kept.
+func writeEmbedded(path string, tags []embedTag, args []string) error {
+	if nativeWrite == nil {
+		return runExiftool(path, args)
+	}
+	err := keepModTime(path, func() error { return nativeWrite(path, tags) })
+	if err == nil {
+		return nil
+	}
+	if _, lookErr := exec.LookPath("exiftool"); lookErr != nil {
+		return fmt.Errorf("exiv2: %w", err)
+	}
+	if fallbackErr := runExiftool(path, args); fallbackErr != nil {
+		return fmt.Errorf("exiv2: %v; %w", err, fallbackErr)
+	}
+	return nil
+}
+
+// runExiftool writes into the file with exiftool, keeping its dates.
+func runExiftool(path string, args []string) error {
+	exe, err := exec.LookPath("exiftool")
+	if err != nil {
+		return fmt.Errorf("exiftool not found in PATH; install it and retry")
+	}
+	args = append([]string{"-q", "-q", "-overwrite_original", "-P", "-m"}, args...)
+	args = append(args, path)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(exe, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("exiftool: %s", msg)
+		}
+		return fmt.Errorf("exiftool error: %w", err)
+	}
+	return nil
+}
+
+// keepModTime runs write and restores the modification time path had before,
+// as exiftool's -P does.
+func keepModTime(path string, write func() error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	return os.Chtimes(path, info.ModTime(), info.ModTime())
+}
@@ -0,0 +1,135 @@
+//go:build exiv2 && cgo
+
+package media
+
+/*
+#cgo pkg-config: gexiv2
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <gexiv2/gexiv2.h>
+
+#define GEORAW_ADD 1
+#define GEORAW_OPTIONAL 2
+
+// georaw_supports reports whether the file can hold the family of tag.
+static int georaw_supports(GExiv2Metadata *meta, const char *tag) {
+	if (strncmp(tag, "Xmp.", 4) == 0) {
+		return gexiv2_metadata_get_supports_xmp(meta);
+	}
+	if (strncmp(tag, "Iptc.", 5) == 0) {
+		return gexiv2_metadata_get_supports_iptc(meta);
+	}
+	return gexiv2_metadata_get_supports_exif(meta);
+}
+
+// georaw_add_value adds value to the list tag holds unless it is there already.
+static int georaw_add_value(GExiv2Metadata *meta, const char *tag, const char *value, GError **err) {
+	gchar **old = gexiv2_metadata_try_get_tag_multiple(meta, tag, err);
+	if (*err != NULL) {
+		return 0;
+	}
+	int n = 0;
+	for (; old != NULL && old[n] != NULL; n++) {
+		if (strcmp(old[n], value) == 0) {
+			g_strfreev(old);
+			return 1;
+		}
+	}
+	const gchar **values = calloc(n + 2, sizeof(gchar *));
+	for (int i = 0; i < n; i++) {
+		values[i] = old[i];
+	}
+	values[n] = value;
+	int ok = gexiv2_metadata_try_set_tag_multiple(meta, tag, values, err);
+	free(values);
+	g_strfreev(old);
+	return ok;
+}
+
+// georaw_write_tags sets n tags of the file at path and saves it. A tag flagged
+// GEORAW_ADD gets its value added to its list, and one flagged GEORAW_OPTIONAL
+// is left out when the format cannot hold its family. On failure it returns 0
+// with the reason in *msg, which the caller frees.
+static int georaw_write_tags(const char *path, char **tags, char **values, int *flags, int n, char **msg) {
+	GError *err = NULL;
+	const char *unsupported = NULL;
+	GExiv2Metadata *meta = gexiv2_metadata_new();
+	int ok = gexiv2_metadata_open_path(meta, path, &err);
+	for (int i = 0; ok && i < n; i++) {
+		if (!georaw_supports(meta, tags[i])) {
+			if (flags[i] & GEORAW_OPTIONAL) {
+				continue;
+			}
+			unsupported = tags[i];
+			ok = 0;
+		} else if (flags[i] & GEORAW_ADD) {
+			ok = georaw_add_value(meta, tags[i], values[i], &err);
+		} else {
+			ok = gexiv2_metadata_try_set_tag_string(meta, tags[i], values[i], &err);
+		}
+	}
+	if (ok) {
+		ok = gexiv2_metadata_save_file(meta, path, &err);
+	}
+	if (!ok && unsupported != NULL) {
+		size_t size = strlen(unsupported) + 32;
+		*msg = malloc(size);
+		snprintf(*msg, size, "the format cannot hold %s", unsupported);
+	} else if (!ok) {
+		*msg = strdup(err != NULL ? err->message : "unknown error");
+	}
+	if (err != NULL) {
+		g_error_free(err);
+	}
+	g_object_unref(meta);
+	return ok;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"sync"
+	"unsafe"
+)
+
+// Built with -tags exiv2 (and cgo), embedded writes go through gexiv2, the C
+// interface of the exiv2 library (0.14 or newer), so they work without exiftool.
+func init() {
+	nativeWrite = exiv2Write
+}
+
+var exiv2Init sync.Once
+
+// exiv2Write sets the tags in the file and saves it in place.
+func exiv2Write(path string, tags []embedTag) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	exiv2Init.Do(func() { C.gexiv2_initialize() })
+
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+	cTags := make([]*C.char, len(tags))
+	cValues := make([]*C.char, len(tags))
+	cFlags := make([]C.int, len(tags))
+	for i, tag := range tags {
+		cTags[i] = C.CString(tag.Key)
+		defer C.free(unsafe.Pointer(cTags[i]))
+		cValues[i] = C.CString(tag.Value)
+		defer C.free(unsafe.Pointer(cValues[i]))
+		if tag.Add {
+			cFlags[i] |= C.GEORAW_ADD
+		}
+		if tag.Optional {
+			cFlags[i] |= C.GEORAW_OPTIONAL
+		}
+	}
+	var msg *C.char
+	if C.georaw_write_tags(cPath, &cTags[0], &cValues[0], &cFlags[0], C.int(len(tags)), &msg) == 0 {
+		defer C.free(unsafe.Pointer(msg))
+		return errors.New(C.GoString(msg))
+	}
+	return nil
+}
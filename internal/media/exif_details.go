@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/evanoberholster/imagemeta/exif2"
+	"github.com/nir0k/GeoRAW/internal/fsretry"
 	"github.com/nir0k/GeoRAW/internal/xmp"
 )
 
@@ -68,7 +69,7 @@ func ReadExifDetails(path string, includeXmp bool) (*ExifDetails, error) {
 		return nil, fmt.Errorf("file type is not supported for EXIF viewing")
 	}
 
-	file, err := os.Open(path)
+	file, err := fsretry.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("open %s: %w", path, err)
 	}
@@ -312,7 +313,7 @@ func resolutionLabel(x, y uint32, unit uint16) string {
 
 func readKeywords(rawPath string) []string {
 	sidecar := xmp.SidecarPath(rawPath)
-	data, err := os.ReadFile(sidecar)
+	data, err := fsretry.ReadFile(sidecar)
 	if err != nil || len(data) == 0 {
 		return nil
 	}
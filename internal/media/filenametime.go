@@ -0,0 +1,203 @@
+package media
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultFilenameTimePatterns are the file name layouts of phones, action cams
+// and export tools tried when no patterns are configured.
+var DefaultFilenameTimePatterns = []string{
+	"%Y%m%d_%H%M%S",     // IMG_20240712_101530, PXL_20240712_101530123
+	"%Y%m%d-%H%M%S",     // 20240712-101530
+	"%Y%m%dT%H%M%S",     // 20240712T101530
+	"%Y-%m-%d %H.%M.%S", // 2024-07-12 10.15.30
+	"%Y-%m-%d_%H-%M-%S", // 2024-07-12_10-15-30
+	"%Y-%m-%d-%H-%M-%S", // 2024-07-12-10-15-30
+	"%Y-%m-%d %H-%M-%S", // 2024-07-12 10-15-30
+	"%Y%m%d%H%M%S",      // 20240712101530
+}
+
+var monthNames = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+// FilenameTimePattern reads a capture time from a file name. Patterns use
+// strptime-like directives: %Y (4-digit year), %y (2-digit year), %m, %d, %H,
+// %M, %S (2 digits each), %b (month name such as Jul), %j (day of the year),
+// %f (fraction of a second) and %% (a percent sign); * matches any text and ?
+// any single character. The pattern may match anywhere in the name.
+type FilenameTimePattern struct {
+	spec   string
+	re     *regexp.Regexp
+	fields []byte // directive letter of each capture group
+}
+
+// ParseFilenameTimePattern compiles spec. It must name the year and either the
+// month and day or the day of the year.
+func ParseFilenameTimePattern(spec string) (FilenameTimePattern, error) {
+	p := FilenameTimePattern{spec: spec}
+	var expr strings.Builder
+	seen := map[byte]bool{}
+	for i := 0; i < len(spec); i++ {
+		c := spec[i]
+		switch c {
+		case '*':
+			expr.WriteString(".*?")
+			continue
+		case '?':
+			expr.WriteString(".")
+			continue
+		case '%':
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+			continue
+		}
+		if i+1 >= len(spec) {
+			return p, fmt.Errorf("filename time pattern %q ends with %%", spec)
+		}
+		i++
+		d := spec[i]
+		var group string
+		switch d {
+		case 'Y':
+			group = `(\d{4})`
+		case 'y', 'm', 'd', 'H', 'M', 'S':
+			group = `(\d{2})`
+		case 'j':
+			group = `(\d{3})`
+		case 'b':
+			group = `([A-Za-z]{3})`
+		case 'f':
+			group = `(\d{1,9})`
+		case '%':
+			expr.WriteString("%")
+			continue
+		default:
+			return p, fmt.Errorf("filename time pattern %q: unknown directive %%%c", spec, d)
+		}
+		if seen[d] {
+			return p, fmt.Errorf("filename time pattern %q repeats %%%c", spec, d)
+		}
+		seen[d] = true
+		if len(p.fields) == 0 && expr.Len() == 0 && d != 'b' {
+			// A leading number must not continue a longer one (IMG_120240712).
+			expr.WriteString(`(?:^|\D)`)
+		}
+		expr.WriteString(group)
+		p.fields = append(p.fields, d)
+	}
+	if !seen['Y'] && !seen['y'] {
+		return p, fmt.Errorf("filename time pattern %q has no year (%%Y or %%y)", spec)
+	}
+	if !seen['j'] && (!seen['m'] && !seen['b'] || !seen['d']) {
+		return p, fmt.Errorf("filename time pattern %q needs the month and day (%%m or %%b, and %%d) or the day of the year (%%j)", spec)
+	}
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return p, fmt.Errorf("filename time pattern %q: %w", spec, err)
+	}
+	p.re = re
+	return p, nil
+}
+
+// String returns the pattern as given.
+func (p FilenameTimePattern) String() string {
+	return p.spec
+}
+
+// Time returns the time the pattern finds in name, as a wall-clock time in UTC
+// like the EXIF capture times.
+func (p FilenameTimePattern) Time(name string) (time.Time, bool) {
+	if p.re == nil {
+		return time.Time{}, false
+	}
+	m := p.re.FindStringSubmatch(name)
+	if m == nil {
+		return time.Time{}, false
+	}
+	year, month, day, yday := 0, time.January, 1, 0
+	var clock [3]int
+	nanos := 0
+	for i, d := range p.fields {
+		v := m[i+1]
+		n, _ := strconv.Atoi(v)
+		switch d {
+		case 'Y':
+			year = n
+		case 'y':
+			year = 2000 + n
+			if n >= 70 {
+				year = 1900 + n
+			}
+		case 'm':
+			month = time.Month(n)
+		case 'b':
+			var ok bool
+			if month, ok = monthNames[strings.ToLower(v)]; !ok {
+				return time.Time{}, false
+			}
+		case 'd':
+			day = n
+		case 'j':
+			yday = n
+		case 'H':
+			clock[0] = n
+		case 'M':
+			clock[1] = n
+		case 'S':
+			clock[2] = n
+		case 'f':
+			nanos, _ = strconv.Atoi((v + "000000000")[:9])
+		}
+	}
+	if month < 1 || month > 12 || clock[0] > 23 || clock[1] > 59 || clock[2] > 59 {
+		return time.Time{}, false
+	}
+	t := time.Date(year, month, day, clock[0], clock[1], clock[2], nanos, time.UTC)
+	if yday > 0 {
+		t = time.Date(year, time.January, yday, clock[0], clock[1], clock[2], nanos, time.UTC)
+		if t.Year() != year {
+			return time.Time{}, false
+		}
+	} else if t.Day() != day || t.Month() != month {
+		return time.Time{}, false // e.g. 31 June
+	}
+	return t, true
+}
+
+// ParseFilenameTimePatterns compiles specs, or DefaultFilenameTimePatterns when
+// there are none.
+func ParseFilenameTimePatterns(specs []string) ([]FilenameTimePattern, error) {
+	if len(specs) == 0 {
+		specs = DefaultFilenameTimePatterns
+	}
+	patterns := make([]FilenameTimePattern, 0, len(specs))
+	for _, spec := range specs {
+		p, err := ParseFilenameTimePattern(spec)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// FilenameTime returns the time the first matching pattern finds in the name
+// of path, without its extension.
+func FilenameTime(path string, patterns []FilenameTimePattern) (time.Time, bool) {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	for _, p := range patterns {
+		if t, ok := p.Time(name); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,97 @@
+package media
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// globFiles expands pattern like filepath.Glob, but also accepts `**` as a path
+// segment matching any number of directories, and compares names case-insensitively
+// so `*.cr3` finds `IMG_0001.CR3` on every platform.
+func globFiles(pattern string) ([]string, error) {
+	root, rest := splitGlob(pattern)
+	for _, seg := range rest {
+		if _, err := filepath.Match(seg, ""); err != nil {
+			return nil, err
+		}
+	}
+	recursive := false
+	for _, seg := range rest {
+		if seg == "**" {
+			recursive = true
+		}
+	}
+
+	var matches []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil // unreadable subfolders are skipped, as Glob ignores them
+		}
+		if path == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if matchSegments(rest, parts) {
+			matches = append(matches, path)
+		}
+		if d.IsDir() && !recursive && len(parts) >= len(rest) {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// splitGlob returns the folder to walk for pattern and the path segments to match below it.
+func splitGlob(pattern string) (string, []string) {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	static := 0
+	for static < len(segments) && !containsGlob(segments[static]) {
+		static++
+	}
+	return globRoot(segments[:static]), segments[static:]
+}
+
+// globRoot joins the leading wildcard-free segments into the folder to walk.
+func globRoot(segments []string) string {
+	if len(segments) == 0 {
+		return "."
+	}
+	root := strings.Join(segments, "/")
+	if root == "" || strings.HasSuffix(root, ":") {
+		root += "/" // filesystem root or a bare Windows volume
+	}
+	return filepath.FromSlash(root)
+}
+
+// matchSegments reports whether the path segments in name match pattern, where a
+// `**` pattern segment stands for zero or more path segments.
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, _ := filepath.Match(strings.ToLower(pattern[0]), strings.ToLower(name[0]))
+	return ok && matchSegments(pattern[1:], name[1:])
+}
@@ -0,0 +1,60 @@
+package media
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// headerLimits holds how many leading bytes of a file are enough to decode its
+// metadata. Formats whose IFDs may live anywhere in the file (e.g. DNG) are absent
+// and are always read in full.
+var headerLimits = map[string]int64{
+	".3fr":  1 << 20,
+	".arw":  1 << 20,
+	".cr2":  1 << 20,
+	".cr3":  1 << 20,
+	".erf":  1 << 20,
+	".kdc":  1 << 20,
+	".mrw":  1 << 20,
+	".nef":  1 << 20,
+	".nrw":  1 << 20,
+	".orf":  1 << 20,
+	".pef":  1 << 20,
+	".raf":  1 << 20,
+	".raw":  1 << 20,
+	".rw2":  1 << 20,
+	".rwl":  1 << 20,
+	".sr2":  1 << 20,
+	".srf":  1 << 20,
+	".srw":  1 << 20,
+	".jpg":  256 << 10,
+	".jpeg": 256 << 10,
+	".jpe":  256 << 10,
+	".hif":  1 << 20,
+	".heic": 1 << 20,
+	".heif": 1 << 20,
+}
+
+func headerLimit(path string) int64 {
+	return headerLimits[strings.ToLower(filepath.Ext(path))]
+}
+
+// decodeHeaderFirst decodes metadata from the format-specific file prefix and
+// falls back to the whole file when the prefix fails to decode or misses data.
+func decodeHeaderFirst[T any](file *os.File, path string, decode func(io.ReadSeeker, string) (T, error), complete func(T) bool) (T, error) {
+	if limit := headerLimit(path); limit > 0 {
+		if info, err := file.Stat(); err == nil && info.Size() > limit {
+			res, err := decode(io.NewSectionReader(file, 0, limit), path)
+			if err == nil && complete(res) {
+				return res, nil
+			}
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				var zero T
+				return zero, err
+			}
+		}
+	}
+	return decode(file, path)
+}
@@ -0,0 +1,45 @@
+package media
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Extensions of the two halves of an iPhone Live Photo, in the spellings the
+// camera and common import tools use.
+var (
+	livePhotoStillExts = []string{".HEIC", ".heic", ".HEIF", ".heif", ".JPG", ".jpg", ".JPEG", ".jpeg"}
+	livePhotoVideoExts = []string{".MOV", ".mov"}
+)
+
+// LivePhotoStill returns the still photo of a Live Photo video: the HEIC or JPEG
+// with the same base name in the same folder. ok is false for other files.
+func LivePhotoStill(path string) (string, bool) {
+	if !strings.EqualFold(filepath.Ext(path), ".mov") {
+		return "", false
+	}
+	return sibling(path, livePhotoStillExts)
+}
+
+// LivePhotoVideo returns the video of a Live Photo still, when there is one.
+func LivePhotoVideo(path string) (string, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".heic", ".heif", ".jpg", ".jpeg":
+	default:
+		return "", false
+	}
+	return sibling(path, livePhotoVideoExts)
+}
+
+// sibling returns the first existing file that differs from path only by one of exts.
+func sibling(path string, exts []string) (string, bool) {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, ext := range exts {
+		candidate := base + ext
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
@@ -2,9 +2,9 @@ package media
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -19,13 +19,31 @@ import (
 	"github.com/evanoberholster/imagemeta/isobmff"
 	"github.com/evanoberholster/imagemeta/jpeg"
 	"github.com/evanoberholster/imagemeta/tiff"
+
+	"github.com/nir0k/GeoRAW/internal/errcode"
+	"github.com/nir0k/GeoRAW/internal/fsretry"
 )
 
+// ErrNoCaptureTime is returned by ReadMetadata, with the rest of the metadata,
+// when the file records no capture time.
+var ErrNoCaptureTime = errors.New("capture time not found in metadata")
+
 // Metadata represents a subset of photo metadata required for geotagging.
 type Metadata struct {
 	CaptureTime time.Time
 	CameraMake  string
 	CameraModel string
+	Lens        string          // lens model, empty when not recorded
+	GPS         *GPSPosition    // position recorded by the camera, nil when absent
+	Drone       *DroneTelemetry // flight data of DJI drone photos, nil for other cameras
+}
+
+// GPSPosition is a location stored in the file's own EXIF GPS block.
+type GPSPosition struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  *float64
+	Time      time.Time // GPS timestamp (UTC), zero when not recorded
 }
 
 // SeriesMetadata represents richer metadata needed for series detection/tagging.
@@ -36,7 +54,10 @@ type SeriesMetadata struct {
 	ExposureTime float64 // seconds
 	FNumber      float64 // aperture value (f/x)
 	ISO          uint32
-	HDRHint      bool // true when maker note indicates HDR=On (for JPEG/HIF merged output)
+	ExposureBias float64 // exposure compensation in EV
+	FocalLength  float64 // millimeters
+	Distance     float64 // subject (focus) distance in meters, 0 when not recorded
+	HDRHint      bool    // true when maker note indicates HDR=On (for JPEG/HIF merged output)
 }
 
 // SupportedRaw reports whether the provided path has a supported RAW extension.
@@ -45,17 +66,25 @@ func SupportedRaw(path string) bool {
 	return rawExt[ext]
 }
 
-// ReadMetadata extracts capture time and camera details from a RAW file.
+// ReadMetadata extracts capture time and camera details from a RAW file. A file
+// without a capture time returns its other details with ErrNoCaptureTime; a file
+// the decoder panicked on in an earlier run is not read and returns ErrSuspect.
 func ReadMetadata(path string) (Metadata, error) {
-	file, err := os.Open(path)
+	file, err := fsretry.Open(path)
 	if err != nil {
-		return Metadata{}, fmt.Errorf("open %s: %w", path, err)
+		return Metadata{}, errcode.Wrap(err, errcode.MetadataUnreadable, errcode.Params{"path": path})
 	}
 	defer file.Close()
+	if err := checkSuspect(file, path); err != nil {
+		return Metadata{}, err
+	}
 
-	exif, err := decodeExifSafe(file, path)
+	exif, err := decodeHeaderFirst(file, path, decodeExifSafe, func(ex exif2.Exif) bool {
+		return !ex.DateTimeOriginal().IsZero() || !ex.CreateDate().IsZero()
+	})
+	noteDecode(file, path, err)
 	if err != nil {
-		return Metadata{}, fmt.Errorf("decode metadata: %w", err)
+		return Metadata{}, errcode.Wrap(err, errcode.MetadataUnreadable, errcode.Params{"path": path})
 	}
 
 	ts := exif.DateTimeOriginal()
@@ -65,22 +94,53 @@ func ReadMetadata(path string) (Metadata, error) {
 	if ts.IsZero() {
 		ts = exif.ModifyDate()
 	}
-	if ts.IsZero() {
-		return Metadata{}, fmt.Errorf("capture time not found in metadata")
-	}
 
-	return Metadata{
+	meta := Metadata{
 		CaptureTime: ts,
 		CameraMake:  strings.TrimSpace(exif.Make),
 		CameraModel: strings.TrimSpace(exif.Model),
-	}, nil
+		Lens:        strings.TrimSpace(exif.LensModel),
+		GPS:         embeddedGPS(exif),
+	}
+	if isDroneCamera(meta.CameraMake, meta.CameraModel) {
+		// DJI records its fix in the drone-dji XMP properties, and some models
+		// leave the EXIF GPS block empty.
+		meta.Drone = readDroneTelemetry(file)
+		if meta.GPS == nil {
+			meta.GPS = meta.Drone.position()
+		}
+	}
+	if ts.IsZero() {
+		return meta, errcode.Wrap(ErrNoCaptureTime, errcode.NoCaptureTime, errcode.Params{"path": path})
+	}
+	return meta, nil
+}
+
+// embeddedGPS returns the EXIF GPS position, treating 0,0 as "not recorded"
+// since cameras without a fix often write zeroed GPS tags.
+func embeddedGPS(exif exif2.Exif) *GPSPosition {
+	lat := exif.GPS.Latitude()
+	lon := exif.GPS.Longitude()
+	if lat == 0 && lon == 0 {
+		return nil
+	}
+	pos := &GPSPosition{
+		Latitude:  lat,
+		Longitude: lon,
+		Time:      exif.GPS.Date().UTC(),
+	}
+	if alt := exif.GPS.Altitude(); alt != 0 {
+		v := float64(alt)
+		pos.Altitude = &v
+	}
+	return pos
 }
 
 // decodeExifSafe protects against panics from the decoder on malformed files.
 func decodeExifSafe(r io.ReadSeeker, path string) (ex exif2.Exif, err error) {
 	defer func() {
 		if rec := recover(); rec != nil {
-			err = fmt.Errorf("panic while decoding %s: %v", path, rec)
+			err = &decoderPanic{path: path, value: rec}
 		}
 	}()
 
@@ -91,15 +151,21 @@ func decodeExifSafe(r io.ReadSeeker, path string) (ex exif2.Exif, err error) {
 // ReadSeriesMetadata extracts detailed fields for series detection (Canon only).
 // It uses a custom EXIF parser to capture maker note flags and exposure data.
 func ReadSeriesMetadata(path string) (SeriesMetadata, error) {
-	file, err := os.Open(path)
+	file, err := fsretry.Open(path)
 	if err != nil {
-		return SeriesMetadata{}, fmt.Errorf("open %s: %w", path, err)
+		return SeriesMetadata{}, errcode.Wrap(err, errcode.MetadataUnreadable, errcode.Params{"path": path})
 	}
 	defer file.Close()
+	if err := checkSuspect(file, path); err != nil {
+		return SeriesMetadata{}, err
+	}
 
-	meta, err := decodeSeriesExifSafe(file, path)
+	meta, err := decodeHeaderFirst(file, path, decodeSeriesExifSafe, func(se seriesExif) bool {
+		return !se.captureTime.IsZero() && se.exposureTime > 0
+	})
+	noteDecode(file, path, err)
 	if err != nil {
-		return SeriesMetadata{}, fmt.Errorf("decode metadata: %w", err)
+		return SeriesMetadata{}, errcode.Wrap(err, errcode.MetadataUnreadable, errcode.Params{"path": path})
 	}
 
 	ts := meta.captureTime
@@ -110,7 +176,7 @@ func ReadSeriesMetadata(path string) (SeriesMetadata, error) {
 		ts = meta.modifyDate
 	}
 	if ts.IsZero() {
-		return SeriesMetadata{}, fmt.Errorf("capture time not found in metadata")
+		return SeriesMetadata{}, errcode.Wrap(ErrNoCaptureTime, errcode.NoCaptureTime, errcode.Params{"path": path})
 	}
 
 	return SeriesMetadata{
@@ -120,6 +186,9 @@ func ReadSeriesMetadata(path string) (SeriesMetadata, error) {
 		ExposureTime: meta.exposureTime,
 		FNumber:      meta.fNumber,
 		ISO:          meta.iso,
+		ExposureBias: meta.exposureBias,
+		FocalLength:  meta.focalLength,
+		Distance:     meta.distance,
 		HDRHint:      meta.hdr,
 	}, nil
 }
@@ -134,13 +203,16 @@ type seriesExif struct {
 	exposureTime float64
 	fNumber      float64
 	iso          uint32
+	exposureBias float64
+	focalLength  float64
+	distance     float64
 	hdr          bool
 }
 
 func decodeSeriesExifSafe(r io.ReadSeeker, path string) (se seriesExif, err error) {
 	defer func() {
 		if rec := recover(); rec != nil {
-			err = fmt.Errorf("panic while decoding %s: %v", path, rec)
+			err = &decoderPanic{path: path, value: rec}
 		}
 	}()
 	se, err = decodeSeriesExif(r)
@@ -252,6 +324,23 @@ func makeSeriesTagParser(dst *seriesExif) exif2.TagParserFn {
 				}
 			case exififd.ISOSpeedRatings:
 				dst.iso = p.ParseUint32(t)
+			case exififd.ExposureBiasValue:
+				// A signed rational; the two halves keep their bits as uint32.
+				val := p.ParseRationalU(t)
+				if val[1] != 0 {
+					dst.exposureBias = float64(int32(val[0])) / float64(int32(val[1]))
+				}
+			case exififd.FocalLength:
+				val := p.ParseRationalU(t)
+				if val[1] != 0 {
+					dst.focalLength = float64(val[0]) / float64(val[1])
+				}
+			case exififd.SubjectDistance:
+				// 0xFFFFFFFF/1 means infinity, kept as unknown like 0.
+				val := p.ParseRationalU(t)
+				if val[1] != 0 && val[0] != 0xFFFFFFFF {
+					dst.distance = float64(val[0]) / float64(val[1])
+				}
 			}
 		case ifds.MknoteIFD, ifds.MkNoteCanonIFD:
 			if t.ID == tag.ID(canon.CanonHDRInfo) {
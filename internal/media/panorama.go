@@ -0,0 +1,37 @@
+package media
+
+import (
+	"io"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/fsretry"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// gpanoLimit is how far into a file its XMP packet is looked for; JPEG and HEIF
+// store it in the first segments.
+const gpanoLimit = 1 << 20
+
+// ReadGPano returns the GPano properties embedded in a panorama (e.g. an
+// equirectangular JPEG from a 360° camera or a stitcher), or nil when it has
+// none.
+func ReadGPano(path string) (map[string]string, error) {
+	file, err := fsretry.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	buf := make([]byte, gpanoLimit)
+	n, err := file.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !strings.Contains(string(buf[:n]), xmp.GPanoNamespace) {
+		return nil, nil
+	}
+	props := xmp.ParseGPano(buf[:n])
+	if len(props) == 0 {
+		return nil, nil
+	}
+	return props, nil
+}
@@ -0,0 +1,145 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"os"
+)
+
+const (
+	// maxPreviewScan limits how much of a RAW file is searched for embedded JPEG previews.
+	maxPreviewScan = 32 << 20
+	// previewTargetSide is the preferred longest side of a decoded preview.
+	previewTargetSide = 2048
+)
+
+// PreviewThumbnail returns a JPEG thumbnail, at most maxSide pixels on the longest side,
+// built from the embedded preview of a photo.
+func PreviewThumbnail(path string, maxSide int) ([]byte, error) {
+	img, err := decodeEmbeddedPreview(path)
+	if err != nil {
+		return nil, err
+	}
+	thumb := downscale(img, maxSide)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 75}); err != nil {
+		return nil, fmt.Errorf("encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeEmbeddedPreview(path string) (img image.Image, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic while decoding preview of %s: %v", path, rec)
+		}
+	}()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxPreviewScan))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	best := -1
+	bestSide := 0
+	for off := 0; off+3 <= len(data); {
+		idx := bytes.Index(data[off:], []byte{0xFF, 0xD8, 0xFF})
+		if idx == -1 {
+			break
+		}
+		start := off + idx
+		off = start + 3
+
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(data[start:]))
+		if err != nil {
+			continue
+		}
+		side := cfg.Width
+		if cfg.Height > side {
+			side = cfg.Height
+		}
+		if previewBetter(side, bestSide) {
+			best = start
+			bestSide = side
+		}
+	}
+	if best == -1 {
+		return nil, fmt.Errorf("no embedded JPEG preview found")
+	}
+
+	img, err = jpeg.Decode(bytes.NewReader(data[best:]))
+	if err != nil {
+		return nil, fmt.Errorf("decode preview: %w", err)
+	}
+	return img, nil
+}
+
+// previewBetter prefers the largest preview that does not exceed the target size,
+// falling back to the smallest oversized one.
+func previewBetter(side, current int) bool {
+	if current == 0 {
+		return true
+	}
+	if side <= previewTargetSide {
+		return current > previewTargetSide || side > current
+	}
+	return current > previewTargetSide && side < current
+}
+
+// downscale shrinks img with a box filter so its longest side is at most maxSide.
+func downscale(img image.Image, maxSide int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if maxSide <= 0 || (w <= maxSide && h <= maxSide) {
+		return img
+	}
+	scale := float64(maxSide) / float64(w)
+	if h > w {
+		scale = float64(maxSide) / float64(h)
+	}
+	tw, th := int(float64(w)*scale), int(float64(h)*scale)
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for ty := 0; ty < th; ty++ {
+		y0 := bounds.Min.Y + ty*h/th
+		y1 := bounds.Min.Y + (ty+1)*h/th
+		for tx := 0; tx < tw; tx++ {
+			x0 := bounds.Min.X + tx*w/tw
+			x1 := bounds.Min.X + (tx+1)*w/tw
+			var r, g, b, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					cr, cg, cb, _ := img.At(x, y).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					n++
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			i := out.PixOffset(tx, ty)
+			out.Pix[i] = uint8((r / n) >> 8)
+			out.Pix[i+1] = uint8((g / n) >> 8)
+			out.Pix[i+2] = uint8((b / n) >> 8)
+			out.Pix[i+3] = 0xff
+		}
+	}
+	return out
+}
@@ -0,0 +1,58 @@
+package media
+
+import (
+	"image"
+)
+
+// sharpnessSampleSide is the longest side of the grid the Laplacian is evaluated on.
+const sharpnessSampleSide = 640
+
+// PreviewSharpness estimates focus quality from the embedded JPEG preview of a file.
+// The score is the variance of a Laplacian over a downsampled grayscale preview;
+// higher values mean more fine detail. Scores are only comparable between frames
+// from the same camera and scene.
+func PreviewSharpness(path string) (float64, error) {
+	img, err := decodeEmbeddedPreview(path)
+	if err != nil {
+		return 0, err
+	}
+	return laplacianVariance(img), nil
+}
+
+func laplacianVariance(img image.Image) float64 {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w < 3 || h < 3 {
+		return 0
+	}
+
+	step := 1
+	for (w/step) > sharpnessSampleSide || (h/step) > sharpnessSampleSide {
+		step++
+	}
+	gw, gh := w/step, h/step
+	gray := make([]float64, gw*gh)
+	for y := 0; y < gh; y++ {
+		for x := 0; x < gw; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x*step, bounds.Min.Y+y*step).RGBA()
+			gray[y*gw+x] = 0.299*float64(r>>8) + 0.587*float64(g>>8) + 0.114*float64(b>>8)
+		}
+	}
+
+	var sum, sumSq float64
+	n := 0
+	for y := 1; y < gh-1; y++ {
+		for x := 1; x < gw-1; x++ {
+			c := gray[y*gw+x]
+			lap := gray[(y-1)*gw+x] + gray[(y+1)*gw+x] + gray[y*gw+x-1] + gray[y*gw+x+1] - 4*c
+			sum += lap
+			sumSq += lap * lap
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	mean := sum / float64(n)
+	return sumSq/float64(n) - mean*mean
+}
@@ -0,0 +1,176 @@
+package media
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/errcode"
+)
+
+// ErrSuspect is returned by ReadMetadata and ReadSeriesMetadata, without reading
+// the file, for a file whose metadata made the decoder panic in an earlier run.
+var ErrSuspect = errors.New("metadata decoder crashed on this file before")
+
+// decoderPanic is a panic of the metadata decoder, recovered as an error.
+type decoderPanic struct {
+	path  string
+	value interface{}
+}
+
+func (e *decoderPanic) Error() string {
+	return fmt.Sprintf("panic while decoding %s: %v", e.path, e.value)
+}
+
+// suspect is a remembered file the decoder panicked on. Size and Mod identify
+// the version of the file; a changed file is decoded again.
+type suspect struct {
+	Size  int64     `json:"size"`
+	Mod   int64     `json:"mod"`
+	Panic string    `json:"panic"`
+	Seen  time.Time `json:"seen"`
+}
+
+// suspects are kept in the per-user cache across runs, keyed by absolute path.
+// They are guarded by suspectsMu and loaded on first use.
+var (
+	suspectsMu    sync.Mutex
+	suspects      map[string]suspect
+	suspectsPath  string
+	retrySuspects bool
+)
+
+// RetrySuspects makes the metadata readers decode remembered suspect files again
+// instead of refusing them; one that now decodes cleanly is forgotten.
+func RetrySuspects(retry bool) {
+	suspectsMu.Lock()
+	retrySuspects = retry
+	suspectsMu.Unlock()
+}
+
+// SuspectCachePath returns the file the suspect files are remembered in.
+func SuspectCachePath() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve cache dir: %w", err)
+	}
+	return filepath.Join(base, "georaw", "suspect-files.json"), nil
+}
+
+// loadSuspects reads the remembered files once; suspectsMu must be held. Without
+// a cache folder they are only remembered for the life of the process.
+func loadSuspects() {
+	if suspects != nil {
+		return
+	}
+	suspects = make(map[string]suspect)
+	path, err := SuspectCachePath()
+	if err != nil {
+		return
+	}
+	suspectsPath = path
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &suspects)
+	}
+}
+
+// saveSuspect remembers s under key, or forgets key when s is nil, and writes the
+// remembered files back; suspectsMu must be held. The file is read again first,
+// so files another process remembered meanwhile are kept, and replaced in one
+// rename, so a concurrent reader never sees half of it. A failed write only costs
+// the next run a decode.
+func saveSuspect(key string, s *suspect) {
+	if suspectsPath != "" {
+		if data, err := os.ReadFile(suspectsPath); err == nil {
+			var onDisk map[string]suspect
+			if json.Unmarshal(data, &onDisk) == nil && onDisk != nil {
+				suspects = onDisk
+			}
+		}
+	}
+	if s != nil {
+		suspects[key] = *s
+	} else {
+		delete(suspects, key)
+	}
+	if suspectsPath == "" {
+		return
+	}
+	data, err := json.MarshalIndent(suspects, "", "  ")
+	if err != nil {
+		return
+	}
+	dir := filepath.Dir(suspectsPath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(dir, ".suspect-files-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err != nil || closeErr != nil {
+		return
+	}
+	_ = os.Rename(tmp.Name(), suspectsPath)
+}
+
+// checkSuspect refuses a file the decoder panicked on before, unless it changed
+// since or RetrySuspects is set.
+func checkSuspect(file *os.File, path string) error {
+	info, err := file.Stat()
+	if err != nil {
+		return nil
+	}
+	key := suspectKey(path)
+	suspectsMu.Lock()
+	defer suspectsMu.Unlock()
+	loadSuspects()
+	s, ok := suspects[key]
+	if !ok || retrySuspects || s.Size != info.Size() || s.Mod != info.ModTime().UnixNano() {
+		return nil
+	}
+	return errcode.Wrap(ErrSuspect, errcode.SuspectFile, errcode.Params{
+		"path":  path,
+		"since": s.Seen.Local().Format(time.DateTime),
+	})
+}
+
+// noteDecode remembers file when decodeErr is a decoder panic, and forgets a
+// remembered file that decoded without one.
+func noteDecode(file *os.File, path string, decodeErr error) {
+	var p *decoderPanic
+	crashed := errors.As(decodeErr, &p)
+	key := suspectKey(path)
+	suspectsMu.Lock()
+	defer suspectsMu.Unlock()
+	loadSuspects()
+	if _, known := suspects[key]; !crashed {
+		if known {
+			saveSuspect(key, nil)
+		}
+		return
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+	saveSuspect(key, &suspect{
+		Size:  info.Size(),
+		Mod:   info.ModTime().UnixNano(),
+		Panic: fmt.Sprint(p.value),
+		Seen:  time.Now().UTC(),
+	})
+}
+
+func suspectKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,24 @@
+package media
+
+import "time"
+
+// WriteCaptureTime rewrites the capture time embedded in the file
+// (DateTimeOriginal and CreateDate, plus the EXIF offsets when offset is set,
+// e.g. "+02:00"), with the built-in exiv2 when available and exiftool otherwise
+// or when exiv2 cannot write the file. The file's modification time is kept.
+func WriteCaptureTime(path string, t time.Time, offset string) error {
+	stamp := t.Format("2006:01:02 15:04:05")
+	tags := []embedTag{
+		{Key: "Exif.Photo.DateTimeOriginal", Value: stamp},
+		{Key: "Exif.Photo.DateTimeDigitized", Value: stamp},
+	}
+	args := []string{"-EXIF:DateTimeOriginal=" + stamp, "-EXIF:CreateDate=" + stamp}
+	if offset != "" {
+		tags = append(tags,
+			embedTag{Key: "Exif.Photo.OffsetTimeOriginal", Value: offset},
+			embedTag{Key: "Exif.Photo.OffsetTimeDigitized", Value: offset},
+		)
+		args = append(args, "-EXIF:OffsetTimeOriginal="+offset, "-EXIF:OffsetTimeDigitized="+offset)
+	}
+	return writeEmbedded(path, tags, args)
+}
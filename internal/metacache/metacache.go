@@ -0,0 +1,178 @@
+// Package metacache remembers the position and capture time of the photos in a
+// library between scans, so rescanning a large archive only reads files that
+// changed. Entries are keyed by path and invalidated when the file or its sidecar
+// changes size or modification time.
+package metacache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// Photo is a file of the library with a known position.
+type Photo struct {
+	Path      string    `json:"path"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Capture   time.Time `json:"capture,omitempty"`
+}
+
+// Stats summarizes a scan.
+type Stats struct {
+	Files   int `json:"files"`   // photos found under the root
+	Cached  int `json:"cached"`  // photos answered from the cache without being read
+	WithGPS int `json:"withGps"` // photos with a position in the sidecar or the file
+}
+
+// entry is the cached state of one file. Size and Mod identify the version of the
+// file and SidecarMod that of its sidecar (zero when there is none).
+type entry struct {
+	Size       int64     `json:"size"`
+	Mod        int64     `json:"mod"`
+	SidecarMod int64     `json:"sidecarMod,omitempty"`
+	GPS        bool      `json:"gps,omitempty"`
+	Lat        float64   `json:"lat,omitempty"`
+	Lon        float64   `json:"lon,omitempty"`
+	Capture    time.Time `json:"capture,omitempty"`
+}
+
+// DefaultCacheDir returns the per-user folder used for cached library metadata.
+func DefaultCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve cache dir: %w", err)
+	}
+	return filepath.Join(base, "georaw", "metadata"), nil
+}
+
+// Scan walks root recursively and returns the photos with a position, taken from
+// the sidecar when it has one and from the file's own EXIF otherwise. Answers are
+// cached per root in cacheDir; an empty cacheDir reads every file. progress, when
+// set, is called with the number of photos seen so far.
+func Scan(ctx context.Context, root, cacheDir string, progress func(scanned int)) ([]Photo, Stats, error) {
+	root, err := filepath.Abs(strings.TrimSpace(root))
+	if err != nil {
+		return nil, Stats{}, fmt.Errorf("resolve %s: %w", root, err)
+	}
+	var cachePath string
+	old := make(map[string]entry)
+	if cacheDir != "" {
+		sum := sha256.Sum256([]byte(root))
+		cachePath = filepath.Join(cacheDir, hex.EncodeToString(sum[:8])+".json")
+		if data, err := os.ReadFile(cachePath); err == nil {
+			_ = json.Unmarshal(data, &old)
+		}
+	}
+
+	var (
+		photos []Photo
+		stats  Stats
+		seen   = make(map[string]entry)
+	)
+	err = media.WalkFiles([]string{root}, true, func(path string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if strings.EqualFold(filepath.Ext(path), ".xmp") || !media.SupportedExif(path) {
+			return nil
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil
+		}
+		stats.Files++
+		if progress != nil {
+			progress(stats.Files)
+		}
+
+		sidecar, sidecarMod := findSidecar(path)
+		e, ok := old[path]
+		if ok && e.Size == info.Size() && e.Mod == info.ModTime().UnixNano() && e.SidecarMod == sidecarMod {
+			stats.Cached++
+		} else {
+			e = read(path, sidecar)
+			e.Size, e.Mod, e.SidecarMod = info.Size(), info.ModTime().UnixNano(), sidecarMod
+		}
+		seen[path] = e
+		if e.GPS {
+			stats.WithGPS++
+			photos = append(photos, Photo{Path: path, Latitude: e.Lat, Longitude: e.Lon, Capture: e.Capture})
+		}
+		return nil
+	})
+	if cachePath != "" {
+		if err != nil {
+			// Keep what the interrupted scan did not get to for the next one.
+			for path, e := range old {
+				if _, ok := seen[path]; !ok {
+					seen[path] = e
+				}
+			}
+		}
+		save(cachePath, seen)
+	}
+	if err != nil {
+		return nil, stats, err
+	}
+	return photos, stats, nil
+}
+
+// findSidecar returns the sidecar of path that exists, preferring IMG_0001.xmp for
+// RAW files and IMG_0001.JPG.xmp otherwise, with its modification time.
+func findSidecar(path string) (string, int64) {
+	candidates := []string{xmp.SidecarPath(path), xmp.CompanionSidecarPath(path)}
+	if !media.SupportedRaw(path) {
+		candidates[0], candidates[1] = candidates[1], candidates[0]
+	}
+	for _, c := range candidates {
+		if info, err := os.Stat(c); err == nil && !info.IsDir() {
+			return c, info.ModTime().UnixNano()
+		}
+	}
+	return "", 0
+}
+
+// read takes the position from the sidecar, falling back to embedded GPS, and the
+// capture time from the file. Unreadable files are cached without a position.
+func read(path, sidecar string) entry {
+	var e entry
+	meta, err := media.ReadMetadata(path)
+	if err == nil {
+		e.Capture = meta.CaptureTime
+		if meta.GPS != nil {
+			e.GPS, e.Lat, e.Lon = true, meta.GPS.Latitude, meta.GPS.Longitude
+		}
+	}
+	if sidecar != "" {
+		if coord, ok, err := xmp.ReadGPS(sidecar); err == nil && ok {
+			e.GPS, e.Lat, e.Lon = true, coord.Latitude, coord.Longitude
+		}
+	}
+	return e
+}
+
+// save writes the cache; failures only cost a slower next scan.
+func save(path string, entries map[string]entry) {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, path)
+}
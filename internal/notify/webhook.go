@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/nir0k/GeoRAW/internal/version"
+)
+
+const webhookTimeout = 15 * time.Second
+
+// Config selects where run completion events are sent.
+type Config struct {
+	// URL receives a JSON POST when a run finishes or fails. Empty disables webhooks.
+	URL string
+	// IncludeFiles adds the per-file results to the payload.
+	IncludeFiles bool
+}
+
+// Event is the JSON body posted to the webhook.
+type Event struct {
+	Event    string           `json:"event"` // run_completed or run_failed
+	Command  string           `json:"command"`
+	Version  string           `json:"version"`
+	Host     string           `json:"host,omitempty"`
+	Finished time.Time        `json:"finished"`
+	Error    string           `json:"error,omitempty"`
+	Summary  *app.Summary     `json:"summary,omitempty"`
+	Files    []app.FileResult `json:"files,omitempty"`
+}
+
+// Send posts the outcome of a run to cfg.URL. It is a no-op when no URL is set.
+// Non-2xx responses are reported as errors.
+func Send(ctx context.Context, cfg Config, command string, sum *app.Summary, runErr error) error {
+	url := strings.TrimSpace(cfg.URL)
+	if url == "" {
+		return nil
+	}
+
+	ev := Event{
+		Event:    "run_completed",
+		Command:  command,
+		Version:  version.Version,
+		Finished: time.Now().UTC(),
+	}
+	if host, err := os.Hostname(); err == nil {
+		ev.Host = host
+	}
+	if runErr != nil {
+		ev.Event = "run_failed"
+		ev.Error = runErr.Error()
+	}
+	if sum != nil {
+		counts := *sum
+		counts.Files = nil
+		ev.Summary = &counts
+		if cfg.IncludeFiles {
+			ev.Files = sum.Files
+		}
+	}
+
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("encode webhook payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GeoRAW/"+version.Version)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+// Package pathnorm makes file names compare equal regardless of their Unicode
+// normalization. macOS writes names decomposed (NFD, "e" + combining accent) while
+// Linux and Windows tools usually write them composed (NFC), so an archive copied
+// between them can hold "Café.CR3" next to a "Café.xmp" whose bytes differ.
+package pathnorm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Key returns path in NFC, the form used to compare and index names.
+func Key(path string) string {
+	if isASCII(path) {
+		return path
+	}
+	return norm.NFC.String(path)
+}
+
+// PairKey identifies files that belong together, like a RAW and its JPEG: same
+// folder and base name in any normalization and case, any extension.
+func PairKey(path string) string {
+	return strings.ToLower(Key(strings.TrimSuffix(path, filepath.Ext(path))))
+}
+
+// Existing returns the entry of path's folder whose name equals path's base name
+// once both are normalized, so a sidecar written under the other normalization is
+// found. It returns path unchanged when it exists as written, when nothing matches,
+// or when the name is plain ASCII.
+func Existing(path string) string {
+	base := filepath.Base(path)
+	if isASCII(base) {
+		return path
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		return path
+	}
+	dir := filepath.Dir(path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return path
+	}
+	want := norm.NFC.String(base)
+	for _, e := range entries {
+		if name := e.Name(); name != base && Key(name) == want {
+			return filepath.Join(dir, name)
+		}
+	}
+	return path
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package pathnorm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// "Café" composed (NFC, as Linux and Windows write it) and decomposed (NFD, as
+// macOS writes it).
+const (
+	nfc = "Caf\u00e9"
+	nfd = "Cafe\u0301"
+)
+
+func TestKey(t *testing.T) {
+	if Key(nfd+".CR3") != nfc+".CR3" {
+		t.Errorf("Key(NFD) = %q, want the NFC form", Key(nfd+".CR3"))
+	}
+	if Key(nfc+".CR3") != nfc+".CR3" {
+		t.Errorf("Key(NFC) = %q, want it unchanged", Key(nfc+".CR3"))
+	}
+	if Key("/photos/IMG_0001.CR3") != "/photos/IMG_0001.CR3" {
+		t.Errorf("Key changed an ASCII path")
+	}
+}
+
+func TestPairKey(t *testing.T) {
+	raw := PairKey("/photos/" + nfc + ".CR3")
+	for _, companion := range []string{"/photos/" + nfd + ".jpg", "/photos/CAFE\u0301.HEIC"} {
+		if got := PairKey(companion); got != raw {
+			t.Errorf("PairKey(%q) = %q, want %q", companion, got, raw)
+		}
+	}
+	if PairKey("/photos/Cafe.jpg") == raw || PairKey("/other/"+nfc+".jpg") == raw {
+		t.Errorf("PairKey joined different names")
+	}
+}
+
+func TestExisting(t *testing.T) {
+	for _, tc := range []struct{ onDisk, asked string }{
+		{nfd, nfc},
+		{nfc, nfd},
+	} {
+		dir := t.TempDir()
+		onDisk := filepath.Join(dir, tc.onDisk+".xmp")
+		if err := os.WriteFile(onDisk, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if got := Existing(filepath.Join(dir, tc.asked+".xmp")); got != onDisk {
+			t.Errorf("Existing(%q) = %q, want %q", tc.asked, got, onDisk)
+		}
+		if got := Existing(onDisk); got != onDisk {
+			t.Errorf("Existing of the name as written = %q, want it unchanged", got)
+		}
+	}
+
+	dir := t.TempDir()
+	missing := filepath.Join(dir, nfc+".xmp")
+	if got := Existing(missing); got != missing {
+		t.Errorf("Existing without a match = %q, want it unchanged", got)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cafe.xmp"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := Existing(missing); got != missing {
+		t.Errorf("Existing matched a different name: %q", got)
+	}
+	if got := Existing(filepath.Join(dir, "missing", nfc+".xmp")); got != filepath.Join(dir, "missing", nfc+".xmp") {
+		t.Errorf("Existing in a missing folder = %q, want it unchanged", got)
+	}
+}
@@ -0,0 +1,126 @@
+package profiling
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	rpprof "runtime/pprof"
+	"runtime/trace"
+	"strings"
+)
+
+// Config selects which diagnostics to collect for a run. Empty fields are disabled.
+type Config struct {
+	// Dir receives cpu.pprof and heap.pprof for the run.
+	Dir string
+	// Addr serves net/http/pprof handlers (e.g. localhost:6060) while the run is active.
+	Addr string
+	// TracePath receives a runtime/trace execution trace.
+	TracePath string
+}
+
+// Enabled reports whether any diagnostics are requested.
+func (c Config) Enabled() bool {
+	return strings.TrimSpace(c.Dir) != "" || strings.TrimSpace(c.Addr) != "" || strings.TrimSpace(c.TracePath) != ""
+}
+
+// Session holds active profilers. A nil Session is valid and Stop is a no-op.
+type Session struct {
+	dir       string
+	cpuFile   *os.File
+	traceFile *os.File
+	server    *http.Server
+	Addr      string // actual listen address of the pprof server, if any
+}
+
+// Start begins collecting the diagnostics described by cfg.
+func Start(cfg Config) (*Session, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	s := &Session{dir: strings.TrimSpace(cfg.Dir)}
+
+	if s.dir != "" {
+		if err := os.MkdirAll(s.dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create profile dir: %w", err)
+		}
+		f, err := os.Create(filepath.Join(s.dir, "cpu.pprof"))
+		if err != nil {
+			return nil, fmt.Errorf("create cpu profile: %w", err)
+		}
+		if err := rpprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("start cpu profile: %w", err)
+		}
+		s.cpuFile = f
+	}
+
+	if path := strings.TrimSpace(cfg.TracePath); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			s.Stop()
+			return nil, fmt.Errorf("create trace file: %w", err)
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			s.Stop()
+			return nil, fmt.Errorf("start trace: %w", err)
+		}
+		s.traceFile = f
+	}
+
+	if addr := strings.TrimSpace(cfg.Addr); addr != "" {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			s.Stop()
+			return nil, fmt.Errorf("listen pprof: %w", err)
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		s.server = &http.Server{Handler: mux}
+		s.Addr = ln.Addr().String()
+		go s.server.Serve(ln)
+	}
+
+	return s, nil
+}
+
+// Stop flushes profiles, writes the heap profile, and shuts the pprof server down.
+func (s *Session) Stop() error {
+	if s == nil {
+		return nil
+	}
+	var errs []error
+	if s.cpuFile != nil {
+		rpprof.StopCPUProfile()
+		errs = append(errs, s.cpuFile.Close())
+		s.cpuFile = nil
+
+		runtime.GC()
+		f, err := os.Create(filepath.Join(s.dir, "heap.pprof"))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("create heap profile: %w", err))
+		} else {
+			errs = append(errs, rpprof.WriteHeapProfile(f), f.Close())
+		}
+	}
+	if s.traceFile != nil {
+		trace.Stop()
+		errs = append(errs, s.traceFile.Close())
+		s.traceFile = nil
+	}
+	if s.server != nil {
+		errs = append(errs, s.server.Close())
+		s.server = nil
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,192 @@
+// Package runlock keeps two GeoRAW runs (GUI and CLI, or two terminals) from
+// writing sidecars in the same folder at once, using an advisory lock file.
+package runlock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileName is the lock file created in the locked folder.
+const FileName = ".georaw.lock"
+
+const (
+	heartbeat    = 30 * time.Second // how often a held lock is touched
+	staleAfter   = 2 * time.Minute  // untouched for this long, a lock is considered abandoned
+	pollInterval = 2 * time.Second
+)
+
+// Holder describes the run that owns a lock.
+type Holder struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Command string    `json:"command"`
+	Started time.Time `json:"started"`
+}
+
+func (h Holder) String() string {
+	return fmt.Sprintf("%s (pid %d on %s, since %s)", h.Command, h.PID, h.Host, h.Started.Local().Format(time.DateTime))
+}
+
+// LockedError reports a folder that another live run holds.
+type LockedError struct {
+	Path   string // lock file of the other run, in the folder or one of its parents
+	Holder Holder
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s is in use by another GeoRAW run: %s; wait for it or delete the lock if that run is gone", e.Path, e.Holder)
+}
+
+// Lock is a held folder lock.
+type Lock struct {
+	path string
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Acquire locks dir for command. A live lock in dir or in one of its parent folders
+// (a run over a whole tree) blocks it: Acquire then returns a *LockedError, or, with
+// wait, calls onWait once and polls until the lock is free or ctx ends. Locks whose
+// process is gone, or which stopped being refreshed, are taken over.
+func Acquire(ctx context.Context, dir, command string, wait bool, onWait func(*LockedError)) (*Lock, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", dir, err)
+	}
+	host, _ := os.Hostname()
+	self := Holder{PID: os.Getpid(), Host: host, Command: command, Started: time.Now().UTC()}
+	path := filepath.Join(abs, FileName)
+
+	notified := false
+	for {
+		locked := parentLock(abs, host)
+		if locked == nil {
+			var l *Lock
+			l, locked, err = create(path, self, host)
+			if err != nil {
+				return nil, err
+			}
+			if l != nil {
+				return l, nil
+			}
+		}
+		if !wait {
+			return nil, locked
+		}
+		if !notified && onWait != nil {
+			onWait(locked)
+			notified = true
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// create writes the lock file, replacing a stale one. It returns the lock, or the
+// live holder that prevents it.
+func create(path string, self Holder, host string) (*Lock, *LockedError, error) {
+	data, err := json.Marshal(self)
+	if err != nil {
+		return nil, nil, err
+	}
+	for range 2 {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			_, werr := file.Write(data)
+			if cerr := file.Close(); werr == nil {
+				werr = cerr
+			}
+			if werr != nil {
+				os.Remove(path)
+				return nil, nil, fmt.Errorf("write run lock: %w", werr)
+			}
+			l := &Lock{path: path, stop: make(chan struct{}), done: make(chan struct{})}
+			go l.refresh()
+			return l, nil, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, nil, fmt.Errorf("create run lock: %w", err)
+		}
+		if holder, live := readLive(path, host); live {
+			return nil, &LockedError{Path: path, Holder: holder}, nil
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, fmt.Errorf("remove stale run lock: %w", err)
+		}
+	}
+	holder, _ := readLive(path, host)
+	return nil, &LockedError{Path: path, Holder: holder}, nil
+}
+
+// parentLock returns the live lock of a parent folder of dir, if any.
+func parentLock(dir, host string) *LockedError {
+	for p := filepath.Dir(dir); ; p = filepath.Dir(p) {
+		path := filepath.Join(p, FileName)
+		if holder, live := readLive(path, host); live {
+			return &LockedError{Path: path, Holder: holder}
+		}
+		if filepath.Dir(p) == p {
+			return nil
+		}
+	}
+}
+
+// readLive reads the lock at path and reports whether it is held by a live run.
+func readLive(path, host string) (Holder, bool) {
+	var holder Holder
+	info, err := os.Stat(path)
+	if err != nil {
+		return holder, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || json.Unmarshal(data, &holder) != nil {
+		// Unreadable or half-written: trust it only while it is fresh.
+		return holder, time.Since(info.ModTime()) < staleAfter
+	}
+	if time.Since(info.ModTime()) > staleAfter {
+		return holder, false
+	}
+	if holder.Host == host && !processAlive(holder.PID) {
+		return holder, false
+	}
+	return holder, true
+}
+
+// refresh touches the lock file until Release, so other runs see it is still held.
+func (l *Lock) refresh() {
+	defer close(l.done)
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			_ = os.Chtimes(l.path, now, now)
+		}
+	}
+}
+
+// Release removes the lock file.
+func (l *Lock) Release() error {
+	if l == nil {
+		return nil
+	}
+	close(l.stop)
+	<-l.done
+	if err := os.Remove(l.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove run lock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package runlock
+
+import "os"
+
+// processAlive reports whether a process with pid exists on this machine; on
+// Windows FindProcess opens the process and fails when it is gone.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,197 @@
+package series
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// adjustmentsFile stores manual group boundary overrides next to the photos.
+const adjustmentsFile = ".georaw-series.json"
+
+// Adjustments records manual corrections to automatic series boundaries.
+// Entries are base file names or paths relative to the adjustments folder
+// (e.g. "day2/IMG_0001.CR3"), matched case-insensitively. A base name matches
+// that file in every subfolder.
+type Adjustments struct {
+	// SplitAt lists frames that must start a new group.
+	SplitAt []string `json:"split_at,omitempty"`
+	// MergeAt lists frames whose group is joined with the following group.
+	MergeAt []string `json:"merge_at,omitempty"`
+}
+
+func (a Adjustments) empty() bool {
+	return len(a.SplitAt) == 0 && len(a.MergeAt) == 0
+}
+
+// add merges new entries into the adjustment set, keeping it sorted and unique.
+func (a Adjustments) add(other Adjustments) Adjustments {
+	return Adjustments{
+		SplitAt: mergeNames(a.SplitAt, other.SplitAt),
+		MergeAt: mergeNames(a.MergeAt, other.MergeAt),
+	}
+}
+
+func mergeNames(base, extra []string) []string {
+	seen := make(map[string]struct{}, len(base)+len(extra))
+	var out []string
+	for _, name := range append(append([]string{}, base...), extra...) {
+		name = adjustmentName(name)
+		if name == "" || name == "." {
+			continue
+		}
+		key := strings.ToLower(name)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// adjustmentsDir picks the folder that holds the adjustments file for a run: the
+// input folder, or the folder of the first file.
+func adjustmentsDir(inputs []string, files []string) string {
+	if len(inputs) == 1 {
+		if info, err := os.Stat(inputs[0]); err == nil && info.IsDir() {
+			return inputs[0]
+		}
+	}
+	if len(files) == 0 {
+		return ""
+	}
+	return filepath.Dir(files[0])
+}
+
+func loadAdjustments(dir string) (Adjustments, error) {
+	var adj Adjustments
+	if dir == "" {
+		return adj, nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, adjustmentsFile))
+	if os.IsNotExist(err) {
+		return adj, nil
+	}
+	if err != nil {
+		return adj, fmt.Errorf("read series adjustments: %w", err)
+	}
+	if err := json.Unmarshal(data, &adj); err != nil {
+		return adj, fmt.Errorf("parse series adjustments: %w", err)
+	}
+	return adj, nil
+}
+
+func saveAdjustments(dir string, adj Adjustments) error {
+	if dir == "" {
+		return fmt.Errorf("no folder to store series adjustments")
+	}
+	path := filepath.Join(dir, adjustmentsFile)
+	if adj.empty() {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove series adjustments: %w", err)
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(adj, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode series adjustments: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write series adjustments: %w", err)
+	}
+	return nil
+}
+
+// adjustmentName normalizes an entry to a slash-separated relative path.
+func adjustmentName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ""
+	}
+	return filepath.ToSlash(filepath.Clean(name))
+}
+
+// apply splits and merges chronologically ordered groups according to the adjustments.
+// root is the adjustments folder that relative entries are resolved against.
+func (a Adjustments) apply(groups []seriesGroup, root string) []seriesGroup {
+	if a.empty() || len(groups) == 0 {
+		return groups
+	}
+	splitSet := nameSet(a.SplitAt)
+	mergeSet := nameSet(a.MergeAt)
+
+	var split []seriesGroup
+	for _, group := range groups {
+		current := seriesGroup{ForcedType: group.ForcedType}
+		for _, job := range group.Jobs {
+			if splitSet.matches(job.Path, root) && len(current.Jobs) > 0 {
+				split = append(split, current)
+				current = seriesGroup{ForcedType: group.ForcedType}
+			}
+			current.Jobs = append(current.Jobs, job)
+		}
+		split = append(split, current)
+	}
+
+	var merged []seriesGroup
+	for i := 0; i < len(split); i++ {
+		group := split[i]
+		for i+1 < len(split) && groupHasName(group, mergeSet, root) {
+			next := split[i+1]
+			group.Jobs = append(group.Jobs, next.Jobs...)
+			if group.ForcedType == nil {
+				group.ForcedType = next.ForcedType
+			}
+			i++
+			// Only the frames of the group that was merged in can request another merge.
+			if !groupHasName(next, mergeSet, root) {
+				break
+			}
+		}
+		merged = append(merged, group)
+	}
+	return merged
+}
+
+// adjustmentSet holds lower-cased adjustment entries.
+type adjustmentSet map[string]struct{}
+
+func nameSet(names []string) adjustmentSet {
+	set := make(adjustmentSet, len(names))
+	for _, name := range names {
+		if name = adjustmentName(name); name != "" {
+			set[strings.ToLower(name)] = struct{}{}
+		}
+	}
+	return set
+}
+
+// matches reports whether path is listed by base name or by its path relative to root.
+func (s adjustmentSet) matches(path, root string) bool {
+	if _, ok := s[strings.ToLower(filepath.Base(path))]; ok {
+		return true
+	}
+	if root == "" {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	_, ok := s[strings.ToLower(filepath.ToSlash(rel))]
+	return ok
+}
+
+func groupHasName(group seriesGroup, set adjustmentSet, root string) bool {
+	for _, job := range group.Jobs {
+		if set.matches(job.Path, root) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package series
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultCameraInclude keeps series detection to Canon bodies, whose bracketing
+// metadata it reads, unless other include rules are given.
+var DefaultCameraInclude = []string{"make:canon"}
+
+// CameraRules decide which cameras' photos series detection accepts. A rule is
+// "make:TEXT", "model:TEXT" or plain TEXT (make or model), matched
+// case-insensitively as a substring; "*" matches every camera.
+type CameraRules struct {
+	Include []string // a photo must match one of these; DefaultCameraInclude when empty
+	Exclude []string // a photo must match none of these
+
+	include, exclude []cameraRule
+}
+
+type cameraRule struct {
+	field string // "make", "model", or "" for either
+	text  string // lower case; "" matches everything
+	spec  string
+}
+
+// load parses the rules.
+func (r *CameraRules) load() error {
+	include := r.Include
+	if len(include) == 0 {
+		include = DefaultCameraInclude
+	}
+	var err error
+	if r.include, err = parseCameraRules(include); err != nil {
+		return err
+	}
+	r.exclude, err = parseCameraRules(r.Exclude)
+	return err
+}
+
+func parseCameraRules(specs []string) ([]cameraRule, error) {
+	var rules []cameraRule
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		rule := cameraRule{spec: spec}
+		field, text, ok := strings.Cut(spec, ":")
+		switch field = strings.ToLower(strings.TrimSpace(field)); {
+		case ok && (field == "make" || field == "model"):
+			rule.field, rule.text = field, strings.TrimSpace(text)
+		case ok:
+			return nil, fmt.Errorf("invalid camera rule %q (expected make:TEXT, model:TEXT or TEXT)", spec)
+		default:
+			rule.text = spec
+		}
+		switch rule.text {
+		case "":
+			return nil, fmt.Errorf("camera rule %q has no text to match", spec)
+		case "*":
+			rule.text = ""
+		}
+		rule.text = strings.ToLower(rule.text)
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (c cameraRule) match(makeStr, model string) bool {
+	makeStr, model = strings.ToLower(makeStr), strings.ToLower(model)
+	switch c.field {
+	case "make":
+		return strings.Contains(makeStr, c.text)
+	case "model":
+		return strings.Contains(model, c.text)
+	}
+	return strings.Contains(makeStr, c.text) || strings.Contains(model, c.text)
+}
+
+// Allows reports whether photos of the camera are used for series detection
+// and, when they are not, the rule that turned them away.
+func (r CameraRules) Allows(makeStr, model string) (bool, string) {
+	for _, rule := range r.exclude {
+		if rule.match(makeStr, model) {
+			return false, "excluded by " + rule.spec
+		}
+	}
+	var specs []string
+	for _, rule := range r.include {
+		if rule.match(makeStr, model) {
+			return true, ""
+		}
+		specs = append(specs, rule.spec)
+	}
+	return false, "not included by " + strings.Join(specs, ", ")
+}
@@ -0,0 +1,22 @@
+package series
+
+import "github.com/nir0k/GeoRAW/internal/app"
+
+// collectFiles lists the input with the run's file limit, logging the progress
+// of long walks and passing it to opts.ScanProgress. It reports whether the limit
+// cut the list short.
+func collectFiles(opts Options, infof, warnf func(string, ...interface{})) ([]string, bool, error) {
+	var files []string
+	w := app.InputWalk{Inputs: opts.Inputs, Recursive: opts.Recursive, MaxFiles: opts.MaxFiles, ScanProgress: opts.ScanProgress}
+	truncated, err := app.WalkInput(w, infof, warnf, func(path string) error {
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if truncated {
+		warnf("Series crossing the limit of %d files may be split", opts.MaxFiles)
+	}
+	return files, truncated, nil
+}
@@ -0,0 +1,161 @@
+package series
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/media"
+)
+
+const contactThumbSide = 240
+
+// sheetRow describes one detected series on the contact sheet.
+type sheetRow struct {
+	ID       string
+	Type     string
+	Tagged   bool
+	Reason   string
+	EVSpread float64
+	Start    time.Time
+	Duration time.Duration
+	Frames   []sheetFrame
+}
+
+type sheetFrame struct {
+	Name     string
+	Exposure string
+	Thumb    template.URL
+	Pick     bool
+}
+
+// contactSheet accumulates series rows during a run and renders them as HTML.
+type contactSheet struct {
+	rows []sheetRow
+}
+
+func (c *contactSheet) add(group []seriesJob, id, typeTag string, tagged bool, reason string, pickIdx int) {
+	if c == nil || len(group) == 0 {
+		return
+	}
+	first := group[0].Meta.CaptureTime
+	last := group[len(group)-1].Meta.CaptureTime
+	row := sheetRow{
+		ID:       id,
+		Type:     typeTag,
+		Tagged:   tagged,
+		Reason:   reason,
+		EVSpread: evSpread(group),
+		Start:    first,
+		Duration: last.Sub(first),
+	}
+	for i, job := range group {
+		frame := sheetFrame{
+			Name:     filepath.Base(job.Path),
+			Exposure: exposureLabel(job.Meta),
+			Pick:     i == pickIdx,
+		}
+		if thumb, err := media.PreviewThumbnail(job.Path, contactThumbSide); err == nil {
+			frame.Thumb = template.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(thumb))
+		}
+		row.Frames = append(row.Frames, frame)
+	}
+	c.rows = append(c.rows, row)
+}
+
+func (c *contactSheet) write(path string, generated time.Time) error {
+	if c == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create contact sheet dir: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create contact sheet: %w", err)
+	}
+	defer file.Close()
+
+	data := struct {
+		Generated string
+		Rows      []sheetRow
+	}{
+		Generated: generated.Format("2006-01-02 15:04:05"),
+		Rows:      c.rows,
+	}
+	if err := contactSheetTemplate.Execute(file, data); err != nil {
+		return fmt.Errorf("render contact sheet: %w", err)
+	}
+	return file.Close()
+}
+
+func exposureLabel(meta media.SeriesMetadata) string {
+	label := ""
+	if meta.ExposureTime > 0 {
+		if meta.ExposureTime < 1 {
+			label = fmt.Sprintf("1/%.0fs", 1/meta.ExposureTime)
+		} else {
+			label = fmt.Sprintf("%.1fs", meta.ExposureTime)
+		}
+	}
+	if meta.FNumber > 0 {
+		label += fmt.Sprintf(" f/%.1f", meta.FNumber)
+	}
+	if meta.ISO > 0 {
+		label += fmt.Sprintf(" ISO%d", meta.ISO)
+	}
+	return label
+}
+
+var contactSheetTemplate = template.Must(template.New("sheet").Funcs(template.FuncMap{
+	"ev": func(v float64) string { return fmt.Sprintf("%.1f EV", v) },
+	"ts": func(t time.Time) string { return t.Format("2006-01-02 15:04:05.000") },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>GeoRAW series review</title>
+<style>
+  body { font-family: system-ui, sans-serif; background: #0f172a; color: #e6eefc; margin: 24px; }
+  h1 { font-size: 20px; margin: 0 0 4px; }
+  .muted { color: #9ca3af; font-size: 13px; }
+  .series { border: 1px solid rgba(255,255,255,0.12); border-radius: 8px; padding: 12px; margin: 16px 0; }
+  .series.untagged { opacity: 0.6; }
+  .head { display: flex; gap: 16px; align-items: baseline; flex-wrap: wrap; margin-bottom: 8px; }
+  .id { font-weight: 600; }
+  .badge { background: #38bdf8; color: #0f172a; border-radius: 999px; padding: 1px 8px; font-size: 12px; }
+  .frames { display: flex; gap: 8px; overflow-x: auto; }
+  figure { margin: 0; text-align: center; font-size: 12px; }
+  figure img, figure .noimg { width: 240px; height: 160px; object-fit: contain; background: #1e293b; display: block; border-radius: 4px; }
+  figure.pick img { outline: 3px solid #facc15; }
+  figure .noimg { line-height: 160px; color: #9ca3af; }
+</style>
+</head>
+<body>
+<h1>Series review</h1>
+<div class="muted">Generated {{.Generated}} &middot; {{len .Rows}} series</div>
+{{range .Rows}}
+<div class="series{{if not .Tagged}} untagged{{end}}">
+  <div class="head">
+    <span class="id">{{if .ID}}{{.ID}}{{else}}(not tagged){{end}}</span>
+    {{if .Tagged}}<span class="badge">{{.Type}}</span>{{else}}<span class="muted">{{.Reason}}</span>{{end}}
+    <span class="muted">{{len .Frames}} frames</span>
+    <span class="muted">spread {{ev .EVSpread}}</span>
+    <span class="muted">{{ts .Start}} &middot; {{.Duration}}</span>
+  </div>
+  <div class="frames">
+    {{range .Frames}}
+    <figure{{if .Pick}} class="pick"{{end}}>
+      {{if .Thumb}}<img src="{{.Thumb}}" alt="{{.Name}}">{{else}}<div class="noimg">no preview</div>{{end}}
+      <figcaption>{{.Name}}<br><span class="muted">{{.Exposure}}</span></figcaption>
+    </figure>
+    {{end}}
+  </div>
+</div>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,15 @@
+package series
+
+import "github.com/nir0k/GeoRAW/internal/media"
+
+// embedKeywords also adds the series keywords to the file itself when requested
+// and the format holds them, with the writer media.EmbeddedWriter names. A
+// failure is only a warning: the sidecar already has the keywords.
+func embedKeywords(opts Options, path string, tags []string, warnf func(string, ...interface{})) {
+	if !opts.EmbedKeywords || !media.SupportedEmbed(path) {
+		return
+	}
+	if err := media.AddKeywords(path, tags); err != nil {
+		warnf("Failed to write series keywords into %s: %v (the sidecar has them)", path, err)
+	}
+}
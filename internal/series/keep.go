@@ -0,0 +1,111 @@
+package series
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// typeTagModes maps the type keywords of tagged series to their mode.
+var typeTagModes = map[string]Mode{seriesTypeTag: ModeHDR, panoTypeTag: ModePano}
+
+// idPattern matches the series IDs tmpl produces, whatever their date, camera and
+// index. Besides prefix, IDs may start with either type keyword, the default
+// prefixes of the two modes.
+func idPattern(tmpl, prefix string) *regexp.Regexp {
+	prefixes := regexp.QuoteMeta(prefix) + "|" + seriesTypeTag + "|" + panoTypeTag
+	var b strings.Builder
+	b.WriteString("^")
+	last := 0
+	for _, loc := range placeholderRegex.FindAllStringSubmatchIndex(tmpl, -1) {
+		b.WriteString(regexp.QuoteMeta(tmpl[last:loc[0]]))
+		switch tmpl[loc[2]:loc[3]] {
+		case "prefix":
+			b.WriteString("(?:" + prefixes + ")")
+		case "date":
+			b.WriteString(`(?:\d{8}|nodate)`)
+		case "camera":
+			b.WriteString(`[A-Za-z0-9_-]+`)
+		case "type":
+			b.WriteString("(?:" + seriesTypeTag + "|" + panoTypeTag + ")")
+		case "index":
+			b.WriteString(`\d+`)
+		}
+		last = loc[1]
+	}
+	b.WriteString(regexp.QuoteMeta(tmpl[last:]))
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// taggedGroups takes the frames whose sidecars already carry a series type
+// keyword and ID out of jobs and groups them by that ID, so corrections made in
+// a photo editor (keywords moved to other frames or removed) are kept. jobs must
+// be sorted by capture time; the frames without series keywords are returned for
+// automatic grouping.
+func taggedGroups(jobs []seriesJob, opts Options, warnf func(string, ...interface{})) ([]seriesGroup, []seriesJob) {
+	pattern := idPattern(opts.IDTemplate, opts.Prefix)
+	byID := make(map[string]*seriesGroup)
+	var ids []string
+	free := make([]seriesJob, 0, len(jobs))
+	for _, job := range jobs {
+		sidecar := xmp.SidecarPath(job.Path)
+		keywords, err := xmp.ReadKeywords(sidecar)
+		if err != nil {
+			warnf("Failed to read series keywords of %s: %v", job.Path, err)
+		}
+		mode, id := taggedSeries(keywords, pattern, sidecar)
+		if id == "" {
+			free = append(free, job)
+			continue
+		}
+		group, ok := byID[id]
+		if !ok {
+			group = &seriesGroup{ID: id, ForcedType: &mode}
+			byID[id] = group
+			ids = append(ids, id)
+		}
+		group.Jobs = append(group.Jobs, job)
+	}
+
+	groups := make([]seriesGroup, 0, len(ids))
+	for _, id := range ids {
+		groups = append(groups, *byID[id])
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Jobs[0].Meta.CaptureTime.Before(groups[j].Jobs[0].Meta.CaptureTime)
+	})
+	return groups, free
+}
+
+// taggedSeries returns the mode and ID of the series a frame's keywords put it
+// in, or an empty ID when it has no type keyword or no recognizable ID. An ID the
+// template does not match is still taken from georaw:SeriesID while it is one
+// of the keywords.
+func taggedSeries(keywords []string, pattern *regexp.Regexp, sidecar string) (Mode, string) {
+	var mode Mode
+	for _, k := range keywords {
+		if m, ok := typeTagModes[k]; ok {
+			mode = m
+			break
+		}
+	}
+	if mode == "" {
+		return "", ""
+	}
+	for _, k := range keywords {
+		if _, ok := typeTagModes[k]; !ok && pattern.MatchString(k) {
+			return mode, k
+		}
+	}
+	if id := xmp.ReadSeriesID(sidecar); id != "" {
+		for _, k := range keywords {
+			if k == id {
+				return mode, id
+			}
+		}
+	}
+	return mode, ""
+}
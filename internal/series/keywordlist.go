@@ -0,0 +1,94 @@
+package series
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// keywordListRoot is the category every generated keyword is filed under. The
+// brackets mark it as a Lightroom category, so it is never exported with photos.
+const keywordListRoot = "[GeoRAW]"
+
+// keywordList collects the keywords a run wrote so they can be saved as a
+// Lightroom keyword list: one keyword per line, children indented by tabs.
+type keywordList struct {
+	series map[string]map[string]bool // type tag -> series IDs
+	other  map[string]bool            // extra tags and the pick keyword
+}
+
+func newKeywordList() *keywordList {
+	return &keywordList{series: make(map[string]map[string]bool), other: make(map[string]bool)}
+}
+
+// add records the keywords of a frame whose sidecar now carries them. Series IDs
+// are filed under their type tag; everything else sits directly under the root.
+func (k *keywordList) add(f frameTask) {
+	if k == nil {
+		return
+	}
+	ids := k.series[f.TypeTag]
+	if ids == nil {
+		ids = make(map[string]bool)
+		k.series[f.TypeTag] = ids
+	}
+	for _, tag := range f.Tags {
+		switch tag {
+		case f.TypeTag:
+		case f.SeriesID:
+			ids[tag] = true
+		default:
+			k.other[tag] = true
+		}
+	}
+}
+
+// count is the number of keywords in the list, not counting the root category.
+func (k *keywordList) count() int {
+	n := len(k.other)
+	for typeTag, ids := range k.series {
+		if !k.other[typeTag] {
+			n++
+		}
+		n += len(ids)
+	}
+	return n
+}
+
+func (k *keywordList) write(path string) error {
+	if k == nil {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString(keywordListRoot + "\n")
+	for _, typeTag := range sortedKeys(k.series) {
+		b.WriteString("\t" + typeTag + "\n")
+		for _, id := range sortedKeys(k.series[typeTag]) {
+			b.WriteString("\t\t" + id + "\n")
+		}
+	}
+	for _, tag := range sortedKeys(k.other) {
+		if _, isType := k.series[tag]; !isType {
+			b.WriteString("\t" + tag + "\n")
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create keyword list dir: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		return fmt.Errorf("write keyword list: %w", err)
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,45 @@
+package series
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultLabels are the color labels written by --color-labels, so series stand
+// out in Lightroom and Bridge without keyword filtering.
+var DefaultLabels = map[Mode]string{ModeHDR: "Blue", ModePano: "Purple"}
+
+// standardLabels are the color label names of the default Lightroom/Bridge label
+// set; other names are written as given, for custom label sets.
+var standardLabels = []string{"Red", "Yellow", "Green", "Blue", "Purple"}
+
+// ParseLabels reads TYPE=LABEL entries (e.g. "hdr=Blue") into a label per series
+// type, starting from base. Standard color names are matched case-insensitively
+// and written in their canonical spelling.
+func ParseLabels(base map[Mode]string, entries []string) (map[Mode]string, error) {
+	labels := make(map[Mode]string, len(base)+len(entries))
+	for mode, label := range base {
+		labels[mode] = label
+	}
+	for _, entry := range entries {
+		key, label, ok := strings.Cut(entry, "=")
+		key, label = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(label)
+		if !ok || key == "" || label == "" {
+			return nil, fmt.Errorf("invalid label %q (expected TYPE=LABEL, e.g. hdr=Blue)", entry)
+		}
+		if Mode(key) != ModeHDR && Mode(key) != ModePano {
+			return nil, fmt.Errorf("unknown series type %q in label %q (expected hdr or pano)", key, entry)
+		}
+		labels[Mode(key)] = canonicalLabel(label)
+	}
+	return labels, nil
+}
+
+func canonicalLabel(label string) string {
+	for _, std := range standardLabels {
+		if strings.EqualFold(label, std) {
+			return std
+		}
+	}
+	return label
+}
@@ -0,0 +1,261 @@
+package series
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// Limits of a consistent bracket: the most two exposure steps may differ in EV,
+// and how much the focus distance or focal length may drift (as a fraction).
+const (
+	matrixStepTolerance  = 0.34
+	matrixFocusTolerance = 0.05
+	matrixFocalTolerance = 0.02
+)
+
+// MatrixFrame is one frame's row in a series comparison.
+type MatrixFrame struct {
+	Path         string    `json:"path"`
+	Time         time.Time `json:"time"`
+	Gap          float64   `json:"gap"`          // seconds from the end of the previous frame's exposure
+	ExposureTime float64   `json:"exposureTime"` // seconds
+	FNumber      float64   `json:"fNumber"`
+	ISO          uint32    `json:"iso"`
+	ExposureBias float64   `json:"exposureBias"` // EV
+	FocalLength  float64   `json:"focalLength"`  // millimeters
+	Distance     float64   `json:"distance"`     // subject (focus) distance in meters, 0 when not recorded
+	Stops        float64   `json:"stops"`        // exposure relative to the first frame in EV, brighter positive
+	Error        string    `json:"error,omitempty"`
+}
+
+// Matrix compares the key fields of the frames of one series, so a broken
+// bracket (a changed aperture, a refocus, a missing step) shows at a glance.
+type Matrix struct {
+	ID     string        `json:"id"`   // empty for selected frames without series keywords
+	Type   string        `json:"type"` // series type keyword the checks follow
+	Camera string        `json:"camera"`
+	Frames []MatrixFrame `json:"frames"`
+	Issues []string      `json:"issues,omitempty"`
+}
+
+// CompareSeries reads the frames of opts.Inputs and returns one matrix per
+// series ID found in their sidecar keywords, plus one for the frames without
+// series keywords. A non-empty id keeps only that series. Nothing is written.
+func CompareSeries(ctx context.Context, opts Options, id string) ([]Matrix, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	id = strings.TrimSpace(id)
+	nop := func(string, ...interface{}) {}
+	files, _, err := collectFiles(opts, nop, nop)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern := idPattern(opts.IDTemplate, opts.Prefix)
+	type series struct {
+		mode   Mode
+		jobs   []seriesJob
+		errors []MatrixFrame
+	}
+	byID := make(map[string]*series)
+	var ids []string
+	for _, path := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext == ".xmp" || filepath.Base(path) == adjustmentsFile {
+			continue
+		}
+		if !media.SupportedRaw(path) && !isHDRMergedCandidate(ext) {
+			continue
+		}
+		if _, ok := media.LivePhotoStill(path); ok {
+			continue
+		}
+		sidecar := xmp.SidecarPath(path)
+		keywords, _ := xmp.ReadKeywords(sidecar)
+		mode, tagged := taggedSeries(keywords, pattern, sidecar)
+		if id != "" && tagged != id {
+			continue
+		}
+		s, ok := byID[tagged]
+		if !ok {
+			s = &series{mode: mode}
+			byID[tagged] = s
+			ids = append(ids, tagged)
+		}
+		meta, err := media.ReadSeriesMetadata(path)
+		if err != nil {
+			s.errors = append(s.errors, MatrixFrame{Path: path, Error: err.Error()})
+			continue
+		}
+		s.jobs = append(s.jobs, seriesJob{Path: path, Meta: meta, Seq: parseSequence(path)})
+	}
+	if len(ids) == 0 {
+		if id != "" {
+			return nil, fmt.Errorf("no frames tagged with series %q found", id)
+		}
+		return nil, fmt.Errorf("no photos found to compare")
+	}
+
+	matrices := make([]Matrix, 0, len(ids))
+	for _, key := range ids {
+		s := byID[key]
+		mode := s.mode
+		if mode == "" {
+			mode = opts.Mode
+			if mode == ModeAuto && isPanoSource(s.jobs) {
+				mode = ModePano
+			}
+		}
+		matrices = append(matrices, seriesMatrix(key, mode, s.jobs, s.errors))
+	}
+	sort.SliceStable(matrices, func(i, j int) bool {
+		a, b := matrices[i].Frames, matrices[j].Frames
+		return len(b) == 0 || (len(a) > 0 && a[0].Time.Before(b[0].Time))
+	})
+	return matrices, nil
+}
+
+// seriesMatrix lays out the frames of one series in capture order, unreadable
+// ones last, and checks them against the series type.
+func seriesMatrix(id string, mode Mode, jobs []seriesJob, unreadable []MatrixFrame) Matrix {
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].Meta.CaptureTime.Before(jobs[j].Meta.CaptureTime)
+	})
+	m := Matrix{ID: id, Type: mode.typeTag()}
+	for i, job := range jobs {
+		meta := job.Meta
+		frame := MatrixFrame{
+			Path:         job.Path,
+			Time:         meta.CaptureTime,
+			ExposureTime: meta.ExposureTime,
+			FNumber:      meta.FNumber,
+			ISO:          meta.ISO,
+			ExposureBias: meta.ExposureBias,
+			FocalLength:  meta.FocalLength,
+			Distance:     meta.Distance,
+		}
+		if i > 0 {
+			prev := jobs[i-1].Meta
+			end := prev.CaptureTime.Add(durationFromExposure(prev.ExposureTime))
+			frame.Gap = math.Round(meta.CaptureTime.Sub(end).Seconds()*1000) / 1000
+		}
+		if first := jobs[0].Meta; ev(first) != 0 && ev(meta) != 0 {
+			frame.Stops = math.Round((ev(first)-ev(meta))*100) / 100
+		}
+		if m.Camera == "" {
+			m.Camera = app.CameraName(meta.CameraMake, meta.CameraModel)
+		}
+		m.Frames = append(m.Frames, frame)
+	}
+	m.Frames = append(m.Frames, unreadable...)
+	m.Issues = matrixIssues(mode, jobs, len(unreadable))
+	return m
+}
+
+// matrixIssues lists what breaks the integrity of a series of mode: frames
+// from other cameras, a changed aperture, focal length or focus, exposure steps
+// that do not fit the type, and pauses longer than detection allows.
+func matrixIssues(mode Mode, jobs []seriesJob, unreadable int) []string {
+	var issues []string
+	if unreadable > 0 {
+		issues = append(issues, fmt.Sprintf("%d frame(s) unreadable", unreadable))
+	}
+	if len(jobs) < 2 {
+		return append(issues, fmt.Sprintf("only %d readable frame(s)", len(jobs)))
+	}
+
+	cameras := make(map[string]bool)
+	var apertures, focals, distances, evs []float64
+	for _, job := range jobs {
+		cameras[app.CameraName(job.Meta.CameraMake, job.Meta.CameraModel)] = true
+		if job.Meta.FNumber > 0 {
+			apertures = append(apertures, job.Meta.FNumber)
+		}
+		if job.Meta.FocalLength > 0 {
+			focals = append(focals, job.Meta.FocalLength)
+		}
+		if job.Meta.Distance > 0 {
+			distances = append(distances, job.Meta.Distance)
+		}
+		if v := ev(job.Meta); v != 0 {
+			evs = append(evs, v)
+		}
+	}
+	if len(cameras) > 1 {
+		issues = append(issues, fmt.Sprintf("frames from %d cameras", len(cameras)))
+	}
+	if lo, hi := floatRange(apertures); hi-lo > 0.05 {
+		issues = append(issues, fmt.Sprintf("aperture changes from f/%.1f to f/%.1f", lo, hi))
+	}
+	if lo, hi := floatRange(focals); lo > 0 && (hi-lo)/lo > matrixFocalTolerance {
+		issues = append(issues, fmt.Sprintf("focal length changes from %.0f to %.0f mm", lo, hi))
+	}
+	if lo, hi := floatRange(distances); lo > 0 && (hi-lo)/lo > matrixFocusTolerance {
+		issues = append(issues, fmt.Sprintf("focus distance changes from %.2f to %.2f m", lo, hi))
+	}
+
+	maxGap := maxGapSequential
+	sort.Float64s(evs)
+	spread := 0.0
+	if len(evs) > 0 {
+		spread = evs[len(evs)-1] - evs[0]
+	}
+	switch mode {
+	case ModePano:
+		maxGap = maxGapPano
+		if spread >= evPanoTolerance {
+			issues = append(issues, fmt.Sprintf("exposure changes by %.1f EV across the panorama", spread))
+		}
+	default:
+		if spread < evHDRThreshold {
+			issues = append(issues, fmt.Sprintf("exposure hardly changes (%.1f EV), not a bracket", spread))
+		} else if steps := bracketSteps(evs); len(steps) > 1 {
+			if lo, hi := floatRange(steps); hi-lo > matrixStepTolerance {
+				issues = append(issues, fmt.Sprintf("uneven bracket steps from %.1f to %.1f EV", lo, hi))
+			}
+		}
+	}
+	for i := 1; i < len(jobs); i++ {
+		prev := jobs[i-1].Meta
+		gap := jobs[i].Meta.CaptureTime.Sub(prev.CaptureTime.Add(durationFromExposure(prev.ExposureTime)))
+		if gap > maxGap {
+			issues = append(issues, fmt.Sprintf("%s pause before %s", gap.Round(100*time.Millisecond), filepath.Base(jobs[i].Path)))
+		}
+	}
+	return issues
+}
+
+// bracketSteps returns the EV differences between neighbouring exposures of a
+// sorted list, whatever order the camera shot them in.
+func bracketSteps(sorted []float64) []float64 {
+	steps := make([]float64, 0, len(sorted))
+	for i := 1; i < len(sorted); i++ {
+		steps = append(steps, sorted[i]-sorted[i-1])
+	}
+	return steps
+}
+
+// floatRange returns the smallest and largest value, zeros when there are none.
+func floatRange(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values[1:] {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	return lo, hi
+}
@@ -7,6 +7,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/nir0k/GeoRAW/internal/errcode"
 )
 
 // Mode represents detection mode.
@@ -15,35 +18,77 @@ type Mode string
 const (
 	ModeAuto Mode = "auto"
 	ModeHDR  Mode = "hdr"
+	ModePano Mode = "pano" // constant-exposure sequences shot as panorama sources
+)
+
+const (
+	seriesTypeTag = "hdr_mode"
+	panoTypeTag   = "pano_mode"
 )
 
-const seriesTypeTag = "hdr_mode"
+// typeTag is the keyword marking the series found in mode.
+func (m Mode) typeTag() string {
+	if m == ModePano {
+		return panoTypeTag
+	}
+	return seriesTypeTag
+}
 
 // Options represents user-provided parameters for series tagging.
 type Options struct {
-	InputPath    string
-	Recursive    bool
-	LogLevel     string
-	LogFile      string
-	Overwrite    bool
-	Mode         Mode
-	Prefix       string
-	StartIndex   int
-	ExtraTags    string
-	PrintSummary bool
-	Progress     func(done, total int)
+	Inputs           []string // photo files, folders or glob patterns, each taken whole
+	Recursive        bool
+	LogLevel         string
+	LogFile          string
+	Overwrite        bool
+	Mode             Mode
+	Prefix           string
+	StartIndex       int
+	IDTemplate       string
+	ExtraTags        string
+	SplitAt          []string
+	MergeAt          []string
+	ResetAdjustments bool
+	Pick             PickMode
+	PickKeyword      string
+	PickRating       int
+	WritePosition    bool
+	Labels           map[Mode]string // xmp:Label written to the frames of each series type; none when empty
+	ContactSheet     string
+	KeywordList      string // Lightroom keyword list of every keyword written; none when empty
+	EmbedKeywords    bool   // also add the keywords to JPEG, TIFF and DNG files themselves, see media.AddKeywords
+	GPano            bool   // write GPano source hints into the frames of panorama series
+	DryRun           bool   // detect and report the series without writing sidecars or adjustments
+	KeepTagged       bool   // keep frames that already carry series keywords in those series; group only the rest
+	SyncPairs        bool
+	PrintSummary     bool
+	Confirm          app.ConfirmFunc         // optional review of planned writes before anything is written
+	RunID            string                  // identifies the run in logs and stamps; generated when empty
+	StampRun         bool                    // write georaw:LastRunID/LastRunDate into every written sidecar
+	Attribution      app.AttributionTemplate // creator/copyright written to every tagged sidecar
+	Lock             app.LockMode            // behaviour when another run writes the same folder tree; refuse by default
+	Cameras          CameraRules             // make/model rules for the photos used; Canon only by default
+	MaxFiles         int                     // stop walking the input after this many files; 0 means no limit
+	ScanProgress     func(dirs, files int)   // optional report of the input walk, before processing progress is known
+	Progress         func(done, total int)
 }
 
 // Validate performs basic validation and assigns defaults where needed.
 func (o *Options) Validate() error {
-	o.InputPath = strings.TrimSpace(o.InputPath)
 	o.LogLevel = strings.TrimSpace(o.LogLevel)
 	o.LogFile = strings.TrimSpace(o.LogFile)
 	o.Prefix = strings.TrimSpace(o.Prefix)
 	o.ExtraTags = strings.TrimSpace(o.ExtraTags)
+	o.IDTemplate = strings.TrimSpace(o.IDTemplate)
+	o.PickKeyword = strings.TrimSpace(o.PickKeyword)
+	o.ContactSheet = strings.TrimSpace(o.ContactSheet)
+	o.KeywordList = strings.TrimSpace(o.KeywordList)
+	if o.RunID == "" {
+		o.RunID = app.NewRunID()
+	}
 
-	if o.InputPath == "" {
-		return fmt.Errorf("input path is required")
+	if len(o.Inputs) == 0 {
+		return errcode.New(errcode.InputRequired, nil)
 	}
 	if o.LogLevel == "" {
 		o.LogLevel = "info"
@@ -60,13 +105,16 @@ func (o *Options) Validate() error {
 		o.Mode = ModeAuto
 	}
 	switch o.Mode {
-	case ModeAuto, ModeHDR:
+	case ModeAuto, ModeHDR, ModePano:
 	default:
-		return fmt.Errorf("invalid mode %q (expected auto or hdr)", o.Mode)
+		return fmt.Errorf("invalid mode %q (expected auto, hdr or pano)", o.Mode)
+	}
+	if o.GPano && o.Mode != ModePano {
+		return fmt.Errorf("GPano hints are only written in pano mode")
 	}
 
 	if o.Prefix == "" {
-		o.Prefix = seriesTypeTag
+		o.Prefix = o.Mode.typeTag()
 	}
 	if len(o.Prefix) < 3 {
 		return fmt.Errorf("prefix must be at least 3 characters")
@@ -74,6 +122,45 @@ func (o *Options) Validate() error {
 	if o.StartIndex < 1 {
 		o.StartIndex = 1
 	}
+	if o.IDTemplate == "" {
+		o.IDTemplate = DefaultIDTemplate
+	}
+	if err := validateIDTemplate(o.IDTemplate); err != nil {
+		return err
+	}
+	if err := o.Attribution.Validate(); err != nil {
+		return err
+	}
+	if err := o.Cameras.load(); err != nil {
+		return err
+	}
+	lock, err := app.ParseLockMode(string(o.Lock))
+	if err != nil {
+		return err
+	}
+	o.Lock = lock
+
+	o.Pick = PickMode(strings.ToLower(string(o.Pick)))
+	if o.Pick == "" {
+		o.Pick = PickOff
+	}
+	switch o.Pick {
+	case PickOff, PickRating, PickKeyword:
+	default:
+		return fmt.Errorf("invalid pick mode %q (expected off, rating or keyword)", o.Pick)
+	}
+	if o.PickKeyword == "" {
+		o.PickKeyword = defaultPickKeyword
+	}
+	if o.PickRating == 0 {
+		o.PickRating = defaultPickRating
+	}
+	if o.PickRating < 1 || o.PickRating > 5 {
+		return fmt.Errorf("pick rating must be between 1 and 5")
+	}
+	if o.MaxFiles < 0 {
+		return fmt.Errorf("max files must not be negative")
+	}
 
 	return nil
 }
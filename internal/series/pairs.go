@@ -0,0 +1,43 @@
+package series
+
+import (
+	"errors"
+
+	"github.com/nir0k/GeoRAW/internal/xmp"
+)
+
+// syncPairKeywords makes the RAW sidecar and the sidecars of its JPEG/HEIF companions
+// carry the union of their keywords. It returns the companions that were updated.
+func syncPairKeywords(rawPath string, companions []string) ([]string, error) {
+	sidecars := []string{xmp.SidecarPath(rawPath)}
+	for _, c := range companions {
+		sidecars = append(sidecars, xmp.CompanionSidecarPath(c))
+	}
+
+	var union []string
+	for _, sidecar := range sidecars {
+		kws, err := xmp.ReadKeywords(sidecar)
+		if err != nil {
+			return nil, err
+		}
+		union = append(union, kws...)
+	}
+	if len(union) == 0 {
+		return nil, nil
+	}
+
+	var updated []string
+	for i, sidecar := range sidecars {
+		wrote, err := xmp.MergeKeywords(sidecar, union, false)
+		if errors.Is(err, xmp.ErrKeywordsAlreadyPresent) {
+			continue
+		}
+		if err != nil {
+			return updated, err
+		}
+		if wrote && i > 0 {
+			updated = append(updated, companions[i-1])
+		}
+	}
+	return updated, nil
+}
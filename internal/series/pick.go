@@ -0,0 +1,59 @@
+package series
+
+import (
+	"sort"
+)
+
+// PickMode controls how the best frame of a series is marked.
+type PickMode string
+
+const (
+	PickOff     PickMode = "off"
+	PickRating  PickMode = "rating"
+	PickKeyword PickMode = "keyword"
+)
+
+const (
+	defaultPickKeyword = "series_pick"
+	defaultPickRating  = 5
+)
+
+// selectBestFrame returns the index of the preferred frame within a group.
+// Exposure brackets use the middle exposure; other series use the sharpest preview.
+func selectBestFrame(group []seriesJob, sharpness func(path string) (float64, error), warnf func(string, ...interface{})) int {
+	if len(group) == 0 {
+		return -1
+	}
+	if evSpread(group) >= evHDRThreshold {
+		return middleExposure(group)
+	}
+
+	best := -1
+	bestScore := 0.0
+	for i, job := range group {
+		score, err := sharpness(job.Path)
+		if err != nil {
+			warnf("Unable to score sharpness for %s: %v", job.Path, err)
+			continue
+		}
+		if best == -1 || score > bestScore {
+			best = i
+			bestScore = score
+		}
+	}
+	if best == -1 {
+		return middleExposure(group)
+	}
+	return best
+}
+
+func middleExposure(group []seriesJob) int {
+	idx := make([]int, len(group))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		return ev(group[idx[a]].Meta) < ev(group[idx[b]].Meta)
+	})
+	return idx[len(idx)/2]
+}
@@ -1,11 +1,10 @@
 package series
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"math"
 	"path/filepath"
 	"sort"
@@ -14,9 +13,11 @@ import (
 	"time"
 
 	"github.com/nir0k/GeoRAW/internal/app"
+	"github.com/nir0k/GeoRAW/internal/errcode"
+	"github.com/nir0k/GeoRAW/internal/logging"
 	"github.com/nir0k/GeoRAW/internal/media"
+	"github.com/nir0k/GeoRAW/internal/pathnorm"
 	"github.com/nir0k/GeoRAW/internal/xmp"
-	"github.com/nir0k/logger"
 )
 
 const (
@@ -24,6 +25,10 @@ const (
 	maxGapDefault            = 1100 * time.Millisecond
 	maxGapSequential         = 2200 * time.Millisecond
 	evHDRThreshold   float64 = 0.7
+	// Panorama frames are turned by hand between shots, so they are further apart
+	// than brackets but keep one exposure.
+	maxGapPano              = 10 * time.Second
+	evPanoTolerance float64 = 0.34
 )
 
 type seriesJob struct {
@@ -43,6 +48,7 @@ type hdrHint struct {
 type seriesGroup struct {
 	Jobs       []seriesJob
 	ForcedType *Mode
+	ID         string // series ID kept from the frames' keywords (Options.KeepTagged)
 }
 
 // Run is the main entry point for series detection/tagging.
@@ -50,64 +56,84 @@ func Run(ctx context.Context, opts Options) (*app.Summary, error) {
 	return run(ctx, opts, nil)
 }
 
-// RunWithLogger allows piping logs into an in-memory buffer instead of a file.
-func RunWithLogger(ctx context.Context, opts Options, buf *bytes.Buffer) (*app.Summary, error) {
-	return run(ctx, opts, buf)
+// RunWithLogger also writes the log lines to out, e.g. an events.Bus feeding the GUI.
+func RunWithLogger(ctx context.Context, opts Options, out io.Writer) (*app.Summary, error) {
+	return run(ctx, opts, out)
 }
 
-func run(ctx context.Context, opts Options, buf *bytes.Buffer) (*app.Summary, error) {
+func run(ctx context.Context, opts Options, out io.Writer) (sum *app.Summary, err error) {
 	if err := opts.Validate(); err != nil {
 		return nil, err
 	}
 
-	cfg := logger.LogConfig{
-		FilePath:       opts.LogFile,
-		Format:         "standard",
-		FileLevel:      opts.LogLevel,
-		ConsoleLevel:   opts.LogLevel,
-		ConsoleOutput:  buf != nil,
-		EnableRotation: true,
-		RotationConfig: logger.RotationConfig{
-			MaxSize:    25,
-			MaxBackups: 5,
-			MaxAge:     30,
-			Compress:   true,
-		},
-	}
-	logInstance, err := logger.NewLogger(cfg)
+	logInstance, err := logging.New(logging.Config{File: opts.LogFile, Level: opts.LogLevel, RunID: opts.RunID, Out: out})
 	if err != nil {
 		return nil, err
 	}
-	if buf != nil {
-		logInstance.Config.ConsoleOutput = true
-		logInstance.ConsoleLogger = log.New(buf, "", 0)
-	}
+	debugf, infof, warnf, errorf := logInstance.Debugf, logInstance.Infof, logInstance.Warningf, logInstance.Errorf
+	started := time.Now()
+	defer func() {
+		rec := app.RunRecord{RunID: opts.RunID, Command: "series", Started: started, Summary: sum}
+		if err != nil {
+			rec.Error = err.Error()
+		}
+		if _, recErr := app.RecordRun(rec, opts.LogFile, opts); recErr != nil {
+			warnf("Failed to save the run record: %v", recErr)
+		}
+	}()
 
-	infof := logInstance.Infof
-	warnf := logInstance.Warningf
-	errorf := logInstance.Errorf
+	release, err := app.LockInput(ctx, opts.Inputs, opts.Lock, "series", infof)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 
 	extraTags := parseExtraTags(opts.ExtraTags)
-	infof("Starting series tagging with input=%s recursive=%t mode=%s overwrite=%t prefix=%s start=%d extraTags=%q",
-		opts.InputPath, opts.Recursive, opts.Mode, opts.Overwrite, opts.Prefix, opts.StartIndex, strings.Join(extraTags, ","))
+	infof("Starting series tagging with input=%s recursive=%t mode=%s overwrite=%t prefix=%s start=%d idTemplate=%q extraTags=%q",
+		strings.Join(opts.Inputs, "; "), opts.Recursive, opts.Mode, opts.Overwrite, opts.Prefix, opts.StartIndex, opts.IDTemplate, strings.Join(extraTags, ","))
 
-	files, err := media.CollectFiles(opts.InputPath, opts.Recursive)
+	files, truncated, err := collectFiles(opts, infof, warnf)
 	if err != nil {
 		return nil, err
 	}
 	if len(files) == 0 {
-		return nil, fmt.Errorf("no files found to process")
+		return nil, errcode.New(errcode.NoFiles, nil)
+	}
+
+	adjustDir := adjustmentsDir(opts.Inputs, files)
+	adjustments, err := loadAdjustments(adjustDir)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ResetAdjustments {
+		adjustments = Adjustments{}
+	}
+	requested := Adjustments{SplitAt: opts.SplitAt, MergeAt: opts.MergeAt}
+	if (opts.ResetAdjustments || !requested.empty()) && opts.DryRun {
+		adjustments = adjustments.add(requested)
+		infof("Dry run: series adjustments are applied but not saved (splits=%d merges=%d)", len(adjustments.SplitAt), len(adjustments.MergeAt))
+	} else if opts.ResetAdjustments || !requested.empty() {
+		adjustments = adjustments.add(requested)
+		if err := saveAdjustments(adjustDir, adjustments); err != nil {
+			return nil, err
+		}
+		infof("Saved series adjustments to %s (splits=%d merges=%d)", filepath.Join(adjustDir, adjustmentsFile), len(adjustments.SplitAt), len(adjustments.MergeAt))
+	} else if !adjustments.empty() {
+		infof("Loaded series adjustments from %s (splits=%d merges=%d)", filepath.Join(adjustDir, adjustmentsFile), len(adjustments.SplitAt), len(adjustments.MergeAt))
 	}
 
 	totalFiles := 0
 	for _, path := range files {
 		ext := strings.ToLower(filepath.Ext(path))
-		if ext == ".xmp" {
+		if ext == ".xmp" || filepath.Base(path) == adjustmentsFile {
 			continue
 		}
 		if isHDRMergedCandidate(ext) {
 			continue
 		}
+		if _, ok := media.LivePhotoStill(path); ok {
+			continue
+		}
 		totalFiles++
 	}
 	progressTotal := totalFiles * 2
@@ -138,7 +164,11 @@ func run(ctx context.Context, opts Options, buf *bytes.Buffer) (*app.Summary, er
 		failed    int
 		metaError int
 		hints     []hdrHint
+		tagged    []string
+		frames    []frameTask
 	)
+	// companions maps the pathnorm.PairKey of a RAW to JPEG/HEIF files with the same base name.
+	companions := make(map[string][]string)
 
 	jobs := make([]seriesJob, 0, len(files))
 	for _, path := range files {
@@ -149,16 +179,17 @@ func run(ctx context.Context, opts Options, buf *bytes.Buffer) (*app.Summary, er
 		}
 
 		ext := strings.ToLower(filepath.Ext(path))
-		if ext == ".xmp" {
+		if ext == ".xmp" || filepath.Base(path) == adjustmentsFile {
 			continue
 		}
 		if isHDRMergedCandidate(ext) {
+			companions[pathnorm.PairKey(path)] = append(companions[pathnorm.PairKey(path)], path)
 			meta, err := media.ReadSeriesMetadata(path)
 			if err != nil {
 				warnf("Failed to read metadata for %s: %v", path, err)
 				continue
 			}
-			if !isCanon(meta.CameraMake) {
+			if ok, _ := opts.Cameras.Allows(meta.CameraMake, meta.CameraModel); !ok {
 				continue
 			}
 			hints = append(hints, hdrHint{
@@ -169,6 +200,10 @@ func run(ctx context.Context, opts Options, buf *bytes.Buffer) (*app.Summary, er
 			})
 			continue
 		}
+		if still, ok := media.LivePhotoStill(path); ok {
+			debugf("Treating %s as part of the Live Photo %s", path, still)
+			continue
+		}
 		if !media.SupportedRaw(path) {
 			warnf("Skipping non-RAW file: %s", path)
 			skipped++
@@ -180,23 +215,24 @@ func run(ctx context.Context, opts Options, buf *bytes.Buffer) (*app.Summary, er
 		meta, err := media.ReadSeriesMetadata(path)
 		if err != nil {
 			warnf("Failed to read metadata for %s: %v", path, err)
-			metaError++
-			results = append(results, app.FileResult{
-				Path:    path,
-				Status:  "meta_error",
-				Message: err.Error(),
-			})
+			status := app.MetadataStatus(err)
+			if status == "skipped" {
+				skipped++
+			} else {
+				metaError++
+			}
+			results = append(results, app.FailedResult(path, status, err))
 			advance(2)
 			continue
 		}
 
-		if !isCanon(meta.CameraMake) {
-			warnf("Skipping non-Canon file: %s (%s)", path, meta.CameraMake)
+		if ok, reason := opts.Cameras.Allows(meta.CameraMake, meta.CameraModel); !ok {
+			warnf("Skipping %s: camera %s %s is %s", path, meta.CameraMake, meta.CameraModel, reason)
 			skipped++
 			results = append(results, app.FileResult{
 				Path:    path,
 				Status:  "skipped",
-				Message: "Not a Canon RAW",
+				Message: "Camera " + reason,
 			})
 			advance(2)
 			continue
@@ -211,7 +247,7 @@ func run(ctx context.Context, opts Options, buf *bytes.Buffer) (*app.Summary, er
 	}
 
 	if len(jobs) == 0 {
-		return nil, fmt.Errorf("no Canon RAW files to process")
+		return nil, fmt.Errorf("no RAW files of the included cameras to process (see --camera-include)")
 	}
 
 	sort.Slice(jobs, func(i, j int) bool {
@@ -224,7 +260,27 @@ func run(ctx context.Context, opts Options, buf *bytes.Buffer) (*app.Summary, er
 		return jobs[i].Meta.CaptureTime.Before(jobs[j].Meta.CaptureTime)
 	})
 
-	hdrGroups, assigned := detectHDRGroups(hints, jobs, warnf)
+	var (
+		kept      []seriesGroup
+		keptIDs   = make(map[string]bool)
+		hdrGroups []seriesGroup
+		assigned  map[string]struct{}
+	)
+	if opts.KeepTagged {
+		kept, jobs = taggedGroups(jobs, opts, warnf)
+		for _, group := range kept {
+			keptIDs[group.ID] = true
+		}
+		if len(kept) > 0 {
+			infof("Keeping %d series already tagged in the sidecars; grouping the %d untagged frames", len(kept), len(jobs))
+		}
+	}
+	same := sameSeries
+	if opts.Mode == ModePano {
+		same = samePanoSeries
+	} else {
+		hdrGroups, assigned = detectHDRGroups(hints, jobs, warnf)
+	}
 
 	autoJobs := make([]seriesJob, 0, len(jobs))
 	for _, job := range jobs {
@@ -234,11 +290,21 @@ func run(ctx context.Context, opts Options, buf *bytes.Buffer) (*app.Summary, er
 		autoJobs = append(autoJobs, job)
 	}
 
-	groups := append(hdrGroups, buildGroups(autoJobs)...)
+	groups := append(append(kept, hdrGroups...), adjustments.apply(buildGroups(autoJobs, same), adjustDir)...)
 	if len(groups) == 0 {
 		return nil, fmt.Errorf("no candidate series found")
 	}
 
+	var sheet *contactSheet
+	if opts.ContactSheet != "" {
+		sheet = &contactSheet{}
+	}
+	var keywords *keywordList
+	if opts.KeywordList != "" && !opts.DryRun {
+		keywords = newKeywordList()
+	}
+
+	detection := &app.SeriesStats{Groups: len(groups), Sizes: make(map[int]int), MinLen: minSeriesLen}
 	seriesIdx := opts.StartIndex
 	for _, group := range groups {
 		select {
@@ -246,7 +312,9 @@ func run(ctx context.Context, opts Options, buf *bytes.Buffer) (*app.Summary, er
 			return nil, ctx.Err()
 		default:
 		}
-		if len(group.Jobs) < minSeriesLen {
+		detection.Sizes[len(group.Jobs)]++
+		if len(group.Jobs) < minSeriesLen && group.ID == "" {
+			detection.TooShort++
 			for _, job := range group.Jobs {
 				skipped++
 				results = append(results, app.FileResult{
@@ -259,90 +327,320 @@ func run(ctx context.Context, opts Options, buf *bytes.Buffer) (*app.Summary, er
 			continue
 		}
 
-		typeTag := seriesTypeTag
-		if group.ForcedType == nil && !shouldTagHDR(group.Jobs, opts) {
+		typeTag, labelMode := seriesTypeTag, ModeHDR
+		detected, reason := group.ForcedType != nil || shouldTagHDR(group.Jobs, opts), "Not detected as HDR"
+		if opts.Mode == ModePano {
+			typeTag, labelMode = panoTypeTag, ModePano
+			detected, reason = isPanoSource(group.Jobs), "Not detected as panorama"
+		}
+		if group.ID != "" {
+			labelMode = *group.ForcedType
+			typeTag, detected = labelMode.typeTag(), true
+		}
+		if !detected {
+			detection.Rejected++
+			sheet.add(group.Jobs, "", typeTag, false, reason, -1)
 			for _, job := range group.Jobs {
 				skipped++
 				results = append(results, app.FileResult{
 					Path:    job.Path,
 					Status:  "skipped",
-					Message: "Not detected as HDR",
+					Message: reason,
 				})
 				advance(1)
 			}
 			continue
 		}
-		seriesID := fmt.Sprintf("%s_%05d", opts.Prefix, seriesIdx)
-		seriesIdx++
+		if typeTag == panoTypeTag {
+			detection.Panorama++
+		} else {
+			detection.HDR++
+		}
+		first := group.Jobs[0].Meta
+		seriesID := group.ID
+		if seriesID != "" {
+			detection.Kept++
+		}
+		for seriesID == "" || (group.ID == "" && keptIDs[seriesID]) {
+			seriesID = formatSeriesID(opts.IDTemplate, idFields{
+				Prefix:  opts.Prefix,
+				Date:    first.CaptureTime,
+				Camera:  first.CameraModel,
+				Index:   seriesIdx,
+				TypeTag: typeTag,
+			})
+			seriesIdx++
+		}
+
+		pickIdx := -1
+		if opts.Pick != PickOff {
+			pickIdx = selectBestFrame(group.Jobs, media.PreviewSharpness, warnf)
+		}
+		sheet.add(group.Jobs, seriesID, typeTag, true, "", pickIdx)
 
-		for _, job := range group.Jobs {
-			tags := make([]string, 0, 2+len(extraTags))
+		for i, job := range group.Jobs {
+			tags := make([]string, 0, 3+len(extraTags))
 			tags = append(tags, typeTag, seriesID)
 			tags = append(tags, extraTags...)
-			sidecar := xmp.SidecarPath(job.Path)
+			isPick := i == pickIdx
+			if isPick && opts.Pick == PickKeyword {
+				tags = append(tags, opts.PickKeyword)
+			}
+			frames = append(frames, frameTask{
+				Job:      job,
+				Slot:     len(results),
+				SeriesID: seriesID,
+				TypeTag:  typeTag,
+				Tags:     tags,
+				Label:    opts.Labels[labelMode],
+				Index:    i + 1,
+				Count:    len(group.Jobs),
+				First:    group.Jobs[0].Meta.CaptureTime,
+				Last:     group.Jobs[len(group.Jobs)-1].Meta.CaptureTime,
+				Locked:   evSpread(group.Jobs) == 0,
+				Pick:     isPick,
+			})
+			results = append(results, app.FileResult{Path: job.Path})
+		}
+	}
 
-			wrote, err := xmp.MergeKeywords(sidecar, tags, opts.Overwrite)
-			if errors.Is(err, xmp.ErrKeywordsAlreadyPresent) {
-				infof("Series tags already present for %s", job.Path)
-				unchanged++
-				results = append(results, app.FileResult{
-					Path:    job.Path,
-					Status:  "unchanged",
-					Message: "Series tags already present",
-				})
-				advance(1)
+	if opts.PrintSummary && opts.DryRun {
+		fmt.Println(detection)
+	}
+	infof("%s", detection)
+	if opts.DryRun {
+		for _, f := range frames {
+			unchanged++
+			results[f.Slot] = app.FileResult{
+				Path:    f.Job.Path,
+				Status:  "unchanged",
+				Message: fmt.Sprintf("%s [%s] (dry run)", f.TypeTag, f.SeriesID),
+				Pick:    f.Pick,
+			}
+			advance(1)
+		}
+		frames = nil
+	}
+
+	if opts.Confirm != nil && len(frames) > 0 {
+		plan := make([]app.PlannedWrite, len(frames))
+		for i, f := range frames {
+			plan[i] = app.PlannedWrite{Path: f.Job.Path, Tags: f.Tags}
+		}
+		approved, err := opts.Confirm(plan)
+		if err != nil {
+			return nil, err
+		}
+		keep := app.ApprovedPaths(approved)
+		confirmed := frames[:0]
+		for _, f := range frames {
+			if keep[f.Job.Path] {
+				confirmed = append(confirmed, f)
 				continue
 			}
+			skipped++
+			results[f.Slot] = app.FileResult{
+				Path:    f.Job.Path,
+				Status:  "skipped",
+				Message: "Declined at confirmation",
+			}
+			advance(1)
+		}
+		frames = confirmed
+	}
+
+	for _, f := range frames {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		job, seriesID, typeTag, tags, isPick := f.Job, f.SeriesID, f.TypeTag, f.Tags, f.Pick
+		sidecar := xmp.SidecarPath(job.Path)
+
+		if isPick {
+			switch opts.Pick {
+			case PickKeyword:
+				infof("Best frame of %s: %s (keyword %s)", seriesID, job.Path, opts.PickKeyword)
+			case PickRating:
+				if _, err := xmp.SetRating(sidecar, opts.PickRating, opts.Overwrite); err != nil {
+					errorf("Failed to write rating for %s: %v", job.Path, err)
+					failed++
+					results[f.Slot] = app.FailedResult(job.Path, "failed", app.SidecarError(job.Path, err))
+					advance(1)
+					continue
+				}
+				infof("Best frame of %s: %s (rating %d)", seriesID, job.Path, opts.PickRating)
+			}
+		}
+
+		if opts.WritePosition {
+			written, err := xmp.SetSeriesPosition(sidecar, seriesID, f.Index, f.Count, opts.Overwrite)
 			if err != nil {
-				errorf("Failed to write sidecar for %s: %v", job.Path, err)
+				errorf("Failed to write series position for %s: %v", job.Path, err)
 				failed++
-				results = append(results, app.FileResult{
-					Path:    job.Path,
-					Status:  "failed",
-					Message: err.Error(),
-				})
+				results[f.Slot] = app.FailedResult(job.Path, "failed", app.SidecarError(job.Path, err))
 				advance(1)
 				continue
 			}
-
-			infof("Tagged %s as %s (%s) -> %s", job.Path, typeTag, seriesID, sidecar)
-			if wrote {
-				processed++
-				results = append(results, app.FileResult{
-					Path:    job.Path,
-					Status:  "processed",
-					Message: fmt.Sprintf("%s [%s]", typeTag, seriesID),
-				})
+			if written {
+				debugf("Series position for %s: %d of %d in %s", job.Path, f.Index, f.Count, seriesID)
 			} else {
-				unchanged++
-				results = append(results, app.FileResult{
-					Path:    job.Path,
-					Status:  "unchanged",
-					Message: "Sidecar unchanged",
-				})
+				debugf("Kept the series position %s already has (use --overwrite to replace it)", sidecar)
 			}
+		}
+
+		if opts.GPano && typeTag == panoTypeTag {
+			if _, err := xmp.SetPanoramaSource(sidecar, f.First, f.Last, f.Count, f.Locked); err != nil {
+				errorf("Failed to write GPano hints for %s: %v", job.Path, err)
+				failed++
+				results[f.Slot] = app.FailedResult(job.Path, "failed", app.SidecarError(job.Path, err))
+				advance(1)
+				continue
+			}
+			debugf("GPano hints for %s: frame of %d in %s", job.Path, f.Count, seriesID)
+		}
+
+		if f.Label != "" {
+			if _, err := xmp.SetLabel(sidecar, f.Label, opts.Overwrite); err != nil {
+				errorf("Failed to write color label for %s: %v", job.Path, err)
+				failed++
+				results[f.Slot] = app.FailedResult(job.Path, "failed", app.SidecarError(job.Path, err))
+				advance(1)
+				continue
+			}
+			debugf("Color label for %s: %s", job.Path, f.Label)
+		}
+
+		wrote, err := xmp.MergeKeywords(sidecar, tags, opts.Overwrite)
+		if errors.Is(err, xmp.ErrKeywordsAlreadyPresent) {
+			infof("Series tags already present for %s", job.Path)
+			embedKeywords(opts, job.Path, tags, warnf)
+			tagged = append(tagged, job.Path)
+			keywords.add(f)
+			unchanged++
+			results[f.Slot] = app.FileResult{
+				Path:    job.Path,
+				Status:  "unchanged",
+				Message: "Series tags already present",
+				Pick:    isPick,
+			}
+			advance(1)
+			continue
+		}
+		if err != nil {
+			errorf("Failed to write sidecar for %s: %v", job.Path, err)
+			failed++
+			results[f.Slot] = app.FailedResult(job.Path, "failed", app.SidecarError(job.Path, err))
 			advance(1)
+			continue
+		}
+
+		infof("Tagged %s as %s (%s) -> %s", job.Path, typeTag, seriesID, sidecar)
+		embedKeywords(opts, job.Path, tags, warnf)
+		tagged = append(tagged, job.Path)
+		keywords.add(f)
+		if wrote {
+			camera := app.CameraName(job.Meta.CameraMake, job.Meta.CameraModel)
+			if _, err := opts.Attribution.Write(sidecar, job.Meta.CaptureTime, camera); err != nil {
+				warnf("Failed to write attribution into %s: %v", sidecar, err)
+			}
+			if opts.StampRun {
+				if _, err := xmp.SetLastRun(sidecar, opts.RunID, time.Now()); err != nil {
+					warnf("Failed to stamp run ID into %s: %v", sidecar, err)
+				}
+			}
+			processed++
+			results[f.Slot] = app.FileResult{
+				Path:    job.Path,
+				Status:  "processed",
+				Message: fmt.Sprintf("%s [%s]", typeTag, seriesID),
+				Pick:    isPick,
+			}
+		} else {
+			unchanged++
+			results[f.Slot] = app.FileResult{
+				Path:    job.Path,
+				Status:  "unchanged",
+				Message: "Sidecar unchanged",
+				Pick:    isPick,
+			}
+		}
+		advance(1)
+	}
+
+	if opts.SyncPairs {
+		for _, path := range tagged {
+			pairs := companions[pathnorm.PairKey(path)]
+			if len(pairs) == 0 {
+				continue
+			}
+			updated, err := syncPairKeywords(path, pairs)
+			if err != nil {
+				errorf("Failed to sync keywords between %s and its JPEG: %v", path, err)
+				continue
+			}
+			for _, c := range updated {
+				infof("Synced keywords of %s -> %s", path, xmp.CompanionSidecarPath(c))
+			}
+		}
+	}
+
+	if sheet != nil {
+		if err := sheet.write(opts.ContactSheet, time.Now()); err != nil {
+			errorf("Failed to write contact sheet: %v", err)
+		} else {
+			infof("Contact sheet written to %s (%d series)", opts.ContactSheet, len(sheet.rows))
+		}
+	}
+	if keywords != nil {
+		if err := keywords.write(opts.KeywordList); err != nil {
+			errorf("Failed to write keyword list: %v", err)
+		} else {
+			infof("Keyword list written to %s (%d keywords)", opts.KeywordList, keywords.count())
 		}
 	}
 
-	sum := &app.Summary{
-		Processed: processed,
-		Skipped:   skipped,
-		Unchanged: unchanged,
-		Failed:    failed,
-		MetaError: metaError,
-		Files:     results,
+	app.FillRelativePaths(results)
+	sum = &app.Summary{
+		RunID:       opts.RunID,
+		Processed:   processed,
+		Skipped:     skipped,
+		Unchanged:   unchanged,
+		Failed:      failed,
+		MetaError:   metaError,
+		Transient:   app.CountTransient(results),
+		ReadOnly:    app.CountReadOnly(results),
+		Detection:   detection,
+		Truncated:   truncated,
+		Directories: app.SummarizeDirectories(results),
+		Files:       results,
 	}
 
 	if opts.PrintSummary {
 		fmt.Printf("Finished. processed=%d skipped=%d unchanged=%d failed=%d meta_errors=%d\n", processed, skipped, unchanged, failed, metaError)
 	}
 	infof("Finished. processed=%d skipped=%d unchanged=%d failed=%d meta_errors=%d", processed, skipped, unchanged, failed, metaError)
+	if truncated {
+		warnf("Only the first %d files of the input were processed; raise --max-files or narrow the input to process the rest", opts.MaxFiles)
+	}
 	return sum, nil
 }
 
-func isCanon(makeStr string) bool {
-	return strings.Contains(strings.ToLower(makeStr), "canon")
+// frameTask is a frame of a detected series waiting for its sidecar to be written.
+type frameTask struct {
+	Job      seriesJob
+	Slot     int // index of the result entry reserved for this frame
+	SeriesID string
+	TypeTag  string
+	Tags     []string
+	Label    string // xmp:Label color label, if any
+	Index    int    // 1-based position within the series
+	Count    int
+	First    time.Time // capture time of the series' first frame
+	Last     time.Time // capture time of the series' last frame
+	Locked   bool      // every frame of the series has the same exposure
+	Pick     bool
 }
 
 func parseExtraTags(raw string) []string {
@@ -361,24 +659,43 @@ func parseExtraTags(raw string) []string {
 	return tags
 }
 
-func buildGroups(jobs []seriesJob) []seriesGroup {
+// buildGroups splits chronologically sorted jobs into series. Series never span
+// folders, so each directory is grouped on its own and the result is ordered by
+// the first frame of each group.
+func buildGroups(jobs []seriesJob, same func(prev, next seriesJob) bool) []seriesGroup {
 	if len(jobs) == 0 {
 		return nil
 	}
-	var groups []seriesGroup
-	current := []seriesJob{jobs[0]}
+	var dirs []string
+	byDir := make(map[string][]seriesJob)
+	for _, job := range jobs {
+		dir := filepath.Dir(job.Path)
+		if _, ok := byDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], job)
+	}
 
-	for i := 1; i < len(jobs); i++ {
-		prev := current[len(current)-1]
-		next := jobs[i]
-		if sameSeries(prev, next) {
-			current = append(current, next)
-			continue
+	var groups []seriesGroup
+	for _, dir := range dirs {
+		dirJobs := byDir[dir]
+		current := []seriesJob{dirJobs[0]}
+		for i := 1; i < len(dirJobs); i++ {
+			prev := current[len(current)-1]
+			next := dirJobs[i]
+			if same(prev, next) {
+				current = append(current, next)
+				continue
+			}
+			groups = append(groups, seriesGroup{Jobs: current})
+			current = []seriesJob{next}
 		}
 		groups = append(groups, seriesGroup{Jobs: current})
-		current = []seriesJob{next}
 	}
-	groups = append(groups, seriesGroup{Jobs: current})
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Jobs[0].Meta.CaptureTime.Before(groups[j].Jobs[0].Meta.CaptureTime)
+	})
 	return groups
 }
 
@@ -390,9 +707,14 @@ func detectHDRGroups(hints []hdrHint, jobs []seriesJob, warnf func(string, ...in
 		return hints[i].Seq < hints[j].Seq
 	})
 
-	jobBySeq := make(map[int]seriesJob, len(jobs))
+	// File numbers are only meaningful inside one folder.
+	type seqKey struct {
+		dir string
+		seq int
+	}
+	jobBySeq := make(map[seqKey]seriesJob, len(jobs))
 	for _, job := range jobs {
-		jobBySeq[job.Seq] = job
+		jobBySeq[seqKey{filepath.Dir(job.Path), job.Seq}] = job
 	}
 
 	assigned := make(map[string]struct{})
@@ -406,10 +728,10 @@ func detectHDRGroups(hints []hdrHint, jobs []seriesJob, warnf func(string, ...in
 			continue
 		}
 
-		seqs := []int{hint.Seq - 3, hint.Seq - 2, hint.Seq - 1}
-		j1, ok1 := jobBySeq[seqs[0]]
-		j2, ok2 := jobBySeq[seqs[1]]
-		j3, ok3 := jobBySeq[seqs[2]]
+		dir := filepath.Dir(hint.Path)
+		j1, ok1 := jobBySeq[seqKey{dir, hint.Seq - 3}]
+		j2, ok2 := jobBySeq[seqKey{dir, hint.Seq - 2}]
+		j3, ok3 := jobBySeq[seqKey{dir, hint.Seq - 1}]
 		if !(ok1 && ok2 && ok3) {
 			continue
 		}
@@ -515,6 +837,9 @@ func isHDRMergedCandidate(ext string) bool {
 }
 
 func sameSeries(prev, next seriesJob) bool {
+	if filepath.Dir(prev.Path) != filepath.Dir(next.Path) {
+		return false
+	}
 	gap := next.Meta.CaptureTime.Sub(prev.Meta.CaptureTime)
 	allowed := maxGapDefault
 
@@ -529,6 +854,30 @@ func sameSeries(prev, next seriesJob) bool {
 	return gap >= 0 && gap <= allowed
 }
 
+// samePanoSeries is sameSeries for panorama frames: consecutive shots in one
+// folder at most maxGapPano apart.
+func samePanoSeries(prev, next seriesJob) bool {
+	if filepath.Dir(prev.Path) != filepath.Dir(next.Path) {
+		return false
+	}
+	if prev.Seq >= 0 && next.Seq >= 0 && next.Seq-prev.Seq != 1 {
+		return false
+	}
+	gap := next.Meta.CaptureTime.Sub(prev.Meta.CaptureTime)
+	return gap >= 0 && gap <= maxGapPano
+}
+
+// isPanoSource reports whether a group looks like the frames of a panorama: one
+// exposure throughout, unlike an HDR bracket.
+func isPanoSource(group []seriesJob) bool {
+	for _, job := range group {
+		if job.Meta.HDRHint {
+			return false
+		}
+	}
+	return evSpread(group) < evPanoTolerance
+}
+
 func shouldTagHDR(group []seriesJob, opts Options) bool {
 	if len(group) == 0 {
 		return false
@@ -543,6 +892,11 @@ func shouldTagHDR(group []seriesJob, opts Options) bool {
 		}
 	}
 
+	return evSpread(group) >= evHDRThreshold
+}
+
+// evSpread returns the exposure range of a group in EV, ignoring frames without exposure data.
+func evSpread(group []seriesJob) float64 {
 	evValues := make([]float64, 0, len(group))
 	for _, job := range group {
 		if job.Meta.ExposureTime <= 0 || job.Meta.FNumber <= 0 {
@@ -552,11 +906,10 @@ func shouldTagHDR(group []seriesJob, opts Options) bool {
 	}
 
 	if len(evValues) == 0 {
-		return false
+		return 0
 	}
 	sort.Float64s(evValues)
-	rangeEv := evValues[len(evValues)-1] - evValues[0]
-	return rangeEv >= evHDRThreshold
+	return evValues[len(evValues)-1] - evValues[0]
 }
 
 func ev(meta media.SeriesMetadata) float64 {
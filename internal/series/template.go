@@ -0,0 +1,102 @@
+package series
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultIDTemplate reproduces the historical PREFIX_00001 series IDs.
+const DefaultIDTemplate = "{prefix}_{index}"
+
+const defaultIndexWidth = 5
+
+var placeholderRegex = regexp.MustCompile(`\{([a-z]+)(?::(\d+))?\}`)
+
+// idFields carries the values available to series ID templates.
+type idFields struct {
+	Prefix  string
+	Date    time.Time
+	Camera  string
+	Index   int
+	TypeTag string
+}
+
+// validateIDTemplate ensures the template only references known placeholders
+// and contains {index}, which keeps generated IDs unique within a run.
+func validateIDTemplate(tmpl string) error {
+	if strings.TrimSpace(tmpl) == "" {
+		return fmt.Errorf("id template is empty")
+	}
+	hasIndex := false
+	for _, m := range placeholderRegex.FindAllStringSubmatch(tmpl, -1) {
+		switch m[1] {
+		case "index":
+			hasIndex = true
+		case "prefix", "date", "camera", "type":
+			if m[2] != "" {
+				return fmt.Errorf("placeholder {%s} does not accept a width", m[1])
+			}
+		default:
+			return fmt.Errorf("unknown placeholder {%s} in id template", m[1])
+		}
+	}
+	if !hasIndex {
+		return fmt.Errorf("id template must contain {index}")
+	}
+	return nil
+}
+
+// formatSeriesID expands placeholders in tmpl using the provided fields.
+func formatSeriesID(tmpl string, f idFields) string {
+	return placeholderRegex.ReplaceAllStringFunc(tmpl, func(token string) string {
+		m := placeholderRegex.FindStringSubmatch(token)
+		switch m[1] {
+		case "prefix":
+			return f.Prefix
+		case "date":
+			if f.Date.IsZero() {
+				return "nodate"
+			}
+			return f.Date.Format("20060102")
+		case "camera":
+			return sanitizeIDPart(f.Camera)
+		case "type":
+			return f.TypeTag
+		case "index":
+			width := defaultIndexWidth
+			if m[2] != "" {
+				if w, err := strconv.Atoi(m[2]); err == nil {
+					width = w
+				}
+			}
+			return fmt.Sprintf("%0*d", width, f.Index)
+		default:
+			return token
+		}
+	})
+}
+
+// sanitizeIDPart turns free-form EXIF strings into keyword-friendly tokens.
+func sanitizeIDPart(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "unknown"
+	}
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	out := strings.Trim(b.String(), "_")
+	if out == "" {
+		return "unknown"
+	}
+	return out
+}
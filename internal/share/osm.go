@@ -0,0 +1,76 @@
+package share
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const defaultOSMAPI = "https://api.openstreetmap.org"
+
+// uploadOSM creates a GPS trace with the API 0.6 gpx/create call and returns the
+// API address of the new trace.
+func uploadOSM(ctx context.Context, t Target, name string, points []Point) (string, error) {
+	token := strings.TrimSpace(os.Getenv(EnvOSMToken))
+	if token == "" {
+		return "", fmt.Errorf("osm: set %s to an OAuth 2 token with the write_gpx scope", EnvOSMToken)
+	}
+	data, err := BuildGPX(name, points)
+	if err != nil {
+		return "", err
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", traceFileName(name))
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.Write(data); err != nil {
+		return "", err
+	}
+	for _, f := range [][2]string{
+		{"description", name},
+		{"tags", "georaw,photos"},
+		{"visibility", t.Visibility},
+	} {
+		if err := form.WriteField(f[0], f[1]); err != nil {
+			return "", err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	api := strings.TrimRight(t.URL, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, api+"/api/0.6/gpx/create", &body)
+	if err != nil {
+		return "", fmt.Errorf("osm: build upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := send(&http.Client{Timeout: requestTimeout}, req)
+	if err != nil {
+		return "", fmt.Errorf("osm: upload trace: %w", err)
+	}
+	id := strings.TrimSpace(string(resp))
+	if id == "" {
+		return "", fmt.Errorf("osm: upload response has no trace ID")
+	}
+	return api + "/api/0.6/gpx/" + id, nil
+}
+
+// traceFileName turns the trace name into a file name OSM accepts.
+func traceFileName(name string) string {
+	clean := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == '"' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+	return clean + ".gpx"
+}
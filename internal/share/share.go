@@ -0,0 +1,220 @@
+// Package share uploads the photo positions of a run to OpenStreetMap, as a GPS
+// trace, or to a uMap map, as a new layer, so others can see where the photos
+// were taken.
+package share
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/version"
+)
+
+const requestTimeout = 60 * time.Second
+
+// Services a Target can upload to.
+const (
+	ServiceOSM  = "osm"
+	ServiceUMap = "umap"
+)
+
+// Environment variables holding the credentials of the services.
+const (
+	EnvOSMToken    = "OSM_ACCESS_TOKEN" // OAuth 2 token with the write_gpx scope
+	EnvUMapSession = "UMAP_SESSION"     // sessionid cookie of a uMap login that may edit the map
+)
+
+// Visibilities of an OpenStreetMap trace.
+var osmVisibilities = []string{"private", "public", "trackable", "identifiable"}
+
+// Target is where the positions are uploaded.
+type Target struct {
+	Service    string // ServiceOSM or ServiceUMap
+	URL        string // OSM API root, or the uMap map URL
+	Visibility string // visibility of an OSM trace; private by default
+}
+
+// IsZero reports whether no upload is configured.
+func (t Target) IsZero() bool {
+	return t.Service == ""
+}
+
+// ParseTarget reads an upload target: "osm" for openstreetmap.org, "osm:URL" for
+// another OSM API (e.g. the dev server), or "umap:MAP_URL" for a uMap map. An
+// empty spec is the zero Target.
+func ParseTarget(spec, visibility string) (Target, error) {
+	spec, visibility = strings.TrimSpace(spec), strings.ToLower(strings.TrimSpace(visibility))
+	if spec == "" {
+		return Target{}, nil
+	}
+	service, rawURL, _ := strings.Cut(spec, ":")
+	t := Target{Service: strings.ToLower(service), URL: strings.TrimSpace(rawURL)}
+	switch t.Service {
+	case ServiceOSM:
+		if t.URL == "" {
+			t.URL = defaultOSMAPI
+		}
+		if visibility == "" {
+			visibility = "private"
+		}
+		valid := false
+		for _, v := range osmVisibilities {
+			valid = valid || v == visibility
+		}
+		if !valid {
+			return Target{}, fmt.Errorf("unknown trace visibility %q (expected %s)", visibility, strings.Join(osmVisibilities, ", "))
+		}
+		t.Visibility = visibility
+	case ServiceUMap:
+		if t.URL == "" {
+			return Target{}, fmt.Errorf("umap upload needs the map URL, e.g. umap:https://umap.openstreetmap.fr/en/map/trip_12345")
+		}
+	default:
+		return Target{}, fmt.Errorf("unknown upload target %q (expected osm, osm:API_URL or umap:MAP_URL)", spec)
+	}
+	u, err := url.Parse(t.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return Target{}, fmt.Errorf("invalid upload URL %q", t.URL)
+	}
+	return t, nil
+}
+
+// Point is the position of one photo.
+type Point struct {
+	Name      string // file name
+	Latitude  float64
+	Longitude float64
+	Altitude  *float64
+	Time      time.Time
+}
+
+// Upload sends points to the target under name and returns the address of the
+// uploaded trace or layer.
+func Upload(ctx context.Context, t Target, name string, points []Point) (string, error) {
+	if len(points) == 0 {
+		return "", fmt.Errorf("no photo positions to upload")
+	}
+	points = append([]Point(nil), points...)
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+	switch t.Service {
+	case ServiceOSM:
+		return uploadOSM(ctx, t, name, points)
+	case ServiceUMap:
+		return uploadUMap(ctx, t, name, points)
+	}
+	return "", fmt.Errorf("unknown upload target %q", t.Service)
+}
+
+type gpxDoc struct {
+	XMLName   xml.Name   `xml:"gpx"`
+	Version   string     `xml:"version,attr"`
+	Creator   string     `xml:"creator,attr"`
+	Namespace string     `xml:"xmlns,attr"`
+	Name      string     `xml:"metadata>name"`
+	Waypoints []gpxPoint `xml:"wpt"`
+	Track     gpxTrack   `xml:"trk"`
+}
+
+type gpxTrack struct {
+	Name   string     `xml:"name"`
+	Points []gpxPoint `xml:"trkseg>trkpt"`
+}
+
+type gpxPoint struct {
+	Lat  float64  `xml:"lat,attr"`
+	Lon  float64  `xml:"lon,attr"`
+	Ele  *float64 `xml:"ele,omitempty"`
+	Time string   `xml:"time,omitempty"`
+	Name string   `xml:"name,omitempty"`
+}
+
+// BuildGPX returns a GPX document with a waypoint per photo and a track joining
+// them in time order; OSM only imports track points.
+func BuildGPX(name string, points []Point) ([]byte, error) {
+	doc := gpxDoc{
+		Version:   "1.1",
+		Creator:   "GeoRAW " + version.Version,
+		Namespace: "http://www.topografix.com/GPX/1/1",
+		Name:      name,
+		Track:     gpxTrack{Name: name},
+	}
+	for _, p := range points {
+		pt := gpxPoint{Lat: p.Latitude, Lon: p.Longitude, Ele: p.Altitude}
+		if !p.Time.IsZero() {
+			pt.Time = p.Time.UTC().Format(time.RFC3339)
+		}
+		doc.Track.Points = append(doc.Track.Points, pt)
+		pt.Name = p.Name
+		doc.Waypoints = append(doc.Waypoints, pt)
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode gpx: %w", err)
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// BuildGeoJSON returns a FeatureCollection with a point per photo, named after
+// the file and described by its capture time.
+func BuildGeoJSON(name string, points []Point) ([]byte, error) {
+	type feature struct {
+		Type     string `json:"type"`
+		Geometry struct {
+			Type        string    `json:"type"`
+			Coordinates []float64 `json:"coordinates"`
+		} `json:"geometry"`
+		Properties map[string]string `json:"properties"`
+	}
+	coll := struct {
+		Type     string    `json:"type"`
+		Name     string    `json:"name"`
+		Features []feature `json:"features"`
+	}{Type: "FeatureCollection", Name: name, Features: []feature{}}
+	for _, p := range points {
+		f := feature{Type: "Feature", Properties: map[string]string{"name": p.Name}}
+		f.Geometry.Type = "Point"
+		f.Geometry.Coordinates = []float64{p.Longitude, p.Latitude}
+		if p.Altitude != nil {
+			f.Geometry.Coordinates = append(f.Geometry.Coordinates, *p.Altitude)
+		}
+		if !p.Time.IsZero() {
+			f.Properties["description"] = p.Time.Format("2006-01-02 15:04:05 MST")
+		}
+		coll.Features = append(coll.Features, f)
+	}
+	data, err := json.Marshal(coll)
+	if err != nil {
+		return nil, fmt.Errorf("encode geojson: %w", err)
+	}
+	return data, nil
+}
+
+// send performs req and returns the response body, failing on a non-2xx status.
+func send(client *http.Client, req *http.Request) ([]byte, error) {
+	req.Header.Set("User-Agent", "GeoRAW/"+version.Version+" (+https://github.com/nir0k/GeoRAW)")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(body))
+		if len(msg) > 512 {
+			msg = msg[:512]
+		}
+		return nil, fmt.Errorf("%s: %s", resp.Status, msg)
+	}
+	return body, nil
+}
@@ -0,0 +1,110 @@
+package share
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// umapIDRegex finds the map ID at the end of a uMap map URL (".../map/trip_12345").
+var umapIDRegex = regexp.MustCompile(`/map/(?:[^/]*_)?(\d+)/?$`)
+
+// uploadUMap adds the points as a new layer of a uMap 2 map. uMap has no token
+// API, so the upload signs in with the session cookie of a login that may edit
+// the map and passes Django's CSRF check with the token of a map page load.
+func uploadUMap(ctx context.Context, t Target, name string, points []Point) (string, error) {
+	session := strings.TrimSpace(os.Getenv(EnvUMapSession))
+	if session == "" {
+		return "", fmt.Errorf("umap: set %s to the sessionid cookie of a login that may edit the map", EnvUMapSession)
+	}
+	mapURL, err := url.Parse(t.URL)
+	if err != nil {
+		return "", fmt.Errorf("umap: %w", err)
+	}
+	m := umapIDRegex.FindStringSubmatch(mapURL.Path)
+	if m == nil {
+		return "", fmt.Errorf("umap: no map ID in %s", t.URL)
+	}
+	origin := &url.URL{Scheme: mapURL.Scheme, Host: mapURL.Host, Path: "/"}
+
+	jar, _ := cookiejar.New(nil)
+	jar.SetCookies(origin, []*http.Cookie{{Name: "sessionid", Value: session}})
+	client := &http.Client{Timeout: requestTimeout, Jar: jar}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.URL, nil)
+	if err != nil {
+		return "", fmt.Errorf("umap: build map request: %w", err)
+	}
+	if _, err := send(client, req); err != nil {
+		return "", fmt.Errorf("umap: open map: %w", err)
+	}
+	csrf := ""
+	for _, c := range jar.Cookies(origin) {
+		if c.Name == "csrftoken" {
+			csrf = c.Value
+		}
+	}
+	if csrf == "" {
+		return "", fmt.Errorf("umap: the map page set no CSRF token")
+	}
+
+	data, err := BuildGeoJSON(name, points)
+	if err != nil {
+		return "", err
+	}
+	settings, _ := json.Marshal(map[string]any{"name": name, "displayOnLoad": true})
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for _, f := range [][2]string{
+		{"name", name},
+		{"display_on_load", "true"},
+		{"rank", "0"},
+		{"settings", string(settings)},
+	} {
+		if err := form.WriteField(f[0], f[1]); err != nil {
+			return "", err
+		}
+	}
+	file, err := form.CreateFormFile("geojson", "blob")
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.Write(data); err != nil {
+		return "", err
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	endpoint := origin.JoinPath("map", m[1], "datalayer", "create", newUUID()).String() + "/"
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return "", fmt.Errorf("umap: build upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("X-CSRFToken", csrf)
+	req.Header.Set("Referer", t.URL)
+	if _, err := send(client, req); err != nil {
+		return "", fmt.Errorf("umap: create layer: %w", err)
+	}
+	return t.URL, nil
+}
+
+// newUUID returns a random (version 4) UUID, which uMap 2 expects as the ID of
+// a new layer.
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
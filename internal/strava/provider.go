@@ -0,0 +1,45 @@
+package strava
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+	"github.com/nir0k/GeoRAW/internal/track"
+)
+
+// Scheme selects Strava as the track source: --gpx strava:
+const Scheme = "strava"
+
+// windowMargin widens the capture time range, since capture times are still in
+// camera clock time when the activities are fetched. It also catches activities
+// that started up to a day before the first photo, which the API would miss as it
+// filters by start time only.
+const windowMargin = 24 * time.Hour
+
+func init() {
+	track.Register(Scheme, track.ProviderFunc(load))
+}
+
+// load downloads the activities around the requested capture times with the
+// credentials from the environment and combines their streams into one track.
+func load(ctx context.Context, req track.Request) (*gpx.TrackIndex, error) {
+	creds := CredentialsFromEnv()
+	if creds.IsZero() {
+		return nil, fmt.Errorf("strava: set %s, %s and %s", EnvClientID, EnvClientSecret, EnvRefreshToken)
+	}
+	from, to := req.From.Add(-windowMargin), req.To.Add(windowMargin)
+	activities, err := Fetch(ctx, creds, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(activities) == 0 {
+		return nil, fmt.Errorf("no Strava activities with GPS found between %s and %s", from.Format(time.DateOnly), to.Format(time.DateOnly))
+	}
+	pieces := make([][]gpx.Point, 0, len(activities))
+	for _, a := range activities {
+		pieces = append(pieces, a.Points)
+	}
+	return gpx.NewTrack(pieces)
+}
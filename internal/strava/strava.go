@@ -0,0 +1,279 @@
+// Package strava downloads activity streams from the Strava API so they can be
+// used as a track source instead of an exported GPX file.
+package strava
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+	"github.com/nir0k/GeoRAW/internal/version"
+)
+
+const (
+	tokenURL       = "https://www.strava.com/oauth/token"
+	apiBase        = "https://www.strava.com/api/v3"
+	requestTimeout = 30 * time.Second
+	pageSize       = 100
+)
+
+// Environment variables read by CredentialsFromEnv.
+const (
+	EnvClientID     = "STRAVA_CLIENT_ID"
+	EnvClientSecret = "STRAVA_CLIENT_SECRET"
+	EnvRefreshToken = "STRAVA_REFRESH_TOKEN"
+)
+
+// Credentials identify a Strava API application and the athlete that authorized it
+// (with the activity:read_all scope).
+type Credentials struct {
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+
+	// envToken is the refresh token set in the environment when RefreshToken is
+	// a rotated one saved since.
+	envToken string
+}
+
+// CredentialsFromEnv reads credentials from the STRAVA_* environment variables.
+// When Strava rotated the refresh token since, the saved successor is used
+// instead of the one in the environment.
+func CredentialsFromEnv() Credentials {
+	creds := Credentials{
+		ClientID:     strings.TrimSpace(os.Getenv(EnvClientID)),
+		ClientSecret: strings.TrimSpace(os.Getenv(EnvClientSecret)),
+		RefreshToken: strings.TrimSpace(os.Getenv(EnvRefreshToken)),
+	}
+	if creds.RefreshToken != "" {
+		if saved := savedRefreshToken(creds.RefreshToken); saved != "" {
+			creds.envToken, creds.RefreshToken = creds.RefreshToken, saved
+		}
+	}
+	return creds
+}
+
+// IsZero reports whether no credential is set.
+func (c Credentials) IsZero() bool {
+	return c.ClientID == "" && c.ClientSecret == "" && c.RefreshToken == ""
+}
+
+// Validate ensures all three credentials are present.
+func (c Credentials) Validate() error {
+	var missing []string
+	if c.ClientID == "" {
+		missing = append(missing, "client ID")
+	}
+	if c.ClientSecret == "" {
+		missing = append(missing, "client secret")
+	}
+	if c.RefreshToken == "" {
+		missing = append(missing, "refresh token")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("strava: missing %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Activity is a recorded activity with its GPS stream.
+type Activity struct {
+	ID     int64
+	Name   string
+	Start  time.Time
+	End    time.Time
+	Points []gpx.Point
+}
+
+// Fetch downloads the activities of the athlete that start within [from, to]
+// together with their position streams; the API filters by start time only, so
+// callers widen the range to catch long activities that began earlier. Activities
+// without GPS (indoor or manual entries) are left out. A refresh token Strava
+// rotates is saved for the next run (see TokenPath).
+func Fetch(ctx context.Context, creds Credentials, from, to time.Time) ([]Activity, error) {
+	if err := creds.Validate(); err != nil {
+		return nil, err
+	}
+	c := &client{http: &http.Client{Timeout: requestTimeout}}
+	if err := c.authorize(ctx, creds); err != nil {
+		return nil, err
+	}
+
+	list, err := c.activities(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var out []Activity
+	for _, a := range list {
+		points, err := c.stream(ctx, a)
+		if err != nil {
+			return nil, fmt.Errorf("strava activity %d: %w", a.ID, err)
+		}
+		if len(points) == 0 {
+			continue
+		}
+		a.Points = points
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+type client struct {
+	http  *http.Client
+	token string
+}
+
+// authorize exchanges the refresh token for a short-lived access token.
+func (c *client) authorize(ctx context.Context, creds Credentials) error {
+	form := url.Values{
+		"client_id":     {creds.ClientID},
+		"client_secret": {creds.ClientSecret},
+		"refresh_token": {creds.RefreshToken},
+		"grant_type":    {"refresh_token"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("strava: build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.do(req, &token); err != nil {
+		return fmt.Errorf("strava: refresh access token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return fmt.Errorf("strava: token response has no access token")
+	}
+	c.token = token.AccessToken
+	if token.RefreshToken != "" && token.RefreshToken != creds.RefreshToken {
+		// The old token stops working once a new one is issued, so losing it
+		// would lock the next run out.
+		envToken := creds.envToken
+		if envToken == "" {
+			envToken = creds.RefreshToken
+		}
+		if err := saveRefreshToken(envToken, token.RefreshToken); err != nil {
+			return fmt.Errorf("strava: the refresh token was rotated but could not be saved: %w", err)
+		}
+	}
+	return nil
+}
+
+// activities lists the activities starting within [from, to], page by page.
+func (c *client) activities(ctx context.Context, from, to time.Time) ([]Activity, error) {
+	after, before := from.Unix(), to.Unix()
+
+	var out []Activity
+	for page := 1; ; page++ {
+		q := url.Values{
+			"after":    {strconv.FormatInt(after, 10)},
+			"before":   {strconv.FormatInt(before, 10)},
+			"page":     {strconv.Itoa(page)},
+			"per_page": {strconv.Itoa(pageSize)},
+		}
+		var batch []struct {
+			ID          int64     `json:"id"`
+			Name        string    `json:"name"`
+			StartDate   time.Time `json:"start_date"`
+			ElapsedTime int64     `json:"elapsed_time"`
+		}
+		if err := c.get(ctx, "/athlete/activities?"+q.Encode(), &batch); err != nil {
+			return nil, fmt.Errorf("strava: list activities: %w", err)
+		}
+		for _, a := range batch {
+			out = append(out, Activity{
+				ID:    a.ID,
+				Name:  a.Name,
+				Start: a.StartDate.UTC(),
+				End:   a.StartDate.UTC().Add(time.Duration(a.ElapsedTime) * time.Second),
+			})
+		}
+		if len(batch) < pageSize {
+			return out, nil
+		}
+	}
+}
+
+// stream downloads the latlng, time and altitude streams of an activity.
+func (c *client) stream(ctx context.Context, a Activity) ([]gpx.Point, error) {
+	var streams struct {
+		LatLng struct {
+			Data [][2]float64 `json:"data"`
+		} `json:"latlng"`
+		Time struct {
+			Data []int64 `json:"data"`
+		} `json:"time"`
+		Altitude struct {
+			Data []float64 `json:"data"`
+		} `json:"altitude"`
+	}
+	path := fmt.Sprintf("/activities/%d/streams?keys=latlng,time,altitude&key_by_type=true", a.ID)
+	var status *statusError
+	if err := c.get(ctx, path, &streams); errors.As(err, &status) && status.code == http.StatusNotFound {
+		return nil, nil // manual activities have no streams
+	} else if err != nil {
+		return nil, err
+	}
+
+	latlng, offsets, alt := streams.LatLng.Data, streams.Time.Data, streams.Altitude.Data
+	n := min(len(latlng), len(offsets))
+	points := make([]gpx.Point, 0, n)
+	for i := 0; i < n; i++ {
+		pt := gpx.Point{
+			Time:  a.Start.Add(time.Duration(offsets[i]) * time.Second),
+			Coord: gpx.Coordinate{Latitude: latlng[i][0], Longitude: latlng[i][1]},
+		}
+		if i < len(alt) {
+			v := alt[i]
+			pt.Coord.Altitude = &v
+		}
+		points = append(points, pt)
+	}
+	return points, nil
+}
+
+func (c *client) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiBase+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	return c.do(req, out)
+}
+
+func (c *client) do(req *http.Request, out any) error {
+	req.Header.Set("User-Agent", "GeoRAW/"+version.Version)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &statusError{code: resp.StatusCode, msg: resp.Status + ": " + strings.TrimSpace(string(body))}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// statusError is a non-2xx API response.
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string { return e.msg }
@@ -0,0 +1,84 @@
+package strava
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Strava may answer a refresh with a new refresh token, after which the old one
+// stops working. The environment cannot be updated, so the rotated token is kept
+// in the user config folder and used for as long as the environment still holds
+// the token it replaced; setting a different token in the environment wins.
+
+// rotatedToken is the saved state: Replaces is the hash of the environment token
+// the saved one descends from, so the environment token itself is not stored.
+type rotatedToken struct {
+	Replaces     string `json:"replaces"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// TokenPath returns the file rotated refresh tokens are saved in.
+func TokenPath() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve config dir: %w", err)
+	}
+	return filepath.Join(base, "georaw", "strava-token.json"), nil
+}
+
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// savedRefreshToken returns the rotated token that replaced envToken, or "" when
+// there is none.
+func savedRefreshToken(envToken string) string {
+	path, err := TokenPath()
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	var saved rotatedToken
+	if json.Unmarshal(data, &saved) != nil || saved.Replaces != tokenHash(envToken) {
+		return ""
+	}
+	return saved.RefreshToken
+}
+
+// saveRefreshToken records token as the successor of envToken. The file is only
+// readable by the user and replaced in one rename, so a failed write keeps the
+// previous token.
+func saveRefreshToken(envToken, token string) error {
+	path, err := TokenPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(rotatedToken{Replaces: tokenHash(envToken), RefreshToken: token}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".strava-token-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
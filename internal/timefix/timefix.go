@@ -0,0 +1,215 @@
+// Package timefix models corrections of camera clock mistakes: a fixed offset,
+// linear drift, and a time zone the camera was wrongly set to.
+package timefix
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+// Correction maps a camera clock reading to the true local time.
+// Camera times are naive wall-clock readings; only their fields are used.
+type Correction struct {
+	// Offset is added to every capture time.
+	Offset time.Duration
+	// DriftPerDay is extra correction accumulated per day after Anchor; negative
+	// when the camera clock runs fast.
+	DriftPerDay time.Duration
+	// Anchor is the camera time at which the drift term is zero.
+	Anchor time.Time
+	// FromZone and ToZone re-express times recorded with the camera set to FromZone
+	// as wall-clock time in ToZone, with DST rules applied per photo.
+	FromZone string
+	ToZone   string
+}
+
+// IsZero reports whether the correction changes nothing.
+func (c Correction) IsZero() bool {
+	return c.Offset == 0 && c.DriftPerDay == 0 && c.FromZone == "" && c.ToZone == ""
+}
+
+// Validate checks that the time zones are known and come as a pair.
+func (c Correction) Validate() error {
+	if (c.FromZone == "") != (c.ToZone == "") {
+		return fmt.Errorf("time zone change needs both a from and a to zone")
+	}
+	for _, name := range []string{c.FromZone, c.ToZone} {
+		if name == "" {
+			continue
+		}
+		if _, err := time.LoadLocation(name); err != nil {
+			return fmt.Errorf("unknown time zone %q: %w", name, err)
+		}
+	}
+	if c.DriftPerDay != 0 && c.Anchor.IsZero() {
+		return fmt.Errorf("drift correction needs an anchor time")
+	}
+	return nil
+}
+
+// Apply returns the corrected time for a camera reading. Zone change comes first,
+// then drift and the fixed offset.
+func (c Correction) Apply(t time.Time) time.Time {
+	out := t
+	if c.FromZone != "" && c.ToZone != "" {
+		from, errFrom := time.LoadLocation(c.FromZone)
+		to, errTo := time.LoadLocation(c.ToZone)
+		if errFrom == nil && errTo == nil {
+			local := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), from).In(to)
+			out = time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), local.Nanosecond(), t.Location())
+		}
+	}
+	if c.DriftPerDay != 0 {
+		elapsed := t.Sub(c.Anchor)
+		out = out.Add(time.Duration(float64(c.DriftPerDay) * (float64(elapsed) / float64(day))))
+	}
+	return out.Add(c.Offset)
+}
+
+// ZoneOffset returns the UTC offset, e.g. "+02:00", of a corrected wall-clock
+// time in ToZone, or "" when the correction has no zone change.
+func (c Correction) ZoneOffset(t time.Time) string {
+	if c.ToZone == "" {
+		return ""
+	}
+	to, err := time.LoadLocation(c.ToZone)
+	if err != nil {
+		return ""
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), to).Format("-07:00")
+}
+
+// String describes the correction, e.g. "+1h0m0s, drift +2s/day, Europe/Berlin -> Asia/Tokyo".
+func (c Correction) String() string {
+	var parts []string
+	if c.Offset != 0 {
+		parts = append(parts, signed(c.Offset))
+	}
+	if c.DriftPerDay != 0 {
+		parts = append(parts, fmt.Sprintf("drift %s/day from %s", signed(c.DriftPerDay), c.Anchor.Format(time.DateTime)))
+	}
+	if c.FromZone != "" {
+		parts = append(parts, c.FromZone+" -> "+c.ToZone)
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func signed(d time.Duration) string {
+	if d < 0 {
+		return d.String()
+	}
+	return "+" + d.String()
+}
+
+// Sample pairs a camera reading with the true time it should have shown, such as a
+// photo of a GPS or phone clock.
+type Sample struct {
+	Camera time.Time
+	True   time.Time
+}
+
+// Fit derives a correction from reference samples: one sample gives a fixed offset,
+// two or more fit offset and drift by least squares, anchored at the first sample.
+func Fit(samples []Sample) (Correction, error) {
+	if len(samples) == 0 {
+		return Correction{}, fmt.Errorf("no reference samples")
+	}
+	anchor := samples[0].Camera
+	for _, s := range samples[1:] {
+		if s.Camera.Before(anchor) {
+			anchor = s.Camera
+		}
+	}
+	if len(samples) == 1 {
+		return Correction{Offset: samples[0].True.Sub(samples[0].Camera).Round(time.Second)}, nil
+	}
+
+	// Regress the needed correction (true - camera) against days since the anchor.
+	var sumX, sumY, sumXX, sumXY float64
+	n := float64(len(samples))
+	for _, s := range samples {
+		x := float64(s.Camera.Sub(anchor)) / float64(day)
+		y := float64(s.True.Sub(s.Camera))
+		sumX += x
+		sumY += y
+		sumXX += x * x
+		sumXY += x * y
+	}
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		// All samples taken at the same camera time: average the offsets.
+		return Correction{Offset: time.Duration(sumY / n).Round(time.Second)}, nil
+	}
+	slope := (n*sumXY - sumX*sumY) / denom
+	intercept := (sumY - slope*sumX) / n
+	return Correction{
+		Offset:      time.Duration(intercept).Round(time.Second),
+		DriftPerDay: time.Duration(slope).Round(10 * time.Millisecond),
+		Anchor:      anchor,
+	}, nil
+}
+
+// fileFormat is the JSON form of a saved correction.
+type fileFormat struct {
+	Offset      string `json:"offset,omitempty"`
+	DriftPerDay string `json:"drift_per_day,omitempty"`
+	Anchor      string `json:"anchor,omitempty"`
+	FromZone    string `json:"from_zone,omitempty"`
+	ToZone      string `json:"to_zone,omitempty"`
+}
+
+// Save writes the correction to path as JSON so it can be reapplied later, for
+// example to another card from the same camera.
+func Save(path string, c Correction) error {
+	f := fileFormat{FromZone: c.FromZone, ToZone: c.ToZone}
+	if c.Offset != 0 {
+		f.Offset = c.Offset.String()
+	}
+	if c.DriftPerDay != 0 {
+		f.DriftPerDay = c.DriftPerDay.String()
+		f.Anchor = c.Anchor.Format(time.DateTime)
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write time correction: %w", err)
+	}
+	return nil
+}
+
+// Load reads a correction written by Save.
+func Load(path string) (Correction, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Correction{}, fmt.Errorf("read time correction: %w", err)
+	}
+	var f fileFormat
+	if err := json.Unmarshal(data, &f); err != nil {
+		return Correction{}, fmt.Errorf("parse time correction: %w", err)
+	}
+	c := Correction{FromZone: f.FromZone, ToZone: f.ToZone}
+	if f.Offset != "" {
+		if c.Offset, err = time.ParseDuration(f.Offset); err != nil {
+			return Correction{}, fmt.Errorf("parse offset: %w", err)
+		}
+	}
+	if f.DriftPerDay != "" {
+		if c.DriftPerDay, err = time.ParseDuration(f.DriftPerDay); err != nil {
+			return Correction{}, fmt.Errorf("parse drift: %w", err)
+		}
+		if c.Anchor, err = time.Parse(time.DateTime, f.Anchor); err != nil {
+			return Correction{}, fmt.Errorf("parse anchor: %w", err)
+		}
+	}
+	return c, c.Validate()
+}
@@ -0,0 +1,43 @@
+package track
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/garmin"
+	"github.com/nir0k/GeoRAW/internal/gpx"
+)
+
+// FileScheme is the provider for local track files (GPX, .gpx.gz, zip archives,
+// Apple Health exports) and Garmin Connect export folders; it also handles URIs
+// without a registered scheme.
+const FileScheme = "file"
+
+// exportMargin widens the capture time range when selecting activities from an
+// export folder, since capture times are still in camera clock time.
+const exportMargin = 24 * time.Hour
+
+func init() {
+	Register(FileScheme, ProviderFunc(loadFile))
+}
+
+func loadFile(ctx context.Context, req Request) (*gpx.TrackIndex, error) {
+	path := strings.TrimSpace(req.URI)
+	if strings.HasPrefix(strings.ToLower(path), "file://") {
+		path = path[len("file://"):]
+		if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+			path = path[1:] // file:///C:/tracks/day1.gpx
+		}
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		from, to := req.From, req.To
+		if !from.IsZero() || !to.IsZero() {
+			from, to = from.Add(-exportMargin), to.Add(exportMargin)
+		}
+		return garmin.LoadExport(ctx, path, from, to)
+	}
+	track, _, err := gpx.LoadTrackCached(path, req.CacheDir)
+	return track, err
+}
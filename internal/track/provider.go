@@ -0,0 +1,105 @@
+// Package track resolves track sources (GPX files, exports, online services) to a
+// lookup index through registered providers, so new sources plug into the same
+// pipeline without changes to the tagging workflow.
+package track
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+)
+
+// Request describes the track a run needs.
+type Request struct {
+	// URI selects the provider by scheme ("strava:", "file:///..."); plain paths
+	// are handled by the file provider.
+	URI string
+	// From and To bound the photos' capture times, still in camera clock time, so
+	// providers that download data can limit what they fetch.
+	From, To time.Time
+	// CacheDir is a folder providers may use for caches; empty disables caching.
+	CacheDir string
+}
+
+// Provider loads a track. Providers that produce plain points can build the index
+// with gpx.NewTrack.
+type Provider interface {
+	Load(ctx context.Context, req Request) (*gpx.TrackIndex, error)
+}
+
+// ProviderFunc adapts a function to Provider.
+type ProviderFunc func(ctx context.Context, req Request) (*gpx.TrackIndex, error)
+
+// Load calls f.
+func (f ProviderFunc) Load(ctx context.Context, req Request) (*gpx.TrackIndex, error) {
+	return f(ctx, req)
+}
+
+var (
+	mu        sync.RWMutex
+	providers = make(map[string]Provider)
+)
+
+// schemeRegex matches a URI scheme; single letters are left out so Windows drive
+// letters (C:\...) stay plain paths.
+var schemeRegex = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]+):`)
+
+// Register makes a provider available for URIs with the given scheme. It panics
+// when the scheme is already registered, like database/sql drivers.
+func Register(scheme string, p Provider) {
+	scheme = strings.ToLower(scheme)
+	mu.Lock()
+	defer mu.Unlock()
+	if p == nil {
+		panic("track: Register provider is nil")
+	}
+	if _, dup := providers[scheme]; dup {
+		panic("track: Register called twice for scheme " + scheme)
+	}
+	providers[scheme] = p
+}
+
+// Schemes returns the registered schemes in sorted order.
+func Schemes() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]string, 0, len(providers))
+	for scheme := range providers {
+		out = append(out, scheme)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Scheme returns the provider scheme of uri: its URI scheme when one is registered,
+// otherwise "file".
+func Scheme(uri string) string {
+	if m := schemeRegex.FindStringSubmatch(strings.TrimSpace(uri)); m != nil {
+		scheme := strings.ToLower(m[1])
+		mu.RLock()
+		_, ok := providers[scheme]
+		mu.RUnlock()
+		if ok {
+			return scheme
+		}
+	}
+	return FileScheme
+}
+
+// Load resolves req.URI to its provider and loads the track.
+func Load(ctx context.Context, req Request) (*gpx.TrackIndex, error) {
+	scheme := Scheme(req.URI)
+	mu.RLock()
+	p, ok := providers[scheme]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no track provider for %q", scheme)
+	}
+	return p.Load(ctx, req)
+}
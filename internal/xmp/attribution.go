@@ -0,0 +1,129 @@
+package xmp
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/fsretry"
+)
+
+// Attribution carries the creator and copyright fields written to sidecars.
+// Empty fields are left untouched.
+type Attribution struct {
+	Creator string // dc:creator
+	Rights  string // dc:rights (x-default)
+	Credit  string // photoshop:Credit
+}
+
+// IsZero reports whether no field is set.
+func (a Attribution) IsZero() bool {
+	return a.Creator == "" && a.Rights == "" && a.Credit == ""
+}
+
+var liTextRegex = regexp.MustCompile(`(?is)<rdf:li\b[^>]*>(.*?)</rdf:li>`)
+
+// SetAttribution writes dc:creator, dc:rights and photoshop:Credit into a sidecar.
+// Properties the sidecar already has are kept unless overwrite is true; everything
+// else in the sidecar is preserved.
+func SetAttribution(path string, a Attribution, overwrite bool) (bool, error) {
+	existing, err := fsretry.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("read existing sidecar: %w", err)
+	}
+
+	text := string(existing)
+	if blankSidecar(existing) {
+		text = string(buildAttrsSidecar("dc", dcNamespace, nil))
+	}
+	changed := false
+	for _, el := range []struct {
+		name, value, array, lang string
+	}{
+		// New blocks go first in rdf:Description, so insert in reverse display order.
+		{"dc:rights", a.Rights, "rdf:Alt", "x-default"},
+		{"dc:creator", a.Creator, "rdf:Seq", ""},
+	} {
+		if el.value == "" {
+			continue
+		}
+		var (
+			updated string
+			ok      bool
+		)
+		if el.array == "rdf:Alt" {
+			// Keep the other language alternatives of dc:rights.
+			updated, ok, err = setLangAlt(text, el.name, []LangValue{{Lang: el.lang, Value: el.value}}, overwrite)
+		} else {
+			updated, ok, err = setArrayElement(text, el.name, el.array, el.lang, el.value, overwrite)
+		}
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			text, changed = updated, true
+		}
+	}
+
+	if changed {
+		if err := writeSidecarFile(path, existing, []byte(text)); err != nil {
+			return false, err
+		}
+	}
+
+	if a.Credit != "" {
+		wrote, err := writeDescriptionAttrs(path, "photoshop", photoshopNamespace, []attrValue{
+			{Name: "photoshop:Credit", Value: a.Credit},
+		}, overwrite)
+		if err != nil {
+			return false, err
+		}
+		changed = changed || wrote
+	}
+	return changed, nil
+}
+
+// setArrayElement sets a single-item dc array property (e.g. dc:creator) to value,
+// replacing an existing block or inserting a new one. It reports false when the
+// sidecar already holds value, or any value and overwrite is false.
+func setArrayElement(text, name, array, lang, value string, overwrite bool) (string, bool, error) {
+	block := buildArrayBlock(name, array, lang, value)
+	loc := arrayBlockRegex(name).FindStringSubmatchIndex(text)
+	if loc == nil {
+		updated, err := insertDescriptionBlock(text, "dc", dcNamespace, block)
+		if err != nil {
+			return "", false, err
+		}
+		return updated, true, nil
+	}
+
+	current := ""
+	if m := liTextRegex.FindStringSubmatch(text[loc[0]:loc[1]]); m != nil {
+		current = strings.TrimSpace(htmlUnescape(m[1]))
+	}
+	if current == value || (!overwrite && current != "") {
+		return text, false, nil
+	}
+	indent := text[loc[2]:loc[3]]
+	return text[:loc[0]] + indentBlock(block, indent) + text[loc[1]:], true, nil
+}
+
+func arrayBlockRegex(name string) *regexp.Regexp {
+	q := regexp.QuoteMeta(name)
+	return regexp.MustCompile(`(?is)([ \t]*)<` + q + `\b[^>]*>.*?</` + q + `>`)
+}
+
+func buildArrayBlock(name, array, lang, value string) string {
+	li := "<rdf:li>"
+	if lang != "" {
+		li = fmt.Sprintf(`<rdf:li xml:lang="%s">`, lang)
+	}
+	var b strings.Builder
+	b.WriteString("<" + name + ">\n")
+	b.WriteString("  <" + array + ">\n")
+	b.WriteString("    " + li + xmlEscape(value) + "</rdf:li>\n")
+	b.WriteString("  </" + array + ">\n")
+	b.WriteString("</" + name + ">")
+	return b.String()
+}
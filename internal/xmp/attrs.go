@@ -0,0 +1,135 @@
+package xmp
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/fsretry"
+)
+
+// attrValue is a qualified rdf:Description attribute such as xmp:Rating.
+type attrValue struct {
+	Name  string
+	Value string
+}
+
+// writeDescriptionAttrs sets simple attributes from a single namespace on the sidecar's
+// rdf:Description, replacing attribute or element forms of the same properties.
+// When overwrite is false and any of the properties already exist, nothing is written.
+func writeDescriptionAttrs(path, prefix, uri string, values []attrValue, overwrite bool) (bool, error) {
+	existing, err := fsretry.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("read existing sidecar: %w", err)
+	}
+
+	var payload []byte
+	if blankSidecar(existing) {
+		payload = buildAttrsSidecar(prefix, uri, values)
+	} else {
+		current := readDescriptionAttrs(existing, values)
+		if sameAttrs(current, values) {
+			return false, nil
+		}
+		if !overwrite && len(current) > 0 {
+			return false, nil
+		}
+		payload, err = mergeAttrsInPlace(existing, prefix, uri, values)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if err := writeSidecarFile(path, existing, payload); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func attrRegex(name string) *regexp.Regexp {
+	return regexp.MustCompile(`(?is)\s+` + regexp.QuoteMeta(name) + `\s*=\s*("[^"]*"|'[^']*')`)
+}
+
+func elementRegex(name string) *regexp.Regexp {
+	q := regexp.QuoteMeta(name)
+	return regexp.MustCompile(`(?is)\s*<` + q + `(?:\s[^>]*)?>(.*?)</` + q + `>`)
+}
+
+func namespaceRegex(prefix string) *regexp.Regexp {
+	return regexp.MustCompile(`(?is)\bxmlns:` + regexp.QuoteMeta(prefix) + `\s*=\s*("[^"]*"|'[^']*')`)
+}
+
+// readDescriptionAttrs returns the current values of the requested properties that exist in data.
+func readDescriptionAttrs(data []byte, values []attrValue) map[string]string {
+	text := string(data)
+	out := make(map[string]string)
+	for _, v := range values {
+		if m := attrRegex(v.Name).FindStringSubmatch(text); m != nil {
+			out[v.Name] = htmlUnescape(strings.Trim(m[1], `"'`))
+			continue
+		}
+		if m := elementRegex(v.Name).FindStringSubmatch(text); m != nil {
+			out[v.Name] = strings.TrimSpace(htmlUnescape(m[1]))
+		}
+	}
+	return out
+}
+
+func sameAttrs(current map[string]string, values []attrValue) bool {
+	if len(current) != len(values) {
+		return false
+	}
+	for _, v := range values {
+		if current[v.Name] != v.Value {
+			return false
+		}
+	}
+	return true
+}
+
+func mergeAttrsInPlace(existing []byte, prefix, uri string, values []attrValue) ([]byte, error) {
+	text := string(existing)
+	for _, v := range values {
+		text = elementRegex(v.Name).ReplaceAllString(text, "")
+	}
+	loc := descriptionTagRegex.FindStringIndex(text)
+	if loc == nil {
+		return nil, fmt.Errorf("rdf:Description tag not found")
+	}
+
+	clean := text[loc[0]:loc[1]]
+	for _, v := range values {
+		clean = attrRegex(v.Name).ReplaceAllString(clean, "")
+	}
+	attrs := make([]string, 0, len(values)+1)
+	if !namespaceRegex(prefix).MatchString(clean) {
+		attrs = append(attrs, fmt.Sprintf(`xmlns:%s="%s"`, prefix, uri))
+	}
+	for _, v := range values {
+		attrs = append(attrs, fmt.Sprintf(`%s="%s"`, v.Name, xmlEscape(v.Value)))
+	}
+
+	updatedTag, err := insertTagAttributes(clean, attrs)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(text[:loc[0]] + updatedTag + text[loc[1]:]), nil
+}
+
+func buildAttrsSidecar(prefix, uri string, values []attrValue) []byte {
+	var b strings.Builder
+	b.WriteString(`<?xpacket begin=" " id="W5M0MpCehiHzreSzNTczkc9d"?>`)
+	b.WriteString("\n<x:xmpmeta xmlns:x=\"adobe:ns:meta/\" x:xmptk=\"GeoRAW\">\n")
+	b.WriteString("  <rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	b.WriteString(fmt.Sprintf("    <rdf:Description rdf:about=\"\" xmlns:%s=\"%s\"", prefix, uri))
+	for _, v := range values {
+		b.WriteString(fmt.Sprintf(" %s=\"%s\"", v.Name, xmlEscape(v.Value)))
+	}
+	b.WriteString(">\n")
+	b.WriteString("    </rdf:Description>\n")
+	b.WriteString("  </rdf:RDF>\n")
+	b.WriteString("</x:xmpmeta>\n")
+	b.WriteString("<?xpacket end=\"w\"?>")
+	return []byte(b.String())
+}
@@ -0,0 +1,61 @@
+package xmp
+
+import (
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/fsretry"
+)
+
+const photoshopNamespace = "http://ns.adobe.com/photoshop/1.0/"
+
+// xmpDateLayout is the zone-less XMP date form; camera clocks record local time.
+const xmpDateLayout = "2006-01-02T15:04:05"
+
+// SetCaptureTime records a corrected capture time as exif:DateTimeOriginal and
+// photoshop:DateCreated, and the total correction relative to the time embedded
+// in the photo as georaw:TimeShift.
+func SetCaptureTime(path string, ts time.Time, shift time.Duration) (bool, error) {
+	layout := xmpDateLayout
+	if ts.Nanosecond() != 0 {
+		layout += ".000"
+	}
+	date := ts.Format(layout)
+
+	wroteExif, err := writeDescriptionAttrs(path, "exif", exifNamespace, []attrValue{
+		{Name: "exif:DateTimeOriginal", Value: date},
+	}, true)
+	if err != nil {
+		return false, err
+	}
+	wrotePS, err := writeDescriptionAttrs(path, "photoshop", photoshopNamespace, []attrValue{
+		{Name: "photoshop:DateCreated", Value: date},
+	}, true)
+	if err != nil {
+		return false, err
+	}
+	wroteShift, err := writeDescriptionAttrs(path, "georaw", GeoRAWNamespace, []attrValue{
+		{Name: "georaw:TimeShift", Value: shift.String()},
+	}, true)
+	if err != nil {
+		return false, err
+	}
+	return wroteExif || wrotePS || wroteShift, nil
+}
+
+// ReadTimeShift returns georaw:TimeShift from a sidecar. ok is false when the
+// capture time was never corrected by GeoRAW or the sidecar is unreadable.
+func ReadTimeShift(path string) (shift time.Duration, ok bool) {
+	data, err := fsretry.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	raw, found := readDescriptionAttrs(data, []attrValue{{Name: "georaw:TimeShift"}})["georaw:TimeShift"]
+	if !found {
+		return 0, false
+	}
+	shift, err = time.ParseDuration(raw)
+	if err != nil {
+		return 0, false
+	}
+	return shift, true
+}
@@ -0,0 +1,85 @@
+package xmp
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// GPanoNamespace is the Google Photo Sphere namespace read by Street View and
+// panorama viewers.
+const GPanoNamespace = "http://ns.google.com/photos/1.0/panorama/"
+
+// gpanoProperties are the GPano properties carried from a panorama into its
+// sidecar.
+var gpanoProperties = []string{
+	"GPano:UsePanoramaViewer",
+	"GPano:CaptureSoftware",
+	"GPano:StitchingSoftware",
+	"GPano:ProjectionType",
+	"GPano:PoseHeadingDegrees",
+	"GPano:PosePitchDegrees",
+	"GPano:PoseRollDegrees",
+	"GPano:InitialViewHeadingDegrees",
+	"GPano:InitialViewPitchDegrees",
+	"GPano:InitialViewRollDegrees",
+	"GPano:InitialHorizontalFOVDegrees",
+	"GPano:FirstPhotoDate",
+	"GPano:LastPhotoDate",
+	"GPano:SourcePhotosCount",
+	"GPano:ExposureLockUsed",
+	"GPano:CroppedAreaImageWidthPixels",
+	"GPano:CroppedAreaImageHeightPixels",
+	"GPano:FullPanoWidthPixels",
+	"GPano:FullPanoHeightPixels",
+	"GPano:CroppedAreaLeftPixels",
+	"GPano:CroppedAreaTopPixels",
+}
+
+// ParseGPano returns the GPano properties of an XMP packet by qualified name
+// (e.g. "GPano:ProjectionType"), in attribute or element form.
+func ParseGPano(packet []byte) map[string]string {
+	values := make([]attrValue, len(gpanoProperties))
+	for i, name := range gpanoProperties {
+		values[i] = attrValue{Name: name}
+	}
+	return readDescriptionAttrs(packet, values)
+}
+
+// SetGPano writes GPano properties into a sidecar, keeping GPano data the
+// sidecar already has unless overwrite is true.
+func SetGPano(path string, props map[string]string, overwrite bool) (bool, error) {
+	var values []attrValue
+	for _, name := range gpanoProperties {
+		if v, ok := props[name]; ok && v != "" {
+			values = append(values, attrValue{Name: name, Value: v})
+		}
+	}
+	if len(values) == 0 {
+		return false, nil
+	}
+	return writeDescriptionAttrs(path, "GPano", GPanoNamespace, values, overwrite)
+}
+
+// xmpBool formats an XMP Boolean, which is spelled True or False.
+func xmpBool(v bool) string {
+	if v {
+		return "True"
+	}
+	return "False"
+}
+
+// SetPanoramaSource marks a frame as one of count source photos of a panorama
+// shot between first and last, so stitchers can carry GPano:FirstPhotoDate,
+// LastPhotoDate, SourcePhotosCount and ExposureLockUsed into the result.
+func SetPanoramaSource(path string, first, last time.Time, count int, exposureLocked bool) (bool, error) {
+	if count < 1 || last.Before(first) {
+		return false, fmt.Errorf("invalid panorama of %d photos from %s to %s", count, first, last)
+	}
+	return SetGPano(path, map[string]string{
+		"GPano:FirstPhotoDate":    first.Format(xmpDateLayout),
+		"GPano:LastPhotoDate":     last.Format(xmpDateLayout),
+		"GPano:SourcePhotosCount": strconv.Itoa(count),
+		"GPano:ExposureLockUsed":  xmpBool(exposureLocked),
+	}, true)
+}
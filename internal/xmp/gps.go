@@ -0,0 +1,95 @@
+package xmp
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/fsretry"
+	"github.com/nir0k/GeoRAW/internal/gpx"
+)
+
+// ReadGPS returns the position stored in a sidecar. ok is false when the sidecar
+// does not exist or carries no latitude/longitude.
+func ReadGPS(path string) (coord gpx.Coordinate, ok bool, err error) {
+	data, err := fsretry.ReadFile(path)
+	if os.IsNotExist(err) {
+		return coord, false, nil
+	}
+	if err != nil {
+		return coord, false, err
+	}
+	vals := readDescriptionAttrs(data, []attrValue{
+		{Name: "exif:GPSLatitude"},
+		{Name: "exif:GPSLongitude"},
+		{Name: "exif:GPSAltitude"},
+		{Name: "exif:GPSAltitudeRef"},
+	})
+	latText, lonText := vals["exif:GPSLatitude"], vals["exif:GPSLongitude"]
+	if latText == "" || lonText == "" {
+		return coord, false, nil
+	}
+	if coord.Latitude, err = parseGPSCoordinate(latText); err != nil {
+		return coord, false, fmt.Errorf("latitude: %w", err)
+	}
+	if coord.Longitude, err = parseGPSCoordinate(lonText); err != nil {
+		return coord, false, fmt.Errorf("longitude: %w", err)
+	}
+	if alt, err := parseRational(vals["exif:GPSAltitude"]); err == nil {
+		if vals["exif:GPSAltitudeRef"] == "1" {
+			alt = -alt
+		}
+		coord.Altitude = &alt
+	}
+	return coord, true, nil
+}
+
+// parseGPSCoordinate reads the XMP "DDD,MM.mmmmR" / "DDD,MM,SSR" forms, as well as
+// plain signed decimal degrees.
+func parseGPSCoordinate(text string) (float64, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, fmt.Errorf("empty coordinate")
+	}
+	sign := 1.0
+	switch text[len(text)-1] {
+	case 'S', 's', 'W', 'w':
+		sign = -1
+		text = text[:len(text)-1]
+	case 'N', 'n', 'E', 'e':
+		text = text[:len(text)-1]
+	}
+	parts := strings.Split(text, ",")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid coordinate %q", text)
+	}
+	value := 0.0
+	scale := 1.0
+	for _, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid coordinate %q", text)
+		}
+		value += v / scale
+		scale *= 60
+	}
+	return sign * value, nil
+}
+
+// parseRational reads "n/d" or a plain decimal number.
+func parseRational(text string) (float64, error) {
+	text = strings.TrimSpace(text)
+	if num, den, ok := strings.Cut(text, "/"); ok {
+		n, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return 0, err
+		}
+		d, err := strconv.ParseFloat(den, 64)
+		if err != nil || d == 0 {
+			return 0, fmt.Errorf("invalid rational %q", text)
+		}
+		return n / d, nil
+	}
+	return strconv.ParseFloat(text, 64)
+}
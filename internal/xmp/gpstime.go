@@ -0,0 +1,51 @@
+package xmp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GPSTimeFormat selects how the time of a written position is stored, for
+// parsers that only accept one of the forms in use.
+type GPSTimeFormat string
+
+// The three rationals of the EXIF tag are not offered: the XMP exif schema types
+// exif:GPSTimeStamp as a date, so "10/1,15/1,30/1" is invalid XMP that Adobe
+// applications and exiftool reject.
+const (
+	GPSTimeString   GPSTimeFormat = "string"   // exif:GPSDateStamp and exif:GPSTimeStamp as HH:MM:SS (default)
+	GPSTimeDateTime GPSTimeFormat = "datetime" // date and time combined in exif:GPSTimeStamp, as the XMP exif schema defines it
+)
+
+// ParseGPSTimeFormat validates a GPS time format; an empty value selects
+// GPSTimeString.
+func ParseGPSTimeFormat(raw string) (GPSTimeFormat, error) {
+	switch format := GPSTimeFormat(strings.ToLower(strings.TrimSpace(raw))); format {
+	case "":
+		return GPSTimeString, nil
+	case GPSTimeString, GPSTimeDateTime:
+		return format, nil
+	default:
+		return "", fmt.Errorf("invalid GPS time format %q (expected string or datetime)", raw)
+	}
+}
+
+// gpsTimeAttrs returns the attributes recording ts in format. Every format is
+// whole seconds in UTC, like the GPS clock.
+func gpsTimeAttrs(ts time.Time, format GPSTimeFormat) []string {
+	ts = ts.UTC()
+	switch format {
+	case GPSTimeDateTime:
+		// Adobe and exiftool read this as the full GPS date-time (exiftool's
+		// XMP-exif:GPSDateTime); there is no separate date stamp in XMP.
+		return []string{
+			fmt.Sprintf(`exif:GPSTimeStamp="%s"`, ts.Format("2006-01-02T15:04:05Z")),
+		}
+	default:
+		return []string{
+			fmt.Sprintf(`exif:GPSDateStamp="%s"`, ts.Format("2006:01:02")),
+			fmt.Sprintf(`exif:GPSTimeStamp="%s"`, ts.Format("15:04:05")),
+		}
+	}
+}
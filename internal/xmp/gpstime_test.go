@@ -0,0 +1,164 @@
+package xmp
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/gpx"
+)
+
+func TestParseGPSTimeFormat(t *testing.T) {
+	for raw, want := range map[string]GPSTimeFormat{
+		"":           GPSTimeString,
+		"string":     GPSTimeString,
+		" DateTime ": GPSTimeDateTime,
+	} {
+		if got, err := ParseGPSTimeFormat(raw); err != nil || got != want {
+			t.Errorf("ParseGPSTimeFormat(%q) = %q, %v, want %q", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"rational", "unix"} {
+		if _, err := ParseGPSTimeFormat(raw); err == nil {
+			t.Errorf("ParseGPSTimeFormat(%q) accepted", raw)
+		}
+	}
+}
+
+// gpsTimeForms are the attributes each format must write for gpsTimeSample, and
+// must not leave behind from the other format.
+var gpsTimeForms = map[GPSTimeFormat][]string{
+	GPSTimeString:   {`exif:GPSDateStamp="2024:07:12"`, `exif:GPSTimeStamp="10:15:30"`},
+	GPSTimeDateTime: {`exif:GPSTimeStamp="2024-07-12T10:15:30Z"`},
+}
+
+// gpsTimeSample is in a zone east of UTC with a fraction of a second, both of
+// which the written time must drop.
+var gpsTimeSample = time.Date(2024, 7, 12, 12, 15, 30, 400e6, time.FixedZone("CEST", 2*3600))
+
+func TestGPSTimeRoundTrip(t *testing.T) {
+	want := time.Date(2024, 7, 12, 10, 15, 30, 0, time.UTC)
+	alt := 312.5
+	coord := gpx.Coordinate{Latitude: 47.4979, Longitude: 19.0402, Altitude: &alt}
+	for format, attrs := range gpsTimeForms {
+		path := filepath.Join(t.TempDir(), "a.xmp")
+		if _, err := MergeAndWrite(path, coord, gpsTimeSample, "", format, false); err != nil {
+			t.Fatalf("%s: write: %v", format, err)
+		}
+		data, _ := os.ReadFile(path)
+		for _, attr := range attrs {
+			if !strings.Contains(string(data), attr) {
+				t.Errorf("%s: sidecar lacks %s:\n%s", format, attr, data)
+			}
+		}
+		if format == GPSTimeDateTime && strings.Contains(string(data), "GPSDateStamp") {
+			t.Errorf("%s: sidecar has a date stamp:\n%s", format, data)
+		}
+		got, ok, err := readGPSTime(path)
+		if err != nil || !ok || !got.Equal(want) {
+			t.Errorf("%s: readGPSTime = %v, %v, %v, want %v", format, got, ok, err, want)
+		}
+		if read, ok, err := ReadGPS(path); err != nil || !ok || read.Altitude == nil ||
+			math.Abs(read.Latitude-coord.Latitude) > 1e-6 || math.Abs(read.Longitude-coord.Longitude) > 1e-6 || *read.Altitude != alt {
+			t.Errorf("%s: ReadGPS = %+v, %v, %v", format, read, ok, err)
+		}
+	}
+}
+
+// TestGPSTimeSwitchFormat rewrites a sidecar in the other format, including the
+// element form Adobe applications write, and checks only the new form is left.
+func TestGPSTimeSwitchFormat(t *testing.T) {
+	want := time.Date(2024, 7, 12, 10, 15, 30, 0, time.UTC)
+	coord := gpx.Coordinate{Latitude: -33.8568, Longitude: 151.2153}
+	adobe := `<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about="" xmlns:exif="http://ns.adobe.com/exif/1.0/" xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:Rating="3">
+   <exif:GPSLatitude>1,2.5N</exif:GPSLatitude>
+   <exif:GPSLongitude>3,4.5E</exif:GPSLongitude>
+   <exif:GPSTimeStamp>2020-01-02T03:04:05Z</exif:GPSTimeStamp>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+`
+	for from := range gpsTimeForms {
+		for to, attrs := range gpsTimeForms {
+			path := filepath.Join(t.TempDir(), "a.xmp")
+			if _, err := MergeAndWrite(path, coord, gpsTimeSample.Add(-time.Hour), "", from, false); err != nil {
+				t.Fatalf("%s: write: %v", from, err)
+			}
+			if _, err := MergeAndWrite(path, coord, gpsTimeSample, "", to, true); err != nil {
+				t.Fatalf("%s to %s: rewrite: %v", from, to, err)
+			}
+			checkGPSTimeForm(t, path, to, attrs, want)
+		}
+	}
+	for to, attrs := range gpsTimeForms {
+		path := filepath.Join(t.TempDir(), "a.xmp")
+		if err := os.WriteFile(path, []byte(adobe), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := MergeAndWrite(path, coord, gpsTimeSample, "", to, true); err != nil {
+			t.Fatalf("adobe to %s: rewrite: %v", to, err)
+		}
+		checkGPSTimeForm(t, path, to, attrs, want)
+		if data, _ := os.ReadFile(path); !strings.Contains(string(data), `xmp:Rating="3"`) {
+			t.Errorf("adobe to %s: rating lost:\n%s", to, data)
+		}
+	}
+}
+
+func checkGPSTimeForm(t *testing.T, path string, format GPSTimeFormat, attrs []string, want time.Time) {
+	t.Helper()
+	data, _ := os.ReadFile(path)
+	text := string(data)
+	for _, attr := range attrs {
+		if strings.Count(text, attr) != 1 {
+			t.Errorf("%s: want %s once:\n%s", format, attr, text)
+		}
+	}
+	if n := strings.Count(text, "GPSTimeStamp"); n != 1 {
+		t.Errorf("%s: GPSTimeStamp appears %d times:\n%s", format, n, text)
+	}
+	if n, wantN := strings.Count(text, "GPSDateStamp"), strings.Count(strings.Join(attrs, " "), "GPSDateStamp"); n != wantN {
+		t.Errorf("%s: GPSDateStamp appears %d times, want %d:\n%s", format, n, wantN, text)
+	}
+	got, ok, err := readGPSTime(path)
+	if err != nil || !ok || !got.Equal(want) {
+		t.Errorf("%s: readGPSTime = %v, %v, %v, want %v", format, got, ok, err, want)
+	}
+}
+
+// readGPSTime returns the GPS time stored in a sidecar, in either format. ok is
+// false when the sidecar does not exist or carries no GPS time.
+func readGPSTime(path string) (ts time.Time, ok bool, err error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ts, false, nil
+	}
+	if err != nil {
+		return ts, false, err
+	}
+	vals := readDescriptionAttrs(data, []attrValue{
+		{Name: "exif:GPSDateStamp"},
+		{Name: "exif:GPSTimeStamp"},
+	})
+	date, clock := vals["exif:GPSDateStamp"], vals["exif:GPSTimeStamp"]
+	switch {
+	case clock == "":
+		return ts, false, nil
+	case strings.Contains(clock, "T"):
+		ts, err = time.Parse(time.RFC3339, clock)
+	case date == "":
+		return ts, false, fmt.Errorf("GPS time %q has no date stamp", clock)
+	default:
+		ts, err = time.Parse("2006:01:02 15:04:05", date+" "+clock)
+	}
+	if err != nil {
+		return ts, false, fmt.Errorf("GPS time: %w", err)
+	}
+	return ts.UTC(), true, nil
+}
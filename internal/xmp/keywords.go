@@ -1,15 +1,14 @@
 package xmp
 
 import (
-	"bytes"
-	"encoding/xml"
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/fsretry"
 )
 
 // ErrKeywordsAlreadyPresent is returned when requested tags are already present and overwriting is disabled.
@@ -23,7 +22,7 @@ func MergeKeywords(path string, tags []string, overwrite bool) (bool, error) {
 		return false, fmt.Errorf("no tags provided")
 	}
 
-	existing, err := os.ReadFile(path)
+	existing, err := fsretry.ReadFile(path)
 	if err != nil && !os.IsNotExist(err) {
 		return false, fmt.Errorf("read existing sidecar: %w", err)
 	}
@@ -39,56 +38,112 @@ func MergeKeywords(path string, tags []string, overwrite bool) (bool, error) {
 		return false, ErrKeywordsAlreadyPresent
 	}
 
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return false, fmt.Errorf("create sidecar dir: %w", err)
-	}
-	if err := os.WriteFile(path, payload, 0o644); err != nil {
+	if err := writeSidecarFile(path, existing, payload); err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
+const dcNamespace = "http://purl.org/dc/elements/1.1/"
+
+var (
+	subjectBlockRegex = regexp.MustCompile(`(?is)([ \t]*)<dc:subject\b[^>]*>.*?</dc:subject>`)
+	// containerRegex matches the rdf:Bag, rdf:Seq or rdf:Alt of a dc:subject block:
+	// its start tag, its entries, and the indent of its end tag.
+	containerRegex = regexp.MustCompile(`(?is)<rdf:(?:Bag|Seq|Alt)\b[^>/]*>(.*?)([ \t]*)</rdf:(?:Bag|Seq|Alt)>`)
+	liEntryRegex   = regexp.MustCompile(`(?is)([ \t]*)<rdf:li\b([^>]*)>(.*?)</rdf:li>`)
+)
+
+// mergeKeywordPayload edits dc:subject in the existing text so everything else in the
+// sidecar (other namespaces, formatting, packet header) is kept byte for byte.
 func mergeKeywordPayload(existing []byte, tags []string, overwrite bool) ([]byte, bool, error) {
-	if len(bytes.TrimSpace(existing)) == 0 {
+	if blankSidecar(existing) {
 		return buildKeywordsSidecar(tags), true, nil
 	}
 
-	doc, err := parseXMP(existing)
-	if err != nil {
-		return nil, false, fmt.Errorf("parse existing xmp: %w", err)
+	text := string(existing)
+	if blocks := subjectBlockRegex.FindAllStringIndex(text, -1); len(blocks) > 0 {
+		var extra []string
+		for _, b := range blocks[1:] {
+			extra = append(extra, liEntryRegex.FindAllString(text[b[0]:b[1]], -1)...)
+		}
+		first := text[blocks[0][0]:blocks[0][1]]
+		if block, changed, ok := mergeSubjectContainer(first, extra, tags, overwrite); ok {
+			if !changed && !overwrite {
+				return nil, false, ErrKeywordsAlreadyPresent
+			}
+			for i := len(blocks) - 1; i > 0; i-- {
+				text = text[:blocks[i][0]] + text[blocks[i][1]:]
+			}
+			text = text[:blocks[0][0]] + block + text[blocks[0][1]:]
+			return []byte(text), true, nil
+		}
 	}
 
-	descIdx := selectDescription(doc.RDF.Descriptions)
-	if descIdx == -1 {
-		descIdx = 0
-		doc.RDF.Descriptions = append(doc.RDF.Descriptions, rdfDescription{})
+	merged, changed := mergeKeywordList(extractKeywords(text), tags, overwrite)
+	if !changed {
+		return nil, false, ErrKeywordsAlreadyPresent
 	}
 
-	desc := doc.RDF.Descriptions[descIdx]
-	desc.Attrs = ensureDCNamespace(desc.Attrs)
+	if blocks := subjectBlockRegex.FindAllStringSubmatchIndex(text, -1); len(blocks) > 0 {
+		// Drop duplicate dc:subject blocks (their keywords are already merged), then replace the first.
+		for i := len(blocks) - 1; i > 0; i-- {
+			text = text[:blocks[i][0]] + text[blocks[i][1]:]
+		}
+		first := blocks[0]
+		indent := text[first[2]:first[3]]
+		text = text[:first[0]] + indentBlock(buildSubjectBlock(merged), indent) + text[first[1]:]
+		return []byte(text), true, nil
+	}
 
-	inner, changed, err := mergeKeywordsInner(desc.Inner, tags, overwrite)
+	text, err := insertDescriptionBlock(text, "dc", dcNamespace, buildSubjectBlock(merged))
 	if err != nil {
 		return nil, false, err
 	}
-	if !changed {
-		return nil, false, ErrKeywordsAlreadyPresent
+	return []byte(text), true, nil
+}
+
+// insertDescriptionBlock adds an element block as the first child of the first
+// rdf:Description, declaring the namespace prefix when the sidecar lacks it.
+func insertDescriptionBlock(text, prefix, uri, block string) (string, error) {
+	loc := descriptionTagRegex.FindStringIndex(text)
+	if loc == nil {
+		return "", fmt.Errorf("parse existing xmp: rdf:Description tag not found")
+	}
+	tag := text[loc[0]:loc[1]]
+	if !namespaceRegex(prefix).MatchString(text) {
+		updated, err := insertTagAttributes(tag, []string{fmt.Sprintf(`xmlns:%s="%s"`, prefix, uri)})
+		if err != nil {
+			return "", err
+		}
+		tag = updated
 	}
-	desc.Inner = inner
-	doc.RDF.Descriptions[descIdx] = desc
 
-	out, err := marshalXMP(doc)
-	if err != nil {
-		return nil, false, fmt.Errorf("marshal merged xmp: %w", err)
+	descIndent := lineIndent(text, loc[0])
+	block = indentBlock(block, descIndent+"  ")
+	if strings.HasSuffix(tag, "/>") {
+		tag = strings.TrimRight(strings.TrimSuffix(tag, "/>"), " \t\r\n") + ">\n" + block + "\n" + descIndent + "</rdf:Description>"
+	} else {
+		tag += "\n" + block
 	}
-	return out, true, nil
+	return text[:loc[0]] + tag + text[loc[1]:], nil
 }
 
-func mergeKeywordsInner(inner string, tags []string, overwrite bool) (string, bool, error) {
-	existing := extractKeywords(inner)
-	// If overwrite is disabled and all tags exist, nothing to do.
+// lineIndent returns the whitespace that starts the line containing offset.
+func lineIndent(text string, offset int) string {
+	lineStart := strings.LastIndex(text[:offset], "\n") + 1
+	i := lineStart
+	for i < offset && (text[i] == ' ' || text[i] == '\t') {
+		i++
+	}
+	return text[lineStart:i]
+}
+
+// mergeKeywordList combines existing keywords with tags. It reports false when
+// overwrite is disabled and every tag is already present.
+func mergeKeywordList(existing, tags []string, overwrite bool) ([]string, bool) {
 	if !overwrite && containsAll(existing, tags) {
-		return inner, false, nil
+		return existing, false
 	}
 
 	merged := make([]string, 0, len(existing)+len(tags))
@@ -122,13 +177,7 @@ func mergeKeywordsInner(inner string, tags []string, overwrite bool) (string, bo
 	}
 
 	sort.Strings(merged)
-
-	trimmed := strings.TrimSpace(stripSubject(inner))
-	subjectBlock := buildSubjectBlock(merged)
-	if trimmed == "" {
-		return subjectBlock, true, nil
-	}
-	return trimmed + "\n" + subjectBlock, true, nil
+	return merged, true
 }
 
 func normalizeTags(tags []string) []string {
@@ -182,9 +231,74 @@ func extractKeywords(inner string) []string {
 	return out
 }
 
-func stripSubject(inner string) string {
-	subjectRe := regexp.MustCompile(`(?is)<dc:subject[^>]*>.*?</dc:subject>`)
-	return strings.TrimSpace(subjectRe.ReplaceAllString(inner, ""))
+// mergeSubjectContainer adds the missing tags to the rdf:Bag, rdf:Seq or rdf:Alt
+// of a dc:subject block in place, so its container type, the order of its
+// entries and their attributes (such as xml:lang) are kept. extra are the rdf:li
+// entries of duplicate dc:subject blocks, moved into this one. With overwrite,
+// entries matching a tag in another case take the tag's spelling. ok is false
+// when the block has no container to merge into.
+func mergeSubjectContainer(block string, extra, tags []string, overwrite bool) (string, bool, bool) {
+	loc := containerRegex.FindStringSubmatchIndex(block)
+	if loc == nil {
+		return "", false, false
+	}
+	body := block[loc[2]:loc[3]]
+
+	spelling := make(map[string]string, len(tags))
+	for _, t := range tags {
+		spelling[strings.ToLower(t)] = t
+	}
+	seen := make(map[string]struct{})
+	changed := false
+	entryIndent := ""
+	body = liEntryRegex.ReplaceAllStringFunc(body, func(entry string) string {
+		m := liEntryRegex.FindStringSubmatch(entry)
+		entryIndent = m[1]
+		value := strings.TrimSpace(htmlUnescape(m[3]))
+		lower := strings.ToLower(value)
+		seen[lower] = struct{}{}
+		if tag, ok := spelling[lower]; ok && overwrite && tag != value {
+			changed = true
+			return m[1] + "<rdf:li" + m[2] + ">" + xmlEscape(tag) + "</rdf:li>"
+		}
+		return entry
+	})
+
+	var added []string
+	for _, entry := range extra {
+		m := liEntryRegex.FindStringSubmatch(entry)
+		lower := strings.ToLower(strings.TrimSpace(htmlUnescape(m[3])))
+		if _, ok := seen[lower]; ok || lower == "" {
+			continue
+		}
+		seen[lower] = struct{}{}
+		added = append(added, strings.TrimLeft(entry, " \t"))
+	}
+	for _, t := range tags {
+		if _, ok := seen[strings.ToLower(t)]; ok {
+			continue
+		}
+		seen[strings.ToLower(t)] = struct{}{}
+		added = append(added, "<rdf:li>"+xmlEscape(t)+"</rdf:li>")
+	}
+	if len(added) == 0 && !changed {
+		return block, false, true
+	}
+
+	// Follow the layout of the container: one entry per line, or all on one line.
+	closeIndent := block[loc[4]:loc[5]]
+	if strings.HasSuffix(strings.TrimRight(body, " \t"), "\n") || (body == "" && closeIndent != "") {
+		if entryIndent == "" {
+			entryIndent = closeIndent + "  "
+		}
+		for _, entry := range added {
+			body += entryIndent + entry + "\n"
+		}
+		body = strings.TrimRight(body, " \t")
+	} else {
+		body += strings.Join(added, "")
+	}
+	return block[:loc[2]] + body + block[loc[4]:], true, true
 }
 
 func buildSubjectBlock(keywords []string) string {
@@ -199,18 +313,6 @@ func buildSubjectBlock(keywords []string) string {
 	return b.String()
 }
 
-func ensureDCNamespace(attrs []xml.Attr) []xml.Attr {
-	for _, attr := range attrs {
-		if attr.Name.Local == "xmlns:dc" || (attr.Name.Space == "xmlns" && attr.Name.Local == "dc") {
-			return attrs
-		}
-	}
-	return append(attrs, xml.Attr{
-		Name:  xml.Name{Space: "xmlns", Local: "dc"},
-		Value: "http://purl.org/dc/elements/1.1/",
-	})
-}
-
 func htmlUnescape(s string) string {
 	replacer := strings.NewReplacer(
 		"&amp;", "&",
@@ -238,7 +340,7 @@ func buildKeywordsSidecar(keywords []string) []byte {
 	b.WriteString(`<?xpacket begin=" " id="W5M0MpCehiHzreSzNTczkc9d"?>`)
 	b.WriteString("\n<x:xmpmeta xmlns:x=\"adobe:ns:meta/\" x:xmptk=\"GeoRAW\">\n")
 	b.WriteString("  <rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
-	b.WriteString("    <rdf:Description rdf:about=\"\" xmlns:dc=\"http://purl.org/dc/elements/1.1/\">\n")
+	b.WriteString("    <rdf:Description rdf:about=\"\" xmlns:dc=\"" + dcNamespace + "\">\n")
 	b.WriteString(indentBlock(buildSubjectBlock(keywords), "      "))
 	b.WriteString("\n    </rdf:Description>\n")
 	b.WriteString("  </rdf:RDF>\n")
@@ -257,3 +359,15 @@ func indentBlock(block, prefix string) string {
 	}
 	return strings.Join(lines, "\n")
 }
+
+// ReadKeywords returns the dc:subject keywords of a sidecar; a missing file has none.
+func ReadKeywords(path string) ([]string, error) {
+	data, err := fsretry.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read sidecar: %w", err)
+	}
+	return extractKeywords(string(data)), nil
+}
@@ -0,0 +1,148 @@
+package xmp
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/nir0k/GeoRAW/internal/fsretry"
+)
+
+// DefaultLang is the xml:lang of the default alternative of a language
+// alternative such as dc:title or dc:description.
+const DefaultLang = "x-default"
+
+var (
+	liLangRegex = regexp.MustCompile(`(?is)<rdf:li\b([^>]*)>(.*?)</rdf:li>`)
+	langAttr    = regexp.MustCompile(`(?i)\bxml:lang\s*=\s*("[^"]*"|'[^']*')`)
+)
+
+// LangValue is one alternative of a language alternative (rdf:Alt).
+type LangValue struct {
+	Lang  string // xml:lang, e.g. "x-default", "en" or "hu"
+	Value string
+}
+
+// SetLangAlt writes values into the rdf:Alt property name (e.g. dc:title) of a
+// sidecar. Alternatives in languages not among values are kept; one already in
+// the sidecar is replaced only when overwrite is true. Everything else in the
+// sidecar is preserved.
+func SetLangAlt(path, name string, values []LangValue, overwrite bool) (bool, error) {
+	existing, err := fsretry.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("read existing sidecar: %w", err)
+	}
+
+	text := string(existing)
+	if blankSidecar(existing) {
+		text = string(buildAttrsSidecar("dc", dcNamespace, nil))
+	}
+	updated, changed, err := setLangAlt(text, name, values, overwrite)
+	if err != nil || !changed {
+		return false, err
+	}
+	if err := writeSidecarFile(path, existing, []byte(updated)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReadLangAlt returns the alternatives of the rdf:Alt property name in a
+// sidecar, in document order; a missing file or property has none.
+func ReadLangAlt(path, name string) ([]LangValue, error) {
+	data, err := fsretry.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read sidecar: %w", err)
+	}
+	text := string(data)
+	loc := arrayBlockRegex(name).FindStringIndex(text)
+	if loc == nil {
+		return nil, nil
+	}
+	return parseLangAlt(text[loc[0]:loc[1]]), nil
+}
+
+// setLangAlt merges values into the rdf:Alt block of name, inserting the block
+// when the sidecar has none. The default alternative is always written first.
+func setLangAlt(text, name string, values []LangValue, overwrite bool) (string, bool, error) {
+	loc := arrayBlockRegex(name).FindStringSubmatchIndex(text)
+	var current []LangValue
+	if loc != nil {
+		current = parseLangAlt(text[loc[0]:loc[1]])
+	}
+
+	merged := append([]LangValue(nil), current...)
+	changed := false
+	for _, v := range values {
+		if v.Value == "" {
+			continue
+		}
+		i := langIndex(merged, v.Lang)
+		switch {
+		case i < 0:
+			merged = append(merged, v)
+			changed = true
+		case merged[i].Value != v.Value && (overwrite || merged[i].Value == ""):
+			merged[i].Value = v.Value
+			changed = true
+		}
+	}
+	if !changed {
+		return text, false, nil
+	}
+	if i := langIndex(merged, DefaultLang); i > 0 {
+		def := merged[i]
+		merged = append(merged[:i], merged[i+1:]...)
+		merged = append([]LangValue{def}, merged...)
+	}
+
+	block := buildLangAltBlock(name, merged)
+	if loc == nil {
+		updated, err := insertDescriptionBlock(text, "dc", dcNamespace, block)
+		if err != nil {
+			return "", false, err
+		}
+		return updated, true, nil
+	}
+	indent := text[loc[2]:loc[3]]
+	return text[:loc[0]] + indentBlock(block, indent) + text[loc[1]:], true, nil
+}
+
+// parseLangAlt reads the rdf:li entries of an rdf:Alt block; an entry without
+// xml:lang counts as the default.
+func parseLangAlt(block string) []LangValue {
+	var out []LangValue
+	for _, m := range liLangRegex.FindAllStringSubmatch(block, -1) {
+		lang := DefaultLang
+		if a := langAttr.FindStringSubmatch(m[1]); a != nil {
+			lang = strings.Trim(a[1], `"'`)
+		}
+		out = append(out, LangValue{Lang: lang, Value: strings.TrimSpace(htmlUnescape(m[2]))})
+	}
+	return out
+}
+
+func langIndex(values []LangValue, lang string) int {
+	for i, v := range values {
+		if strings.EqualFold(v.Lang, lang) {
+			return i
+		}
+	}
+	return -1
+}
+
+func buildLangAltBlock(name string, values []LangValue) string {
+	var b strings.Builder
+	b.WriteString("<" + name + ">\n")
+	b.WriteString("  <rdf:Alt>\n")
+	for _, v := range values {
+		fmt.Fprintf(&b, "    <rdf:li xml:lang=\"%s\">%s</rdf:li>\n", xmlEscape(v.Lang), xmlEscape(v.Value))
+	}
+	b.WriteString("  </rdf:Alt>\n")
+	b.WriteString("</" + name + ">")
+	return b.String()
+}
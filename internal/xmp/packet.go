@@ -0,0 +1,102 @@
+package xmp
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nir0k/GeoRAW/internal/fsretry"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+var packetEndMarker = []byte("<?xpacket end")
+
+// minPacketPadding is the smallest whitespace run before <?xpacket end?> that is
+// treated as deliberate padding rather than a line break.
+const minPacketPadding = 16
+
+// sidecarLayout captures byte-level conventions of an existing sidecar that some
+// readers depend on: a leading UTF-8 BOM and the whitespace padding that lets
+// editors update the packet in place.
+type sidecarLayout struct {
+	bom     bool
+	size    int
+	padding int
+}
+
+func detectLayout(data []byte) sidecarLayout {
+	layout := sidecarLayout{bom: bytes.HasPrefix(data, utf8BOM), size: len(data)}
+	if start, end, ok := paddingSpan(data); ok {
+		layout.padding = end - start
+	}
+	return layout
+}
+
+// apply restores the BOM and, when the original packet was padded, resizes the
+// padding so the file keeps its original size where the new content fits.
+func (l sidecarLayout) apply(out []byte) []byte {
+	if l.bom && !bytes.HasPrefix(out, utf8BOM) {
+		out = append(append([]byte{}, utf8BOM...), out...)
+	}
+	if l.padding < minPacketPadding {
+		return out
+	}
+	start, end, ok := paddingSpan(out)
+	if !ok {
+		return out
+	}
+	want := l.size - (len(out) - (end - start))
+	if want < 1 {
+		want = l.padding
+	}
+	resized := make([]byte, 0, len(out)-(end-start)+want)
+	resized = append(resized, out[:start]...)
+	resized = append(resized, makePadding(want)...)
+	return append(resized, out[end:]...)
+}
+
+// paddingSpan locates the whitespace run right before the xpacket end marker.
+func paddingSpan(data []byte) (int, int, bool) {
+	end := bytes.LastIndex(data, packetEndMarker)
+	if end == -1 {
+		return 0, 0, false
+	}
+	start := end
+	for start > 0 && isXMLSpace(data[start-1]) {
+		start--
+	}
+	return start, end, true
+}
+
+func isXMLSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// makePadding returns n bytes of space-filled lines, starting and ending with a newline.
+func makePadding(n int) []byte {
+	pad := bytes.Repeat([]byte{' '}, n)
+	for i := 0; i < n; i += 100 {
+		pad[i] = '\n'
+	}
+	pad[n-1] = '\n'
+	return pad
+}
+
+// blankSidecar reports whether existing holds no XMP content (missing, empty, or only a BOM).
+func blankSidecar(existing []byte) bool {
+	return len(bytes.TrimSpace(bytes.TrimPrefix(existing, utf8BOM))) == 0
+}
+
+// writeSidecarFile writes payload to path, keeping the BOM and padding conventions of
+// the sidecar it replaces.
+func writeSidecarFile(path string, existing, payload []byte) error {
+	if len(existing) > 0 {
+		payload = detectLayout(existing).apply(payload)
+	}
+	if err := fsretry.Do(func() error { return os.MkdirAll(filepath.Dir(path), 0o755) }); err != nil {
+		return fmt.Errorf("create sidecar dir: %w", err)
+	}
+	return fsretry.WriteFile(path, payload, 0o644)
+}
@@ -0,0 +1,31 @@
+package xmp
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const xmpNamespace = "http://ns.adobe.com/xap/1.0/"
+
+// SetRating writes xmp:Rating into the sidecar, preserving other tags.
+// When overwrite is false an existing rating is kept and false is returned.
+func SetRating(path string, rating int, overwrite bool) (bool, error) {
+	if rating < -1 || rating > 5 {
+		return false, fmt.Errorf("rating %d is out of range (-1..5)", rating)
+	}
+	return writeDescriptionAttrs(path, "xmp", xmpNamespace, []attrValue{
+		{Name: "xmp:Rating", Value: strconv.Itoa(rating)},
+	}, overwrite)
+}
+
+// SetLabel writes the xmp:Label color label (e.g. "Blue") into the sidecar,
+// preserving other tags. When overwrite is false an existing label is kept and
+// false is returned.
+func SetLabel(path, label string, overwrite bool) (bool, error) {
+	if label == "" {
+		return false, fmt.Errorf("label is empty")
+	}
+	return writeDescriptionAttrs(path, "xmp", xmpNamespace, []attrValue{
+		{Name: "xmp:Label", Value: label},
+	}, overwrite)
+}
@@ -0,0 +1,61 @@
+package xmp
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nir0k/GeoRAW/internal/fsretry"
+)
+
+// GeoRAWNamespace holds GeoRAW-specific properties written to sidecars.
+const GeoRAWNamespace = "https://github.com/nir0k/GeoRAW/ns/1.0/"
+
+// SetSeriesPosition records the series ID and the frame's 1-based position within it
+// as georaw:SeriesID, georaw:SeriesIndex and georaw:SeriesCount. When overwrite is
+// false a position the sidecar already has is kept and false is returned.
+func SetSeriesPosition(path, seriesID string, index, count int, overwrite bool) (bool, error) {
+	if index < 1 || count < 1 || index > count {
+		return false, fmt.Errorf("invalid series position %d of %d", index, count)
+	}
+	return writeDescriptionAttrs(path, "georaw", GeoRAWNamespace, []attrValue{
+		{Name: "georaw:SeriesID", Value: seriesID},
+		{Name: "georaw:SeriesIndex", Value: strconv.Itoa(index)},
+		{Name: "georaw:SeriesCount", Value: strconv.Itoa(count)},
+	}, overwrite)
+}
+
+// SetLastRun records the run that last wrote the sidecar as georaw:LastRunID and
+// georaw:LastRunDate.
+func SetLastRun(path, runID string, at time.Time) (bool, error) {
+	return writeDescriptionAttrs(path, "georaw", GeoRAWNamespace, []attrValue{
+		{Name: "georaw:LastRunID", Value: runID},
+		{Name: "georaw:LastRunDate", Value: at.UTC().Format(time.RFC3339)},
+	}, true)
+}
+
+// SetGeotagConfidence records the confidence (0..1) of an interpolated position as
+// georaw:GeotagConfidence.
+func SetGeotagConfidence(path string, confidence float64) (bool, error) {
+	return writeDescriptionAttrs(path, "georaw", GeoRAWNamespace, []attrValue{
+		{Name: "georaw:GeotagConfidence", Value: strconv.FormatFloat(confidence, 'f', 2, 64)},
+	}, true)
+}
+
+// ReadLastRun returns georaw:LastRunID from a sidecar, or "" when absent or unreadable.
+func ReadLastRun(path string) string {
+	data, err := fsretry.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return readDescriptionAttrs(data, []attrValue{{Name: "georaw:LastRunID"}})["georaw:LastRunID"]
+}
+
+// ReadSeriesID returns georaw:SeriesID from a sidecar, or "" when absent or unreadable.
+func ReadSeriesID(path string) string {
+	data, err := fsretry.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return readDescriptionAttrs(data, []attrValue{{Name: "georaw:SeriesID"}})["georaw:SeriesID"]
+}
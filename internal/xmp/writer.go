@@ -1,8 +1,6 @@
 package xmp
 
 import (
-	"bytes"
-	"encoding/xml"
 	"errors"
 	"fmt"
 	"math"
@@ -11,9 +9,12 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/nir0k/GeoRAW/internal/fsretry"
 	"github.com/nir0k/GeoRAW/internal/gpx"
+	"github.com/nir0k/GeoRAW/internal/pathnorm"
 )
 
 // ErrGPSAlreadyPresent is returned when GPS tags already exist and overwriting is disabled.
@@ -21,8 +22,17 @@ var ErrGPSAlreadyPresent = errors.New("gps already present in sidecar")
 
 const exifNamespace = "http://ns.adobe.com/exif/1.0/"
 
-// BuildSidecar returns XMP payload with GPS information.
-func BuildSidecar(coord gpx.Coordinate, ts time.Time) []byte {
+// ErrInvalidCoordinate is returned when a coordinate cannot be written (NaN or infinite values).
+var ErrInvalidCoordinate = errors.New("invalid gps coordinate")
+
+// BuildSidecar returns XMP payload with GPS information, its time stored in
+// timeFormat.
+func BuildSidecar(coord gpx.Coordinate, ts time.Time, timeFormat GPSTimeFormat) ([]byte, error) {
+	coord, err := sanitizeCoordinate(coord)
+	if err != nil {
+		return nil, err
+	}
+
 	latVal, latRef := formatGPSCoordinate(coord.Latitude, "N", "S")
 	lonVal, lonRef := formatGPSCoordinate(coord.Longitude, "E", "W")
 
@@ -40,9 +50,6 @@ func BuildSidecar(coord gpx.Coordinate, ts time.Time) []byte {
 		hasAlt = true
 	}
 
-	gpsDate := ts.UTC().Format("2006:01:02")
-	gpsTime := ts.UTC().Format("15:04:05")
-
 	var builder strings.Builder
 	builder.WriteString(`<?xpacket begin=" " id="W5M0MpCehiHzreSzNTczkc9d"?>`)
 	builder.WriteString("\n<x:xmpmeta xmlns:x=\"adobe:ns:meta/\" x:xmptk=\"GeoRAW\">\n")
@@ -57,15 +64,34 @@ func BuildSidecar(coord gpx.Coordinate, ts time.Time) []byte {
 		builder.WriteString(fmt.Sprintf(" exif:GPSAltitudeRef=\"%d\"", altRef))
 	}
 	builder.WriteString(" exif:GPSVersionID=\"2.3.0.0\"")
-	builder.WriteString(fmt.Sprintf(" exif:GPSDateStamp=\"%s\"", gpsDate))
-	builder.WriteString(fmt.Sprintf(" exif:GPSTimeStamp=\"%s\"", gpsTime))
+	for _, attr := range gpsTimeAttrs(ts, timeFormat) {
+		builder.WriteString(" " + attr)
+	}
 	builder.WriteString(">\n")
 	builder.WriteString("    </rdf:Description>\n")
 	builder.WriteString("  </rdf:RDF>\n")
 	builder.WriteString("</x:xmpmeta>\n")
 	builder.WriteString("<?xpacket end=\"w\"?>")
 
-	return []byte(builder.String())
+	return []byte(builder.String()), nil
+}
+
+// sanitizeCoordinate rejects NaN/Inf values and clamps latitude to ±90 and longitude to ±180,
+// so rounding or interpolation artifacts never reach the sidecar.
+func sanitizeCoordinate(coord gpx.Coordinate) (gpx.Coordinate, error) {
+	if !isFinite(coord.Latitude) || !isFinite(coord.Longitude) {
+		return coord, fmt.Errorf("%w: lat=%v lon=%v", ErrInvalidCoordinate, coord.Latitude, coord.Longitude)
+	}
+	if coord.Altitude != nil && !isFinite(*coord.Altitude) {
+		return coord, fmt.Errorf("%w: alt=%v", ErrInvalidCoordinate, *coord.Altitude)
+	}
+	coord.Latitude = math.Max(-90, math.Min(90, coord.Latitude))
+	coord.Longitude = math.Max(-180, math.Min(180, coord.Longitude))
+	return coord, nil
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
 }
 
 func formatGPSCoordinate(value float64, positiveRef, negativeRef string) (string, string) {
@@ -95,7 +121,8 @@ func formatGPSCoordinate(value float64, positiveRef, negativeRef string) (string
 	return fmt.Sprintf("%s,%s%s", degStr, minStr, ref), ref
 }
 
-// SidecarPath returns the expected XMP filename for a RAW file.
+// SidecarPath returns the expected XMP filename for a RAW file, inside the sidecar
+// folder when one is set with SetSidecarDir.
 func SidecarPath(rawPath string) string {
 	// If path already ends with .xmp (or .XMP), strip it first, then drop the previous extension.
 	path := rawPath
@@ -105,42 +132,254 @@ func SidecarPath(rawPath string) string {
 
 	ext := filepath.Ext(path)
 	if ext == "" {
-		return path + ".xmp"
+		return pathnorm.Existing(relocateSidecar(path + ".xmp"))
+	}
+	return pathnorm.Existing(relocateSidecar(strings.TrimSuffix(path, ext) + ".xmp"))
+}
+
+// CompanionSidecarPath returns the sidecar for a JPEG/HEIF that shares its base name
+// with a RAW file. IMG_0001.xmp belongs to the RAW, so the full file name is kept
+// (IMG_0001.JPG.xmp).
+func CompanionSidecarPath(path string) string {
+	return pathnorm.Existing(relocateSidecar(path + ".xmp"))
+}
+
+// sidecarDir is the separate sidecar tree configured by SetSidecarDir.
+var sidecarDir struct {
+	sync.RWMutex
+	inputRoot string
+	dir       string
+}
+
+// SetSidecarDir makes every sidecar read and write go to dir instead of next to the
+// photo, mirroring the folders below inputRoot (e.g. to keep sidecars off a read-only
+// archive volume). Photos outside inputRoot are mirrored by their absolute path. The
+// setting is process-wide; an empty dir puts sidecars next to the photos again.
+func SetSidecarDir(inputRoot, dir string) error {
+	sidecarDir.Lock()
+	defer sidecarDir.Unlock()
+	if dir == "" {
+		sidecarDir.inputRoot, sidecarDir.dir = "", ""
+		return nil
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("resolve sidecar dir: %w", err)
+	}
+	absRoot, err := filepath.Abs(inputRoot)
+	if err != nil {
+		return fmt.Errorf("resolve input root: %w", err)
+	}
+	sidecarDir.inputRoot, sidecarDir.dir = absRoot, absDir
+	return nil
+}
+
+// relocateSidecar maps a sidecar path next to its photo into the sidecar folder.
+func relocateSidecar(path string) string {
+	sidecarDir.RLock()
+	root, dir := sidecarDir.inputRoot, sidecarDir.dir
+	sidecarDir.RUnlock()
+	if dir == "" {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
 	}
-	return strings.TrimSuffix(path, ext) + ".xmp"
+	if rel, err := filepath.Rel(root, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.Join(dir, rel)
+	}
+	return filepath.Join(dir, strings.TrimPrefix(abs, filepath.VolumeName(abs)))
+}
+
+// SidecarFolder returns the folder the sidecars of photos in dir are written to:
+// dir itself, or its mirror when a sidecar folder is set.
+func SidecarFolder(dir string) string {
+	return relocateSidecar(filepath.Clean(dir))
+}
+
+// PhotoFolder is the reverse of SidecarFolder: the folder whose photos own the
+// sidecars in dir. Without a sidecar folder, or for a dir outside it, that is dir.
+// Mirrors of photos outside the input root are not mapped back.
+func PhotoFolder(dir string) string {
+	sidecarDir.RLock()
+	root, sidecars := sidecarDir.inputRoot, sidecarDir.dir
+	sidecarDir.RUnlock()
+	if sidecars == "" {
+		return dir
+	}
+	rel, ok := below(sidecars, dir)
+	if !ok {
+		return dir
+	}
+	return filepath.Join(root, rel)
+}
+
+// InSidecarDir reports whether path lies in the folder set by SetSidecarDir.
+func InSidecarDir(path string) bool {
+	sidecarDir.RLock()
+	dir := sidecarDir.dir
+	sidecarDir.RUnlock()
+	if dir == "" {
+		return false
+	}
+	_, ok := below(dir, path)
+	return ok
+}
+
+// below returns path relative to dir when path is dir or lies inside it.
+func below(dir, path string) (string, bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
 }
 
 // MergeAndWrite updates or creates an XMP sidecar with GPS tags, preserving other tags.
+// The GPS time is stored in timeFormat. The written position is also recorded as
+// georaw:GPSWritten, see GPSWrittenByGeoRAW, and where it came from as
+// georaw:GPSSource unless source is empty.
 // It returns true if data was written, false if skipped due to existing GPS when overwrite is false.
-func MergeAndWrite(path string, coord gpx.Coordinate, ts time.Time, overwrite bool) (bool, error) {
-	existing, err := os.ReadFile(path)
+func MergeAndWrite(path string, coord gpx.Coordinate, ts time.Time, source GPSSource, timeFormat GPSTimeFormat, overwrite bool) (bool, error) {
+	existing, err := fsretry.ReadFile(path)
 	if err != nil && !os.IsNotExist(err) {
 		return false, fmt.Errorf("read existing sidecar: %w", err)
 	}
 
-	if !overwrite && len(existing) > 0 && hasGPSData(existing) {
+	if _, err := sanitizeCoordinate(coord); err != nil {
+		return false, err
+	}
+	if !overwrite && !blankSidecar(existing) && hasGPSData(existing) {
 		return false, ErrGPSAlreadyPresent
 	}
 
-	payload, err := mergeSidecar(existing, coord, ts)
+	payload, err := mergeSidecar(existing, coord, ts, timeFormat)
 	if err != nil {
 		return false, err
 	}
+	values := []attrValue{{Name: gpsMarkerAttr, Value: gpsMarker(coord)}}
+	if source != "" {
+		values = append(values, attrValue{Name: gpsSourceAttr, Value: string(source)})
+	}
+	payload, err = mergeAttrsInPlace(payload, "georaw", GeoRAWNamespace, values)
+	if err != nil {
+		return false, err
+	}
+
+	if err := writeSidecarFile(path, existing, payload); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// gpsMarkerAttr holds the latitude and longitude GeoRAW last wrote, so GPS set or
+// edited by other tools afterwards is not mistaken for GeoRAW's.
+const gpsMarkerAttr = "georaw:GPSWritten"
+
+// GPSSource says where a position GeoRAW wrote came from. It is recorded as
+// georaw:GPSSource next to the GPS, so later runs and other tools can tell
+// track-matched and hand-set positions from copies of what the camera recorded.
+type GPSSource string
+
+const (
+	GPSSourceInterpolated GPSSource = "gpx-interpolated" // between two track points, or bridged across a gap
+	GPSSourceNearest      GPSSource = "gpx-nearest"      // a track point recorded at the capture time
+	GPSSourceManual       GPSSource = "manual"           // given per photo in a job list, CSV or shutter log
+	GPSSourceMirror       GPSSource = "embedded-mirror"  // copied from GPS embedded in the photo or its paired JPEG
+)
+
+const gpsSourceAttr = "georaw:GPSSource"
+
+func gpsMarker(coord gpx.Coordinate) string {
+	coord, _ = sanitizeCoordinate(coord)
+	lat, _ := formatGPSCoordinate(coord.Latitude, "N", "S")
+	lon, _ := formatGPSCoordinate(coord.Longitude, "E", "W")
+	return lat + " " + lon
+}
+
+// GPSWrittenByGeoRAW reports whether the sidecar's GPS is still the position GeoRAW
+// wrote into it. Sidecars without the marker, or whose coordinates were changed
+// since, report false.
+func GPSWrittenByGeoRAW(path string) bool {
+	data, err := fsretry.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	vals := readDescriptionAttrs(data, []attrValue{
+		{Name: gpsMarkerAttr},
+		{Name: "exif:GPSLatitude"},
+		{Name: "exif:GPSLongitude"},
+	})
+	marker := vals[gpsMarkerAttr]
+	return marker != "" && marker == vals["exif:GPSLatitude"]+" "+vals["exif:GPSLongitude"]
+}
+
+// SetGPSAltitude replaces only the altitude of the sidecar's GPS, keeping the
+// recorded latitude and longitude.
+func SetGPSAltitude(path string, altitude float64) (bool, error) {
+	if !isFinite(altitude) {
+		return false, fmt.Errorf("%w: alt=%v", ErrInvalidCoordinate, altitude)
+	}
+	ref := 0
+	if altitude < 0 {
+		ref = 1
+	}
+	return writeDescriptionAttrs(path, "exif", exifNamespace, []attrValue{
+		{Name: "exif:GPSAltitude", Value: fmt.Sprintf("%0.2f", math.Abs(altitude))},
+		{Name: "exif:GPSAltitudeRef", Value: strconv.Itoa(ref)},
+	}, true)
+}
+
+// gpsAnyAttrRegex matches every exif:GPS attribute and the GeoRAW marker and
+// source of a written position.
+var gpsAnyAttrRegex = regexp.MustCompile(`(?is)\s+(?:exif:GPS[A-Za-z]+|` + gpsMarkerAttr + `|` + gpsSourceAttr + `)\s*=\s*("[^"]*"|'[^']*')`)
 
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return false, fmt.Errorf("create sidecar dir: %w", err)
+// RemoveGPS deletes the GPS of a sidecar, keeping everything else in it. It
+// reports false when the sidecar is missing or has no GPS.
+func RemoveGPS(path string) (bool, error) {
+	existing, err := fsretry.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("read existing sidecar: %w", err)
 	}
-	if err := os.WriteFile(path, payload, 0o644); err != nil {
+	if blankSidecar(existing) || !hasGPSData(existing) {
+		return false, nil
+	}
+	text := stripGPSTagsFromXMP(gpsAnyAttrRegex.ReplaceAllString(string(existing), ""))
+	if err := writeSidecarFile(path, existing, []byte(text)); err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
-func mergeSidecar(existing []byte, coord gpx.Coordinate, ts time.Time) ([]byte, error) {
-	if len(bytes.TrimSpace(existing)) == 0 {
-		return BuildSidecar(coord, ts), nil
+// SetGPSImgDirection records the direction the camera pointed, in degrees from
+// true north, as exif:GPSImgDirection.
+func SetGPSImgDirection(path string, degrees float64) (bool, error) {
+	if !isFinite(degrees) {
+		return false, fmt.Errorf("invalid image direction %v", degrees)
+	}
+	return writeDescriptionAttrs(path, "exif", exifNamespace, []attrValue{
+		{Name: "exif:GPSImgDirection", Value: fmt.Sprintf("%d/100", int(math.Round(degrees*100)))},
+		{Name: "exif:GPSImgDirectionRef", Value: "T"},
+	}, true)
+}
+
+func mergeSidecar(existing []byte, coord gpx.Coordinate, ts time.Time, timeFormat GPSTimeFormat) ([]byte, error) {
+	if blankSidecar(existing) {
+		return BuildSidecar(coord, ts, timeFormat)
+	}
+	coord, err := sanitizeCoordinate(coord)
+	if err != nil {
+		return nil, err
 	}
-	merged, err := mergeGPSInPlace(existing, coord, ts)
+	merged, err := mergeGPSInPlace(existing, coord, ts, timeFormat)
 	if err != nil {
 		return nil, err
 	}
@@ -151,7 +390,7 @@ var descriptionTagRegex = regexp.MustCompile(`(?is)<rdf:Description\b[^>]*>`)
 var gpsAttrRegex = regexp.MustCompile(`(?is)\s+exif:GPS(?:Latitude|LatitudeRef|Longitude|LongitudeRef|Altitude|AltitudeRef|VersionID|DateStamp|TimeStamp)\s*=\s*("[^"]*"|'[^']*')`)
 var exifNamespaceRegex = regexp.MustCompile(`(?is)\bxmlns:exif\s*=\s*("[^"]*"|'[^']*')`)
 
-func mergeGPSInPlace(existing []byte, coord gpx.Coordinate, ts time.Time) ([]byte, error) {
+func mergeGPSInPlace(existing []byte, coord gpx.Coordinate, ts time.Time, timeFormat GPSTimeFormat) ([]byte, error) {
 	text := string(existing)
 	loc := descriptionTagRegex.FindStringIndex(text)
 	if loc == nil {
@@ -159,7 +398,7 @@ func mergeGPSInPlace(existing []byte, coord gpx.Coordinate, ts time.Time) ([]byt
 	}
 
 	tag := text[loc[0]:loc[1]]
-	updatedTag, err := updateDescriptionTag(tag, coord, ts)
+	updatedTag, err := updateDescriptionTag(tag, coord, ts, timeFormat)
 	if err != nil {
 		return nil, err
 	}
@@ -170,7 +409,7 @@ func mergeGPSInPlace(existing []byte, coord gpx.Coordinate, ts time.Time) ([]byt
 	return []byte(updated), nil
 }
 
-func updateDescriptionTag(tag string, coord gpx.Coordinate, ts time.Time) (string, error) {
+func updateDescriptionTag(tag string, coord gpx.Coordinate, ts time.Time, timeFormat GPSTimeFormat) (string, error) {
 	latVal, latRef := formatGPSCoordinate(coord.Latitude, "N", "S")
 	lonVal, lonRef := formatGPSCoordinate(coord.Longitude, "E", "W")
 
@@ -188,9 +427,6 @@ func updateDescriptionTag(tag string, coord gpx.Coordinate, ts time.Time) (strin
 		hasAlt = true
 	}
 
-	gpsDate := ts.UTC().Format("2006:01:02")
-	gpsTime := ts.UTC().Format("15:04:05")
-
 	clean := gpsAttrRegex.ReplaceAllString(tag, "")
 
 	attrs := make([]string, 0, 10)
@@ -203,9 +439,8 @@ func updateDescriptionTag(tag string, coord gpx.Coordinate, ts time.Time) (strin
 		fmt.Sprintf(`exif:GPSLongitude="%s"`, lonVal),
 		fmt.Sprintf(`exif:GPSLongitudeRef="%s"`, lonRef),
 		`exif:GPSVersionID="2.3.0.0"`,
-		fmt.Sprintf(`exif:GPSDateStamp="%s"`, gpsDate),
-		fmt.Sprintf(`exif:GPSTimeStamp="%s"`, gpsTime),
 	)
+	attrs = append(attrs, gpsTimeAttrs(ts, timeFormat)...)
 	if hasAlt {
 		attrs = append(attrs,
 			fmt.Sprintf(`exif:GPSAltitude="%0.2f"`, altVal),
@@ -314,88 +549,3 @@ var gpsTagRegexes = []*regexp.Regexp{
 	regexp.MustCompile(`(?is)<exif:GPSDateStamp[^>]*>.*?</exif:GPSDateStamp>`),
 	regexp.MustCompile(`(?is)<exif:GPSTimeStamp[^>]*>.*?</exif:GPSTimeStamp>`),
 }
-
-type xmpPacket struct {
-	XMLName xml.Name     `xml:"xmpmeta"`
-	Attrs   []xml.Attr   `xml:",any,attr"`
-	RDF     rdfContainer `xml:"RDF"`
-}
-
-type rdfContainer struct {
-	XMLName      xml.Name         `xml:"RDF"`
-	Attrs        []xml.Attr       `xml:",any,attr"`
-	Descriptions []rdfDescription `xml:"Description"`
-}
-
-type rdfDescription struct {
-	XMLName xml.Name   `xml:"Description"`
-	Attrs   []xml.Attr `xml:",any,attr"`
-	Inner   string     `xml:",innerxml"`
-}
-
-func parseXMP(data []byte) (xmpPacket, error) {
-	var pkt xmpPacket
-	if err := xml.Unmarshal(data, &pkt); err == nil && len(pkt.RDF.Descriptions) > 0 {
-		return pkt, nil
-	}
-
-	// Try fallback when root is rdf:RDF without xmpmeta wrapper.
-	var rdfOnly rdfContainer
-	if err := xml.Unmarshal(data, &rdfOnly); err == nil && len(rdfOnly.Descriptions) > 0 {
-		return xmpPacket{
-			XMLName: xml.Name{Local: "xmpmeta"},
-			RDF:     rdfOnly,
-		}, nil
-	}
-
-	return xmpPacket{}, fmt.Errorf("unsupported XMP structure")
-}
-
-func marshalXMP(doc xmpPacket) ([]byte, error) {
-	buf := &bytes.Buffer{}
-	buf.WriteString(`<?xpacket begin=" " id="W5M0MpCehiHzreSzNTczkc9d"?>`)
-	buf.WriteString("\n")
-
-	enc := xml.NewEncoder(buf)
-	enc.Indent("", "  ")
-	if err := enc.Encode(doc); err != nil {
-		return nil, err
-	}
-	if err := enc.Flush(); err != nil {
-		return nil, err
-	}
-
-	buf.WriteString("\n<?xpacket end=\"w\"?>")
-	return buf.Bytes(), nil
-}
-
-func selectDescription(descriptions []rdfDescription) int {
-	if len(descriptions) == 0 {
-		return -1
-	}
-	for i, d := range descriptions {
-		for _, attr := range d.Attrs {
-			if (attr.Name.Space == "xmlns" && attr.Name.Local == "exif") ||
-				attr.Name.Local == "xmlns:exif" ||
-				(attr.Name.Local == "exif" && strings.Contains(attr.Value, exifNamespace)) {
-				return i
-			}
-		}
-	}
-	return 0
-}
-
-func ensureExifNamespace(attrs []xml.Attr) []xml.Attr {
-	for _, attr := range attrs {
-		if attr.Name.Local == "exif" && strings.Contains(attr.Value, exifNamespace) {
-			return attrs
-		}
-		if attr.Name.Local == "xmlns:exif" || (attr.Name.Space == "xmlns" && attr.Name.Local == "exif") {
-			return attrs
-		}
-	}
-	return append(attrs, xml.Attr{
-		Name:  xml.Name{Space: "xmlns", Local: "exif"},
-		Value: exifNamespace,
-	})
-}
@@ -0,0 +1,40 @@
+package xmp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSidecarPathNormalization checks that the sidecar of a RAW is found when
+// its name was written in the other Unicode normalization.
+func TestSidecarPathNormalization(t *testing.T) {
+	const (
+		nfc = "Caf\u00e9"
+		nfd = "Cafe\u0301"
+	)
+	for _, tc := range []struct{ raw, sidecar string }{
+		{nfc, nfd},
+		{nfd, nfc},
+	} {
+		dir := t.TempDir()
+		raw := filepath.Join(dir, tc.raw+".CR3")
+		if got, want := SidecarPath(raw), filepath.Join(dir, tc.raw+".xmp"); got != want {
+			t.Errorf("SidecarPath without a sidecar = %q, want %q", got, want)
+		}
+
+		sidecar := filepath.Join(dir, tc.sidecar+".xmp")
+		companion := filepath.Join(dir, tc.sidecar+".JPG.xmp")
+		for _, path := range []string{sidecar, companion} {
+			if err := os.WriteFile(path, nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := SidecarPath(raw); got != sidecar {
+			t.Errorf("SidecarPath(%q) = %q, want %q", raw, got, sidecar)
+		}
+		if got := CompanionSidecarPath(filepath.Join(dir, tc.raw+".JPG")); got != companion {
+			t.Errorf("CompanionSidecarPath = %q, want %q", got, companion)
+		}
+	}
+}